@@ -1,34 +1,41 @@
 package main
 
 import (
+	"context"
 	"database/sql"
+	"fmt"
 	"log"
 	"net/http"
+	"os"
+	"os/signal"
+	"syscall"
 	"time"
 
 	"anon-backend/internal/config"
 	httpx "anon-backend/internal/http"
+	"anon-backend/internal/security"
 	"anon-backend/internal/store"
+	"anon-backend/internal/ws"
 
 	"github.com/joho/godotenv"
 	_ "github.com/lib/pq"
 )
 
 // startSessionCleanupJob runs a background job to clean up expired sessions
-func startSessionCleanupJob() {
+func startSessionCleanupJob(ctx context.Context) {
 	ticker := time.NewTicker(10 * time.Minute) // Run every 10 minutes
 	defer ticker.Stop()
 
 	// Run immediately on startup
-	cleanupSessions()
+	cleanupSessions(ctx)
 
 	for range ticker.C {
-		cleanupSessions()
+		cleanupSessions(ctx)
 	}
 }
 
-func cleanupSessions() {
-	count, err := store.DefaultStore().CleanupExpiredSessions()
+func cleanupSessions(ctx context.Context) {
+	count, err := store.DefaultStore().CleanupExpiredSessions(ctx)
 	if err != nil {
 		log.Printf("Session cleanup error: %v", err)
 		return
@@ -36,6 +43,121 @@ func cleanupSessions() {
 	if count > 0 {
 		log.Printf("Cleaned up %d expired session(s)", count)
 	}
+
+	// Idle rate limit buckets are full again anyway; a day keeps recent
+	// offenders visible on the abuse dashboard.
+	if _, err := store.DefaultStore().CleanupRateLimitBuckets(ctx, time.Now().Add(-24*time.Hour)); err != nil {
+		log.Printf("Rate limit cleanup error: %v", err)
+	}
+
+	if _, err := store.DefaultStore().CleanupQuotaUsage(ctx, time.Now()); err != nil {
+		log.Printf("Quota cleanup error: %v", err)
+	}
+
+	if _, err := store.DefaultStore().CleanupWSTickets(ctx, time.Now()); err != nil {
+		log.Printf("WS ticket cleanup error: %v", err)
+	}
+}
+
+// startAuditRetentionJob prunes audit entries older than the retention
+// window once a day. Pruning itself is recorded in the audit trail.
+func startAuditRetentionJob(ctx context.Context, retention time.Duration) {
+	if retention <= 0 {
+		return
+	}
+	ticker := time.NewTicker(24 * time.Hour)
+	defer ticker.Stop()
+
+	pruneAuditLogs(ctx, retention)
+	for range ticker.C {
+		pruneAuditLogs(ctx, retention)
+	}
+}
+
+func pruneAuditLogs(ctx context.Context, retention time.Duration) {
+	cutoff := time.Now().Add(-retention)
+	count, err := store.DefaultStore().PruneAuditLogs(ctx, cutoff)
+	if err != nil {
+		log.Printf("Audit retention error: %v", err)
+		return
+	}
+	if count == 0 {
+		return
+	}
+	log.Printf("Pruned %d audit entries older than %s", count, retention)
+	err = store.DefaultStore().LogAuditEvent(ctx, store.AuditLog{
+		Action:  "audit_retention_pruned",
+		ActorID: "system",
+		Details: fmt.Sprintf("pruned %d entries before %s", count, cutoff.UTC().Format(time.RFC3339)),
+	})
+	if err != nil {
+		log.Printf("Audit retention error: %v", err)
+	}
+}
+
+// startTrendingJob recomputes the post rankings served by /posts/trending
+// every cfg.Interval. Every replica runs it; the store drops a run that
+// overlaps another instance's.
+func startTrendingJob(ctx context.Context, cfg config.Trending) {
+	policy := store.RankingPolicy{
+		Like:          cfg.LikeWeight,
+		Dislike:       cfg.DislikeWeight,
+		Comment:       cfg.CommentWeight,
+		Report:        cfg.ReportWeight,
+		HotTimescale:  cfg.HotTimescale,
+		RisingGravity: cfg.RisingGravity,
+	}
+	ticker := time.NewTicker(cfg.Interval)
+	defer ticker.Stop()
+
+	refreshTrending(ctx, policy, cfg.Horizon)
+	for range ticker.C {
+		refreshTrending(ctx, policy, cfg.Horizon)
+	}
+}
+
+func refreshTrending(ctx context.Context, policy store.RankingPolicy, horizon time.Duration) {
+	if _, err := store.RefreshPostScores(ctx, store.DefaultStore(), policy, horizon, time.Now()); err != nil {
+		log.Printf("Trending refresh error: %v", err)
+	}
+}
+
+// reloadKeysOnSignal re-reads the JWT keys on SIGHUP, so a new signing key
+// can be rolled out without a restart. A bad file keeps the current keys.
+func reloadKeysOnSignal(cfg config.Config) {
+	sig := make(chan os.Signal, 1)
+	signal.Notify(sig, syscall.SIGHUP)
+	for range sig {
+		if err := cfg.Keys.Reload(cfg.JWTSecret, cfg.JWTKeysFile); err != nil {
+			log.Printf("JWT key reload failed, keeping current keys: %v", err)
+			continue
+		}
+		log.Println("✓ Reloaded JWT keys")
+	}
+}
+
+// bootstrapAdmin creates a superadmin from ADMIN_EMAIL/ADMIN_PASSWORD when
+// there is no admin account yet. With PostgreSQL, prefer `go run ./cmd/admin`.
+func bootstrapAdmin(ctx context.Context, cfg config.Config) {
+	n, err := store.DefaultStore().CountAdmins(ctx)
+	if err != nil {
+		log.Printf("admin bootstrap: %v", err)
+		return
+	}
+	if n > 0 {
+		return
+	}
+	if cfg.AdminEmail == "" || cfg.AdminPass == "" {
+		log.Println("⚠ No admin accounts; create one with `go run ./cmd/admin create`")
+		return
+	}
+
+	admin, err := store.CreateAdminAccount(ctx, store.DefaultStore(), cfg.AdminEmail, cfg.AdminPass, security.RoleSuperadmin)
+	if err != nil {
+		log.Printf("admin bootstrap: %v", err)
+		return
+	}
+	log.Printf("✓ Created superadmin %s from ADMIN_EMAIL", admin.Email)
 }
 
 func main() {
@@ -46,6 +168,22 @@ func main() {
 
 	cfg := config.Load()
 
+	keys, err := security.LoadKeyring(cfg.JWTSecret, cfg.JWTKeysFile)
+	if err != nil {
+		log.Fatalf("failed to load jwt keys: %v", err)
+	}
+	cfg.Keys = keys
+	go reloadKeysOnSignal(cfg)
+
+	quotaTiers, err := config.LoadQuotaTiers(cfg.QuotaPolicyFile)
+	if err != nil {
+		log.Fatalf("failed to load quota policy: %v", err)
+	}
+	cfg.QuotaTiers = quotaTiers
+
+	// chat hub backplane; stays in-process without Postgres
+	var broker ws.Broker
+
 	// Initialize database if DATABASE_URL is provided
 	if cfg.DatabaseURL != "" {
 		db, err := sql.Open("postgres", cfg.DatabaseURL)
@@ -69,6 +207,14 @@ func main() {
 		pgStore := store.NewPgStore(db)
 		store.Initialize(pgStore)
 		log.Println("✓ Using PostgreSQL store")
+
+		pgBroker, err := ws.NewPgBroker(db, cfg.DatabaseURL)
+		if err != nil {
+			log.Fatalf("failed to start ws broker: %v", err)
+		}
+		defer pgBroker.Close()
+		broker = pgBroker
+		log.Println("✓ Using PostgreSQL LISTEN/NOTIFY for chat fan-out")
 	} else {
 		// Fall back to in-memory store
 		memStore := store.NewMemStore()
@@ -76,17 +222,24 @@ func main() {
 		log.Println("⚠ Using in-memory store (set DATABASE_URL for PostgreSQL)")
 	}
 
+	ctx := context.Background()
+	bootstrapAdmin(ctx, cfg)
+
 	if cfg.EnableSeedData {
-		store.SeedTestData()
+		if err := store.SeedTestData(ctx); err != nil {
+			log.Fatalf("failed to seed test data: %v", err)
+		}
 		log.Println("⚠ Seed test data enabled")
 	}
 
 	// Start session cleanup job
-	go startSessionCleanupJob()
+	go startSessionCleanupJob(ctx)
+	go startAuditRetentionJob(ctx, cfg.AuditRetention)
+	go startTrendingJob(ctx, cfg.Trending)
 
 	srv := &http.Server{
 		Addr:              cfg.Addr,
-		Handler:           httpx.NewRouter(cfg),
+		Handler:           httpx.NewRouter(cfg, broker),
 		ReadHeaderTimeout: 5 * time.Second,
 	}
 
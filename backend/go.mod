@@ -10,3 +10,5 @@ require (
 )
 
 require github.com/joho/godotenv v1.5.1
+
+require golang.org/x/crypto v0.31.0
@@ -5,12 +5,20 @@ import (
 	"strconv"
 	"strings"
 	"time"
+
+	"anon-backend/internal/security"
 )
 
 type Config struct {
-	Addr               string
-	JWTSecret          string
+	Addr        string
+	JWTSecret   string
+	JWTKeysFile string
+	// Keys is loaded by the caller from JWTSecret / JWTKeysFile, see
+	// security.LoadKeyring. It is shared by every copy of the Config.
+	Keys               *security.Keyring
 	JWTTTL             time.Duration
+	RefreshTTL         time.Duration
+	AuditRetention     time.Duration
 	AnonHMACKey        string
 	DatabaseURL        string
 	AdminEmail         string
@@ -18,13 +26,87 @@ type Config struct {
 	MaxSessionsPerUser int
 	CORSAllowedOrigins []string
 	EnableSeedData     bool
+	WSOverflowPolicy   string
+	RateLimits         map[string]RateLimit
+	QuotaPolicyFile    string
+	// QuotaTiers is loaded by the caller from QuotaPolicyFile, see
+	// LoadQuotaTiers.
+	QuotaTiers []QuotaTier
+	Trending   Trending
+	// TOTP checks admin second-factor codes; tests give it a fixed clock.
+	TOTP security.TOTP
+}
+
+// RateLimit allows Requests per Per for one key, refilled continuously.
+type RateLimit struct {
+	Requests int
+	Per      time.Duration
+}
+
+// Rate limit policies; see defaultRateLimits. RATE_LIMITS overrides them as
+// "policy=requests/window,..." (e.g. "comments=20/1m,reports=5/1h"); zero
+// requests disables a policy.
+const (
+	RateLimitDeviceChallenge = "device_challenge"
+	RateLimitBootstrap       = "session_bootstrap"
+	RateLimitRefresh         = "session_refresh"
+	RateLimitDeviceLink      = "device_link"
+	RateLimitDeviceLinkCode  = "device_link_code" // polling and approving a link code
+	RateLimitAdminLogin      = "admin_login"
+	RateLimitComments        = "comments"
+	RateLimitReactions       = "reactions"
+	RateLimitReports         = "reports"
+	RateLimitGeoPing         = "geo_ping"
+	RateLimitWSTicket        = "ws_ticket"
+)
+
+func defaultRateLimits() map[string]RateLimit {
+	return map[string]RateLimit{
+		RateLimitDeviceChallenge: {Requests: 20, Per: time.Minute},
+		RateLimitBootstrap:       {Requests: 10, Per: time.Minute},
+		RateLimitRefresh:         {Requests: 30, Per: time.Minute},
+		RateLimitDeviceLink:      {Requests: 10, Per: time.Minute},
+		RateLimitDeviceLinkCode:  {Requests: 30, Per: time.Minute},
+		RateLimitAdminLogin:      {Requests: 10, Per: 15 * time.Minute},
+		RateLimitComments:        {Requests: 30, Per: time.Minute},
+		RateLimitReactions:       {Requests: 120, Per: time.Minute},
+		RateLimitReports:         {Requests: 10, Per: time.Hour},
+		RateLimitGeoPing:         {Requests: 12, Per: time.Minute},
+		RateLimitWSTicket:        {Requests: 30, Per: time.Minute},
+	}
+}
+
+func parseRateLimits(value string) map[string]RateLimit {
+	limits := defaultRateLimits()
+	for _, entry := range splitCSV(value) {
+		name, rule, ok := strings.Cut(entry, "=")
+		if !ok {
+			continue
+		}
+		n, per, ok := strings.Cut(rule, "/")
+		if !ok {
+			continue
+		}
+		requests, err := strconv.Atoi(strings.TrimSpace(n))
+		if err != nil || requests < 0 {
+			continue
+		}
+		window, err := time.ParseDuration(strings.TrimSpace(per))
+		if err != nil || window <= 0 {
+			continue
+		}
+		limits[strings.TrimSpace(name)] = RateLimit{Requests: requests, Per: window}
+	}
+	return limits
 }
 
 func Load() Config {
-	adminEmail := getenv("ADMIN_EMAIL", "papa@gmail.com")
-	adminPass := getenv("ADMIN_PASSWORD", "papa@")
+	// Only used to create the first superadmin when no admin account exists.
+	adminEmail := getenv("ADMIN_EMAIL", "")
+	adminPass := getenv("ADMIN_PASSWORD", "")
 	addr := getenv("ADDR", ":8080")
 	jwtSecret := getenv("JWT_SECRET", "dev_secret_change_me")
+	jwtKeysFile := getenv("JWT_KEYS_FILE", "")
 	anonKey := getenv("ANON_HMAC_KEY", "dev_anon_hmac_change_me")
 	dbURL := getenv("DATABASE_URL", "")
 	if dbURL == "" {
@@ -37,6 +119,17 @@ func Load() Config {
 		d = 30 * time.Minute
 	}
 
+	refreshTTL, err := time.ParseDuration(getenv("REFRESH_TTL", "720h"))
+	if err != nil || refreshTTL <= 0 {
+		refreshTTL = 720 * time.Hour
+	}
+
+	// 0 keeps audit entries forever.
+	auditRetention, err := time.ParseDuration(getenv("AUDIT_RETENTION", "8760h"))
+	if err != nil || auditRetention < 0 {
+		auditRetention = 8760 * time.Hour
+	}
+
 	maxSessions := 5
 	if maxSessionsStr := getenv("MAX_SESSIONS_PER_USER", "5"); maxSessionsStr != "" {
 		if n, err := strconv.Atoi(maxSessionsStr); err == nil && n > 0 {
@@ -51,10 +144,18 @@ func Load() Config {
 
 	enableSeedData := strings.EqualFold(getenv("ENABLE_SEED_DATA", "false"), "true")
 
+	wsOverflowPolicy := strings.ToLower(getenv("WS_OVERFLOW_POLICY", "spill"))
+
+	rateLimits := parseRateLimits(getenv("RATE_LIMITS", ""))
+	quotaPolicyFile := getenv("QUOTA_POLICY_FILE", "")
+
 	return Config{
 		Addr:               addr,
 		JWTSecret:          jwtSecret,
+		JWTKeysFile:        jwtKeysFile,
 		JWTTTL:             d,
+		RefreshTTL:         refreshTTL,
+		AuditRetention:     auditRetention,
 		AnonHMACKey:        anonKey,
 		DatabaseURL:        dbURL,
 		AdminEmail:         adminEmail,
@@ -62,6 +163,12 @@ func Load() Config {
 		MaxSessionsPerUser: maxSessions,
 		CORSAllowedOrigins: corsAllowedOrigins,
 		EnableSeedData:     enableSeedData,
+		WSOverflowPolicy:   wsOverflowPolicy,
+		RateLimits:         rateLimits,
+		QuotaPolicyFile:    quotaPolicyFile,
+		QuotaTiers:         defaultQuotaTiers(),
+		Trending:           loadTrending(),
+		TOTP:               security.DefaultTOTP(),
 	}
 }
 
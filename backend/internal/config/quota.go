@@ -0,0 +1,215 @@
+package config
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"sort"
+	"strings"
+	"time"
+)
+
+// Quota resources and windows. Windows are calendar periods in UTC: the
+// hour, the day, and the ISO week starting Monday.
+const (
+	QuotaPosts    = "posts"
+	QuotaComments = "comments"
+	QuotaReplies  = "replies"
+
+	QuotaHour = "hour"
+	QuotaDay  = "day"
+	QuotaWeek = "week"
+)
+
+// QuotaTier is a set of posting limits for the accounts matching it. Tiers
+// are tried in order against the author's profile and the first match
+// applies; a resource the tier does not mention is unlimited.
+type QuotaTier struct {
+	Name          string
+	MinAccountAge time.Duration
+	MinTrustScore int
+	// StatusLabels limits the tier to profiles with one of these labels;
+	// empty matches any label.
+	StatusLabels []string
+	Limits       []QuotaLimit
+}
+
+// QuotaLimit allows Max creations of Resource per Window.
+type QuotaLimit struct {
+	Resource string
+	Window   string
+	Max      int
+}
+
+// Matches reports whether an account with the given age, trust score and
+// status label falls in the tier.
+func (t QuotaTier) Matches(accountAge time.Duration, trustScore int, statusLabel string) bool {
+	if accountAge < t.MinAccountAge || trustScore < t.MinTrustScore {
+		return false
+	}
+	if len(t.StatusLabels) == 0 {
+		return true
+	}
+	for _, label := range t.StatusLabels {
+		if strings.EqualFold(label, statusLabel) {
+			return true
+		}
+	}
+	return false
+}
+
+// LimitsFor returns the tier's limits on one resource.
+func (t QuotaTier) LimitsFor(resource string) []QuotaLimit {
+	out := make([]QuotaLimit, 0, 3)
+	for _, l := range t.Limits {
+		if l.Resource == resource {
+			out = append(out, l)
+		}
+	}
+	return out
+}
+
+// QuotaTierFor picks the first tier matching the account. The last default
+// tier matches everyone; a custom policy without a catch-all leaves
+// unmatched accounts unlimited.
+func (c Config) QuotaTierFor(accountAge time.Duration, trustScore int, statusLabel string) QuotaTier {
+	for _, t := range c.QuotaTiers {
+		if t.Matches(accountAge, trustScore, statusLabel) {
+			return t
+		}
+	}
+	return QuotaTier{Name: "unlimited"}
+}
+
+func defaultQuotaTiers() []QuotaTier {
+	return []QuotaTier{
+		{
+			Name:         "restricted",
+			StatusLabels: []string{"Flagged", "Under Review"},
+			Limits: []QuotaLimit{
+				{QuotaPosts, QuotaDay, 1},
+				{QuotaPosts, QuotaWeek, 3},
+				{QuotaComments, QuotaHour, 5},
+				{QuotaComments, QuotaDay, 20},
+				{QuotaReplies, QuotaHour, 5},
+				{QuotaReplies, QuotaDay, 20},
+			},
+		},
+		{
+			Name:          "trusted",
+			MinAccountAge: 30 * 24 * time.Hour,
+			MinTrustScore: 50,
+			Limits: []QuotaLimit{
+				{QuotaPosts, QuotaHour, 5},
+				{QuotaPosts, QuotaDay, 10},
+				{QuotaComments, QuotaDay, 300},
+				{QuotaReplies, QuotaDay, 300},
+			},
+		},
+		{
+			Name:          "established",
+			MinAccountAge: 7 * 24 * time.Hour,
+			Limits: []QuotaLimit{
+				{QuotaPosts, QuotaDay, 5},
+				{QuotaPosts, QuotaWeek, 25},
+				{QuotaComments, QuotaHour, 40},
+				{QuotaComments, QuotaDay, 150},
+				{QuotaReplies, QuotaHour, 40},
+				{QuotaReplies, QuotaDay, 150},
+			},
+		},
+		{
+			Name: "new",
+			Limits: []QuotaLimit{
+				{QuotaPosts, QuotaDay, 3},
+				{QuotaComments, QuotaHour, 20},
+				{QuotaComments, QuotaDay, 50},
+				{QuotaReplies, QuotaHour, 20},
+				{QuotaReplies, QuotaDay, 50},
+			},
+		},
+	}
+}
+
+// quotaPolicyFile is the JSON form of QUOTA_POLICY_FILE:
+//
+//	{"tiers": [{"name": "new", "min_account_age": "0s", "min_trust_score": 0,
+//	  "status_labels": [], "limits": {"posts": {"day": 3}, "comments": {"hour": 20}}}]}
+type quotaPolicyFile struct {
+	Tiers []struct {
+		Name          string                    `json:"name"`
+		MinAccountAge string                    `json:"min_account_age"`
+		MinTrustScore int                       `json:"min_trust_score"`
+		StatusLabels  []string                  `json:"status_labels"`
+		Limits        map[string]map[string]int `json:"limits"`
+	} `json:"tiers"`
+}
+
+// LoadQuotaTiers reads the tiers from path, or returns the defaults when path
+// is empty.
+func LoadQuotaTiers(path string) ([]QuotaTier, error) {
+	if path == "" {
+		return defaultQuotaTiers(), nil
+	}
+
+	raw, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("read quota policy: %w", err)
+	}
+	var f quotaPolicyFile
+	if err := json.Unmarshal(raw, &f); err != nil {
+		return nil, fmt.Errorf("parse quota policy: %w", err)
+	}
+
+	tiers := make([]QuotaTier, 0, len(f.Tiers))
+	for i, ft := range f.Tiers {
+		t := QuotaTier{Name: ft.Name, MinTrustScore: ft.MinTrustScore, StatusLabels: ft.StatusLabels}
+		if t.Name == "" {
+			t.Name = fmt.Sprintf("tier%d", i+1)
+		}
+		if ft.MinAccountAge != "" {
+			age, err := time.ParseDuration(ft.MinAccountAge)
+			if err != nil || age < 0 {
+				return nil, fmt.Errorf("quota tier %s: invalid min_account_age %q", t.Name, ft.MinAccountAge)
+			}
+			t.MinAccountAge = age
+		}
+		for resource, windows := range ft.Limits {
+			switch resource {
+			case QuotaPosts, QuotaComments, QuotaReplies:
+			default:
+				return nil, fmt.Errorf("quota tier %s: unknown resource %q", t.Name, resource)
+			}
+			for window, n := range windows {
+				switch window {
+				case QuotaHour, QuotaDay, QuotaWeek:
+				default:
+					return nil, fmt.Errorf("quota tier %s: unknown window %q", t.Name, window)
+				}
+				if n < 0 {
+					return nil, fmt.Errorf("quota tier %s: negative %s/%s limit", t.Name, resource, window)
+				}
+				t.Limits = append(t.Limits, QuotaLimit{Resource: resource, Window: window, Max: n})
+			}
+		}
+		sort.Slice(t.Limits, func(a, b int) bool {
+			if t.Limits[a].Resource != t.Limits[b].Resource {
+				return t.Limits[a].Resource < t.Limits[b].Resource
+			}
+			return quotaWindowOrder(t.Limits[a].Window) < quotaWindowOrder(t.Limits[b].Window)
+		})
+		tiers = append(tiers, t)
+	}
+	return tiers, nil
+}
+
+func quotaWindowOrder(window string) int {
+	switch window {
+	case QuotaHour:
+		return 0
+	case QuotaDay:
+		return 1
+	default:
+		return 2
+	}
+}
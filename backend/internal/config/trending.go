@@ -0,0 +1,76 @@
+package config
+
+import (
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Trending tunes the ranking job behind /posts/trending. The defaults match
+// store.DefaultRankingPolicy.
+type Trending struct {
+	// Interval is how often scores are recomputed.
+	Interval time.Duration
+	// Horizon limits scoring to posts created this recently; 0 scores all.
+	Horizon time.Duration
+	// Weights of likes, dislikes, comments and reports, see TRENDING_WEIGHTS.
+	LikeWeight    float64
+	DislikeWeight float64
+	CommentWeight float64
+	ReportWeight  float64
+	HotTimescale  time.Duration
+	RisingGravity float64
+}
+
+func defaultTrending() Trending {
+	return Trending{
+		Interval:      5 * time.Minute,
+		Horizon:       30 * 24 * time.Hour,
+		LikeWeight:    1,
+		DislikeWeight: 1,
+		CommentWeight: 2,
+		ReportWeight:  5,
+		HotTimescale:  45000 * time.Second,
+		RisingGravity: 1.8,
+	}
+}
+
+func loadTrending() Trending {
+	t := defaultTrending()
+	if d, err := time.ParseDuration(getenv("TRENDING_INTERVAL", "")); err == nil && d > 0 {
+		t.Interval = d
+	}
+	if d, err := time.ParseDuration(getenv("TRENDING_HORIZON", "")); err == nil && d >= 0 {
+		t.Horizon = d
+	}
+	if d, err := time.ParseDuration(getenv("TRENDING_HOT_TIMESCALE", "")); err == nil && d > 0 {
+		t.HotTimescale = d
+	}
+	if g, err := strconv.ParseFloat(getenv("TRENDING_RISING_GRAVITY", ""), 64); err == nil && g >= 0 {
+		t.RisingGravity = g
+	}
+
+	// TRENDING_WEIGHTS is "like=1,dislike=1,comment=2,report=5"; names
+	// left out keep their default.
+	for _, entry := range splitCSV(getenv("TRENDING_WEIGHTS", "")) {
+		name, value, ok := strings.Cut(entry, "=")
+		if !ok {
+			continue
+		}
+		w, err := strconv.ParseFloat(strings.TrimSpace(value), 64)
+		if err != nil || w < 0 {
+			continue
+		}
+		switch strings.TrimSpace(name) {
+		case "like":
+			t.LikeWeight = w
+		case "dislike":
+			t.DislikeWeight = w
+		case "comment":
+			t.CommentWeight = w
+		case "report":
+			t.ReportWeight = w
+		}
+	}
+	return t
+}
@@ -1,11 +1,16 @@
 package http
 
 import (
+	"errors"
 	"net/http"
 	"strings"
+	"time"
 
 	"anon-backend/internal/config"
+	"anon-backend/internal/httpctx"
 	"anon-backend/internal/security"
+	"anon-backend/internal/session"
+	"anon-backend/internal/store"
 )
 
 // AdminAuth middleware checks for admin session token in Authorization header
@@ -19,11 +24,45 @@ func AdminAuth(cfg config.Config) func(http.Handler) http.Handler {
 			}
 
 			token := strings.TrimPrefix(auth, "Bearer ")
-			if _, err := security.VerifyAdminJWT(cfg.JWTSecret, token); err != nil {
+			claims, err := security.VerifyAdminJWT(cfg.Keys, token)
+			if err != nil {
 				http.Error(w, "unauthorized", http.StatusUnauthorized)
 				return
 			}
 
+			// the token is only as good as the account behind it: a
+			// disabled admin, or one whose role changed, logs in again
+			admin, err := session.Admin(r.Context(), claims.Subject, time.Now())
+			if err != nil {
+				if errors.Is(err, store.ErrAdminNotFound) {
+					http.Error(w, "unauthorized", http.StatusUnauthorized)
+					return
+				}
+				http.Error(w, "failed to load admin", http.StatusInternalServerError)
+				return
+			}
+			if admin.Disabled || admin.Role != claims.Role {
+				http.Error(w, "unauthorized", http.StatusUnauthorized)
+				return
+			}
+
+			ctx := httpctx.WithAdminClaims(r.Context(), claims)
+			next.ServeHTTP(w, r.WithContext(ctx))
+		})
+	}
+}
+
+// RequireAdminRole lets a request through only if the admin authenticated
+// by AdminAuth has role or a more privileged one. AdminAuth has checked
+// the claimed role against the stored one.
+func RequireAdminRole(role string) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			claims := httpctx.AdminClaimsFromContext(r.Context())
+			if claims == nil || !security.AdminRoleAllows(claims.Role, role) {
+				http.Error(w, "forbidden: requires "+role, http.StatusForbidden)
+				return
+			}
 			next.ServeHTTP(w, r)
 		})
 	}
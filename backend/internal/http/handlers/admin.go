@@ -2,6 +2,7 @@ package handlers
 
 import (
 	"encoding/json"
+	"errors"
 	"fmt"
 	"log"
 	"net/http"
@@ -10,8 +11,11 @@ import (
 	"time"
 
 	"anon-backend/internal/config"
+	"anon-backend/internal/httpctx"
 	"anon-backend/internal/security"
+	"anon-backend/internal/session"
 	"anon-backend/internal/store"
+	"anon-backend/internal/ws"
 )
 
 // ============ RESPONSE TYPES ============
@@ -58,6 +62,9 @@ type AbuseReport struct {
 	LastPostAt   string      `json:"last_post_at"`
 	RateStatus   string      `json:"rate_status"` // "normal", "warning", "blocked"
 	ReportedPost *ReportInfo `json:"reported_post,omitempty"`
+	// RateLimitHits counts requests rejected by the rate limiter in the
+	// last day, across all policies.
+	RateLimitHits int64 `json:"rate_limit_hits"`
 }
 
 type ReportInfo struct {
@@ -72,8 +79,25 @@ type AdminLoginRequest struct {
 	Password string `json:"password"`
 }
 
+// AdminLoginResponse carries the admin token, or for accounts with TOTP
+// enabled only an MFA token to be exchanged at /admin/login/totp.
 type AdminLoginResponse struct {
-	Token string `json:"token"`
+	Token       string `json:"token,omitempty"`
+	Email       string `json:"email"`
+	Role        string `json:"role,omitempty"`
+	TOTPEnabled bool   `json:"totp_enabled"`
+	MFARequired bool   `json:"mfa_required,omitempty"`
+	MFAToken    string `json:"mfa_token,omitempty"`
+}
+
+type AdminAccountDTO struct {
+	ID          string `json:"id"`
+	Email       string `json:"email"`
+	Role        string `json:"role"`
+	Disabled    bool   `json:"disabled"`
+	TOTPEnabled bool   `json:"totp_enabled"`
+	CreatedAt   string `json:"created_at"`
+	LastLoginAt string `json:"last_login_at,omitempty"`
 }
 
 // ============ ADMIN ENDPOINTS ============
@@ -91,26 +115,151 @@ func AdminLogin(cfg config.Config) http.HandlerFunc {
 			return
 		}
 
-		email := strings.TrimSpace(req.Email)
-		if email != cfg.AdminEmail || req.Password != cfg.AdminPass {
+		admin, err := store.DefaultStore().GetAdminByEmail(r.Context(), req.Email)
+		if err != nil {
+			if !errors.Is(err, store.ErrAdminNotFound) {
+				http.Error(w, "failed to load admin", http.StatusInternalServerError)
+				return
+			}
+			security.CheckPasswordDummy(req.Password)
+			http.Error(w, "unauthorized", http.StatusUnauthorized)
+			return
+		}
+		if !security.CheckPassword(admin.PasswordHash, req.Password) || admin.Disabled {
 			http.Error(w, "unauthorized", http.StatusUnauthorized)
 			return
 		}
 
-		token, err := security.SignAdminJWT(cfg.JWTSecret, cfg.JWTTTL, email)
+		if admin.TOTPEnabled {
+			mfaToken, err := security.SignAdminMFAJWT(cfg.Keys, adminMFATTL, admin.ID)
+			if err != nil {
+				http.Error(w, "failed to sign mfa token", http.StatusInternalServerError)
+				return
+			}
+			w.Header().Set("Content-Type", "application/json")
+			json.NewEncoder(w).Encode(AdminLoginResponse{
+				Email:       admin.Email,
+				TOTPEnabled: true,
+				MFARequired: true,
+				MFAToken:    mfaToken,
+			})
+			return
+		}
+
+		writeAdminLogin(w, r, cfg, admin)
+	}
+}
+
+// writeAdminLogin issues the admin token once every factor has been checked.
+func writeAdminLogin(w http.ResponseWriter, r *http.Request, cfg config.Config, admin *store.Admin) {
+	token, err := security.SignAdminJWT(cfg.Keys, cfg.JWTTTL, admin.ID, admin.Email, admin.Role)
+	if err != nil {
+		http.Error(w, "failed to sign admin token", http.StatusInternalServerError)
+		return
+	}
+	_ = store.DefaultStore().UpdateAdminLastLogin(r.Context(), admin.ID, time.Now())
+	recordAudit(r, store.AuditLog{
+		Action:     "admin_login",
+		ActorID:    admin.ID,
+		ActorEmail: admin.Email,
+		ActorRole:  admin.Role,
+		Target:     "admin:" + admin.ID,
+	})
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(AdminLoginResponse{
+		Token:       token,
+		Email:       admin.Email,
+		Role:        admin.Role,
+		TOTPEnabled: admin.TOTPEnabled,
+	})
+}
+
+// AdminListAdmins lists staff accounts (superadmin only).
+func AdminListAdmins(cfg config.Config) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		admins, err := store.DefaultStore().ListAdmins(r.Context())
 		if err != nil {
-			http.Error(w, "failed to sign admin token", http.StatusInternalServerError)
+			http.Error(w, "failed to list admins", http.StatusInternalServerError)
 			return
 		}
 
+		out := make([]AdminAccountDTO, len(admins))
+		for i, a := range admins {
+			out[i] = AdminAccountDTO{
+				ID:          a.ID,
+				Email:       a.Email,
+				Role:        a.Role,
+				Disabled:    a.Disabled,
+				TOTPEnabled: a.TOTPEnabled,
+				CreatedAt:   a.CreatedAt.Format(time.RFC3339),
+			}
+			if a.LastLoginAt != nil {
+				out[i].LastLoginAt = a.LastLoginAt.Format(time.RFC3339)
+			}
+		}
+
 		w.Header().Set("Content-Type", "application/json")
-		json.NewEncoder(w).Encode(AdminLoginResponse{Token: token})
+		json.NewEncoder(w).Encode(map[string]interface{}{
+			"admins": out,
+			"total":  len(out),
+		})
+	}
+}
+
+// AdminCreateAdmin creates a staff account (superadmin only).
+func AdminCreateAdmin(cfg config.Config) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		var req struct {
+			Email    string `json:"email"`
+			Password string `json:"password"`
+			Role     string `json:"role"`
+		}
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			http.Error(w, "bad json", http.StatusBadRequest)
+			return
+		}
+
+		admin, err := store.CreateAdminAccount(r.Context(), store.DefaultStore(), req.Email, req.Password, strings.TrimSpace(req.Role))
+		if err != nil {
+			switch {
+			case errors.Is(err, store.ErrAdminExists):
+				http.Error(w, "admin already exists", http.StatusConflict)
+			case errors.Is(err, security.ErrPasswordTooShort):
+				http.Error(w, fmt.Sprintf("password must be at least %d characters", security.MinAdminPasswordLen), http.StatusBadRequest)
+			case errors.Is(err, store.ErrInvalidAdminAccount):
+				http.Error(w, err.Error(), http.StatusBadRequest)
+			default:
+				http.Error(w, "failed to create admin", http.StatusInternalServerError)
+			}
+			return
+		}
+
+		recordAudit(r, store.AuditLog{
+			Action:  "create_admin",
+			Target:  "admin:" + admin.ID,
+			After:   auditPayload(map[string]string{"email": admin.Email, "role": admin.Role}),
+			Details: fmt.Sprintf("email=%s, role=%s", admin.Email, admin.Role),
+		})
+
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusCreated)
+		json.NewEncoder(w).Encode(AdminAccountDTO{
+			ID:        admin.ID,
+			Email:     admin.Email,
+			Role:      admin.Role,
+			CreatedAt: admin.CreatedAt.Format(time.RFC3339),
+		})
 	}
 }
 
 func AdminGetPosts(cfg config.Config) http.HandlerFunc {
 	return func(w http.ResponseWriter, r *http.Request) {
-		posts := store.DefaultStore().GetFeed(1000)
+		posts, err := store.DefaultStore().GetFeed(r.Context(), 1000)
+		if err != nil {
+			http.Error(w, "failed to load posts", http.StatusInternalServerError)
+			return
+		}
 
 		out := make([]AdminPostDTO, len(posts))
 		for i, p := range posts {
@@ -132,18 +281,31 @@ func AdminGetPosts(cfg config.Config) http.HandlerFunc {
 
 func AdminGetUsers(cfg config.Config) http.HandlerFunc {
 	return func(w http.ResponseWriter, r *http.Request) {
-		users := store.DefaultStore().GetAllUsers()
-		posts := store.DefaultStore().GetFeed(10000)
+		users, err := store.DefaultStore().GetAllUsers(r.Context())
+		if err != nil {
+			http.Error(w, "failed to load users", http.StatusInternalServerError)
+			return
+		}
+		posts, err := store.DefaultStore().GetFeed(r.Context(), 10000)
+		if err != nil {
+			http.Error(w, "failed to load posts", http.StatusInternalServerError)
+			return
+		}
 		now := time.Now()
 
 		reportsByUser := make(map[string]int)
 		for _, post := range posts {
-			reportsByUser[post.AnonID] += store.DefaultStore().GetPostReportCount(post.ID)
+			count, err := store.DefaultStore().GetPostReportCount(r.Context(), post.ID)
+			if err != nil {
+				http.Error(w, "failed to load reports", http.StatusInternalServerError)
+				return
+			}
+			reportsByUser[post.AnonID] += count
 		}
 
 		out := make([]AdminUserDTO, len(users))
 		for i, u := range users {
-			activeBan, err := store.DefaultStore().GetActiveUserBan(u.AnonID, now)
+			activeBan, err := store.DefaultStore().GetActiveUserBan(r.Context(), u.AnonID, now)
 			if err != nil {
 				http.Error(w, "failed to load user bans", http.StatusInternalServerError)
 				return
@@ -179,7 +341,7 @@ func AdminGetUsers(cfg config.Config) http.HandlerFunc {
 	}
 }
 
-func AdminBanUser(cfg config.Config) http.HandlerFunc {
+func AdminBanUser(cfg config.Config, hub *ws.Hub) http.HandlerFunc {
 	return func(w http.ResponseWriter, r *http.Request) {
 		if r.Method != http.MethodPost {
 			http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
@@ -204,7 +366,7 @@ func AdminBanUser(cfg config.Config) http.HandlerFunc {
 		}
 
 		now := time.Now()
-		activeBan, err := store.DefaultStore().GetActiveUserBan(req.AnonID, now)
+		activeBan, err := store.DefaultStore().GetActiveUserBan(r.Context(), req.AnonID, now)
 		if err != nil {
 			http.Error(w, "failed to verify existing ban", http.StatusInternalServerError)
 			return
@@ -220,24 +382,36 @@ func AdminBanUser(cfg config.Config) http.HandlerFunc {
 			return
 		}
 
-		if err := store.DefaultStore().CreateUserBan(req.AnonID, "admin moderation ban", "admin", now, expiresAt, permanent); err != nil {
+		bannedBy := "admin"
+		if admin := httpctx.AdminClaimsFromContext(r.Context()); admin != nil {
+			bannedBy = admin.Email
+		}
+		if err := store.DefaultStore().CreateUserBan(r.Context(), req.AnonID, "admin moderation ban", bannedBy, now, expiresAt, permanent); err != nil {
 			http.Error(w, "failed to create user ban", http.StatusInternalServerError)
 			return
 		}
 
-		revoked, err := store.DefaultStore().RevokeAllSessionsForUser(req.AnonID)
+		revoked, err := store.DefaultStore().RevokeAllSessionsForUser(r.Context(), req.AnonID)
 		if err != nil {
 			http.Error(w, "failed to revoke user sessions", http.StatusInternalServerError)
 			return
 		}
+		session.Forget(req.AnonID)
+		hub.Disconnect(req.AnonID, ws.CloseUserBanned, "user banned")
 
 		details := fmt.Sprintf("anon_id=%s, duration=%s, permanent=%t", req.AnonID, req.BanDuration, permanent)
 		if expiresAt != nil {
 			details = fmt.Sprintf("%s, expires_at=%s", details, expiresAt.Format(time.RFC3339))
 		}
-		store.DefaultStore().LogAuditEvent(store.AuditLog{
+		after := map[string]interface{}{"permanent": permanent, "sessions_revoked": revoked}
+		if expiresAt != nil {
+			after["expires_at"] = expiresAt.Format(time.RFC3339)
+		}
+		recordAudit(r, store.AuditLog{
 			Action:  "ban_user",
-			AnonID:  "admin",
+			AnonID:  req.AnonID,
+			Target:  "user:" + req.AnonID,
+			After:   auditPayload(after),
 			Details: details,
 		})
 
@@ -319,17 +493,33 @@ func AdminDeletePost(cfg config.Config) http.HandlerFunc {
 			return
 		}
 
-		err := store.DefaultStore().DeletePost(req.PostID)
+		post, err := store.DefaultStore().GetPost(r.Context(), req.PostID)
+		if errors.Is(err, store.ErrNotFound) {
+			http.Error(w, "post not found", http.StatusNotFound)
+			return
+		}
 		if err != nil {
-			http.Error(w, "failed to delete post: "+err.Error(), http.StatusInternalServerError)
+			http.Error(w, "failed to load post", http.StatusInternalServerError)
 			return
 		}
-
-		store.DefaultStore().LogAuditEvent(store.AuditLog{
+		event := store.AuditLog{
 			Action:  "admin_delete_post",
-			AnonID:  "admin",
+			AnonID:  post.AnonID,
+			Target:  "post:" + req.PostID,
 			Details: req.PostID,
-		})
+			Before: auditPayload(map[string]interface{}{
+				"anon_id":    post.AnonID,
+				"text":       post.Text,
+				"created_at": post.CreatedAt.Format(time.RFC3339),
+			}),
+		}
+
+		if err := store.DefaultStore().DeletePost(r.Context(), req.PostID); err != nil {
+			http.Error(w, "failed to delete post: "+err.Error(), http.StatusInternalServerError)
+			return
+		}
+
+		recordAudit(r, event)
 
 		w.Header().Set("Content-Type", "application/json")
 		json.NewEncoder(w).Encode(map[string]string{
@@ -341,16 +531,25 @@ func AdminDeletePost(cfg config.Config) http.HandlerFunc {
 
 func AdminGetStats(cfg config.Config) http.HandlerFunc {
 	return func(w http.ResponseWriter, r *http.Request) {
-		posts := store.DefaultStore().GetFeed(10000)
+		posts, err := store.DefaultStore().GetFeed(r.Context(), 10000)
+		if err != nil {
+			http.Error(w, "failed to load posts", http.StatusInternalServerError)
+			return
+		}
+		sessions, err := store.DefaultStore().GetAllSessions(r.Context())
+		if err != nil {
+			http.Error(w, "failed to load sessions", http.StatusInternalServerError)
+			return
+		}
 
 		// Get counts from store
-		totalUsers, err := store.DefaultStore().GetTotalUsersCount()
+		totalUsers, err := store.DefaultStore().GetTotalUsersCount(r.Context())
 		if err != nil {
 			log.Printf("WARNING: GetTotalUsersCount failed: %v", err)
 			totalUsers = 0
 		}
 
-		activeUsers, err := store.DefaultStore().GetActiveUsersCount()
+		activeUsers, err := store.DefaultStore().GetActiveUsersCount(r.Context())
 		if err != nil {
 			log.Printf("WARNING: GetActiveUsersCount failed: %v", err)
 			activeUsers = 0
@@ -362,7 +561,7 @@ func AdminGetStats(cfg config.Config) http.HandlerFunc {
 			TotalPosts:    len(posts),
 			TotalUsers:    totalUsers,
 			ActiveUsers:   activeUsers,
-			TotalSessions: len(store.DefaultStore().GetAllSessions()),
+			TotalSessions: len(sessions),
 		}
 
 		// Calculate avg posts per day
@@ -422,9 +621,21 @@ func AdminGetHealth(cfg config.Config) http.HandlerFunc {
 	}
 }
 
+// AdminGetWSStats reports chat socket frame counters for this instance.
+func AdminGetWSStats(hub *ws.Hub) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(hub.Stats())
+	}
+}
+
 func AdminGetSessions(cfg config.Config) http.HandlerFunc {
 	return func(w http.ResponseWriter, r *http.Request) {
-		sessions := store.DefaultStore().GetAllSessions()
+		sessions, err := store.DefaultStore().GetAllSessions(r.Context())
+		if err != nil {
+			http.Error(w, "failed to load sessions", http.StatusInternalServerError)
+			return
+		}
 
 		out := make([]struct {
 			AnonID    string `json:"anon_id"`
@@ -457,7 +668,11 @@ func AdminGetSessions(cfg config.Config) http.HandlerFunc {
 func AdminGetTrustGraph(cfg config.Config) http.HandlerFunc {
 	return func(w http.ResponseWriter, r *http.Request) {
 		// Get all trust requests
-		requests := store.DefaultStore().GetAllTrustRequests()
+		requests, err := store.DefaultStore().GetAllTrustRequests(r.Context())
+		if err != nil {
+			http.Error(w, "failed to load trust requests", http.StatusInternalServerError)
+			return
+		}
 
 		type TrustLink struct {
 			From      string `json:"from"`
@@ -486,8 +701,16 @@ func AdminGetTrustGraph(cfg config.Config) http.HandlerFunc {
 
 func AdminGetAbuseDashboard(cfg config.Config) http.HandlerFunc {
 	return func(w http.ResponseWriter, r *http.Request) {
-		posts := store.DefaultStore().GetFeed(1000)
-		users := store.DefaultStore().GetAllUsers()
+		posts, err := store.DefaultStore().GetFeed(r.Context(), 1000)
+		if err != nil {
+			http.Error(w, "failed to load posts", http.StatusInternalServerError)
+			return
+		}
+		users, err := store.DefaultStore().GetAllUsers(r.Context())
+		if err != nil {
+			http.Error(w, "failed to load users", http.StatusInternalServerError)
+			return
+		}
 
 		// Calculate abuse metrics
 		type userStats struct {
@@ -507,7 +730,11 @@ func AdminGetAbuseDashboard(cfg config.Config) http.HandlerFunc {
 
 		fallbackTopReportedByAnon := make(map[string]ReportInfo)
 		for _, p := range posts {
-			reportCount := store.DefaultStore().GetPostReportCount(p.ID)
+			reportCount, err := store.DefaultStore().GetPostReportCount(r.Context(), p.ID)
+			if err != nil {
+				http.Error(w, "failed to load reports", http.StatusInternalServerError)
+				return
+			}
 			if reportCount < 1 {
 				continue
 			}
@@ -520,6 +747,18 @@ func AdminGetAbuseDashboard(cfg config.Config) http.HandlerFunc {
 			}
 		}
 
+		limited, err := store.DefaultStore().ListRateLimitedBuckets(r.Context(), time.Now().Add(-24*time.Hour), 500)
+		if err != nil {
+			log.Printf("WARNING: ListRateLimitedBuckets failed: %v", err)
+			limited = []store.RateLimitBucket{}
+		}
+		hitsByAnon := make(map[string]int64)
+		for _, b := range limited {
+			if anonID, ok := strings.CutPrefix(b.Key, "anon:"); ok {
+				hitsByAnon[anonID] += b.Limited
+			}
+		}
+
 		anonIDSet := make(map[string]struct{})
 		for _, u := range users {
 			anonIDSet[u.AnonID] = struct{}{}
@@ -544,6 +783,9 @@ func AdminGetAbuseDashboard(cfg config.Config) http.HandlerFunc {
 			if stats.PostCount > 50 {
 				rateStatus = "blocked"
 			}
+			if hitsByAnon[anonID] > 0 && rateStatus == "normal" {
+				rateStatus = "warning"
+			}
 
 			lastPostAt := ""
 			if !stats.LastPostAt.IsZero() {
@@ -552,7 +794,11 @@ func AdminGetAbuseDashboard(cfg config.Config) http.HandlerFunc {
 
 			// Get top reported post for this user if there is at least one report
 			var reportedPost *ReportInfo
-			topReport := store.DefaultStore().GetTopReportedPostByAnon(anonID, 1)
+			topReport, err := store.DefaultStore().GetTopReportedPostByAnon(r.Context(), anonID, 1)
+			if err != nil {
+				http.Error(w, "failed to load reports", http.StatusInternalServerError)
+				return
+			}
 			if topReport != nil {
 				reportedPost = &ReportInfo{
 					PostID:         topReport.PostID,
@@ -568,17 +814,19 @@ func AdminGetAbuseDashboard(cfg config.Config) http.HandlerFunc {
 			}
 
 			reports = append(reports, AbuseReport{
-				AnonID:       anonID,
-				PostCount:    stats.PostCount,
-				LastPostAt:   lastPostAt,
-				RateStatus:   rateStatus,
-				ReportedPost: reportedPost,
+				AnonID:        anonID,
+				PostCount:     stats.PostCount,
+				LastPostAt:    lastPostAt,
+				RateStatus:    rateStatus,
+				ReportedPost:  reportedPost,
+				RateLimitHits: hitsByAnon[anonID],
 			})
 		}
 
 		w.Header().Set("Content-Type", "application/json")
 		json.NewEncoder(w).Encode(map[string]interface{}{
 			"abuse_reports": reports,
+			"rate_limited":  limited,
 			"total_users":   len(reports),
 			"warning_count": func() int {
 				count := 0
@@ -595,7 +843,11 @@ func AdminGetAbuseDashboard(cfg config.Config) http.HandlerFunc {
 
 func AdminGetAuditLog(cfg config.Config) http.HandlerFunc {
 	return func(w http.ResponseWriter, r *http.Request) {
-		logs := store.DefaultStore().GetAuditLogs()
+		logs, err := store.DefaultStore().GetAuditLogs(r.Context())
+		if err != nil {
+			http.Error(w, "failed to load audit logs", http.StatusInternalServerError)
+			return
+		}
 
 		w.Header().Set("Content-Type", "application/json")
 		json.NewEncoder(w).Encode(map[string]interface{}{
@@ -605,8 +857,23 @@ func AdminGetAuditLog(cfg config.Config) http.HandlerFunc {
 	}
 }
 
+// AdminVerifyAuditLog walks the audit hash chain and reports the first
+// entry that was altered or removed, if any.
+func AdminVerifyAuditLog(cfg config.Config) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		result, err := store.VerifyAuditLogs(r.Context(), store.DefaultStore())
+		if err != nil {
+			http.Error(w, "failed to verify audit log", http.StatusInternalServerError)
+			return
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(result)
+	}
+}
+
 // AdminRevokeSession revokes a specific session by token
-func AdminRevokeSession(cfg config.Config) http.HandlerFunc {
+func AdminRevokeSession(cfg config.Config, hub *ws.Hub) http.HandlerFunc {
 	return func(w http.ResponseWriter, r *http.Request) {
 		var req struct {
 			Token string `json:"token"`
@@ -623,22 +890,29 @@ func AdminRevokeSession(cfg config.Config) http.HandlerFunc {
 		}
 
 		// Get session details before revoking for audit
-		sess, err := store.DefaultStore().GetSessionByToken(req.Token)
+		sess, err := store.DefaultStore().GetSessionByToken(r.Context(), req.Token)
 		if err != nil {
 			http.Error(w, "session not found", http.StatusNotFound)
 			return
 		}
 
 		// Revoke the session
-		if err := store.DefaultStore().RevokeSession(req.Token); err != nil {
+		if err := store.DefaultStore().RevokeSession(r.Context(), req.Token); err != nil {
 			http.Error(w, "failed to revoke session", http.StatusInternalServerError)
 			return
 		}
+		session.ForgetToken(req.Token)
+		hub.DisconnectSession(sess.AnonID, sess.ID, ws.CloseSessionRevoked, "session revoked")
 
 		// Log audit event
-		store.DefaultStore().LogAuditEvent(store.AuditLog{
-			Action:  "revoke_session",
-			AnonID:  "admin",
+		recordAudit(r, store.AuditLog{
+			Action: "revoke_session",
+			AnonID: sess.AnonID,
+			Target: "session:" + sess.ID,
+			Before: auditPayload(map[string]string{
+				"device_public_id": sess.DevicePublicID,
+				"expires_at":       sess.ExpiresAt.Format(time.RFC3339),
+			}),
 			Details: "Revoked session for anon_id: " + sess.AnonID,
 		})
 
@@ -651,7 +925,7 @@ func AdminRevokeSession(cfg config.Config) http.HandlerFunc {
 }
 
 // AdminRevokeAllUserSessions revokes all sessions for a specific user
-func AdminRevokeAllUserSessions(cfg config.Config) http.HandlerFunc {
+func AdminRevokeAllUserSessions(cfg config.Config, hub *ws.Hub) http.HandlerFunc {
 	return func(w http.ResponseWriter, r *http.Request) {
 		var req struct {
 			AnonID string `json:"anon_id"`
@@ -668,20 +942,21 @@ func AdminRevokeAllUserSessions(cfg config.Config) http.HandlerFunc {
 		}
 
 		// Revoke all sessions
-		count, err := store.DefaultStore().RevokeAllSessionsForUser(req.AnonID)
+		count, err := store.DefaultStore().RevokeAllSessionsForUser(r.Context(), req.AnonID)
 		if err != nil {
 			http.Error(w, "failed to revoke sessions", http.StatusInternalServerError)
 			return
 		}
+		session.Forget(req.AnonID)
+		hub.Disconnect(req.AnonID, ws.CloseSessionRevoked, "all sessions revoked")
 
 		// Log audit event
-		store.DefaultStore().LogAuditEvent(store.AuditLog{
-			Action: "revoke_all_sessions",
-			AnonID: "admin",
-			Details: strings.Join([]string{
-				"Revoked all sessions for anon_id: ", req.AnonID,
-				" (count: ", string(rune(count)), ")",
-			}, ""),
+		recordAudit(r, store.AuditLog{
+			Action:  "revoke_all_sessions",
+			AnonID:  req.AnonID,
+			Target:  "user:" + req.AnonID,
+			After:   auditPayload(map[string]int{"sessions_revoked": count}),
+			Details: fmt.Sprintf("Revoked all sessions for anon_id: %s (count: %d)", req.AnonID, count),
 		})
 
 		w.Header().Set("Content-Type", "application/json")
@@ -702,7 +977,7 @@ func AdminGetUserSessions(cfg config.Config) http.HandlerFunc {
 			return
 		}
 
-		sessions, err := store.DefaultStore().GetSessionsByAnonID(anonID)
+		sessions, err := store.DefaultStore().GetSessionsByAnonID(r.Context(), anonID)
 		if err != nil {
 			http.Error(w, "failed to get sessions", http.StatusInternalServerError)
 			return
@@ -715,94 +990,3 @@ func AdminGetUserSessions(cfg config.Config) http.HandlerFunc {
 		})
 	}
 }
-
-// AdminDeleteAuditLog deletes a single audit log entry
-func AdminDeleteAuditLog(cfg config.Config) http.HandlerFunc {
-	return func(w http.ResponseWriter, r *http.Request) {
-		var req struct {
-			ID string `json:"id"`
-		}
-
-		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
-			http.Error(w, "invalid request", http.StatusBadRequest)
-			return
-		}
-
-		if req.ID == "" {
-			http.Error(w, "id required", http.StatusBadRequest)
-			return
-		}
-
-		if err := store.DefaultStore().DeleteAuditLog(req.ID); err != nil {
-			http.Error(w, "failed to delete audit log", http.StatusInternalServerError)
-			return
-		}
-
-		w.Header().Set("Content-Type", "application/json")
-		json.NewEncoder(w).Encode(map[string]interface{}{
-			"status":  "success",
-			"message": "audit log deleted",
-		})
-	}
-}
-
-// AdminDeleteAuditLogs deletes multiple audit log entries
-func AdminDeleteAuditLogs(cfg config.Config) http.HandlerFunc {
-	return func(w http.ResponseWriter, r *http.Request) {
-		var req struct {
-			IDs []string `json:"ids"`
-		}
-
-		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
-			http.Error(w, "invalid request", http.StatusBadRequest)
-			return
-		}
-
-		if len(req.IDs) == 0 {
-			http.Error(w, "ids required", http.StatusBadRequest)
-			return
-		}
-
-		if err := store.DefaultStore().DeleteAuditLogs(req.IDs); err != nil {
-			http.Error(w, "failed to delete audit logs", http.StatusInternalServerError)
-			return
-		}
-
-		// Log the deletion action
-		store.DefaultStore().LogAuditEvent(store.AuditLog{
-			Action:  "delete_audit_logs",
-			AnonID:  "admin",
-			Details: fmt.Sprintf("deleted %d audit log entries", len(req.IDs)),
-		})
-
-		w.Header().Set("Content-Type", "application/json")
-		json.NewEncoder(w).Encode(map[string]interface{}{
-			"status":  "success",
-			"message": fmt.Sprintf("%d audit logs deleted", len(req.IDs)),
-			"count":   len(req.IDs),
-		})
-	}
-}
-
-// AdminClearAuditLogs clears all audit log entries
-func AdminClearAuditLogs(cfg config.Config) http.HandlerFunc {
-	return func(w http.ResponseWriter, r *http.Request) {
-		if err := store.DefaultStore().ClearAuditLogs(); err != nil {
-			http.Error(w, "failed to clear audit logs", http.StatusInternalServerError)
-			return
-		}
-
-		// Log the clear action
-		store.DefaultStore().LogAuditEvent(store.AuditLog{
-			Action:  "clear_audit_logs",
-			AnonID:  "admin",
-			Details: "cleared all audit log entries",
-		})
-
-		w.Header().Set("Content-Type", "application/json")
-		json.NewEncoder(w).Encode(map[string]interface{}{
-			"status":  "success",
-			"message": "all audit logs cleared",
-		})
-	}
-}
@@ -2,6 +2,7 @@ package handlers
 
 import (
 	"encoding/json"
+	"errors"
 	"net/http"
 	"time"
 
@@ -28,13 +29,21 @@ func AdminGetPostDetail(cfg config.Config) http.HandlerFunc {
 			return
 		}
 
-		post, exists := store.DefaultStore().GetPost(postID)
-		if !exists {
+		post, err := store.DefaultStore().GetPost(r.Context(), postID)
+		if errors.Is(err, store.ErrNotFound) {
 			http.Error(w, "post not found", http.StatusNotFound)
 			return
 		}
+		if err != nil {
+			http.Error(w, "failed to load post", http.StatusInternalServerError)
+			return
+		}
 
-		reportCount := store.DefaultStore().GetPostReportCount(postID)
+		reportCount, err := store.DefaultStore().GetPostReportCount(r.Context(), postID)
+		if err != nil {
+			http.Error(w, "failed to load post", http.StatusInternalServerError)
+			return
+		}
 
 		out := AdminPostDetailDTO{
 			ID:        post.ID,
@@ -0,0 +1,241 @@
+package handlers
+
+import (
+	"encoding/json"
+	"errors"
+	"log"
+	"net/http"
+	"strings"
+	"time"
+
+	"anon-backend/internal/config"
+	"anon-backend/internal/httpctx"
+	"anon-backend/internal/security"
+	"anon-backend/internal/store"
+)
+
+const (
+	totpIssuer            = "ANON"
+	adminMFATTL           = 5 * time.Minute
+	adminRecoveryCodeSize = 10
+
+	// adminMFAMaxFailures wrong second factors lock an admin out of the
+	// TOTP step for adminMFALockout, so six digits cannot be brute-forced
+	// within a single MFA token's lifetime. Failures are counted on the
+	// admin row, so every instance shares them.
+	adminMFAMaxFailures = 5
+	adminMFALockout     = 15 * time.Minute
+)
+
+// AdminLoginTOTP is the second login step for admins with TOTP enabled: it
+// exchanges the MFA token from AdminLogin plus a current code, or one of
+// the recovery codes, for the admin token.
+func AdminLoginTOTP(cfg config.Config) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		var req struct {
+			MFAToken     string `json:"mfa_token"`
+			Code         string `json:"code"`
+			RecoveryCode string `json:"recovery_code"`
+		}
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			http.Error(w, "bad json", http.StatusBadRequest)
+			return
+		}
+
+		claims, err := security.VerifyAdminMFAJWT(cfg.Keys, req.MFAToken)
+		if err != nil {
+			http.Error(w, "unauthorized", http.StatusUnauthorized)
+			return
+		}
+
+		admin, err := store.DefaultStore().GetAdminByID(r.Context(), claims.Subject)
+		if err != nil {
+			if errors.Is(err, store.ErrAdminNotFound) {
+				http.Error(w, "unauthorized", http.StatusUnauthorized)
+				return
+			}
+			http.Error(w, "failed to load admin", http.StatusInternalServerError)
+			return
+		}
+		if admin.Disabled || !admin.TOTPEnabled {
+			http.Error(w, "unauthorized", http.StatusUnauthorized)
+			return
+		}
+
+		if !checkAdminSecondFactor(w, r, cfg, admin, req.Code, req.RecoveryCode) {
+			return
+		}
+		writeAdminLogin(w, r, cfg, admin)
+	}
+}
+
+// checkAdminSecondFactor verifies a current code or an unused recovery code
+// of admin, whose TOTP is enabled. Failures count towards the MFA lockout.
+// When the check fails it writes the response and returns false.
+func checkAdminSecondFactor(w http.ResponseWriter, r *http.Request, cfg config.Config, admin *store.Admin, code, recoveryCode string) bool {
+	now := cfg.TOTP.Now()
+	if admin.MFALocked(now) {
+		http.Error(w, "too many attempts, try again later", http.StatusTooManyRequests)
+		return false
+	}
+
+	var ok bool
+	var err error
+	switch {
+	case strings.TrimSpace(code) != "":
+		counter, valid := cfg.TOTP.Verify(admin.TOTPSecret, code)
+		if valid {
+			ok, err = store.DefaultStore().UseAdminTOTPCounter(r.Context(), admin.ID, counter)
+		}
+	case strings.TrimSpace(recoveryCode) != "":
+		hash := security.HashBackupCode(strings.TrimSpace(recoveryCode))
+		ok, err = store.DefaultStore().UseAdminRecoveryCode(r.Context(), admin.ID, hash, now)
+		if ok {
+			recordAudit(r, store.AuditLog{
+				Action:     "admin_recovery_code_used",
+				ActorID:    admin.ID,
+				ActorEmail: admin.Email,
+				ActorRole:  admin.Role,
+				Target:     "admin:" + admin.ID,
+				Details:    "email=" + admin.Email,
+			})
+		}
+	default:
+		http.Error(w, "code or recovery code required", http.StatusBadRequest)
+		return false
+	}
+	if err != nil {
+		http.Error(w, "failed to verify code", http.StatusInternalServerError)
+		return false
+	}
+	if !ok {
+		if _, err := store.DefaultStore().RecordAdminMFAFailure(r.Context(), admin.ID, now, adminMFAMaxFailures, adminMFALockout); err != nil {
+			log.Printf("record admin mfa failure: %v", err)
+		}
+		http.Error(w, "invalid code", http.StatusUnauthorized)
+		return false
+	}
+
+	if err := store.DefaultStore().ClearAdminMFAFailures(r.Context(), admin.ID); err != nil {
+		log.Printf("clear admin mfa failures: %v", err)
+	}
+	return true
+}
+
+// AdminTOTPEnroll starts TOTP enrolment for the calling admin. The secret
+// stays pending, and any existing factor stays active, until confirmed.
+// An admin who already has TOTP enabled must send a current code or a
+// recovery code, so an admin token alone cannot replace the factor.
+func AdminTOTPEnroll(cfg config.Config) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		claims := httpctx.AdminClaimsFromContext(r.Context())
+		if claims == nil {
+			http.Error(w, "unauthorized", http.StatusUnauthorized)
+			return
+		}
+
+		admin, err := store.DefaultStore().GetAdminByID(r.Context(), claims.Subject)
+		if err != nil {
+			http.Error(w, "failed to load admin", http.StatusInternalServerError)
+			return
+		}
+		if admin.TOTPEnabled {
+			var req struct {
+				CurrentCode  string `json:"current_code"`
+				RecoveryCode string `json:"recovery_code"`
+			}
+			if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+				http.Error(w, "bad json", http.StatusBadRequest)
+				return
+			}
+			if !checkAdminSecondFactor(w, r, cfg, admin, req.CurrentCode, req.RecoveryCode) {
+				return
+			}
+		}
+
+		secret, err := security.NewTOTPSecret()
+		if err != nil {
+			http.Error(w, "failed to generate secret", http.StatusInternalServerError)
+			return
+		}
+		if err := store.DefaultStore().SetAdminTOTPPending(r.Context(), admin.ID, secret); err != nil {
+			http.Error(w, "failed to start enrolment", http.StatusInternalServerError)
+			return
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(map[string]interface{}{
+			"secret":      secret,
+			"otpauth_uri": cfg.TOTP.URI(totpIssuer, claims.Email, secret),
+		})
+	}
+}
+
+// AdminTOTPConfirm checks a first code against the pending secret, turns
+// TOTP on and returns a fresh set of recovery codes. They are only shown
+// here. Replacing an enabled factor again takes a current code of it, or a
+// recovery code, as in AdminTOTPEnroll.
+func AdminTOTPConfirm(cfg config.Config) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		claims := httpctx.AdminClaimsFromContext(r.Context())
+		if claims == nil {
+			http.Error(w, "unauthorized", http.StatusUnauthorized)
+			return
+		}
+
+		var req struct {
+			Code         string `json:"code"`
+			CurrentCode  string `json:"current_code"`
+			RecoveryCode string `json:"recovery_code"`
+		}
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			http.Error(w, "bad json", http.StatusBadRequest)
+			return
+		}
+
+		admin, err := store.DefaultStore().GetAdminByID(r.Context(), claims.Subject)
+		if err != nil {
+			http.Error(w, "failed to load admin", http.StatusInternalServerError)
+			return
+		}
+		if admin.TOTPPendingSecret == "" {
+			http.Error(w, "no enrolment pending", http.StatusConflict)
+			return
+		}
+
+		counter, ok := cfg.TOTP.Verify(admin.TOTPPendingSecret, req.Code)
+		if !ok {
+			http.Error(w, "invalid code", http.StatusBadRequest)
+			return
+		}
+		if admin.TOTPEnabled && !checkAdminSecondFactor(w, r, cfg, admin, req.CurrentCode, req.RecoveryCode) {
+			return
+		}
+
+		codes, hashes, err := security.NewBackupCodes(adminRecoveryCodeSize)
+		if err != nil {
+			http.Error(w, "failed to generate recovery codes", http.StatusInternalServerError)
+			return
+		}
+		if err := store.DefaultStore().EnableAdminTOTP(r.Context(), admin.ID, counter, hashes); err != nil {
+			if errors.Is(err, store.ErrAdminTOTPNotPending) {
+				http.Error(w, "no enrolment pending", http.StatusConflict)
+				return
+			}
+			http.Error(w, "failed to enable totp", http.StatusInternalServerError)
+			return
+		}
+
+		recordAudit(r, store.AuditLog{
+			Action:  "admin_totp_enabled",
+			Target:  "admin:" + admin.ID,
+			Details: "email=" + admin.Email,
+		})
+
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(map[string]interface{}{
+			"status":         "enabled",
+			"recovery_codes": codes,
+		})
+	}
+}
@@ -0,0 +1,134 @@
+package handlers
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"anon-backend/internal/config"
+	"anon-backend/internal/httpctx"
+	"anon-backend/internal/security"
+	"anon-backend/internal/store"
+)
+
+// A code accepted once is refused for the rest of its step.
+func TestAdminLoginTOTPRefusesReplay(t *testing.T) {
+	store.Initialize(store.NewMemStore())
+	t.Cleanup(func() { store.Initialize(nil) })
+
+	keys, err := security.LoadKeyring("test-secret", "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	now := time.Unix(1234567890, 0)
+	cfg := config.Config{Keys: keys, JWTTTL: time.Minute, TOTP: security.DefaultTOTP()}
+	cfg.TOTP.Now = func() time.Time { return now }
+
+	secret, err := security.NewTOTPSecret()
+	if err != nil {
+		t.Fatal(err)
+	}
+	admin := &store.Admin{Email: "admin@example.com", Role: security.RoleSuperadmin, TOTPEnabled: true, TOTPSecret: secret}
+	if err := store.DefaultStore().CreateAdmin(context.Background(), admin); err != nil {
+		t.Fatal(err)
+	}
+	mfa, err := security.SignAdminMFAJWT(keys, time.Minute, admin.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	code, err := cfg.TOTP.CodeAt(secret, cfg.TOTP.Counter(now))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	login := func() int {
+		body := `{"mfa_token":"` + mfa + `","code":"` + code + `"}`
+		rec := httptest.NewRecorder()
+		AdminLoginTOTP(cfg)(rec, httptest.NewRequest(http.MethodPost, "/admin/login/totp", strings.NewReader(body)))
+		return rec.Code
+	}
+	if got := login(); got != http.StatusOK {
+		t.Fatalf("first login = %d, want %d", got, http.StatusOK)
+	}
+	now = now.Add(5 * time.Second)
+	if got := login(); got != http.StatusUnauthorized {
+		t.Fatalf("replayed login = %d, want %d", got, http.StatusUnauthorized)
+	}
+}
+
+// An admin token alone cannot replace an enabled second factor.
+func TestAdminTOTPReenrolRequiresCurrentFactor(t *testing.T) {
+	store.Initialize(store.NewMemStore())
+	t.Cleanup(func() { store.Initialize(nil) })
+
+	now := time.Unix(1234567890, 0)
+	cfg := config.Config{TOTP: security.DefaultTOTP()}
+	cfg.TOTP.Now = func() time.Time { return now }
+
+	secret, err := security.NewTOTPSecret()
+	if err != nil {
+		t.Fatal(err)
+	}
+	admin := &store.Admin{Email: "admin@example.com", Role: security.RoleSuperadmin, TOTPEnabled: true, TOTPSecret: secret}
+	if err := store.DefaultStore().CreateAdmin(context.Background(), admin); err != nil {
+		t.Fatal(err)
+	}
+	claims := &security.AdminClaims{Email: admin.Email, Role: admin.Role}
+	claims.Subject = admin.ID
+
+	call := func(h http.HandlerFunc, body string) int {
+		req := httptest.NewRequest(http.MethodPost, "/admin/totp", strings.NewReader(body))
+		req = req.WithContext(httpctx.WithAdminClaims(req.Context(), claims))
+		rec := httptest.NewRecorder()
+		h(rec, req)
+		return rec.Code
+	}
+	pending := func() string {
+		a, err := store.DefaultStore().GetAdminByID(context.Background(), admin.ID)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return a.TOTPPendingSecret
+	}
+
+	if got := call(AdminTOTPEnroll(cfg), `{}`); got != http.StatusBadRequest {
+		t.Fatalf("enrol without a code = %d, want %d", got, http.StatusBadRequest)
+	}
+	if got := call(AdminTOTPEnroll(cfg), `{"current_code":"000000"}`); got != http.StatusUnauthorized {
+		t.Fatalf("enrol with a wrong code = %d, want %d", got, http.StatusUnauthorized)
+	}
+	if pending() != "" {
+		t.Fatal("refused enrolment left a pending secret")
+	}
+
+	code, err := cfg.TOTP.CodeAt(secret, cfg.TOTP.Counter(now))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := call(AdminTOTPEnroll(cfg), `{"current_code":"`+code+`"}`); got != http.StatusOK {
+		t.Fatalf("enrol with the current code = %d, want %d", got, http.StatusOK)
+	}
+	next := pending()
+	if next == "" {
+		t.Fatal("enrolment left no pending secret")
+	}
+
+	// confirming the new secret takes the old factor again
+	newCode, err := cfg.TOTP.CodeAt(next, cfg.TOTP.Counter(now))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := call(AdminTOTPConfirm(cfg), `{"code":"`+newCode+`"}`); got != http.StatusBadRequest {
+		t.Fatalf("confirm without the current code = %d, want %d", got, http.StatusBadRequest)
+	}
+	a, err := store.DefaultStore().GetAdminByID(context.Background(), admin.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if a.TOTPSecret != secret {
+		t.Fatal("confirm without the current code replaced the secret")
+	}
+}
@@ -0,0 +1,53 @@
+package handlers
+
+import (
+	"encoding/json"
+	"log"
+	"net"
+	"net/http"
+
+	"anon-backend/internal/httpctx"
+	"anon-backend/internal/store"
+
+	"github.com/go-chi/chi/v5/middleware"
+)
+
+// recordAudit appends event to the audit trail, filling in who made the
+// request, from which address and under which request id. Actor fields
+// already set on event win, for flows where the caller is not yet
+// authenticated.
+func recordAudit(r *http.Request, event store.AuditLog) {
+	if event.ActorID == "" {
+		if admin := httpctx.AdminClaimsFromContext(r.Context()); admin != nil {
+			event.ActorID = admin.Subject
+			event.ActorEmail = admin.Email
+			event.ActorRole = admin.Role
+		} else if claims := httpctx.ClaimsFromContext(r.Context()); claims != nil {
+			event.ActorID = claims.AnonID
+		}
+	}
+	event.IP = requestIP(r)
+	event.RequestID = middleware.GetReqID(r.Context())
+
+	if err := store.DefaultStore().LogAuditEvent(r.Context(), event); err != nil {
+		log.Printf("audit: failed to record %s: %v", event.Action, err)
+	}
+}
+
+// auditPayload encodes v for an entry's Before or After field.
+func auditPayload(v any) json.RawMessage {
+	raw, err := json.Marshal(v)
+	if err != nil {
+		return nil
+	}
+	return raw
+}
+
+// requestIP is the client address; middleware.RealIP has already replaced
+// RemoteAddr with X-Forwarded-For / X-Real-IP when present.
+func requestIP(r *http.Request) string {
+	if host, _, err := net.SplitHostPort(r.RemoteAddr); err == nil {
+		return host
+	}
+	return r.RemoteAddr
+}
@@ -0,0 +1,109 @@
+package handlers
+
+import (
+	"encoding/json"
+	"net/http"
+	"strconv"
+
+	"anon-backend/internal/config"
+	"anon-backend/internal/httpctx"
+	"anon-backend/internal/store"
+	"anon-backend/internal/types"
+	"anon-backend/internal/ws"
+
+	"github.com/go-chi/chi/v5"
+)
+
+// ChatHistory handles GET /chat/{peer}/history?before=<cursor>&limit=50
+// Messages are returned newest first; pass next_cursor back as before to page.
+func ChatHistory(cfg config.Config) http.HandlerFunc {
+	_ = cfg
+
+	return func(w http.ResponseWriter, r *http.Request) {
+		claims := httpctx.ClaimsFromContext(r.Context())
+		if claims == nil {
+			writeJSONError(w, http.StatusUnauthorized, "no claims")
+			return
+		}
+		me := claims.AnonID
+
+		peer := chi.URLParam(r, "peer")
+		if peer == "" {
+			writeJSONError(w, http.StatusBadRequest, "peer required")
+			return
+		}
+		if peer == me {
+			writeJSONError(w, http.StatusBadRequest, "peer cannot be self")
+			return
+		}
+
+		str := store.DefaultStore()
+		trusted, err := str.TrustAccepted(r.Context(), me, peer)
+		if err != nil {
+			writeJSONError(w, http.StatusInternalServerError, "failed to check trust")
+			return
+		}
+		if !trusted {
+			writeJSONError(w, http.StatusForbidden, "not trusted")
+			return
+		}
+
+		limit := 50
+		if limitStr := r.URL.Query().Get("limit"); limitStr != "" {
+			if l, err := strconv.Atoi(limitStr); err == nil && l > 0 {
+				limit = l
+			}
+		}
+		if limit > 100 {
+			limit = 100
+		}
+
+		var before *store.Cursor
+		if beforeStr := r.URL.Query().Get("before"); beforeStr != "" {
+			c, err := store.ParseCursor(beforeStr)
+			if err != nil {
+				writeJSONError(w, http.StatusBadRequest, "invalid cursor")
+				return
+			}
+			before = c
+		}
+
+		msgs, err := str.GetChatHistory(r.Context(), ws.RoomID(me, peer), before, limit)
+		if err != nil {
+			writeJSONError(w, http.StatusInternalServerError, "failed to load chat history")
+			return
+		}
+
+		out := make([]types.ChatMessageDTO, 0, len(msgs))
+		for _, m := range msgs {
+			dto := types.ChatMessageDTO{
+				ID:     m.ID,
+				From:   m.FromAnon,
+				To:     m.ToAnon,
+				Kind:   m.Kind,
+				SentAt: chatTimestamp(m.CreatedAt),
+			}
+			if m.Kind == store.ChatKindCiphertext {
+				dto.Ciphertext = m.Text
+			} else {
+				dto.Text = m.Text
+			}
+			if m.DeliveredAt != nil {
+				dto.DeliveredAt = chatTimestamp(*m.DeliveredAt)
+			}
+			if m.ReadAt != nil {
+				dto.ReadAt = chatTimestamp(*m.ReadAt)
+			}
+			out = append(out, dto)
+		}
+
+		resp := types.ChatHistoryResponse{Messages: out}
+		if len(msgs) == limit {
+			last := msgs[len(msgs)-1]
+			resp.NextCursor = store.Cursor{CreatedAt: last.CreatedAt, ID: last.ID}.Encode()
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		_ = json.NewEncoder(w).Encode(resp)
+	}
+}
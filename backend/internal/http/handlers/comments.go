@@ -2,6 +2,8 @@ package handlers
 
 import (
 	"encoding/json"
+	"errors"
+	"log"
 	"net/http"
 	"strings"
 	"time"
@@ -55,6 +57,13 @@ func CommentCreate(cfg config.Config) http.HandlerFunc {
 		}
 
 		now := time.Now()
+		tier, err := quotaTierFor(r.Context(), cfg, claims.AnonID, now)
+		if err != nil {
+			log.Printf("quota tier for %s: %v", claims.AnonID, err)
+			writeJSONError(w, http.StatusInternalServerError, "failed to check quota")
+			return
+		}
+
 		comment := &store.PostComment{
 			ID:        commentID,
 			PostID:    req.PostID,
@@ -66,8 +75,20 @@ func CommentCreate(cfg config.Config) http.HandlerFunc {
 			Deleted:   false,
 		}
 
-		if err := store.DefaultStore().AddComment(comment); err != nil {
-			http.Error(w, err.Error(), http.StatusBadRequest)
+		// The post check, quota take and insert happen in one store transaction
+		_, err = store.DefaultStore().AddCommentWithQuota(r.Context(), comment, tier.LimitsFor(config.QuotaComments), now)
+		var exceeded *store.QuotaExceededError
+		if errors.As(err, &exceeded) {
+			writeQuotaExceeded(w, config.QuotaComments, tier, exceeded.Result, now)
+			return
+		}
+		if errors.Is(err, store.ErrNotFound) {
+			http.Error(w, "post not found", http.StatusNotFound)
+			return
+		}
+		if err != nil {
+			log.Printf("add comment: %v", err)
+			http.Error(w, "failed to create comment", http.StatusInternalServerError)
 			return
 		}
 
@@ -76,7 +97,7 @@ func CommentCreate(cfg config.Config) http.HandlerFunc {
 			ID:           comment.ID,
 			PostID:       comment.PostID,
 			AnonID:       comment.AnonID,
-			Username:     getUsernameByAnonID(comment.AnonID),
+			Username:     getUsernameByAnonID(r.Context(), comment.AnonID),
 			Text:         comment.Text,
 			CreatedAt:    comment.CreatedAt.Format(time.RFC3339),
 			Likes:        comment.Likes,
@@ -101,25 +122,17 @@ func CommentGet(cfg config.Config) http.HandlerFunc {
 			return
 		}
 
-		comments := store.DefaultStore().GetComments(postID)
-		out := make([]types.CommentDTO, len(comments))
-
-		for i, comment := range comments {
-			reaction, _ := store.DefaultStore().GetCommentReaction(comment.ID, claims.AnonID)
-			repliesCount := store.DefaultStore().GetCommentRepliesCount(comment.ID)
-			out[i] = types.CommentDTO{
-				ID:           comment.ID,
-				PostID:       comment.PostID,
-				AnonID:       comment.AnonID,
-				Username:     getUsernameByAnonID(comment.AnonID),
-				Text:         comment.Text,
-				CreatedAt:    comment.CreatedAt.Format(time.RFC3339),
-				Likes:        comment.Likes,
-				Dislikes:     comment.Dislikes,
-				UserReaction: reaction,
-				RepliesCount: repliesCount,
-				Deleted:      comment.Deleted,
-			}
+		comments, err := store.DefaultStore().GetComments(r.Context(), postID)
+		if err != nil {
+			log.Printf("get comments for %s: %v", postID, err)
+			http.Error(w, "failed to load comments", http.StatusInternalServerError)
+			return
+		}
+		out, err := commentDTOs(r.Context(), claims.AnonID, comments)
+		if err != nil {
+			log.Printf("build comments for %s: %v", postID, err)
+			http.Error(w, "failed to load comments", http.StatusInternalServerError)
+			return
 		}
 
 		w.Header().Set("Content-Type", "application/json")
@@ -146,9 +159,17 @@ func CommentDelete(cfg config.Config) http.HandlerFunc {
 			return
 		}
 
-		err := store.DefaultStore().DeleteCommentByUser(req.CommentID, claims.AnonID)
-		if err != nil {
-			http.Error(w, err.Error(), http.StatusForbidden)
+		err := store.DefaultStore().DeleteCommentByUser(r.Context(), req.CommentID, claims.AnonID)
+		switch {
+		case errors.Is(err, store.ErrNotFound):
+			http.Error(w, "comment not found", http.StatusNotFound)
+			return
+		case errors.Is(err, store.ErrForbidden):
+			http.Error(w, "not the comment author", http.StatusForbidden)
+			return
+		case err != nil:
+			log.Printf("delete comment %s: %v", req.CommentID, err)
+			http.Error(w, "failed to delete comment", http.StatusInternalServerError)
 			return
 		}
 
@@ -176,31 +197,50 @@ func CommentLike(cfg config.Config) http.HandlerFunc {
 			return
 		}
 
-		if err := store.DefaultStore().ReactToComment(req.CommentID, claims.AnonID, "like"); err != nil {
-			http.Error(w, err.Error(), http.StatusBadRequest)
+		err := store.DefaultStore().ReactToComment(r.Context(), req.CommentID, claims.AnonID, "like")
+		if errors.Is(err, store.ErrNotFound) {
+			http.Error(w, "comment not found", http.StatusNotFound)
+			return
+		}
+		if err != nil {
+			log.Printf("react to comment %s: %v", req.CommentID, err)
+			http.Error(w, "failed to react to comment", http.StatusInternalServerError)
 			return
 		}
 
-		comment, ok := store.DefaultStore().GetComment(req.CommentID)
-		if !ok {
+		comment, err := store.DefaultStore().GetComment(r.Context(), req.CommentID)
+		if errors.Is(err, store.ErrNotFound) {
 			http.Error(w, "comment not found", http.StatusNotFound)
 			return
 		}
+		if err != nil {
+			http.Error(w, "failed to load comment", http.StatusInternalServerError)
+			return
+		}
 
-		reaction, _ := store.DefaultStore().GetCommentReaction(comment.ID, claims.AnonID)
+		reaction, err := store.DefaultStore().GetCommentReaction(r.Context(), comment.ID, claims.AnonID)
+		if err != nil {
+			http.Error(w, "failed to load comment", http.StatusInternalServerError)
+			return
+		}
+		repliesCount, err := store.DefaultStore().GetCommentRepliesCount(r.Context(), comment.ID)
+		if err != nil {
+			http.Error(w, "failed to load comment", http.StatusInternalServerError)
+			return
+		}
 
 		w.Header().Set("Content-Type", "application/json")
 		_ = json.NewEncoder(w).Encode(types.CommentDTO{
 			ID:           comment.ID,
 			PostID:       comment.PostID,
 			AnonID:       comment.AnonID,
-			Username:     getUsernameByAnonID(comment.AnonID),
+			Username:     getUsernameByAnonID(r.Context(), comment.AnonID),
 			Text:         comment.Text,
 			CreatedAt:    comment.CreatedAt.Format(time.RFC3339),
 			Likes:        comment.Likes,
 			Dislikes:     comment.Dislikes,
 			UserReaction: reaction,
-			RepliesCount: store.DefaultStore().GetCommentRepliesCount(comment.ID),
+			RepliesCount: repliesCount,
 			Deleted:      comment.Deleted,
 		})
 	}
@@ -225,31 +265,50 @@ func CommentDislike(cfg config.Config) http.HandlerFunc {
 			return
 		}
 
-		if err := store.DefaultStore().ReactToComment(req.CommentID, claims.AnonID, "dislike"); err != nil {
-			http.Error(w, err.Error(), http.StatusBadRequest)
+		err := store.DefaultStore().ReactToComment(r.Context(), req.CommentID, claims.AnonID, "dislike")
+		if errors.Is(err, store.ErrNotFound) {
+			http.Error(w, "comment not found", http.StatusNotFound)
+			return
+		}
+		if err != nil {
+			log.Printf("react to comment %s: %v", req.CommentID, err)
+			http.Error(w, "failed to react to comment", http.StatusInternalServerError)
 			return
 		}
 
-		comment, ok := store.DefaultStore().GetComment(req.CommentID)
-		if !ok {
+		comment, err := store.DefaultStore().GetComment(r.Context(), req.CommentID)
+		if errors.Is(err, store.ErrNotFound) {
 			http.Error(w, "comment not found", http.StatusNotFound)
 			return
 		}
+		if err != nil {
+			http.Error(w, "failed to load comment", http.StatusInternalServerError)
+			return
+		}
 
-		reaction, _ := store.DefaultStore().GetCommentReaction(comment.ID, claims.AnonID)
+		reaction, err := store.DefaultStore().GetCommentReaction(r.Context(), comment.ID, claims.AnonID)
+		if err != nil {
+			http.Error(w, "failed to load comment", http.StatusInternalServerError)
+			return
+		}
+		repliesCount, err := store.DefaultStore().GetCommentRepliesCount(r.Context(), comment.ID)
+		if err != nil {
+			http.Error(w, "failed to load comment", http.StatusInternalServerError)
+			return
+		}
 
 		w.Header().Set("Content-Type", "application/json")
 		_ = json.NewEncoder(w).Encode(types.CommentDTO{
 			ID:           comment.ID,
 			PostID:       comment.PostID,
 			AnonID:       comment.AnonID,
-			Username:     getUsernameByAnonID(comment.AnonID),
+			Username:     getUsernameByAnonID(r.Context(), comment.AnonID),
 			Text:         comment.Text,
 			CreatedAt:    comment.CreatedAt.Format(time.RFC3339),
 			Likes:        comment.Likes,
 			Dislikes:     comment.Dislikes,
 			UserReaction: reaction,
-			RepliesCount: store.DefaultStore().GetCommentRepliesCount(comment.ID),
+			RepliesCount: repliesCount,
 			Deleted:      comment.Deleted,
 		})
 	}
@@ -292,6 +351,13 @@ func CommentReplyCreate(cfg config.Config) http.HandlerFunc {
 		}
 
 		now := time.Now()
+		tier, err := quotaTierFor(r.Context(), cfg, claims.AnonID, now)
+		if err != nil {
+			log.Printf("quota tier for %s: %v", claims.AnonID, err)
+			writeJSONError(w, http.StatusInternalServerError, "failed to check quota")
+			return
+		}
+
 		reply := &store.CommentReply{
 			ID:        replyID,
 			CommentID: req.CommentID,
@@ -301,8 +367,20 @@ func CommentReplyCreate(cfg config.Config) http.HandlerFunc {
 			Deleted:   false,
 		}
 
-		if err := store.DefaultStore().AddCommentReply(reply); err != nil {
-			http.Error(w, err.Error(), http.StatusBadRequest)
+		// The comment check, quota take and insert happen in one store transaction
+		_, err = store.DefaultStore().AddCommentReplyWithQuota(r.Context(), reply, tier.LimitsFor(config.QuotaReplies), now)
+		var exceeded *store.QuotaExceededError
+		if errors.As(err, &exceeded) {
+			writeQuotaExceeded(w, config.QuotaReplies, tier, exceeded.Result, now)
+			return
+		}
+		if errors.Is(err, store.ErrNotFound) {
+			http.Error(w, "comment not found", http.StatusNotFound)
+			return
+		}
+		if err != nil {
+			log.Printf("add reply: %v", err)
+			http.Error(w, "failed to create reply", http.StatusInternalServerError)
 			return
 		}
 
@@ -311,7 +389,7 @@ func CommentReplyCreate(cfg config.Config) http.HandlerFunc {
 			ID:        reply.ID,
 			CommentID: reply.CommentID,
 			AnonID:    reply.AnonID,
-			Username:  getUsernameByAnonID(reply.AnonID),
+			Username:  getUsernameByAnonID(r.Context(), reply.AnonID),
 			Text:      reply.Text,
 			CreatedAt: reply.CreatedAt.Format(time.RFC3339),
 			Deleted:   reply.Deleted,
@@ -333,23 +411,17 @@ func CommentReplyGet(cfg config.Config) http.HandlerFunc {
 			return
 		}
 
-		replies := store.DefaultStore().GetCommentReplies(commentID)
-		out := make([]types.CommentReplyDTO, len(replies))
-
-		for i, reply := range replies {
-			reaction, _ := store.DefaultStore().GetReplyReaction(reply.ID, claims.AnonID)
-			out[i] = types.CommentReplyDTO{
-				ID:           reply.ID,
-				CommentID:    reply.CommentID,
-				AnonID:       reply.AnonID,
-				Username:     getUsernameByAnonID(reply.AnonID),
-				Text:         reply.Text,
-				CreatedAt:    reply.CreatedAt.Format(time.RFC3339),
-				Deleted:      reply.Deleted,
-				Likes:        reply.Likes,
-				Dislikes:     reply.Dislikes,
-				UserReaction: reaction,
-			}
+		replies, err := store.DefaultStore().GetCommentReplies(r.Context(), commentID)
+		if err != nil {
+			log.Printf("get replies for %s: %v", commentID, err)
+			http.Error(w, "failed to load replies", http.StatusInternalServerError)
+			return
+		}
+		out, err := replyDTOs(r.Context(), claims.AnonID, replies)
+		if err != nil {
+			log.Printf("build replies for %s: %v", commentID, err)
+			http.Error(w, "failed to load replies", http.StatusInternalServerError)
+			return
 		}
 
 		w.Header().Set("Content-Type", "application/json")
@@ -376,9 +448,17 @@ func CommentReplyDelete(cfg config.Config) http.HandlerFunc {
 			return
 		}
 
-		err := store.DefaultStore().DeleteCommentReplyByUser(req.ReplyID, claims.AnonID)
-		if err != nil {
-			http.Error(w, err.Error(), http.StatusForbidden)
+		err := store.DefaultStore().DeleteCommentReplyByUser(r.Context(), req.ReplyID, claims.AnonID)
+		switch {
+		case errors.Is(err, store.ErrNotFound):
+			http.Error(w, "reply not found", http.StatusNotFound)
+			return
+		case errors.Is(err, store.ErrForbidden):
+			http.Error(w, "not the reply author", http.StatusForbidden)
+			return
+		case err != nil:
+			log.Printf("delete reply %s: %v", req.ReplyID, err)
+			http.Error(w, "failed to delete reply", http.StatusInternalServerError)
 			return
 		}
 
@@ -406,25 +486,39 @@ func CommentReplyLike(cfg config.Config) http.HandlerFunc {
 			return
 		}
 
-		if err := store.DefaultStore().ReactToReply(req.ReplyID, claims.AnonID, "like"); err != nil {
-			http.Error(w, err.Error(), http.StatusBadRequest)
+		err := store.DefaultStore().ReactToReply(r.Context(), req.ReplyID, claims.AnonID, "like")
+		if errors.Is(err, store.ErrNotFound) {
+			http.Error(w, "reply not found", http.StatusNotFound)
+			return
+		}
+		if err != nil {
+			log.Printf("react to reply %s: %v", req.ReplyID, err)
+			http.Error(w, "failed to react to reply", http.StatusInternalServerError)
 			return
 		}
 
-		reply, ok := store.DefaultStore().GetReply(req.ReplyID)
-		if !ok {
+		reply, err := store.DefaultStore().GetReply(r.Context(), req.ReplyID)
+		if errors.Is(err, store.ErrNotFound) {
 			http.Error(w, "reply not found", http.StatusNotFound)
 			return
 		}
+		if err != nil {
+			http.Error(w, "failed to load reply", http.StatusInternalServerError)
+			return
+		}
 
-		reaction, _ := store.DefaultStore().GetReplyReaction(reply.ID, claims.AnonID)
+		reaction, err := store.DefaultStore().GetReplyReaction(r.Context(), reply.ID, claims.AnonID)
+		if err != nil {
+			http.Error(w, "failed to load reply", http.StatusInternalServerError)
+			return
+		}
 
 		w.Header().Set("Content-Type", "application/json")
 		_ = json.NewEncoder(w).Encode(types.CommentReplyDTO{
 			ID:           reply.ID,
 			CommentID:    reply.CommentID,
 			AnonID:       reply.AnonID,
-			Username:     getUsernameByAnonID(reply.AnonID),
+			Username:     getUsernameByAnonID(r.Context(), reply.AnonID),
 			Text:         reply.Text,
 			CreatedAt:    reply.CreatedAt.Format(time.RFC3339),
 			Deleted:      reply.Deleted,
@@ -454,25 +548,39 @@ func CommentReplyDislike(cfg config.Config) http.HandlerFunc {
 			return
 		}
 
-		if err := store.DefaultStore().ReactToReply(req.ReplyID, claims.AnonID, "dislike"); err != nil {
-			http.Error(w, err.Error(), http.StatusBadRequest)
+		err := store.DefaultStore().ReactToReply(r.Context(), req.ReplyID, claims.AnonID, "dislike")
+		if errors.Is(err, store.ErrNotFound) {
+			http.Error(w, "reply not found", http.StatusNotFound)
+			return
+		}
+		if err != nil {
+			log.Printf("react to reply %s: %v", req.ReplyID, err)
+			http.Error(w, "failed to react to reply", http.StatusInternalServerError)
 			return
 		}
 
-		reply, ok := store.DefaultStore().GetReply(req.ReplyID)
-		if !ok {
+		reply, err := store.DefaultStore().GetReply(r.Context(), req.ReplyID)
+		if errors.Is(err, store.ErrNotFound) {
 			http.Error(w, "reply not found", http.StatusNotFound)
 			return
 		}
+		if err != nil {
+			http.Error(w, "failed to load reply", http.StatusInternalServerError)
+			return
+		}
 
-		reaction, _ := store.DefaultStore().GetReplyReaction(reply.ID, claims.AnonID)
+		reaction, err := store.DefaultStore().GetReplyReaction(r.Context(), reply.ID, claims.AnonID)
+		if err != nil {
+			http.Error(w, "failed to load reply", http.StatusInternalServerError)
+			return
+		}
 
 		w.Header().Set("Content-Type", "application/json")
 		_ = json.NewEncoder(w).Encode(types.CommentReplyDTO{
 			ID:           reply.ID,
 			CommentID:    reply.CommentID,
 			AnonID:       reply.AnonID,
-			Username:     getUsernameByAnonID(reply.AnonID),
+			Username:     getUsernameByAnonID(r.Context(), reply.AnonID),
 			Text:         reply.Text,
 			CreatedAt:    reply.CreatedAt.Format(time.RFC3339),
 			Deleted:      reply.Deleted,
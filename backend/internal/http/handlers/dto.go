@@ -0,0 +1,128 @@
+package handlers
+
+import (
+	"context"
+	"time"
+
+	"anon-backend/internal/store"
+	"anon-backend/internal/types"
+)
+
+// The assemblers below turn a page of store rows into DTOs as seen by
+// viewer. Each costs a fixed number of store calls however long the page
+// is, so list handlers should use them instead of per-row lookups.
+
+// postDTOs builds the DTOs for posts, with viewer's reactions and the
+// authors' usernames.
+func postDTOs(ctx context.Context, viewer string, posts []*store.Post) ([]types.PostDTO, error) {
+	postIDs := make([]string, len(posts))
+	anonIDs := make([]string, len(posts))
+	for i, p := range posts {
+		postIDs[i] = p.ID
+		anonIDs[i] = p.AnonID
+	}
+
+	reactions, err := store.DefaultStore().GetPostReactionsFor(ctx, viewer, postIDs)
+	if err != nil {
+		return nil, err
+	}
+	usernames, err := store.DefaultStore().GetUsernames(ctx, anonIDs)
+	if err != nil {
+		return nil, err
+	}
+
+	out := make([]types.PostDTO, len(posts))
+	for i, p := range posts {
+		out[i] = types.PostDTO{
+			ID:           p.ID,
+			AnonID:       p.AnonID,
+			Username:     usernames[p.AnonID],
+			Text:         p.Text,
+			CreatedAt:    p.CreatedAt.Format(time.RFC3339),
+			Likes:        p.Likes,
+			Dislikes:     p.Dislikes,
+			UserReaction: reactions[p.ID],
+			Deleted:      p.Deleted,
+		}
+	}
+	return out, nil
+}
+
+// commentDTOs builds the DTOs for comments, with viewer's reactions, the
+// authors' usernames and the reply counts.
+func commentDTOs(ctx context.Context, viewer string, comments []*store.PostComment) ([]types.CommentDTO, error) {
+	commentIDs := make([]string, len(comments))
+	anonIDs := make([]string, len(comments))
+	for i, c := range comments {
+		commentIDs[i] = c.ID
+		anonIDs[i] = c.AnonID
+	}
+
+	reactions, err := store.DefaultStore().GetCommentReactionsFor(ctx, viewer, commentIDs)
+	if err != nil {
+		return nil, err
+	}
+	usernames, err := store.DefaultStore().GetUsernames(ctx, anonIDs)
+	if err != nil {
+		return nil, err
+	}
+	replyCounts, err := store.DefaultStore().GetReplyCounts(ctx, commentIDs)
+	if err != nil {
+		return nil, err
+	}
+
+	out := make([]types.CommentDTO, len(comments))
+	for i, c := range comments {
+		out[i] = types.CommentDTO{
+			ID:           c.ID,
+			PostID:       c.PostID,
+			AnonID:       c.AnonID,
+			Username:     usernames[c.AnonID],
+			Text:         c.Text,
+			CreatedAt:    c.CreatedAt.Format(time.RFC3339),
+			Likes:        c.Likes,
+			Dislikes:     c.Dislikes,
+			UserReaction: reactions[c.ID],
+			RepliesCount: replyCounts[c.ID],
+			Deleted:      c.Deleted,
+		}
+	}
+	return out, nil
+}
+
+// replyDTOs builds the DTOs for replies, with viewer's reactions and the
+// authors' usernames.
+func replyDTOs(ctx context.Context, viewer string, replies []*store.CommentReply) ([]types.CommentReplyDTO, error) {
+	replyIDs := make([]string, len(replies))
+	anonIDs := make([]string, len(replies))
+	for i, r := range replies {
+		replyIDs[i] = r.ID
+		anonIDs[i] = r.AnonID
+	}
+
+	reactions, err := store.DefaultStore().GetReplyReactionsFor(ctx, viewer, replyIDs)
+	if err != nil {
+		return nil, err
+	}
+	usernames, err := store.DefaultStore().GetUsernames(ctx, anonIDs)
+	if err != nil {
+		return nil, err
+	}
+
+	out := make([]types.CommentReplyDTO, len(replies))
+	for i, r := range replies {
+		out[i] = types.CommentReplyDTO{
+			ID:           r.ID,
+			CommentID:    r.CommentID,
+			AnonID:       r.AnonID,
+			Username:     usernames[r.AnonID],
+			Text:         r.Text,
+			CreatedAt:    r.CreatedAt.Format(time.RFC3339),
+			Deleted:      r.Deleted,
+			Likes:        r.Likes,
+			Dislikes:     r.Dislikes,
+			UserReaction: reactions[r.ID],
+		}
+	}
+	return out, nil
+}
@@ -2,6 +2,7 @@ package handlers
 
 import (
 	"encoding/json"
+	"log"
 	"net/http"
 	"strconv"
 	"time"
@@ -46,7 +47,11 @@ func GeoPing(cfg config.Config) http.HandlerFunc {
 			Timestamp: time.Now(),
 		}
 
-		store.DefaultStore().PutGeo(ping)
+		if err := store.DefaultStore().PutGeo(r.Context(), ping); err != nil {
+			log.Printf("put geo ping: %v", err)
+			http.Error(w, "failed to store ping", http.StatusInternalServerError)
+			return
+		}
 
 		w.Header().Set("Content-Type", "application/json")
 		_ = json.NewEncoder(w).Encode(types.GeoPingResponse{
@@ -108,7 +113,12 @@ func GeoNearby(cfg config.Config) http.HandlerFunc {
 		}
 
 		// Get nearby pings
-		pings := store.DefaultStore().GetNearby(lat, lng, km)
+		pings, err := store.DefaultStore().GetNearby(r.Context(), lat, lng, km)
+		if err != nil {
+			log.Printf("get nearby pings: %v", err)
+			http.Error(w, "failed to load nearby pings", http.StatusInternalServerError)
+			return
+		}
 		out := make([]types.GeoPingResponse, len(pings))
 
 		for i, ping := range pings {
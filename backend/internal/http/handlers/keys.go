@@ -0,0 +1,185 @@
+package handlers
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"strings"
+	"time"
+
+	"anon-backend/internal/config"
+	"anon-backend/internal/httpctx"
+	"anon-backend/internal/store"
+	"anon-backend/internal/types"
+
+	"github.com/go-chi/chi/v5"
+)
+
+const (
+	// maxPublicKeyLen bounds any base64 key or signature in the directory.
+	maxPublicKeyLen = 256
+	// maxOneTimePrekeysPerUpload and maxOneTimePrekeysPerDevice keep a
+	// device from flooding the directory.
+	maxOneTimePrekeysPerUpload = 100
+	maxOneTimePrekeysPerDevice = 200
+)
+
+func validPublicKey(s string) bool {
+	if s == "" || len(s) > maxPublicKeyLen {
+		return false
+	}
+	_, err := base64.StdEncoding.DecodeString(s)
+	return err == nil
+}
+
+// KeysUpload handles PUT /keys: publish the caller's device keys for E2E chat.
+// The identity and signed prekey replace earlier ones; one-time prekeys are
+// added to the device's pool.
+func KeysUpload(cfg config.Config) http.HandlerFunc {
+	_ = cfg
+
+	return func(w http.ResponseWriter, r *http.Request) {
+		claims := httpctx.ClaimsFromContext(r.Context())
+		if claims == nil {
+			writeJSONError(w, http.StatusUnauthorized, "no claims")
+			return
+		}
+
+		var req types.KeysUploadRequest
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			writeJSONError(w, http.StatusBadRequest, "bad json")
+			return
+		}
+
+		devicePublicID := strings.TrimSpace(req.DevicePublicID)
+		if devicePublicID == "" {
+			writeJSONError(w, http.StatusBadRequest, "device_public_id required")
+			return
+		}
+		if !validPublicKey(req.IdentityKey) || !validPublicKey(req.SignedPrekey.PublicKey) || !validPublicKey(req.SignedPrekey.Signature) {
+			writeJSONError(w, http.StatusBadRequest, "invalid identity or signed prekey")
+			return
+		}
+		if len(req.OneTimePrekeys) > maxOneTimePrekeysPerUpload {
+			writeJSONError(w, http.StatusBadRequest, "too many one-time prekeys")
+			return
+		}
+		oneTime := make([]store.OneTimePrekey, 0, len(req.OneTimePrekeys))
+		for _, k := range req.OneTimePrekeys {
+			if !validPublicKey(k.PublicKey) {
+				writeJSONError(w, http.StatusBadRequest, "invalid one-time prekey")
+				return
+			}
+			oneTime = append(oneTime, store.OneTimePrekey{KeyID: k.KeyID, PublicKey: k.PublicKey})
+		}
+
+		str := store.DefaultStore()
+
+		device, err := str.GetDevice(r.Context(), devicePublicID)
+		if err != nil || device.AnonID != claims.AnonID {
+			writeJSONError(w, http.StatusForbidden, "device does not belong to session")
+			return
+		}
+
+		remaining, err := str.CountOneTimePrekeys(r.Context(), devicePublicID)
+		if err != nil {
+			writeJSONError(w, http.StatusInternalServerError, "failed to load keys")
+			return
+		}
+		if remaining+len(oneTime) > maxOneTimePrekeysPerDevice {
+			writeJSONError(w, http.StatusBadRequest, "one-time prekey pool full")
+			return
+		}
+
+		bundle := &store.DeviceKeyBundle{
+			DevicePublicID:        devicePublicID,
+			AnonID:                claims.AnonID,
+			IdentityKey:           req.IdentityKey,
+			SignedPrekeyID:        req.SignedPrekey.KeyID,
+			SignedPrekey:          req.SignedPrekey.PublicKey,
+			SignedPrekeySignature: req.SignedPrekey.Signature,
+			UpdatedAt:             time.Now(),
+		}
+		if err := str.PutDeviceKeys(r.Context(), bundle, oneTime); err != nil {
+			writeJSONError(w, http.StatusInternalServerError, "failed to store keys")
+			return
+		}
+
+		remaining, err = str.CountOneTimePrekeys(r.Context(), devicePublicID)
+		if err != nil {
+			writeJSONError(w, http.StatusInternalServerError, "failed to load keys")
+			return
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		_ = json.NewEncoder(w).Encode(types.KeysUploadResponse{OneTimePrekeysRemaining: remaining})
+	}
+}
+
+// KeysGet handles GET /keys/{anonId}: fetch a key bundle per device of a
+// trusted peer (or of the caller's own other devices) to start an E2E
+// session. Each call consumes one one-time prekey per device.
+func KeysGet(cfg config.Config) http.HandlerFunc {
+	_ = cfg
+
+	return func(w http.ResponseWriter, r *http.Request) {
+		claims := httpctx.ClaimsFromContext(r.Context())
+		if claims == nil {
+			writeJSONError(w, http.StatusUnauthorized, "no claims")
+			return
+		}
+
+		target := strings.TrimSpace(chi.URLParam(r, "anonId"))
+		if target == "" {
+			writeJSONError(w, http.StatusBadRequest, "anon id required")
+			return
+		}
+
+		str := store.DefaultStore()
+		if target != claims.AnonID {
+			trusted, err := str.TrustAccepted(r.Context(), claims.AnonID, target)
+			if err != nil {
+				writeJSONError(w, http.StatusInternalServerError, "failed to check trust")
+				return
+			}
+			if !trusted {
+				writeJSONError(w, http.StatusForbidden, "not trusted")
+				return
+			}
+		}
+
+		bundles, err := str.ClaimDeviceKeys(r.Context(), target)
+		if err != nil {
+			if errors.Is(err, store.ErrDeviceKeysNotFound) {
+				writeJSONError(w, http.StatusNotFound, "no keys published")
+				return
+			}
+			writeJSONError(w, http.StatusInternalServerError, "failed to load keys")
+			return
+		}
+
+		out := make([]types.DeviceKeyBundleDTO, 0, len(bundles))
+		for _, b := range bundles {
+			dto := types.DeviceKeyBundleDTO{
+				DevicePublicID: b.DevicePublicID,
+				IdentityKey:    b.IdentityKey,
+				SignedPrekey: types.SignedPrekeyDTO{
+					KeyID:     b.SignedPrekeyID,
+					PublicKey: b.SignedPrekey,
+					Signature: b.SignedPrekeySignature,
+				},
+			}
+			if b.OneTimePrekey != nil {
+				dto.OneTimePrekey = &types.OneTimePrekeyDTO{
+					KeyID:     b.OneTimePrekey.KeyID,
+					PublicKey: b.OneTimePrekey.PublicKey,
+				}
+			}
+			out = append(out, dto)
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		_ = json.NewEncoder(w).Encode(types.KeysBundleResponse{AnonID: target, Devices: out})
+	}
+}
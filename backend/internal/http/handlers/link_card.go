@@ -44,7 +44,7 @@ func LinkCardCreate(cfg config.Config) http.HandlerFunc {
 			ExpiresAt: now.Add(time.Duration(ttlMin) * time.Minute),
 		}
 
-		if err := store.DefaultStore().PutCard(card); err != nil {
+		if err := store.DefaultStore().PutCard(r.Context(), card); err != nil {
 			log.Printf("persist link card: failed: %v", err)
 			http.Error(w, "failed to persist link card", http.StatusInternalServerError)
 			return
@@ -68,7 +68,11 @@ func LinkCardMine(cfg config.Config) http.HandlerFunc {
 			return
 		}
 
-		cards := store.DefaultStore().CardsByOwner(claims.AnonID)
+		cards, err := store.DefaultStore().CardsByOwner(r.Context(), claims.AnonID)
+		if err != nil {
+			http.Error(w, "failed to load link cards", http.StatusInternalServerError)
+			return
+		}
 		out := make([]types.LinkCardDTO, 0, len(cards))
 
 		now := time.Now()
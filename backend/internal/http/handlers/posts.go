@@ -1,8 +1,13 @@
 package handlers
 
 import (
+	"context"
 	"encoding/json"
+	"errors"
+	"log"
 	"net/http"
+	"net/url"
+	"strconv"
 	"strings"
 	"time"
 
@@ -14,8 +19,8 @@ import (
 )
 
 // getUsernameByAnonID retrieves the username for a given anon_id from the devices table
-func getUsernameByAnonID(anonID string) string {
-	device, err := store.DefaultStore().GetDeviceByAnonID(anonID)
+func getUsernameByAnonID(ctx context.Context, anonID string) string {
+	device, err := store.DefaultStore().GetDeviceByAnonID(ctx, anonID)
 	if err != nil || device == nil {
 		return "" // Return empty if not found
 	}
@@ -51,12 +56,6 @@ func PostCreate(cfg config.Config) http.HandlerFunc {
 			return
 		}
 
-		// Check daily limit (3 posts per day)
-		if !store.DefaultStore().CanCreatePost(claims.AnonID) {
-			http.Error(w, "daily post limit reached", http.StatusTooManyRequests)
-			return
-		}
-
 		// Create post
 		postID, err := security.NewInviteCode(16)
 		if err != nil {
@@ -65,6 +64,13 @@ func PostCreate(cfg config.Config) http.HandlerFunc {
 		}
 
 		now := time.Now()
+		tier, err := quotaTierFor(r.Context(), cfg, claims.AnonID, now)
+		if err != nil {
+			log.Printf("quota tier for %s: %v", claims.AnonID, err)
+			writeJSONError(w, http.StatusInternalServerError, "failed to check quota")
+			return
+		}
+
 		post := &store.Post{
 			ID:        postID,
 			AnonID:    claims.AnonID,
@@ -75,17 +81,27 @@ func PostCreate(cfg config.Config) http.HandlerFunc {
 			Deleted:   false,
 		}
 
-		store.DefaultStore().PutPost(post)
+		// Quota check, increment and insert happen in one store transaction
+		quota, err := store.DefaultStore().CreatePostWithQuota(r.Context(), post, tier.LimitsFor(config.QuotaPosts), now)
+		var exceeded *store.QuotaExceededError
+		if errors.As(err, &exceeded) {
+			writeQuotaExceeded(w, config.QuotaPosts, tier, exceeded.Result, now)
+			return
+		}
+		if err != nil {
+			log.Printf("create post for %s: %v", claims.AnonID, err)
+			writeJSONError(w, http.StatusInternalServerError, "failed to create post")
+			return
+		}
 
-		// Get remaining posts count
-		remainingPosts := store.DefaultStore().GetRemainingPosts(claims.AnonID)
+		postsQuota := quotaDTO(quota.Usage)
 
 		w.Header().Set("Content-Type", "application/json")
 		_ = json.NewEncoder(w).Encode(types.PostCreateResponse{
 			Post: types.PostDTO{
 				ID:           post.ID,
 				AnonID:       post.AnonID,
-				Username:     getUsernameByAnonID(post.AnonID),
+				Username:     getUsernameByAnonID(r.Context(), post.AnonID),
 				Text:         post.Text,
 				CreatedAt:    post.CreatedAt.Format(time.RFC3339),
 				Likes:        post.Likes,
@@ -93,11 +109,17 @@ func PostCreate(cfg config.Config) http.HandlerFunc {
 				UserReaction: "", // New post, no reaction yet
 				Deleted:      post.Deleted,
 			},
-			PostsRemaining: remainingPosts,
+			PostsRemaining: postsQuota.Remaining,
+			Quota:          postsQuota,
 		})
 	}
 }
 
+// PostFeed handles GET /posts/feed?scope=all&before=<cursor>&limit=50
+// scope is all, trusted (authors the caller has an accepted trust with),
+// following (posts carrying a tag the caller follows) or nearby (needs lat
+// and lng, km defaults to 5). Posts are returned newest first; pass
+// next_cursor back as before to page.
 func PostFeed(cfg config.Config) http.HandlerFunc {
 	return func(w http.ResponseWriter, r *http.Request) {
 		claims := httpctx.ClaimsFromContext(r.Context())
@@ -106,32 +128,98 @@ func PostFeed(cfg config.Config) http.HandlerFunc {
 			return
 		}
 
-		// Get up to 50 newest posts
-		posts := store.DefaultStore().GetFeed(50)
-		out := make([]types.PostDTO, len(posts))
-
-		for i, post := range posts {
-			// Get user's reaction to this post
-			userReaction, _ := store.DefaultStore().GetPostReaction(post.ID, claims.AnonID)
+		q := r.URL.Query()
+		feedQuery := store.FeedQuery{Scope: q.Get("scope"), Viewer: claims.AnonID}
+		if err := parseFeedPage(q, &feedQuery); err != nil {
+			http.Error(w, "invalid cursor", http.StatusBadRequest)
+			return
+		}
 
-			out[i] = types.PostDTO{
-				ID:           post.ID,
-				AnonID:       post.AnonID,
-				Username:     getUsernameByAnonID(post.AnonID),
-				Text:         post.Text,
-				CreatedAt:    post.CreatedAt.Format(time.RFC3339),
-				Likes:        post.Likes,
-				Dislikes:     post.Dislikes,
-				UserReaction: userReaction,
-				Deleted:      post.Deleted,
+		if feedQuery.Scope == store.FeedScopeNearby {
+			latStr, lngStr := q.Get("lat"), q.Get("lng")
+			if latStr == "" || lngStr == "" {
+				http.Error(w, "lat and lng required", http.StatusBadRequest)
+				return
+			}
+			lat, err := strconv.ParseFloat(latStr, 64)
+			if err != nil || lat < -90 || lat > 90 {
+				http.Error(w, "lat must be between -90 and 90", http.StatusBadRequest)
+				return
+			}
+			lng, err := strconv.ParseFloat(lngStr, 64)
+			if err != nil || lng < -180 || lng > 180 {
+				http.Error(w, "lng must be between -180 and 180", http.StatusBadRequest)
+				return
+			}
+			feedQuery.Lat, feedQuery.Lng, feedQuery.RadiusKm = lat, lng, 5
+			if kmStr := q.Get("km"); kmStr != "" {
+				if km, err := strconv.ParseFloat(kmStr, 64); err == nil && km > 0 {
+					feedQuery.RadiusKm = km
+				}
 			}
 		}
 
+		posts, err := store.DefaultStore().GetFeedPage(r.Context(), feedQuery)
+		if errors.Is(err, store.ErrInvalidFeedScope) {
+			http.Error(w, "scope must be all, trusted, following or nearby", http.StatusBadRequest)
+			return
+		}
+		if err != nil {
+			log.Printf("get feed: %v", err)
+			http.Error(w, "failed to load feed", http.StatusInternalServerError)
+			return
+		}
+		resp, err := feedResponse(r.Context(), claims.AnonID, posts, feedQuery.Limit)
+		if err != nil {
+			log.Printf("build feed: %v", err)
+			http.Error(w, "failed to load feed", http.StatusInternalServerError)
+			return
+		}
+
 		w.Header().Set("Content-Type", "application/json")
-		_ = json.NewEncoder(w).Encode(types.PostFeedResponse{Posts: out})
+		_ = json.NewEncoder(w).Encode(resp)
 	}
 }
 
+// parseFeedPage reads the limit (default 50, at most 100) and before
+// parameters into fq. It fails only on a malformed cursor.
+func parseFeedPage(q url.Values, fq *store.FeedQuery) error {
+	fq.Limit = 50
+	if limitStr := q.Get("limit"); limitStr != "" {
+		if l, err := strconv.Atoi(limitStr); err == nil && l > 0 {
+			fq.Limit = l
+		}
+	}
+	if fq.Limit > 100 {
+		fq.Limit = 100
+	}
+
+	if beforeStr := q.Get("before"); beforeStr != "" {
+		c, err := store.ParseCursor(beforeStr)
+		if err != nil {
+			return err
+		}
+		fq.Before = c
+	}
+	return nil
+}
+
+// feedResponse renders a page of GetFeedPage for viewer. A full page gets
+// a cursor to the next one.
+func feedResponse(ctx context.Context, viewer string, posts []*store.Post, limit int) (types.PostFeedResponse, error) {
+	out, err := postDTOs(ctx, viewer, posts)
+	if err != nil {
+		return types.PostFeedResponse{}, err
+	}
+
+	resp := types.PostFeedResponse{Posts: out}
+	if len(posts) == limit {
+		last := posts[len(posts)-1]
+		resp.NextCursor = store.Cursor{CreatedAt: last.CreatedAt, ID: last.ID}.Encode()
+	}
+	return resp, nil
+}
+
 func PostRemainingCount(cfg config.Config) http.HandlerFunc {
 	return func(w http.ResponseWriter, r *http.Request) {
 		claims := httpctx.ClaimsFromContext(r.Context())
@@ -140,10 +228,26 @@ func PostRemainingCount(cfg config.Config) http.HandlerFunc {
 			return
 		}
 
-		remaining := store.DefaultStore().GetRemainingPosts(claims.AnonID)
+		now := time.Now()
+		tier, err := quotaTierFor(r.Context(), cfg, claims.AnonID, now)
+		if err != nil {
+			http.Error(w, "failed to load quota", http.StatusInternalServerError)
+			return
+		}
+
+		resp := types.PostRemainingResponse{Tier: tier.Name, Quotas: make(map[string]types.QuotaDTO, len(quotaResources))}
+		for _, resource := range quotaResources {
+			usage, err := store.DefaultStore().GetQuotaUsage(r.Context(), claims.AnonID, resource, tier.LimitsFor(resource), now)
+			if err != nil {
+				http.Error(w, "failed to load quota", http.StatusInternalServerError)
+				return
+			}
+			resp.Quotas[resource] = quotaDTO(usage)
+		}
+		resp.Remaining = resp.Quotas[config.QuotaPosts].Remaining
 
 		w.Header().Set("Content-Type", "application/json")
-		_ = json.NewEncoder(w).Encode(map[string]int{"remaining": remaining})
+		_ = json.NewEncoder(w).Encode(resp)
 	}
 }
 
@@ -166,9 +270,17 @@ func PostDelete(cfg config.Config) http.HandlerFunc {
 			return
 		}
 
-		err := store.DefaultStore().DeletePostByUser(req.PostID, claims.AnonID)
-		if err != nil {
-			http.Error(w, err.Error(), http.StatusForbidden)
+		err := store.DefaultStore().DeletePostByUser(r.Context(), req.PostID, claims.AnonID)
+		switch {
+		case errors.Is(err, store.ErrNotFound):
+			http.Error(w, "post not found", http.StatusNotFound)
+			return
+		case errors.Is(err, store.ErrForbidden):
+			http.Error(w, "not the post author", http.StatusForbidden)
+			return
+		case err != nil:
+			log.Printf("delete post %s: %v", req.PostID, err)
+			http.Error(w, "failed to delete post", http.StatusInternalServerError)
 			return
 		}
 
@@ -196,27 +308,40 @@ func PostLike(cfg config.Config) http.HandlerFunc {
 			return
 		}
 
-		err := store.DefaultStore().ReactToPost(req.PostID, claims.AnonID, "like")
+		err := store.DefaultStore().ReactToPost(r.Context(), req.PostID, claims.AnonID, "like")
+		if errors.Is(err, store.ErrNotFound) {
+			http.Error(w, "post not found", http.StatusNotFound)
+			return
+		}
 		if err != nil {
-			http.Error(w, err.Error(), http.StatusBadRequest)
+			log.Printf("react to post %s: %v", req.PostID, err)
+			http.Error(w, "failed to react to post", http.StatusInternalServerError)
 			return
 		}
 
 		// Get updated post
-		post, found := store.DefaultStore().GetPost(req.PostID)
-		if !found {
+		post, err := store.DefaultStore().GetPost(r.Context(), req.PostID)
+		if errors.Is(err, store.ErrNotFound) {
 			http.Error(w, "post not found", http.StatusNotFound)
 			return
 		}
+		if err != nil {
+			http.Error(w, "failed to load post", http.StatusInternalServerError)
+			return
+		}
 
 		// Get user's reaction
-		userReaction, _ := store.DefaultStore().GetPostReaction(req.PostID, claims.AnonID)
+		userReaction, err := store.DefaultStore().GetPostReaction(r.Context(), req.PostID, claims.AnonID)
+		if err != nil {
+			http.Error(w, "failed to load post", http.StatusInternalServerError)
+			return
+		}
 
 		w.Header().Set("Content-Type", "application/json")
 		_ = json.NewEncoder(w).Encode(types.PostDTO{
 			ID:           post.ID,
 			AnonID:       post.AnonID,
-			Username:     getUsernameByAnonID(post.AnonID),
+			Username:     getUsernameByAnonID(r.Context(), post.AnonID),
 			Text:         post.Text,
 			CreatedAt:    post.CreatedAt.Format(time.RFC3339),
 			Likes:        post.Likes,
@@ -246,27 +371,40 @@ func PostDislike(cfg config.Config) http.HandlerFunc {
 			return
 		}
 
-		err := store.DefaultStore().ReactToPost(req.PostID, claims.AnonID, "dislike")
+		err := store.DefaultStore().ReactToPost(r.Context(), req.PostID, claims.AnonID, "dislike")
+		if errors.Is(err, store.ErrNotFound) {
+			http.Error(w, "post not found", http.StatusNotFound)
+			return
+		}
 		if err != nil {
-			http.Error(w, err.Error(), http.StatusBadRequest)
+			log.Printf("react to post %s: %v", req.PostID, err)
+			http.Error(w, "failed to react to post", http.StatusInternalServerError)
 			return
 		}
 
 		// Get updated post
-		post, found := store.DefaultStore().GetPost(req.PostID)
-		if !found {
+		post, err := store.DefaultStore().GetPost(r.Context(), req.PostID)
+		if errors.Is(err, store.ErrNotFound) {
 			http.Error(w, "post not found", http.StatusNotFound)
 			return
 		}
+		if err != nil {
+			http.Error(w, "failed to load post", http.StatusInternalServerError)
+			return
+		}
 
 		// Get user's reaction
-		userReaction, _ := store.DefaultStore().GetPostReaction(req.PostID, claims.AnonID)
+		userReaction, err := store.DefaultStore().GetPostReaction(r.Context(), req.PostID, claims.AnonID)
+		if err != nil {
+			http.Error(w, "failed to load post", http.StatusInternalServerError)
+			return
+		}
 
 		w.Header().Set("Content-Type", "application/json")
 		_ = json.NewEncoder(w).Encode(types.PostDTO{
 			ID:           post.ID,
 			AnonID:       post.AnonID,
-			Username:     getUsernameByAnonID(post.AnonID),
+			Username:     getUsernameByAnonID(r.Context(), post.AnonID),
 			Text:         post.Text,
 			CreatedAt:    post.CreatedAt.Format(time.RFC3339),
 			Likes:        post.Likes,
@@ -28,12 +28,12 @@ func ProfileMeGet(cfg config.Config) http.HandlerFunc {
 			return
 		}
 		now := time.Now()
-		if err := store.DefaultStore().EnsureProfileForAnon(claims.AnonID, claims.Region, now); err != nil {
+		if err := store.DefaultStore().EnsureProfileForAnon(r.Context(), claims.AnonID, claims.Region, now); err != nil {
 			writeJSONError(w, http.StatusNotFound, "profile not found")
 			return
 		}
 
-		profile, err := store.DefaultStore().GetProfileByAnonID(claims.AnonID)
+		profile, err := store.DefaultStore().GetProfileByAnonID(r.Context(), claims.AnonID)
 		if err != nil {
 			writeJSONError(w, http.StatusNotFound, "profile not found")
 			return
@@ -42,7 +42,7 @@ func ProfileMeGet(cfg config.Config) http.HandlerFunc {
 			profile.Region = strings.TrimSpace(claims.Region)
 		}
 
-		deviceInfo, err := store.DefaultStore().GetProfileDeviceInfo(claims.AnonID)
+		deviceInfo, err := store.DefaultStore().GetProfileDeviceInfo(r.Context(), claims.AnonID)
 		if err != nil {
 			writeJSONError(w, http.StatusInternalServerError, "failed to load device info")
 			return
@@ -86,7 +86,7 @@ func ProfileMePatch(cfg config.Config) http.HandlerFunc {
 			return
 		}
 
-		if err := store.DefaultStore().EnsureProfileForAnon(claims.AnonID, claims.Region, time.Now()); err != nil {
+		if err := store.DefaultStore().EnsureProfileForAnon(r.Context(), claims.AnonID, claims.Region, time.Now()); err != nil {
 			writeJSONError(w, http.StatusNotFound, "profile not found")
 			return
 		}
@@ -128,7 +128,7 @@ func ProfileMePatch(cfg config.Config) http.HandlerFunc {
 			update.IsRegionPublic = req.IsRegionPublic
 		}
 
-		profile, err := store.DefaultStore().UpdateProfile(claims.AnonID, update, time.Now())
+		profile, err := store.DefaultStore().UpdateProfile(r.Context(), claims.AnonID, update, time.Now())
 		if err != nil {
 			switch {
 			case errors.Is(err, store.ErrUsernameTaken):
@@ -143,7 +143,7 @@ func ProfileMePatch(cfg config.Config) http.HandlerFunc {
 			}
 		}
 
-		deviceInfo, err := store.DefaultStore().GetProfileDeviceInfo(claims.AnonID)
+		deviceInfo, err := store.DefaultStore().GetProfileDeviceInfo(r.Context(), claims.AnonID)
 		if err != nil {
 			writeJSONError(w, http.StatusInternalServerError, "failed to load device info")
 			return
@@ -194,20 +194,20 @@ func ProfileByAnonGet(cfg config.Config) http.HandlerFunc {
 		}
 
 		now := time.Now()
-		if err := store.DefaultStore().EnsureProfileForAnon(targetAnonID, "", now); err != nil {
+		if err := store.DefaultStore().EnsureProfileForAnon(r.Context(), targetAnonID, "", now); err != nil {
 			writeJSONError(w, http.StatusNotFound, "profile not found")
 			return
 		}
 		if claims.AnonID == targetAnonID {
-			_ = store.DefaultStore().EnsureProfileForAnon(claims.AnonID, claims.Region, now)
+			_ = store.DefaultStore().EnsureProfileForAnon(r.Context(), claims.AnonID, claims.Region, now)
 		}
 
-		if err := store.DefaultStore().IncrementProfileView(targetAnonID, claims.AnonID); err != nil {
+		if err := store.DefaultStore().IncrementProfileView(r.Context(), targetAnonID, claims.AnonID); err != nil {
 			writeJSONError(w, http.StatusInternalServerError, "failed to update profile views")
 			return
 		}
 
-		profile, err := store.DefaultStore().GetProfileByAnonID(targetAnonID)
+		profile, err := store.DefaultStore().GetProfileByAnonID(r.Context(), targetAnonID)
 		if err != nil {
 			writeJSONError(w, http.StatusNotFound, "profile not found")
 			return
@@ -246,27 +246,25 @@ func ProfilePostsByAnonGet(cfg config.Config) http.HandlerFunc {
 			return
 		}
 
-		posts := store.DefaultStore().GetPostsByAnonID(targetAnonID, 100)
-		profile, err := store.DefaultStore().GetProfileByAnonID(targetAnonID)
-		if err != nil {
+		_, err := store.DefaultStore().GetProfileByAnonID(r.Context(), targetAnonID)
+		if errors.Is(err, store.ErrNotFound) {
 			writeJSONError(w, http.StatusNotFound, "profile not found")
 			return
 		}
+		if err != nil {
+			writeJSONError(w, http.StatusInternalServerError, "failed to load profile")
+			return
+		}
+		posts, err := store.DefaultStore().GetPostsByAnonID(r.Context(), targetAnonID, 100)
+		if err != nil {
+			writeJSONError(w, http.StatusInternalServerError, "failed to load posts")
+			return
+		}
 
-		out := make([]types.PostDTO, 0, len(posts))
-		for _, post := range posts {
-			reaction, _ := store.DefaultStore().GetPostReaction(post.ID, claims.AnonID)
-			out = append(out, types.PostDTO{
-				ID:           post.ID,
-				AnonID:       post.AnonID,
-				Username:     profile.Username,
-				Text:         post.Text,
-				CreatedAt:    post.CreatedAt.Format(time.RFC3339),
-				Likes:        post.Likes,
-				Dislikes:     post.Dislikes,
-				UserReaction: reaction,
-				Deleted:      post.Deleted,
-			})
+		out, err := postDTOs(r.Context(), claims.AnonID, posts)
+		if err != nil {
+			writeJSONError(w, http.StatusInternalServerError, "failed to load posts")
+			return
 		}
 
 		w.Header().Set("Content-Type", "application/json")
@@ -318,14 +316,14 @@ func UsernameCheck(cfg config.Config) http.HandlerFunc {
 		}
 
 		requested := store.BuildUsernameFromSuffix(suffix)
-		currentProfile, _ := store.DefaultStore().GetProfileByAnonID(claims.AnonID)
+		currentProfile, _ := store.DefaultStore().GetProfileByAnonID(r.Context(), claims.AnonID)
 		if currentProfile != nil && strings.EqualFold(currentProfile.Username, requested) {
 			w.Header().Set("Content-Type", "application/json")
 			_ = json.NewEncoder(w).Encode(types.UsernameCheckResponse{Available: true, Message: usernameAvailableMessage})
 			return
 		}
 
-		available, err := store.DefaultStore().IsUsernameAvailable(requested, claims.AnonID)
+		available, err := store.DefaultStore().IsUsernameAvailable(r.Context(), requested, claims.AnonID)
 		if err != nil {
 			writeJSONError(w, http.StatusInternalServerError, "failed to check username")
 			return
@@ -0,0 +1,77 @@
+package handlers
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math"
+	"net/http"
+	"strconv"
+	"time"
+
+	"anon-backend/internal/config"
+	"anon-backend/internal/store"
+	"anon-backend/internal/types"
+)
+
+// quotaResources are reported by PostRemainingCount, in this order.
+var quotaResources = []string{config.QuotaPosts, config.QuotaComments, config.QuotaReplies}
+
+// quotaTierFor picks the posting quota tier from the author's profile. An
+// anon without a profile yet counts as a brand-new, clean account.
+func quotaTierFor(ctx context.Context, cfg config.Config, anonID string, now time.Time) (config.QuotaTier, error) {
+	profile, err := store.DefaultStore().GetProfileByAnonID(ctx, anonID)
+	if errors.Is(err, store.ErrProfileNotFound) {
+		profile = &store.UserProfile{CreatedAt: now, StatusLabel: "Clean"}
+	} else if err != nil {
+		return config.QuotaTier{}, err
+	}
+	return cfg.QuotaTierFor(now.Sub(profile.CreatedAt), profile.TrustScore, profile.StatusLabel), nil
+}
+
+// writeQuotaExceeded answers 429 with the window that blocks the item.
+func writeQuotaExceeded(w http.ResponseWriter, resource string, tier config.QuotaTier, res store.QuotaResult, now time.Time) {
+	blocked, _ := res.Exhausted()
+	retryAfter := int(math.Ceil(blocked.ResetAt.Sub(now).Seconds()))
+	w.Header().Set("Retry-After", strconv.Itoa(retryAfter))
+	writeJSONErrorWithDetails(w, http.StatusTooManyRequests,
+		fmt.Sprintf("%s limit reached (%d per %s)", resource, blocked.Limit, blocked.Window),
+		"QUOTA_EXCEEDED",
+		map[string]interface{}{
+			"resource":    resource,
+			"tier":        tier.Name,
+			"window":      blocked.Window,
+			"limit":       blocked.Limit,
+			"reset_at":    blocked.ResetAt.Format(time.RFC3339),
+			"retry_after": retryAfter,
+		})
+}
+
+// quotaDTO summarises a resource's windows. Remaining is the smallest
+// remaining count and ResetAt when that window resets (the latest one on a
+// tie, since the item is blocked until then).
+func quotaDTO(usage []store.QuotaUsage) types.QuotaDTO {
+	out := types.QuotaDTO{Unlimited: len(usage) == 0, Windows: make([]types.QuotaWindowDTO, len(usage))}
+	var binding *store.QuotaUsage
+	for i := range usage {
+		u := &usage[i]
+		out.Windows[i] = types.QuotaWindowDTO{
+			Window:    u.Window,
+			Limit:     u.Limit,
+			Used:      u.Used,
+			Remaining: u.Remaining,
+			ResetAt:   u.ResetAt.Format(time.RFC3339),
+		}
+		if binding == nil || u.Remaining < binding.Remaining ||
+			(u.Remaining == binding.Remaining && u.ResetAt.After(binding.ResetAt)) {
+			binding = u
+		}
+	}
+	if binding != nil {
+		out.Remaining = binding.Remaining
+		out.ResetAt = binding.ResetAt.Format(time.RFC3339)
+	} else {
+		out.Remaining = -1
+	}
+	return out
+}
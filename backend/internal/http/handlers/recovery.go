@@ -0,0 +1,176 @@
+package handlers
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"strings"
+	"time"
+
+	"anon-backend/internal/config"
+	"anon-backend/internal/httpctx"
+	"anon-backend/internal/security"
+	"anon-backend/internal/store"
+	"anon-backend/internal/types"
+
+	"github.com/go-chi/chi/v5"
+)
+
+const deviceLinkTTL = 10 * time.Minute
+
+// RecoveryKeyCreate issues a new recovery key for the caller's identity,
+// replacing the previous one. The key is only ever shown in this response.
+func RecoveryKeyCreate(cfg config.Config) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		claims := httpctx.ClaimsFromContext(r.Context())
+		if claims == nil {
+			writeJSONError(w, http.StatusUnauthorized, "no claims")
+			return
+		}
+
+		key, hash, err := security.NewRecoveryKey()
+		if err != nil {
+			writeJSONError(w, http.StatusInternalServerError, "failed to create recovery key")
+			return
+		}
+
+		now := time.Now()
+		if err := store.DefaultStore().PutRecoveryKey(r.Context(), claims.AnonID, hash, now); err != nil {
+			writeJSONError(w, http.StatusInternalServerError, "failed to store recovery key")
+			return
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		_ = json.NewEncoder(w).Encode(types.RecoveryKeyResponse{
+			RecoveryKey: key,
+			CreatedAt:   now.Format(time.RFC3339),
+		})
+	}
+}
+
+// DeviceLinkStart is called by a new device that wants to join an existing
+// identity. It returns a short code to be approved from a signed-in device.
+func DeviceLinkStart(cfg config.Config) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		var req types.DeviceLinkStartRequest
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			writeJSONError(w, http.StatusBadRequest, "bad json")
+			return
+		}
+
+		devicePublicID := strings.TrimSpace(req.DevicePublicID)
+		if devicePublicID == "" {
+			writeJSONError(w, http.StatusBadRequest, "device_public_id required")
+			return
+		}
+		if _, err := store.DefaultStore().GetDevice(r.Context(), devicePublicID); err == nil {
+			writeJSONError(w, http.StatusConflict, "device already registered")
+			return
+		}
+
+		code, err := security.NewInviteCode(8)
+		if err != nil {
+			writeJSONError(w, http.StatusInternalServerError, "failed to create link code")
+			return
+		}
+
+		now := time.Now()
+		link := &store.DeviceLink{
+			Code:           code,
+			DevicePublicID: devicePublicID,
+			CreatedAt:      now,
+			ExpiresAt:      now.Add(deviceLinkTTL),
+		}
+		if err := store.DefaultStore().CreateDeviceLink(r.Context(), link); err != nil {
+			writeJSONError(w, http.StatusInternalServerError, "failed to create device link")
+			return
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		_ = json.NewEncoder(w).Encode(types.DeviceLinkStartResponse{
+			LinkCode:     code,
+			ExpiresInSec: int(deviceLinkTTL.Seconds()),
+		})
+	}
+}
+
+// DeviceLinkStatus lets the new device poll until its code is approved and
+// it can bootstrap with link_code. Only the device that started the link
+// may ask.
+func DeviceLinkStatus(cfg config.Config) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		code := strings.ToUpper(strings.TrimSpace(chi.URLParam(r, "code")))
+		devicePublicID := strings.TrimSpace(r.URL.Query().Get("device_public_id"))
+
+		link, err := store.DefaultStore().GetDeviceLink(r.Context(), code)
+		if err != nil || link.DevicePublicID != devicePublicID {
+			if err != nil && !errors.Is(err, store.ErrDeviceLinkNotFound) {
+				writeJSONError(w, http.StatusInternalServerError, "failed to load device link")
+				return
+			}
+			writeJSONError(w, http.StatusNotFound, "device link not found")
+			return
+		}
+
+		status := "pending"
+		switch {
+		case link.ConsumedAt != nil:
+			status = "consumed"
+		case !link.ExpiresAt.After(time.Now()):
+			status = "expired"
+		case link.ApprovedAt != nil:
+			status = "approved"
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		_ = json.NewEncoder(w).Encode(types.DeviceLinkStatusResponse{
+			Status:    status,
+			ExpiresAt: link.ExpiresAt.Format(time.RFC3339),
+		})
+	}
+}
+
+// DeviceLinkApprove binds a pending link code to the caller's identity.
+func DeviceLinkApprove(cfg config.Config) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		claims := httpctx.ClaimsFromContext(r.Context())
+		if claims == nil {
+			writeJSONError(w, http.StatusUnauthorized, "no claims")
+			return
+		}
+
+		var req types.DeviceLinkApproveRequest
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			writeJSONError(w, http.StatusBadRequest, "bad json")
+			return
+		}
+		code := strings.ToUpper(strings.TrimSpace(req.LinkCode))
+		if code == "" {
+			writeJSONError(w, http.StatusBadRequest, "link_code required")
+			return
+		}
+
+		link, err := store.DefaultStore().ApproveDeviceLink(r.Context(), code, claims.AnonID, time.Now())
+		if err != nil {
+			if errors.Is(err, store.ErrDeviceLinkNotFound) {
+				writeJSONError(w, http.StatusNotFound, "invalid or expired link code")
+				return
+			}
+			writeJSONError(w, http.StatusInternalServerError, "failed to approve device link")
+			return
+		}
+
+		recordAudit(r, store.AuditLog{
+			Action:  "device_link_approved",
+			AnonID:  claims.AnonID,
+			Target:  "device:" + link.DevicePublicID,
+			Details: "device_public_id=" + link.DevicePublicID,
+		})
+
+		w.Header().Set("Content-Type", "application/json")
+		_ = json.NewEncoder(w).Encode(map[string]interface{}{
+			"status":           "approved",
+			"device_public_id": link.DevicePublicID,
+		})
+	}
+}
@@ -1,6 +1,7 @@
 package handlers
 
 import (
+	"context"
 	"encoding/json"
 	"errors"
 	"fmt"
@@ -16,16 +17,16 @@ import (
 	"github.com/go-chi/chi/v5"
 )
 
-func submitPostReport(postID, reporterAnonID, reason string) error {
-	post, exists := store.DefaultStore().GetPost(postID)
-	if !exists {
-		return fmt.Errorf("post not found")
+func submitPostReport(ctx context.Context, postID, reporterAnonID, reason string) error {
+	post, err := store.DefaultStore().GetPost(ctx, postID)
+	if err != nil {
+		return err
 	}
 	now := time.Now()
-	if err := store.DefaultStore().ReportPost(postID, post.AnonID, reporterAnonID, reason, now); err != nil {
+	if err := store.DefaultStore().ReportPost(ctx, postID, post.AnonID, reporterAnonID, reason, now); err != nil {
 		return err
 	}
-	if err := store.DefaultStore().ReportPostV2(reporterAnonID, post.AnonID, postID, reason, now); err != nil {
+	if err := store.DefaultStore().ReportPostV2(ctx, reporterAnonID, post.AnonID, postID, reason, now); err != nil {
 		return err
 	}
 	return nil
@@ -56,7 +57,7 @@ func ReportProfile(cfg config.Config) http.HandlerFunc {
 		}
 
 		now := time.Now()
-		if err := store.DefaultStore().ReportProfile(claims.AnonID, target, req.Reason, now); err != nil {
+		if err := store.DefaultStore().ReportProfile(r.Context(), claims.AnonID, target, req.Reason, now); err != nil {
 			if errors.Is(err, store.ErrAlreadyReported) {
 				http.Error(w, "you already reported this profile", http.StatusConflict)
 				return
@@ -90,12 +91,12 @@ func ReportPost(cfg config.Config) http.HandlerFunc {
 			return
 		}
 
-		if err := submitPostReport(postID, claims.AnonID, req.Reason); err != nil {
+		if err := submitPostReport(r.Context(), postID, claims.AnonID, req.Reason); err != nil {
 			if errors.Is(err, store.ErrAlreadyReported) {
 				http.Error(w, "you already reported this post", http.StatusConflict)
 				return
 			}
-			if strings.Contains(strings.ToLower(err.Error()), "not found") {
+			if errors.Is(err, store.ErrNotFound) {
 				http.Error(w, "post not found", http.StatusNotFound)
 				return
 			}
@@ -131,11 +132,15 @@ func PostReport(cfg config.Config) http.HandlerFunc {
 			return
 		}
 
-		if err := submitPostReport(postID, claims.AnonID, req.Reason); err != nil {
+		if err := submitPostReport(r.Context(), postID, claims.AnonID, req.Reason); err != nil {
 			if errors.Is(err, store.ErrAlreadyReported) {
 				http.Error(w, "you already reported this post", http.StatusConflict)
 				return
 			}
+			if errors.Is(err, store.ErrNotFound) {
+				http.Error(w, "post not found", http.StatusNotFound)
+				return
+			}
 			http.Error(w, fmt.Sprintf("failed to report post: %v", err), http.StatusInternalServerError)
 			return
 		}
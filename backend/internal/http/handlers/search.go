@@ -5,7 +5,6 @@ import (
 	"net/http"
 	"strconv"
 	"strings"
-	"time"
 
 	"anon-backend/internal/config"
 	"anon-backend/internal/httpctx"
@@ -15,11 +14,15 @@ import (
 
 // PostSearch handles GET /posts/search?q=QUERY&limit=20&offset=0
 // Supports:
-// - Normal keyword search (e.g., "hero")
+// - Normal keyword search, stemmed (e.g., "hero" also finds "heroes")
+// - Phrases (e.g., "\"lost dog\"")
+// - Prefixes (e.g., "trav*")
 // - Hashtag search (e.g., "#fun")
 // - Multiple hashtags (e.g., "#fun #travel" - AND logic)
 // - Mixed queries (e.g., "hero #fun")
-// - Typo tolerance (fuzzy matching)
+// - Author search by username or anon id
+// - Typo tolerance (trigram matching when nothing else matches)
+// Highlights hold the post text with matched words in <mark></mark>.
 func PostSearch(cfg config.Config) http.HandlerFunc {
 	return func(w http.ResponseWriter, r *http.Request) {
 		claims := httpctx.ClaimsFromContext(r.Context())
@@ -53,46 +56,42 @@ func PostSearch(cfg config.Config) http.HandlerFunc {
 		}
 
 		// Parse query to extract hashtags and keywords
-		hashtags, keywords := parseSearchQuery(query)
+		searchQuery := store.ParseSearchQuery(query)
+		searchQuery.Limit = limit
+		searchQuery.Offset = offset
 
 		// Validate query
-		if keywords == "" && len(hashtags) == 0 {
+		if searchQuery.Text == "" && len(searchQuery.Hashtags) == 0 {
 			http.Error(w, "search query cannot be empty", http.StatusBadRequest)
 			return
 		}
 
 		// Perform search
-		results, totalCount, err := store.DefaultStore().SearchPosts(keywords, hashtags, limit, offset)
+		results, totalCount, err := store.DefaultStore().SearchPosts(r.Context(), searchQuery)
 		if err != nil {
 			http.Error(w, "search failed: "+err.Error(), http.StatusInternalServerError)
 			return
 		}
 
 		// Convert to DTOs
-		searchResults := make([]types.SearchResult, 0, len(results))
-		for _, result := range results {
-			// Get user's reaction to this post
-			userReaction := ""
-			if reaction, exists := store.DefaultStore().GetPostReaction(result.Post.ID, claims.AnonID); exists {
-				userReaction = reaction
-			}
+		posts := make([]*store.Post, len(results))
+		for i, result := range results {
+			posts[i] = result.Post
+		}
+		dtos, err := postDTOs(r.Context(), claims.AnonID, posts)
+		if err != nil {
+			http.Error(w, "search failed: "+err.Error(), http.StatusInternalServerError)
+			return
+		}
 
-			searchResults = append(searchResults, types.SearchResult{
-				Post: types.PostDTO{
-					ID:           result.Post.ID,
-					AnonID:       result.Post.AnonID,
-					Username:     getUsernameByAnonID(result.Post.AnonID),
-					Text:         result.Post.Text,
-					CreatedAt:    result.Post.CreatedAt.Format(time.RFC3339),
-					Likes:        result.Post.Likes,
-					Dislikes:     result.Post.Dislikes,
-					UserReaction: userReaction,
-					Deleted:      result.Post.Deleted,
-				},
+		searchResults := make([]types.SearchResult, len(results))
+		for i, result := range results {
+			searchResults[i] = types.SearchResult{
+				Post:           dtos[i],
 				RelevanceScore: result.RelevanceScore,
 				MatchedTerms:   result.MatchedTerms,
 				Highlights:     result.Highlights,
-			})
+			}
 		}
 
 		// Calculate next cursor for pagination
@@ -106,37 +105,13 @@ func PostSearch(cfg config.Config) http.HandlerFunc {
 			Query:      query,
 			TotalCount: totalCount,
 			NextCursor: nextCursor,
-			Hashtags:   hashtags,
-			Keywords:   []string{keywords},
+			Hashtags:   searchQuery.Hashtags,
+		}
+		if searchQuery.Text != "" {
+			response.Keywords = []string{searchQuery.Text}
 		}
 
 		w.Header().Set("Content-Type", "application/json")
 		_ = json.NewEncoder(w).Encode(response)
 	}
 }
-
-// parseSearchQuery extracts hashtags and keywords from a search query
-// Example: "hero #fun #travel" -> hashtags: ["fun", "travel"], keywords: "hero"
-func parseSearchQuery(query string) ([]string, string) {
-	words := strings.Fields(query)
-	var hashtags []string
-	var keywords []string
-
-	for _, word := range words {
-		if strings.HasPrefix(word, "#") {
-			// Extract hashtag (remove # prefix, normalize to lowercase)
-			tag := strings.TrimPrefix(word, "#")
-			if tag != "" {
-				hashtags = append(hashtags, strings.ToLower(tag))
-			}
-		} else {
-			// Regular keyword
-			keywords = append(keywords, word)
-		}
-	}
-
-	// Join keywords with spaces
-	keywordStr := strings.Join(keywords, " ")
-
-	return hashtags, keywordStr
-}
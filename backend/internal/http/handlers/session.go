@@ -2,7 +2,6 @@ package handlers
 
 import (
 	"crypto/rand"
-	"database/sql"
 	"encoding/base64"
 	"encoding/json"
 	"errors"
@@ -15,6 +14,7 @@ import (
 	"anon-backend/internal/config"
 	"anon-backend/internal/httpctx"
 	"anon-backend/internal/security"
+	"anon-backend/internal/session"
 	"anon-backend/internal/store"
 	"anon-backend/internal/types"
 )
@@ -45,7 +45,7 @@ func DeviceChallenge(cfg config.Config) http.HandlerFunc {
 		}
 
 		expiresAt := time.Now().Add(deviceNonceTTL)
-		if err := store.DefaultStore().CreateDeviceNonce(devicePublicID, nonce, expiresAt); err != nil {
+		if err := store.DefaultStore().CreateDeviceNonce(r.Context(), devicePublicID, nonce, expiresAt); err != nil {
 			writeJSONError(w, http.StatusInternalServerError, "failed to persist nonce")
 			return
 		}
@@ -92,7 +92,7 @@ func SessionBootstrap(cfg config.Config) http.HandlerFunc {
 			return
 		}
 
-		ok, err := store.DefaultStore().ConsumeDeviceNonce(devicePublicID, req.Nonce, now)
+		ok, err := store.DefaultStore().ConsumeDeviceNonce(r.Context(), devicePublicID, req.Nonce, now)
 		if err != nil {
 			writeJSONError(w, http.StatusInternalServerError, "failed to validate nonce")
 			return
@@ -102,9 +102,9 @@ func SessionBootstrap(cfg config.Config) http.HandlerFunc {
 			return
 		}
 
-		device, err := store.DefaultStore().GetDevice(devicePublicID)
+		device, err := store.DefaultStore().GetDevice(r.Context(), devicePublicID)
 		if err != nil {
-			if !isNotFoundErr(err) {
+			if !errors.Is(err, store.ErrNotFound) {
 				writeJSONError(w, http.StatusInternalServerError, "failed to load device")
 				return
 			}
@@ -112,26 +112,49 @@ func SessionBootstrap(cfg config.Config) http.HandlerFunc {
 		}
 
 		deviceSecretHash := strings.TrimSpace(req.DeviceSecretHash)
+		devicePublicKey := strings.TrimSpace(req.DevicePublicKey)
+		authMethod := store.DeviceAuthHMAC
 		if device == nil {
-			if deviceSecretHash == "" {
-				writeJSONError(w, http.StatusBadRequest, "device_secret_hash required for new device")
+			switch {
+			case devicePublicKey != "":
+				if _, err := security.ParseEd25519PublicKey(devicePublicKey); err != nil {
+					writeJSONError(w, http.StatusBadRequest, "invalid device_public_key")
+					return
+				}
+				authMethod = store.DeviceAuthEd25519
+				deviceSecretHash = ""
+			case deviceSecretHash == "":
+				writeJSONError(w, http.StatusBadRequest, "device_public_key or device_secret_hash required for new device")
+				return
+			}
+		} else if device.AuthMethod == store.DeviceAuthEd25519 {
+			if devicePublicKey != "" && devicePublicKey != device.PublicKey {
+				writeJSONError(w, http.StatusUnauthorized, "device key mismatch")
 				return
 			}
+			authMethod = store.DeviceAuthEd25519
+			devicePublicKey = device.PublicKey
 		} else {
 			if deviceSecretHash != "" && deviceSecretHash != device.DeviceSecretHash {
 				writeJSONError(w, http.StatusUnauthorized, "device secret mismatch")
 				return
 			}
 			deviceSecretHash = device.DeviceSecretHash
+			devicePublicKey = ""
 		}
 
-		keyBytes, err := decodeBase64(deviceSecretHash)
-		if err != nil {
-			writeJSONError(w, http.StatusBadRequest, "invalid device_secret_hash")
-			return
-		}
 		message := fmt.Sprintf("%s|%s|%d", devicePublicID, req.Nonce, req.Ts)
-		valid, err := security.VerifyHMACProof(keyBytes, message, req.Proof)
+		var valid bool
+		if authMethod == store.DeviceAuthEd25519 {
+			valid, err = security.VerifyEd25519Proof(devicePublicKey, message, req.Proof)
+		} else {
+			keyBytes, decodeErr := decodeBase64(deviceSecretHash)
+			if decodeErr != nil {
+				writeJSONError(w, http.StatusBadRequest, "invalid device_secret_hash")
+				return
+			}
+			valid, err = security.VerifyHMACProof(keyBytes, message, req.Proof)
+		}
 		if err != nil {
 			writeJSONError(w, http.StatusBadRequest, "invalid proof encoding")
 			return
@@ -141,7 +164,53 @@ func SessionBootstrap(cfg config.Config) http.HandlerFunc {
 			return
 		}
 
-		if device == nil {
+		recoveryKey := strings.TrimSpace(req.RecoveryKey)
+		linkCode := strings.ToUpper(strings.TrimSpace(req.LinkCode))
+		if device != nil && (recoveryKey != "" || linkCode != "") {
+			writeJSONError(w, http.StatusConflict, "device already registered")
+			return
+		}
+
+		if device == nil && (recoveryKey != "" || linkCode != "") {
+			// the device joins an existing identity instead of minting one;
+			// the store refuses banned identities before using up the secret
+			newDevice := &store.Device{
+				DevicePublicID:   devicePublicID,
+				DeviceSecretHash: deviceSecretHash,
+				AuthMethod:       authMethod,
+				PublicKey:        devicePublicKey,
+				CreatedAt:        now,
+				UpdatedAt:        now,
+			}
+			if recoveryKey != "" {
+				err = store.DefaultStore().RedeemRecoveryKey(r.Context(), security.HashRecoveryKey(recoveryKey), newDevice, now)
+			} else {
+				err = store.DefaultStore().ConsumeDeviceLink(r.Context(), linkCode, newDevice, now)
+			}
+			switch {
+			case errors.Is(err, store.ErrRecoveryKeyInvalid):
+				writeJSONError(w, http.StatusUnauthorized, "invalid recovery key")
+				return
+			case errors.Is(err, store.ErrDeviceLinkPending):
+				writeJSONErrorWithDetails(w, http.StatusForbidden, "device link not approved yet", "DEVICE_LINK_PENDING", nil)
+				return
+			case errors.Is(err, store.ErrDeviceLinkNotFound):
+				writeJSONError(w, http.StatusUnauthorized, "invalid or expired link code")
+				return
+			case errors.Is(err, store.ErrIdentityBanned):
+				var details map[string]interface{}
+				if ban, banErr := store.DefaultStore().GetActiveUserBan(r.Context(), newDevice.AnonID, now); banErr == nil && ban != nil {
+					details = session.BanDetails(ban, now)
+				}
+				writeJSONErrorWithDetails(w, http.StatusForbidden, "user is banned", "USER_BANNED", details)
+				return
+			case err != nil:
+				log.Printf("join identity with device %s: failed: %v", devicePublicID, err)
+				writeJSONError(w, http.StatusInternalServerError, "failed to join identity")
+				return
+			}
+			device = newDevice
+		} else if device == nil {
 			anonID := security.AnonID(devicePublicID, cfg.AnonHMACKey)
 			created := false
 			for i := 0; i < 12; i++ {
@@ -154,12 +223,14 @@ func SessionBootstrap(cfg config.Config) http.HandlerFunc {
 				newDevice := &store.Device{
 					DevicePublicID:   devicePublicID,
 					DeviceSecretHash: deviceSecretHash,
+					AuthMethod:       authMethod,
+					PublicKey:        devicePublicKey,
 					AnonID:           anonID,
 					Username:         username,
 					CreatedAt:        now,
 					UpdatedAt:        now,
 				}
-				if err := store.DefaultStore().CreateDevice(newDevice); err != nil {
+				if err := store.DefaultStore().CreateDevice(r.Context(), newDevice); err != nil {
 					if isUsernameConflict(err) {
 						continue
 					}
@@ -175,44 +246,61 @@ func SessionBootstrap(cfg config.Config) http.HandlerFunc {
 				return
 			}
 		} else {
-			_ = store.DefaultStore().UpdateDeviceTimestamp(devicePublicID, now)
+			_ = store.DefaultStore().UpdateDeviceTimestamp(r.Context(), devicePublicID, now)
 		}
 
-		activeBan, err := store.DefaultStore().GetActiveUserBan(device.AnonID, now)
+		activeBan, err := store.DefaultStore().GetActiveUserBan(r.Context(), device.AnonID, now)
 		if err != nil {
 			writeJSONError(w, http.StatusInternalServerError, "failed to verify ban status")
 			return
 		}
 		if activeBan != nil {
-			writeJSONErrorWithDetails(w, http.StatusForbidden, "user is banned", "USER_BANNED", buildBanErrorDetails(activeBan, now))
+			writeJSONErrorWithDetails(w, http.StatusForbidden, "user is banned", "USER_BANNED", session.BanDetails(activeBan, now))
 			return
 		}
 
-		token, err := security.SignSessionJWT(cfg.JWTSecret, cfg.JWTTTL, device.AnonID, req.Region)
+		token, err := security.SignSessionJWT(cfg.Keys, cfg.JWTTTL, device.AnonID, req.Region)
 		if err != nil {
 			writeJSONError(w, http.StatusInternalServerError, "failed to sign token")
 			return
 		}
 
 		// Ensure user exists and mark as active
-		if err := store.DefaultStore().EnsureUser(device.AnonID, now); err != nil {
+		if err := store.DefaultStore().EnsureUser(r.Context(), device.AnonID, now); err != nil {
 			log.Printf("WARNING: ensure user failed for %s: %v", device.AnonID, err)
 			// Continue anyway to maintain backward compatibility
 		} else {
 			log.Printf("User ensured: %s (active)", device.AnonID)
 		}
-		if err := store.DefaultStore().EnsureProfileForAnon(device.AnonID, req.Region, now); err != nil {
+		if err := store.DefaultStore().EnsureProfileForAnon(r.Context(), device.AnonID, req.Region, now); err != nil {
 			log.Printf("WARNING: ensure profile failed for %s: %v", device.AnonID, err)
 		}
 
-		err = store.DefaultStore().PutSession(store.SessionInfo{
-			ID:             "",
-			AnonID:         device.AnonID,
-			Token:          token,
-			IssuedAt:       now,
-			ExpiresAt:      now.Add(cfg.JWTTTL),
-			CreatedAt:      now,
-			LastActivityAt: now,
+		sessionID, err := store.NewSessionID()
+		if err != nil {
+			writeJSONError(w, http.StatusInternalServerError, "failed to create session")
+			return
+		}
+		refreshToken, refreshHash, err := security.NewRefreshToken(sessionID)
+		if err != nil {
+			writeJSONError(w, http.StatusInternalServerError, "failed to create refresh token")
+			return
+		}
+		refreshExpiresAt := now.Add(cfg.RefreshTTL)
+
+		err = store.DefaultStore().PutSession(r.Context(), store.SessionInfo{
+			ID:               sessionID,
+			AnonID:           device.AnonID,
+			Token:            token,
+			IssuedAt:         now,
+			ExpiresAt:        now.Add(cfg.JWTTTL),
+			CreatedAt:        now,
+			LastActivityAt:   now,
+			RefreshTokenHash: refreshHash,
+			RefreshExpiresAt: refreshExpiresAt,
+			DevicePublicID:   devicePublicID,
+			Region:           strings.TrimSpace(req.Region),
+			UserAgent:        clientUserAgent(r),
 		})
 		if err != nil {
 			log.Printf("persist session: failed: %v", err)
@@ -222,7 +310,7 @@ func SessionBootstrap(cfg config.Config) http.HandlerFunc {
 
 		// Enforce session limit per user
 		if cfg.MaxSessionsPerUser > 0 {
-			if err := store.DefaultStore().EnforceSessionLimit(device.AnonID, cfg.MaxSessionsPerUser); err != nil {
+			if err := store.DefaultStore().EnforceSessionLimit(r.Context(), device.AnonID, cfg.MaxSessionsPerUser); err != nil {
 				log.Printf("enforce session limit: failed: %v", err)
 				// Don't fail the request, just log the error
 			}
@@ -232,10 +320,12 @@ func SessionBootstrap(cfg config.Config) http.HandlerFunc {
 
 		expiresAt := now.Add(cfg.JWTTTL).Format(time.RFC3339)
 		resp := types.BootstrapResponse{
-			Token:     token,
-			AnonID:    device.AnonID,
-			Username:  device.Username,
-			ExpiresAt: expiresAt,
+			Token:            token,
+			AnonID:           device.AnonID,
+			Username:         device.Username,
+			ExpiresAt:        expiresAt,
+			RefreshToken:     refreshToken,
+			RefreshExpiresAt: refreshExpiresAt.Format(time.RFC3339),
 		}
 
 		w.Header().Set("Content-Type", "application/json")
@@ -258,10 +348,10 @@ func SessionMe(cfg config.Config) http.HandlerFunc {
 
 		username := ""
 		now := time.Now()
-		_ = store.DefaultStore().EnsureProfileForAnon(claims.AnonID, claims.Region, now)
-		if profile, err := store.DefaultStore().GetProfileByAnonID(claims.AnonID); err == nil {
+		_ = store.DefaultStore().EnsureProfileForAnon(r.Context(), claims.AnonID, claims.Region, now)
+		if profile, err := store.DefaultStore().GetProfileByAnonID(r.Context(), claims.AnonID); err == nil {
 			username = profile.Username
-		} else if device, err := store.DefaultStore().GetDeviceByAnonID(claims.AnonID); err == nil {
+		} else if device, err := store.DefaultStore().GetDeviceByAnonID(r.Context(), claims.AnonID); err == nil {
 			username = device.Username
 		}
 
@@ -277,66 +367,112 @@ func SessionMe(cfg config.Config) http.HandlerFunc {
 	}
 }
 
+// SessionRefresh trades a refresh token for a new access token and a new
+// refresh token. Each refresh token works once; replaying an already used
+// one revokes the whole session so a stolen token dies with the original.
 func SessionRefresh(cfg config.Config) http.HandlerFunc {
 	return func(w http.ResponseWriter, r *http.Request) {
-		claims := httpctx.ClaimsFromContext(r.Context())
-		if claims == nil {
-			writeJSONError(w, http.StatusUnauthorized, "no claims")
+		var req types.RefreshRequest
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			writeJSONError(w, http.StatusBadRequest, "bad json")
+			return
+		}
+
+		presented := strings.TrimSpace(req.RefreshToken)
+		sessionID, ok := security.RefreshTokenSessionID(presented)
+		if !ok {
+			writeJSONError(w, http.StatusUnauthorized, "invalid refresh token")
 			return
 		}
 
-		// Generate new token with extended expiration
-		anonID := claims.AnonID
-		region := claims.Region
-		token, err := security.SignSessionJWT(cfg.JWTSecret, cfg.JWTTTL, anonID, region)
+		sess, err := store.DefaultStore().GetSessionByID(r.Context(), sessionID)
 		if err != nil {
-			writeJSONError(w, http.StatusInternalServerError, "failed to refresh token")
+			if errors.Is(err, store.ErrSessionNotFound) {
+				writeJSONError(w, http.StatusUnauthorized, "session revoked or expired")
+				return
+			}
+			writeJSONError(w, http.StatusInternalServerError, "failed to load session")
 			return
 		}
+		anonID := sess.AnonID
 
 		now := time.Now()
 
-		activeBan, err := store.DefaultStore().GetActiveUserBan(anonID, now)
+		activeBan, err := store.DefaultStore().GetActiveUserBan(r.Context(), anonID, now)
 		if err != nil {
 			writeJSONError(w, http.StatusInternalServerError, "failed to verify ban status")
 			return
 		}
 		if activeBan != nil {
-			writeJSONErrorWithDetails(w, http.StatusForbidden, "user is banned", "USER_BANNED", buildBanErrorDetails(activeBan, now))
+			writeJSONErrorWithDetails(w, http.StatusForbidden, "user is banned", "USER_BANNED", session.BanDetails(activeBan, now))
 			return
 		}
 
-		// Ensure user exists and mark as active
-		if err := store.DefaultStore().EnsureUser(anonID, now); err != nil {
-			log.Printf("WARNING: ensure user failed for %s: %v", anonID, err)
-			// Continue anyway to maintain backward compatibility
-		} else {
-			log.Printf("User ensured: %s (active) - refresh", anonID)
+		region := ""
+		username := ""
+		if profile, err := store.DefaultStore().GetProfileByAnonID(r.Context(), anonID); err == nil {
+			region = profile.Region
+			username = profile.Username
 		}
-		if err := store.DefaultStore().EnsureProfileForAnon(anonID, region, now); err != nil {
-			log.Printf("WARNING: ensure profile failed for %s: %v", anonID, err)
+
+		token, err := security.SignSessionJWT(cfg.Keys, cfg.JWTTTL, anonID, region)
+		if err != nil {
+			writeJSONError(w, http.StatusInternalServerError, "failed to refresh token")
+			return
 		}
+		seed, err := security.NewRefreshSeed()
+		if err != nil {
+			writeJSONError(w, http.StatusInternalServerError, "failed to create refresh token")
+			return
+		}
+		_, refreshHash := security.NextRefreshToken(presented, seed)
 
-		err = store.DefaultStore().PutSession(store.SessionInfo{
-			ID:             "",
-			AnonID:         anonID,
-			Token:          token,
-			IssuedAt:       now,
-			ExpiresAt:      now.Add(cfg.JWTTTL),
-			CreatedAt:      now,
-			LastActivityAt: now,
+		rotated, err := store.DefaultStore().RotateRefreshToken(r.Context(), sessionID, security.HashRefreshToken(presented), store.SessionRotation{
+			Token:            token,
+			RefreshTokenHash: refreshHash,
+			RefreshSeed:      seed,
+			IssuedAt:         now,
+			ExpiresAt:        now.Add(cfg.JWTTTL),
+			RefreshExpiresAt: now.Add(cfg.RefreshTTL),
+			UserAgent:        clientUserAgent(r),
 		})
 		if err != nil {
-			log.Printf("persist refreshed session: failed: %v", err)
-			writeJSONError(w, http.StatusInternalServerError, "failed to persist session")
+			switch {
+			case errors.Is(err, store.ErrRefreshTokenReused):
+				session.Forget(anonID)
+				log.Printf("refresh token reuse detected for session %s (anon %s); session revoked", sessionID, anonID)
+				writeJSONErrorWithDetails(w, http.StatusUnauthorized, "refresh token reused; session revoked", "REFRESH_TOKEN_REUSED", nil)
+			case errors.Is(err, store.ErrSessionNotFound):
+				writeJSONError(w, http.StatusUnauthorized, "session revoked or expired")
+			default:
+				log.Printf("rotate refresh token: failed: %v", err)
+				writeJSONError(w, http.StatusInternalServerError, "failed to persist session")
+			}
 			return
 		}
 
+		// the previous access token is no longer a session row
+		session.Forget(anonID)
+
+		// a retry within store.RefreshGrace gets the successor the first
+		// attempt was given, so both come from the stored session
+		refreshToken, _ := security.NextRefreshToken(presented, rotated.RefreshSeed)
+
+		// Ensure user exists and mark as active
+		if err := store.DefaultStore().EnsureUser(r.Context(), anonID, now); err != nil {
+			log.Printf("WARNING: ensure user failed for %s: %v", anonID, err)
+			// Continue anyway to maintain backward compatibility
+		} else {
+			log.Printf("User ensured: %s (active) - refresh", anonID)
+		}
+
 		resp := types.BootstrapResponse{
-			Token:     token,
-			AnonID:    anonID,
-			Username:  "",
-			ExpiresAt: now.Add(cfg.JWTTTL).Format(time.RFC3339),
+			Token:            rotated.Token,
+			AnonID:           anonID,
+			Username:         username,
+			ExpiresAt:        rotated.ExpiresAt.Format(time.RFC3339),
+			RefreshToken:     refreshToken,
+			RefreshExpiresAt: rotated.RefreshExpiresAt.Format(time.RFC3339),
 		}
 
 		w.Header().Set("Content-Type", "application/json")
@@ -344,6 +480,17 @@ func SessionRefresh(cfg config.Config) http.HandlerFunc {
 	}
 }
 
+// maxUserAgentLen caps what is kept of the User-Agent header per session.
+const maxUserAgentLen = 256
+
+func clientUserAgent(r *http.Request) string {
+	ua := strings.TrimSpace(r.UserAgent())
+	if len(ua) > maxUserAgentLen {
+		ua = ua[:maxUserAgentLen]
+	}
+	return ua
+}
+
 func generateUsernameCandidate() (string, error) {
 	b := make([]byte, 2)
 	if _, err := rand.Read(b); err != nil {
@@ -354,42 +501,10 @@ func generateUsernameCandidate() (string, error) {
 	return fmt.Sprintf("ghost_%05d", num), nil
 }
 
-func isNotFoundErr(err error) bool {
-	if err == nil {
-		return false
-	}
-	return errors.Is(err, sql.ErrNoRows) || strings.Contains(err.Error(), "not found")
-}
-
 func isUsernameConflict(err error) bool {
-	if err == nil {
-		return false
-	}
-	msg := strings.ToLower(err.Error())
-	return strings.Contains(msg, "username") || strings.Contains(msg, "unique")
+	return errors.Is(err, store.ErrUsernameTaken)
 }
 
 func decodeBase64(input string) ([]byte, error) {
 	return base64.StdEncoding.DecodeString(input)
 }
-
-func buildBanErrorDetails(ban *store.UserBan, now time.Time) map[string]interface{} {
-	details := map[string]interface{}{
-		"is_permanent": ban.Permanent,
-	}
-
-	if ban.Permanent || ban.ExpiresAt == nil {
-		details["ban_label"] = "Banned permanently"
-		return details
-	}
-
-	remaining := ban.ExpiresAt.Sub(now)
-	if remaining < 0 {
-		remaining = 0
-	}
-
-	details["ban_expires_at"] = ban.ExpiresAt.Format(time.RFC3339)
-	details["remaining_seconds"] = int64(remaining.Seconds())
-	details["ban_label"] = fmt.Sprintf("Banned until %s", ban.ExpiresAt.Format("2006-01-02 15:04"))
-	return details
-}
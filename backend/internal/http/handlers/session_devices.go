@@ -0,0 +1,142 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"strings"
+	"time"
+
+	"anon-backend/internal/config"
+	"anon-backend/internal/httpctx"
+	"anon-backend/internal/session"
+	"anon-backend/internal/store"
+	"anon-backend/internal/types"
+	"anon-backend/internal/ws"
+)
+
+// SessionDevices lists the caller's sessions, one per signed-in device,
+// marking the one making the request. Tokens are never exposed.
+func SessionDevices(cfg config.Config) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		claims := httpctx.ClaimsFromContext(r.Context())
+		if claims == nil {
+			writeJSONError(w, http.StatusUnauthorized, "no claims")
+			return
+		}
+		current := httpctx.SessionIDFromContext(r.Context())
+
+		sessions, err := store.DefaultStore().GetSessionsByAnonID(r.Context(), claims.AnonID)
+		if err != nil {
+			writeJSONError(w, http.StatusInternalServerError, "failed to load sessions")
+			return
+		}
+
+		out := make([]types.SessionDeviceDTO, 0, len(sessions))
+		for _, sess := range sessions {
+			out = append(out, types.SessionDeviceDTO{
+				ID:             sess.ID,
+				DevicePublicID: sess.DevicePublicID,
+				Region:         sess.Region,
+				UserAgent:      sess.UserAgent,
+				CreatedAt:      sess.CreatedAt.Format(time.RFC3339),
+				LastActivityAt: sess.LastActivityAt.Format(time.RFC3339),
+				ExpiresAt:      sess.ExpiresAt.Format(time.RFC3339),
+				Current:        sess.ID == current,
+			})
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		_ = json.NewEncoder(w).Encode(types.SessionDevicesResponse{Sessions: out})
+	}
+}
+
+// SessionRevoke signs out one of the caller's sessions, or with others=true
+// every session except the current one. Sockets opened from a revoked
+// session are closed.
+func SessionRevoke(cfg config.Config, hub *ws.Hub) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		claims := httpctx.ClaimsFromContext(r.Context())
+		if claims == nil {
+			writeJSONError(w, http.StatusUnauthorized, "no claims")
+			return
+		}
+		current := httpctx.SessionIDFromContext(r.Context())
+
+		var req types.SessionRevokeRequest
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			writeJSONError(w, http.StatusBadRequest, "bad json")
+			return
+		}
+		req.SessionID = strings.TrimSpace(req.SessionID)
+		if req.SessionID == "" && !req.Others {
+			writeJSONError(w, http.StatusBadRequest, "session_id or others required")
+			return
+		}
+
+		var targets []*store.SessionInfo
+		if req.Others {
+			sessions, err := store.DefaultStore().GetSessionsByAnonID(r.Context(), claims.AnonID)
+			if err != nil {
+				writeJSONError(w, http.StatusInternalServerError, "failed to load sessions")
+				return
+			}
+			for _, sess := range sessions {
+				if sess.ID != current {
+					targets = append(targets, sess)
+				}
+			}
+		} else {
+			sess, err := store.DefaultStore().GetSessionByID(r.Context(), req.SessionID)
+			if err != nil && !errors.Is(err, store.ErrSessionNotFound) {
+				writeJSONError(w, http.StatusInternalServerError, "failed to load session")
+				return
+			}
+			// someone else's session looks exactly like a missing one
+			if err != nil || sess.AnonID != claims.AnonID {
+				writeJSONError(w, http.StatusNotFound, "session not found")
+				return
+			}
+			targets = append(targets, sess)
+		}
+
+		revoked := 0
+		for _, sess := range targets {
+			if err := revokeSessionByID(r.Context(), sess); err != nil {
+				writeJSONError(w, http.StatusInternalServerError, "failed to revoke session")
+				return
+			}
+			hub.DisconnectSession(claims.AnonID, sess.ID, ws.CloseSessionRevoked, "signed out from another device")
+			revoked++
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		_ = json.NewEncoder(w).Encode(map[string]interface{}{
+			"status":           "success",
+			"sessions_revoked": revoked,
+		})
+	}
+}
+
+// revokeSessionByID revokes sess even if a concurrent refresh rotated its
+// token after sess was loaded.
+func revokeSessionByID(ctx context.Context, sess *store.SessionInfo) error {
+	token := sess.Token
+	for attempt := 0; attempt < 3; attempt++ {
+		err := store.DefaultStore().RevokeSession(ctx, token)
+		session.ForgetToken(token)
+		if !errors.Is(err, store.ErrSessionNotFound) {
+			return err
+		}
+		fresh, err := store.DefaultStore().GetSessionByID(ctx, sess.ID)
+		if errors.Is(err, store.ErrSessionNotFound) {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+		token = fresh.Token
+	}
+	return errors.New("session keeps rotating")
+}
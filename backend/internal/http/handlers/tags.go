@@ -0,0 +1,172 @@
+package handlers
+
+import (
+	"encoding/json"
+	"log"
+	"net/http"
+	"strconv"
+	"time"
+
+	"anon-backend/internal/config"
+	"anon-backend/internal/httpctx"
+	"anon-backend/internal/store"
+	"anon-backend/internal/types"
+
+	"github.com/go-chi/chi/v5"
+)
+
+// tagWindows are the sliding windows /tags/trending counts over.
+var tagWindows = map[string]time.Duration{
+	"hour": time.Hour,
+	"day":  24 * time.Hour,
+	"week": 7 * 24 * time.Hour,
+}
+
+// TagsTrending handles GET /tags/trending?window=day&limit=20
+// Tags are ranked by how many posts carried them within the window.
+func TagsTrending(cfg config.Config) http.HandlerFunc {
+	_ = cfg
+
+	return func(w http.ResponseWriter, r *http.Request) {
+		claims := httpctx.ClaimsFromContext(r.Context())
+		if claims == nil {
+			writeJSONError(w, http.StatusUnauthorized, "no claims")
+			return
+		}
+
+		window := r.URL.Query().Get("window")
+		if window == "" {
+			window = "day"
+		}
+		span, ok := tagWindows[window]
+		if !ok {
+			writeJSONError(w, http.StatusBadRequest, "window must be hour, day or week")
+			return
+		}
+
+		limit := 20
+		if limitStr := r.URL.Query().Get("limit"); limitStr != "" {
+			if l, err := strconv.Atoi(limitStr); err == nil && l > 0 {
+				limit = l
+			}
+		}
+		if limit > 50 {
+			limit = 50
+		}
+
+		tags, err := store.DefaultStore().GetTrendingTags(r.Context(), time.Now().Add(-span), limit)
+		if err != nil {
+			log.Printf("get trending tags: %v", err)
+			writeJSONError(w, http.StatusInternalServerError, "failed to load trending tags")
+			return
+		}
+
+		out := make([]types.TagCountDTO, len(tags))
+		for i, tc := range tags {
+			out[i] = types.TagCountDTO{Tag: tc.Tag, Posts: tc.Posts}
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		_ = json.NewEncoder(w).Encode(types.TrendingTagsResponse{Window: window, Tags: out})
+	}
+}
+
+// TagPosts handles GET /tags/{tag}/posts?before=<cursor>&limit=50
+// Posts are returned newest first; pass next_cursor back as before to page.
+func TagPosts(cfg config.Config) http.HandlerFunc {
+	_ = cfg
+
+	return func(w http.ResponseWriter, r *http.Request) {
+		claims := httpctx.ClaimsFromContext(r.Context())
+		if claims == nil {
+			writeJSONError(w, http.StatusUnauthorized, "no claims")
+			return
+		}
+
+		tag, err := store.NormalizeTag(chi.URLParam(r, "tag"))
+		if err != nil {
+			writeJSONError(w, http.StatusBadRequest, "invalid tag")
+			return
+		}
+
+		feedQuery := store.FeedQuery{Viewer: claims.AnonID, Tag: tag}
+		if err := parseFeedPage(r.URL.Query(), &feedQuery); err != nil {
+			writeJSONError(w, http.StatusBadRequest, "invalid cursor")
+			return
+		}
+
+		posts, err := store.DefaultStore().GetFeedPage(r.Context(), feedQuery)
+		if err != nil {
+			log.Printf("get posts for tag %s: %v", tag, err)
+			writeJSONError(w, http.StatusInternalServerError, "failed to load posts")
+			return
+		}
+		resp, err := feedResponse(r.Context(), claims.AnonID, posts, feedQuery.Limit)
+		if err != nil {
+			log.Printf("build posts for tag %s: %v", tag, err)
+			writeJSONError(w, http.StatusInternalServerError, "failed to load posts")
+			return
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		_ = json.NewEncoder(w).Encode(resp)
+	}
+}
+
+// TagsFollowing handles GET /tags/following
+func TagsFollowing(cfg config.Config) http.HandlerFunc {
+	_ = cfg
+
+	return func(w http.ResponseWriter, r *http.Request) {
+		claims := httpctx.ClaimsFromContext(r.Context())
+		if claims == nil {
+			writeJSONError(w, http.StatusUnauthorized, "no claims")
+			return
+		}
+
+		tags, err := store.DefaultStore().GetFollowedTags(r.Context(), claims.AnonID)
+		if err != nil {
+			log.Printf("get followed tags: %v", err)
+			writeJSONError(w, http.StatusInternalServerError, "failed to load followed tags")
+			return
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		_ = json.NewEncoder(w).Encode(types.FollowedTagsResponse{Tags: tags})
+	}
+}
+
+// TagFollow handles POST /tags/{tag}/follow (follow) and
+// DELETE /tags/{tag}/follow (unfollow). Both are idempotent.
+func TagFollow(cfg config.Config) http.HandlerFunc {
+	_ = cfg
+
+	return func(w http.ResponseWriter, r *http.Request) {
+		claims := httpctx.ClaimsFromContext(r.Context())
+		if claims == nil {
+			writeJSONError(w, http.StatusUnauthorized, "no claims")
+			return
+		}
+
+		tag, err := store.NormalizeTag(chi.URLParam(r, "tag"))
+		if err != nil {
+			writeJSONError(w, http.StatusBadRequest, "invalid tag")
+			return
+		}
+
+		following := r.Method != http.MethodDelete
+		if following {
+			err = store.DefaultStore().FollowTag(r.Context(), claims.AnonID, tag, time.Now())
+		} else {
+			err = store.DefaultStore().UnfollowTag(r.Context(), claims.AnonID, tag)
+		}
+		if err != nil {
+			log.Printf("follow tag %s: %v", tag, err)
+			writeJSONError(w, http.StatusInternalServerError, "failed to update followed tags")
+			return
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		_ = json.NewEncoder(w).Encode(types.TagFollowResponse{Tag: tag, Following: following})
+	}
+}
@@ -2,9 +2,9 @@ package handlers
 
 import (
 	"encoding/json"
+	"errors"
 	"net/http"
 	"strconv"
-	"time"
 
 	"anon-backend/internal/config"
 	"anon-backend/internal/httpctx"
@@ -12,7 +12,9 @@ import (
 	"anon-backend/internal/types"
 )
 
-// TrendingPosts handles GET /posts/trending?limit=20&offset=0
+// TrendingPosts handles GET /posts/trending?algo=hot&window=all&limit=20&offset=0
+// algo is hot, rising or top; window is day, week or all. Rankings come
+// from the background ranking job, see store.RefreshPostScores.
 func TrendingPosts(cfg config.Config) http.HandlerFunc {
 	_ = cfg
 
@@ -41,33 +43,42 @@ func TrendingPosts(cfg config.Config) http.HandlerFunc {
 			}
 		}
 
-		posts, err := store.DefaultStore().GetTrendingPosts(limit, offset)
+		posts, err := store.DefaultStore().GetTrendingPosts(r.Context(), store.TrendingQuery{
+			Algo:   r.URL.Query().Get("algo"),
+			Window: r.URL.Query().Get("window"),
+			Limit:  limit,
+			Offset: offset,
+		})
+		if errors.Is(err, store.ErrInvalidTrendingQuery) {
+			http.Error(w, "algo must be hot, rising or top and window day, week or all", http.StatusBadRequest)
+			return
+		}
 		if err != nil {
 			http.Error(w, "failed to fetch trending posts", http.StatusInternalServerError)
 			return
 		}
 
-		out := make([]types.TrendingPostDTO, 0, len(posts))
-		for _, post := range posts {
-			userReaction, _ := store.DefaultStore().GetPostReaction(post.ID, claims.AnonID)
+		plain := make([]*store.Post, len(posts))
+		for i := range posts {
+			plain[i] = &posts[i].Post
+		}
+		dtos, err := postDTOs(r.Context(), claims.AnonID, plain)
+		if err != nil {
+			http.Error(w, "failed to fetch trending posts", http.StatusInternalServerError)
+			return
+		}
 
-			out = append(out, types.TrendingPostDTO{
-				PostDTO: types.PostDTO{
-					ID:           post.ID,
-					AnonID:       post.AnonID,
-					Username:     getUsernameByAnonID(post.AnonID),
-					Text:         post.Text,
-					CreatedAt:    post.CreatedAt.Format(time.RFC3339),
-					Likes:        post.Likes,
-					Dislikes:     post.Dislikes,
-					UserReaction: userReaction,
-					Deleted:      post.Deleted,
-				},
+		out := make([]types.TrendingPostDTO, len(posts))
+		for i, post := range posts {
+			out[i] = types.TrendingPostDTO{
+				PostDTO:      dtos[i],
 				LikeCount:    post.LikeCount,
 				DislikeCount: post.DislikeCount,
 				CommentCount: post.CommentCount,
 				HotScore:     post.HotScore,
-			})
+				RisingScore:  post.RisingScore,
+				TopScore:     post.TopScore,
+			}
 		}
 
 		w.Header().Set("Content-Type", "application/json")
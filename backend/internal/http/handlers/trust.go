@@ -2,6 +2,7 @@ package handlers
 
 import (
 	"encoding/json"
+	"errors"
 	"log"
 	"net/http"
 	"strings"
@@ -33,11 +34,16 @@ func TrustRequest(cfg config.Config) http.HandlerFunc {
 		}
 
 		st := store.DefaultStore()
-		card, ok := st.GetCard(req.Code)
-		if !ok {
+		card, err := st.GetCard(r.Context(), req.Code)
+		if errors.Is(err, store.ErrNotFound) {
 			http.Error(w, "code not found", http.StatusNotFound)
 			return
 		}
+		if err != nil {
+			log.Printf("load link card: failed: %v", err)
+			http.Error(w, "failed to load code", http.StatusInternalServerError)
+			return
+		}
 
 		now := time.Now()
 		if card.Status != store.CardActive || now.After(card.ExpiresAt) {
@@ -63,12 +69,12 @@ func TrustRequest(cfg config.Config) http.HandlerFunc {
 			CreatedAt: now,
 			UpdatedAt: now,
 		}
-		if err := st.PutCard(card); err != nil {
+		if err := st.PutCard(r.Context(), card); err != nil {
 			log.Printf("persist trust card update: failed: %v", err)
 			http.Error(w, "failed to persist trust card", http.StatusInternalServerError)
 			return
 		}
-		if err := st.PutTrust(tr); err != nil {
+		if err := st.PutTrust(r.Context(), tr); err != nil {
 			log.Printf("persist trust request: failed: %v", err)
 			http.Error(w, "failed to persist trust request", http.StatusInternalServerError)
 			return
@@ -102,11 +108,16 @@ func TrustRespond(cfg config.Config) http.HandlerFunc {
 		}
 
 		st := store.DefaultStore()
-		tr, ok := st.GetTrust(req.RequestID)
-		if !ok {
+		tr, err := st.GetTrust(r.Context(), req.RequestID)
+		if errors.Is(err, store.ErrNotFound) {
 			http.Error(w, "trust request not found", http.StatusNotFound)
 			return
 		}
+		if err != nil {
+			log.Printf("load trust request: failed: %v", err)
+			http.Error(w, "failed to load trust request", http.StatusInternalServerError)
+			return
+		}
 
 		// Only the recipient (code owner) can respond
 		if tr.ToAnon != claims.AnonID {
@@ -126,7 +137,7 @@ func TrustRespond(cfg config.Config) http.HandlerFunc {
 			tr.Status = store.TrustDeclined
 		}
 
-		if err := st.PutTrust(tr); err != nil {
+		if err := st.PutTrust(r.Context(), tr); err != nil {
 			log.Printf("persist trust response: failed: %v", err)
 			http.Error(w, "failed to persist trust response", http.StatusInternalServerError)
 			return
@@ -148,7 +159,12 @@ func TrustStatus(cfg config.Config) http.HandlerFunc {
 			return
 		}
 
-		all := store.DefaultStore().TrustForAnon(claims.AnonID)
+		all, err := store.DefaultStore().TrustForAnon(r.Context(), claims.AnonID)
+		if err != nil {
+			log.Printf("load trust requests: failed: %v", err)
+			http.Error(w, "failed to load trust requests", http.StatusInternalServerError)
+			return
+		}
 		out := types.TrustStatusOut{
 			Incoming: []types.TrustItem{},
 			Outgoing: []types.TrustItem{},
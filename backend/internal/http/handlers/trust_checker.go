@@ -1,6 +1,10 @@
 package handlers
 
-import "anon-backend/internal/store"
+import (
+	"context"
+
+	"anon-backend/internal/store"
+)
 
 type trustChecker struct {
 	store *store.MemStore
@@ -11,6 +15,6 @@ func NewTrustChecker(s *store.MemStore) TrustChecker {
 }
 
 // IsAccepted returns true if trust is accepted between two anon ids
-func (t *trustChecker) IsAccepted(a, b string) bool {
-	return t.store.TrustAccepted(a, b)
+func (t *trustChecker) IsAccepted(ctx context.Context, a, b string) (bool, error) {
+	return t.store.TrustAccepted(ctx, a, b)
 }
@@ -1,28 +1,34 @@
 package handlers
 
 import (
+	"context"
+	"encoding/base64"
 	"encoding/json"
+	"errors"
+	"log"
 	"net/http"
 	"time"
 
 	"anon-backend/internal/config"
+	"anon-backend/internal/security"
+	"anon-backend/internal/store"
 	"anon-backend/internal/ws"
 
 	"github.com/gorilla/websocket"
 )
 
-type incomingMsg struct {
-	Type   string `json:"type"`    // "msg"
-	Text   string `json:"text"`
-	SentAt string `json:"sent_at"` // ISO
-}
-
-type outboundMsg struct {
-	Type   string `json:"type"` // "msg"
-	From   string `json:"from"`
-	Text   string `json:"text"`
-	SentAt string `json:"sent_at"`
-}
+const (
+	// maxChatTextLen bounds a single persisted chat message. Escaping can
+	// grow it past what a broker carries, so handleChatMsg also checks the
+	// encoded envelope (ws.EnvelopeFits).
+	maxChatTextLen = 4000
+	// maxChatCiphertextLen bounds the base64 payload of an E2E message.
+	maxChatCiphertextLen = 6000
+	// maxChatClientIDLen bounds the sender-chosen de-dupe key.
+	maxChatClientIDLen = 64
+	// maxChatReadIDs bounds how many ids one "read" frame may acknowledge.
+	maxChatReadIDs = 100
+)
 
 var upgrader = websocket.Upgrader{
 	CheckOrigin: func(r *http.Request) bool {
@@ -31,7 +37,294 @@ var upgrader = websocket.Upgrader{
 	},
 }
 
-func WSChat(hub *ws.Hub, tickets *ws.TicketStore, cfg config.Config, trust TrustChecker) http.HandlerFunc {
+func chatTimestamp(t time.Time) string {
+	return t.UTC().Format(time.RFC3339Nano)
+}
+
+func encodeFrame(m ws.ServerMessage) []byte {
+	out, _ := json.Marshal(m)
+	return out
+}
+
+// chatOutbound is the "msg" or "ciphertext" frame for m. The client id is only meant for the
+// sender's own sockets, so callers pass withClientID accordingly.
+func chatOutbound(m *store.ChatMessage, withClientID bool) []byte {
+	frame := ws.ServerMessage{
+		Type:   ws.TypeMsg,
+		ID:     m.ID,
+		From:   m.FromAnon,
+		To:     m.ToAnon,
+		SentAt: chatTimestamp(m.CreatedAt),
+	}
+	if m.Kind == store.ChatKindCiphertext {
+		frame.Type = ws.TypeCiphertext
+		frame.Ciphertext = m.Text
+	} else {
+		frame.Text = m.Text
+	}
+	if withClientID {
+		frame.ClientID = m.ClientID
+	}
+	return encodeFrame(frame)
+}
+
+func chatAck(m *store.ChatMessage) []byte {
+	return encodeFrame(ws.ServerMessage{
+		Type:     ws.TypeAck,
+		ID:       m.ID,
+		ClientID: m.ClientID,
+		SentAt:   chatTimestamp(m.CreatedAt),
+	})
+}
+
+// chatReceipt is a "delivered" or "read" frame; from is the recipient whose
+// sockets the messages reached.
+func chatReceipt(typ, from string, ids []string, at time.Time) []byte {
+	return encodeFrame(ws.ServerMessage{
+		Type: typ,
+		From: from,
+		IDs:  ids,
+		At:   chatTimestamp(at),
+	})
+}
+
+func chatError(code, to, clientID string) []byte {
+	return encodeFrame(ws.ServerMessage{
+		Type:     ws.TypeError,
+		To:       to,
+		ClientID: clientID,
+		Error:    code,
+	})
+}
+
+// replayUndelivered pushes messages stored while me was offline onto c and
+// tells each sender they have now been delivered. A multiplexed socket gets
+// every conversation, a pair socket only the one it is bound to.
+func replayUndelivered(ctx context.Context, hub *ws.Hub, c *ws.Conn, trust TrustChecker, me string) {
+	roomID := ""
+	if !c.Multiplexed() {
+		roomID = ws.RoomID(me, c.Peer())
+	}
+
+	pending, err := store.DefaultStore().GetUndeliveredChatMessages(ctx, roomID, me)
+	if err != nil {
+		log.Printf("chat replay: load failed for %s: %v", me, err)
+		return
+	}
+
+	accepted := map[string]bool{}
+	delivered := make([]string, 0, len(pending))
+	senders := map[string]string{}
+	for _, m := range pending {
+		ok, seen := accepted[m.FromAnon]
+		if !seen {
+			var err error
+			if ok, err = trust.IsAccepted(ctx, me, m.FromAnon); err != nil {
+				log.Printf("chat replay: trust check failed for %s: %v", me, err)
+			}
+			accepted[m.FromAnon] = ok
+		}
+		if !ok {
+			continue
+		}
+		if !c.Offer(chatOutbound(m, false)) {
+			// buffer full; the rest stays undelivered until it drains
+			break
+		}
+		delivered = append(delivered, m.ID)
+		senders[m.ID] = m.FromAnon
+	}
+	if len(delivered) == 0 {
+		return
+	}
+
+	// another socket of me may be replaying the same messages; only the
+	// marks that stick are reported
+	now := time.Now().UTC()
+	updated, err := store.DefaultStore().MarkChatMessagesDelivered(ctx, delivered, now)
+	if err != nil {
+		log.Printf("chat replay: mark delivered failed for %s: %v", me, err)
+		return
+	}
+	bySender := map[string][]string{}
+	for _, id := range updated {
+		bySender[senders[id]] = append(bySender[senders[id]], id)
+	}
+	for sender, ids := range bySender {
+		hub.SendTo(sender, me, chatReceipt(ws.TypeDelivered, me, ids, now))
+	}
+}
+
+// chatBody validates the payload of a "msg" or "ciphertext" frame. Ciphertext
+// is only checked for size and encoding, never looked into.
+func chatBody(in ws.ClientMessage) (kind, body string, ok bool) {
+	if in.Type == ws.TypeCiphertext {
+		if in.Ciphertext == "" || len(in.Ciphertext) > maxChatCiphertextLen {
+			return "", "", false
+		}
+		if _, err := base64.StdEncoding.DecodeString(in.Ciphertext); err != nil {
+			return "", "", false
+		}
+		return store.ChatKindCiphertext, in.Ciphertext, true
+	}
+	if in.Text == "" || len(in.Text) > maxChatTextLen {
+		return "", "", false
+	}
+	return store.ChatKindText, in.Text, true
+}
+
+// handleChatMsg stores, acks and relays one "msg" or "ciphertext" frame.
+// Retries carrying a client_id that was already stored are re-acked without
+// posting twice.
+func handleChatMsg(ctx context.Context, hub *ws.Hub, c *ws.Conn, me, peer string, in ws.ClientMessage) {
+	kind, body, ok := chatBody(in)
+	if !ok || len(in.ClientID) > maxChatClientIDLen {
+		return
+	}
+
+	str := store.DefaultStore()
+
+	if in.ClientID != "" {
+		existing, err := str.GetChatMessageByClientID(ctx, me, in.ClientID)
+		if err == nil {
+			reackChatMsg(c, existing)
+			return
+		}
+		if !errors.Is(err, store.ErrChatMessageNotFound) {
+			log.Printf("chat de-dupe lookup: failed: %v", err)
+		}
+	}
+
+	msgID, err := security.NewInviteCode(16)
+	if err != nil {
+		return
+	}
+	msg := &store.ChatMessage{
+		ID:        msgID,
+		RoomID:    ws.RoomID(me, peer),
+		FromAnon:  me,
+		ToAnon:    peer,
+		Kind:      kind,
+		Text:      body,
+		ClientID:  in.ClientID,
+		CreatedAt: time.Now().UTC(),
+	}
+
+	// refuse what the broker could not relay before anything is stored;
+	// whichever hub reaches the peer records delivery, see ChatDelivered
+	env := ws.Envelope{To: peer, Peer: me, Data: chatOutbound(msg, false), Receipt: msg.ID}
+	echo := ws.Envelope{To: me, Peer: peer, Data: chatOutbound(msg, true)}
+	if !ws.EnvelopeFits(env) || !ws.EnvelopeFits(echo) {
+		c.Enqueue(chatError(ws.ErrCodeTooLarge, peer, in.ClientID))
+		return
+	}
+
+	// persist before relaying so an offline peer can catch up on connect
+	persisted := true
+	if err := str.PutChatMessage(ctx, msg); err != nil {
+		if errors.Is(err, store.ErrDuplicateChatMessage) {
+			// lost a race with a concurrent retry of the same message
+			if existing, err := str.GetChatMessageByClientID(ctx, me, in.ClientID); err == nil {
+				reackChatMsg(c, existing)
+			}
+			return
+		}
+		log.Printf("persist chat message: failed: %v", err)
+		persisted = false
+	}
+
+	if persisted {
+		c.Enqueue(chatAck(msg))
+	} else {
+		// nothing stored, so no delivery to record
+		env.Receipt = ""
+	}
+
+	// deliver to peer and echo back to sender (optional; helps UI)
+	hub.Deliver(env)
+	hub.Deliver(echo)
+}
+
+// ChatDelivered is the ws.Hub delivery hook for stored chat messages: the
+// hub that hands a message to a recipient socket marks it delivered and
+// tells the sender, wherever the sender is connected. Every hub holding a
+// recipient socket runs the hook; only the one whose mark sticks sends the
+// receipt.
+func ChatDelivered(hub *ws.Hub) func(ws.Envelope) {
+	return func(env ws.Envelope) {
+		now := time.Now().UTC()
+		updated, err := store.DefaultStore().MarkChatMessagesDelivered(context.Background(), []string{env.Receipt}, now)
+		if err != nil {
+			log.Printf("mark chat message delivered: failed: %v", err)
+			return
+		}
+		if len(updated) == 0 {
+			return
+		}
+		hub.SendTo(env.Peer, env.To, chatReceipt(ws.TypeDelivered, env.To, []string{env.Receipt}, now))
+	}
+}
+
+func reackChatMsg(c *ws.Conn, m *store.ChatMessage) {
+	c.Enqueue(chatAck(m))
+	if m.DeliveredAt != nil {
+		c.Enqueue(chatReceipt(ws.TypeDelivered, m.ToAnon, []string{m.ID}, *m.DeliveredAt))
+	}
+	if m.ReadAt != nil {
+		c.Enqueue(chatReceipt(ws.TypeRead, m.ToAnon, []string{m.ID}, *m.ReadAt))
+	}
+}
+
+// handleChatRead records that me has seen the given messages and forwards
+// the receipt to the sender.
+func handleChatRead(ctx context.Context, hub *ws.Hub, me, peer string, in ws.ClientMessage) {
+	if len(in.IDs) == 0 || len(in.IDs) > maxChatReadIDs {
+		return
+	}
+
+	now := time.Now().UTC()
+	updated, err := store.DefaultStore().MarkChatMessagesRead(ctx, ws.RoomID(me, peer), me, in.IDs, now)
+	if err != nil {
+		log.Printf("mark chat messages read: failed: %v", err)
+		return
+	}
+	if len(updated) == 0 {
+		return
+	}
+	hub.SendTo(peer, me, chatReceipt(ws.TypeRead, me, updated, now))
+}
+
+// handleChatTyping relays a typing indicator to the peer; nothing is stored.
+func handleChatTyping(hub *ws.Hub, me, peer string, in ws.ClientMessage) {
+	if in.State != ws.TypingStart && in.State != ws.TypingStop {
+		return
+	}
+	hub.SendTo(peer, me, encodeFrame(ws.ServerMessage{
+		Type:  ws.TypeTyping,
+		From:  me,
+		State: in.State,
+	}))
+}
+
+// chatRecipient picks the peer a frame is about. Pair sockets are fixed to
+// their ticket's peer; multiplexed sockets must name one in "to".
+func chatRecipient(c *ws.Conn, me string, in ws.ClientMessage) (string, bool) {
+	if !c.Multiplexed() {
+		if in.To != "" && in.To != c.Peer() {
+			return "", false
+		}
+		return c.Peer(), true
+	}
+	if in.To == "" || in.To == me {
+		return "", false
+	}
+	return in.To, true
+}
+
+// WSChat serves the chat socket. A ticket issued for a peer gives a socket
+// bound to that conversation; a ticket without one gives a multiplexed
+// socket where every frame names its recipient in "to".
+func WSChat(hub *ws.Hub, cfg config.Config, trust TrustChecker) http.HandlerFunc {
 	return func(w http.ResponseWriter, r *http.Request) {
 		tok := r.URL.Query().Get("ticket")
 		if tok == "" {
@@ -39,19 +332,41 @@ func WSChat(hub *ws.Hub, tickets *ws.TicketStore, cfg config.Config, trust Trust
 			return
 		}
 
-		t, ok := tickets.Consume(tok)
-		if !ok {
+		claims, err := security.VerifyWSTicket(cfg.Keys, tok)
+		if err != nil {
+			http.Error(w, "invalid/expired ticket", http.StatusUnauthorized)
+			return
+		}
+		t := &ws.Ticket{
+			ID:        claims.ID,
+			MyAnon:    claims.Subject,
+			PeerAnon:  claims.Peer,
+			SessionID: claims.SessionID,
+			Expires:   claims.ExpiresAt.Time,
+		}
+		fresh, err := store.DefaultStore().RedeemWSTicket(r.Context(), t.ID, t.Expires, time.Now())
+		if err != nil {
+			http.Error(w, "failed to redeem ticket", http.StatusInternalServerError)
+			return
+		}
+		if !fresh {
 			http.Error(w, "invalid/expired ticket", http.StatusUnauthorized)
 			return
 		}
 
 		me := t.MyAnon
-		peer := t.PeerAnon
 
 		// 🔒 trust gate (even if someone steals a ticket)
-		if !trust.IsAccepted(me, peer) {
-			http.Error(w, "not trusted", http.StatusForbidden)
-			return
+		if !t.Multiplexed() {
+			ok, err := trust.IsAccepted(r.Context(), me, t.PeerAnon)
+			if err != nil {
+				http.Error(w, "failed to check trust", http.StatusInternalServerError)
+				return
+			}
+			if !ok {
+				http.Error(w, "not trusted", http.StatusForbidden)
+				return
+			}
 		}
 
 		wsConn, err := upgrader.Upgrade(w, r, nil)
@@ -59,11 +374,21 @@ func WSChat(hub *ws.Hub, tickets *ws.TicketStore, cfg config.Config, trust Trust
 			return
 		}
 
-		c := ws.NewConn(wsConn, me, peer)
+		defaultPolicy := ws.ParseOverflowPolicy(cfg.WSOverflowPolicy, ws.PolicySpill)
+		policy := ws.ParseOverflowPolicy(r.URL.Query().Get("overflow"), defaultPolicy)
+
+		// the request context lives until this handler returns, which is
+		// when the socket closes
+		ctx := r.Context()
+
+		c := ws.NewConn(wsConn, me, t.PeerAnon, t.SessionID, policy)
+		c.OnDrain(func(c *ws.Conn) { replayUndelivered(ctx, hub, c, trust, me) })
 		hub.Register(c)
 		defer hub.Unregister(c)
 
-		go func() { c.WritePump() }() // (we'll add method below)
+		go c.WritePump()
+
+		replayUndelivered(ctx, hub, c, trust, me)
 
 		// read loop: relay -> peer
 		_ = wsConn.SetReadDeadline(time.Now().Add(60 * time.Second))
@@ -78,24 +403,33 @@ func WSChat(hub *ws.Hub, tickets *ws.TicketStore, cfg config.Config, trust Trust
 				return
 			}
 
-			var in incomingMsg
+			var in ws.ClientMessage
 			if err := json.Unmarshal(data, &in); err != nil {
 				continue
 			}
-			if in.Type != "msg" || in.Text == "" {
+
+			peer, ok := chatRecipient(c, me, in)
+			if !ok {
+				c.Enqueue(chatError(ws.ErrCodeBadRecipient, in.To, in.ClientID))
+				continue
+			}
+			// trust can be revoked while the socket is open, so check every frame
+			if ok, err := trust.IsAccepted(ctx, me, peer); err != nil || !ok {
+				if err != nil {
+					log.Printf("chat trust check failed for %s: %v", me, err)
+				}
+				c.Enqueue(chatError(ws.ErrCodeNotTrusted, peer, in.ClientID))
 				continue
 			}
 
-			out, _ := json.Marshal(outboundMsg{
-				Type:   "msg",
-				From:   me,
-				Text:   in.Text,
-				SentAt: time.Now().UTC().Format(time.RFC3339Nano),
-			})
-
-			// deliver to peer and echo back to sender (optional; helps UI)
-			hub.SendTo(peer, out)
-			hub.SendTo(me, out)
+			switch in.Type {
+			case ws.TypeMsg, ws.TypeCiphertext:
+				handleChatMsg(ctx, hub, c, me, peer, in)
+			case ws.TypeRead:
+				handleChatRead(ctx, hub, me, peer, in)
+			case ws.TypeTyping:
+				handleChatTyping(hub, me, peer, in)
+			}
 		}
 	}
 }
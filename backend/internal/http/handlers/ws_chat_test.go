@@ -0,0 +1,52 @@
+package handlers
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"anon-backend/internal/store"
+	"anon-backend/internal/ws"
+)
+
+// A recipient with sockets on two nodes gets the message on both, but the
+// sender hears of the delivery once.
+func TestChatDeliveredAcrossHubs(t *testing.T) {
+	store.Initialize(store.NewMemStore())
+	t.Cleanup(func() { store.Initialize(nil) })
+
+	broker := ws.NewMemBroker()
+	hubA := ws.NewHubWithBroker(broker)
+	hubB := ws.NewHubWithBroker(broker)
+	hubA.OnDelivered(ChatDelivered(hubA))
+	hubB.OnDelivered(ChatDelivered(hubB))
+
+	sender := ws.NewConn(nil, "anon-a", "", "", ws.PolicySpill)
+	onA := ws.NewConn(nil, "anon-b", "", "", ws.PolicySpill)
+	onB := ws.NewConn(nil, "anon-b", "", "", ws.PolicySpill)
+	hubA.Register(sender)
+	hubA.Register(onA)
+	hubB.Register(onB)
+
+	msg := &store.ChatMessage{
+		ID:        "m1",
+		RoomID:    ws.RoomID("anon-a", "anon-b"),
+		FromAnon:  "anon-a",
+		ToAnon:    "anon-b",
+		Kind:      store.ChatKindText,
+		Text:      "hi",
+		CreatedAt: time.Now().UTC(),
+	}
+	if err := store.DefaultStore().PutChatMessage(context.Background(), msg); err != nil {
+		t.Fatal(err)
+	}
+
+	hubA.Deliver(ws.Envelope{To: "anon-b", Peer: "anon-a", Data: chatOutbound(msg, false), Receipt: msg.ID})
+
+	if onA.Stats().Queued != 1 || onB.Stats().Queued != 1 {
+		t.Fatalf("recipient sockets queued %d and %d frames, want 1 each", onA.Stats().Queued, onB.Stats().Queued)
+	}
+	if got := sender.Stats().Queued; got != 1 {
+		t.Fatalf("sender got %d delivery receipts, want 1", got)
+	}
+}
@@ -1,21 +1,25 @@
 package handlers
 
 import (
+	"context"
 	"encoding/json"
+	"io"
 	"net/http"
 	"time"
 
+	"anon-backend/internal/config"
 	"anon-backend/internal/httpctx"
+	"anon-backend/internal/security"
 	"anon-backend/internal/ws"
 )
 
 type TrustChecker interface {
 	// true iff trust is accepted between these two anon ids (either direction)
-	IsAccepted(a, b string) bool
+	IsAccepted(ctx context.Context, a, b string) (bool, error)
 }
 
 type wsTicketReq struct {
-	Peer string `json:"peer"`
+	Peer string `json:"peer,omitempty"` // empty => multiplexed socket for all trusted peers
 }
 
 type wsTicketResp struct {
@@ -23,7 +27,9 @@ type wsTicketResp struct {
 	ExpiresIn int    `json:"expires_in"`
 }
 
-func CreateWSTicket(tickets *ws.TicketStore, trust TrustChecker) http.HandlerFunc {
+const wsTicketTTL = 30 * time.Second
+
+func CreateWSTicket(cfg config.Config, trust TrustChecker) http.HandlerFunc {
 	return func(w http.ResponseWriter, r *http.Request) {
 		claims := httpctx.ClaimsFromContext(r.Context())
 		if claims == nil {
@@ -33,31 +39,39 @@ func CreateWSTicket(tickets *ws.TicketStore, trust TrustChecker) http.HandlerFun
 		me := claims.AnonID
 
 		var req wsTicketReq
-		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		// an empty body is allowed and asks for a multiplexed ticket
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil && err != io.EOF {
 			http.Error(w, "bad json", http.StatusBadRequest)
 			return
 		}
-		if req.Peer == "" {
-			http.Error(w, "peer required", http.StatusBadRequest)
-			return
-		}
 		if req.Peer == me {
 			http.Error(w, "peer cannot be self", http.StatusBadRequest)
 			return
 		}
 
-		if !trust.IsAccepted(me, req.Peer) {
-			http.Error(w, "not trusted", http.StatusForbidden)
-			return
+		// multiplexed tickets are checked per frame on the socket instead
+		if req.Peer != "" {
+			ok, err := trust.IsAccepted(r.Context(), me, req.Peer)
+			if err != nil {
+				http.Error(w, "failed to check trust", http.StatusInternalServerError)
+				return
+			}
+			if !ok {
+				http.Error(w, "not trusted", http.StatusForbidden)
+				return
+			}
 		}
 
-		tok := ws.RandomToken()
-		tickets.Create(tok, me, req.Peer, 30*time.Second)
+		tok, err := security.SignWSTicket(cfg.Keys, wsTicketTTL, ws.RandomToken(), me, req.Peer, httpctx.SessionIDFromContext(r.Context()))
+		if err != nil {
+			http.Error(w, "failed to sign ticket", http.StatusInternalServerError)
+			return
+		}
 
 		w.Header().Set("Content-Type", "application/json")
 		_ = json.NewEncoder(w).Encode(wsTicketResp{
 			Ticket:    tok,
-			ExpiresIn: 30,
+			ExpiresIn: int(wsTicketTTL.Seconds()),
 		})
 	}
 }
@@ -1,12 +1,16 @@
 package http
 
 import (
+	"context"
+	"encoding/json"
 	"net/http"
 	"strings"
+	"time"
 
 	"anon-backend/internal/config"
 	"anon-backend/internal/httpctx"
 	"anon-backend/internal/security"
+	"anon-backend/internal/session"
 	"anon-backend/internal/store"
 )
 
@@ -20,19 +24,52 @@ func SessionAuth(cfg config.Config) func(http.Handler) http.Handler {
 			}
 
 			token := strings.TrimPrefix(auth, "Bearer ")
-			claims, err := security.VerifySessionJWT(cfg.JWTSecret, token)
+			claims, err := security.VerifySessionJWT(cfg.Keys, token)
 			if err != nil {
 				http.Error(w, "invalid token", http.StatusUnauthorized)
 				return
 			}
 
+			// A valid signature is not enough: the session may have been
+			// revoked or its user banned since the token was issued.
+			now := time.Now()
+			status, err := session.Check(r.Context(), token, now)
+			if err != nil {
+				writeJSONError(w, http.StatusInternalServerError, "failed to verify session", "", nil)
+				return
+			}
+			if status.Revoked {
+				writeJSONError(w, http.StatusUnauthorized, "session revoked", "SESSION_REVOKED", nil)
+				return
+			}
+			if status.Banned(now) {
+				writeJSONError(w, http.StatusForbidden, "user is banned", "USER_BANNED", session.BanDetails(status.Ban, now))
+				return
+			}
+
 			// Update session activity in background (don't block on errors)
 			go func() {
-				_ = store.DefaultStore().UpdateSessionActivity(token)
+				_ = store.DefaultStore().UpdateSessionActivity(context.Background(), token)
 			}()
 
 			ctx := httpctx.WithClaims(r.Context(), claims)
+			ctx = httpctx.WithSessionID(ctx, status.SessionID)
 			next.ServeHTTP(w, r.WithContext(ctx))
 		})
 	}
 }
+
+// writeJSONError answers in the {"error", "code", "details"} shape the
+// handlers use.
+func writeJSONError(w http.ResponseWriter, status int, message, code string, details map[string]interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	body := map[string]interface{}{"error": message}
+	if code != "" {
+		body["code"] = code
+	}
+	if details != nil {
+		body["details"] = details
+	}
+	_ = json.NewEncoder(w).Encode(body)
+}
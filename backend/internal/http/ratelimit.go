@@ -0,0 +1,70 @@
+package http
+
+import (
+	"encoding/json"
+	"log"
+	"net"
+	"net/http"
+	"strconv"
+	"time"
+
+	"anon-backend/internal/config"
+	"anon-backend/internal/httpctx"
+	"anon-backend/internal/store"
+)
+
+// RateLimit applies the named policy from cfg.RateLimits. Requests are
+// counted per anon id when SessionAuth ran first and per client IP
+// otherwise, so it belongs after SessionAuth on authenticated routes.
+// Counters live in the store, so they are shared across instances when
+// running on Postgres. A store failure lets the request through.
+func RateLimit(cfg config.Config, policy string) func(http.Handler) http.Handler {
+	limit := cfg.RateLimits[policy]
+	return func(next http.Handler) http.Handler {
+		if limit.Requests <= 0 {
+			return next
+		}
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			res, err := store.DefaultStore().TakeRateLimitToken(r.Context(), policy, rateLimitKey(r), limit.Requests, limit.Per, time.Now())
+			if err != nil {
+				log.Printf("rate limit %s: %v", policy, err)
+				next.ServeHTTP(w, r)
+				return
+			}
+
+			h := w.Header()
+			h.Set("RateLimit-Policy", strconv.Itoa(limit.Requests)+";w="+strconv.Itoa(int(limit.Per.Seconds())))
+			h.Set("RateLimit-Limit", strconv.Itoa(limit.Requests))
+			h.Set("RateLimit-Remaining", strconv.Itoa(res.Remaining))
+			h.Set("RateLimit-Reset", strconv.Itoa(int(res.ResetAfter.Seconds())))
+
+			if !res.Allowed {
+				retryAfter := int(res.RetryAfter.Seconds())
+				h.Set("Retry-After", strconv.Itoa(retryAfter))
+				h.Set("Content-Type", "application/json")
+				w.WriteHeader(http.StatusTooManyRequests)
+				_ = json.NewEncoder(w).Encode(map[string]interface{}{
+					"error": "rate limit exceeded",
+					"code":  "RATE_LIMITED",
+					"details": map[string]interface{}{
+						"policy":      policy,
+						"retry_after": retryAfter,
+					},
+				})
+				return
+			}
+			next.ServeHTTP(w, r)
+		})
+	}
+}
+
+func rateLimitKey(r *http.Request) string {
+	if anonID := httpctx.AnonID(r.Context()); anonID != "" {
+		return "anon:" + anonID
+	}
+	// middleware.RealIP has already applied X-Forwarded-For / X-Real-IP
+	if host, _, err := net.SplitHostPort(r.RemoteAddr); err == nil {
+		return "ip:" + host
+	}
+	return "ip:" + r.RemoteAddr
+}
@@ -1,10 +1,12 @@
 package http
 
 import (
+	"context"
 	"net/http"
 
 	"anon-backend/internal/config"
 	"anon-backend/internal/http/handlers"
+	"anon-backend/internal/security"
 	"anon-backend/internal/store"
 	"anon-backend/internal/ws"
 
@@ -17,11 +19,13 @@ type trustAdapter struct {
 	store store.Store
 }
 
-func (t trustAdapter) IsAccepted(a, b string) bool {
-	return t.store.TrustAccepted(a, b)
+func (t trustAdapter) IsAccepted(ctx context.Context, a, b string) (bool, error) {
+	return t.store.TrustAccepted(ctx, a, b)
 }
 
-func NewRouter(cfg config.Config) http.Handler {
+// NewRouter wires all routes. broker connects the chat hub to the hubs of
+// other API instances; nil keeps chat in-process.
+func NewRouter(cfg config.Config, broker ws.Broker) http.Handler {
 	r := chi.NewRouter()
 
 	// ✅ middleware first
@@ -33,20 +37,28 @@ func NewRouter(cfg config.Config) http.Handler {
 
 	// ✅ shared stores
 	str := store.DefaultStore()
-	tickets := ws.NewTicketStore()
-	hub := ws.NewHub()
+	hub := ws.NewHubWithBroker(broker)
+	hub.OnDelivered(handlers.ChatDelivered(hub))
 
 	trust := trustAdapter{store: str}
 
 	// -------- SESSION --------
 	r.Route("/session", func(sr chi.Router) {
-		sr.Post("/bootstrap", handlers.SessionBootstrap(cfg))
+		sr.With(RateLimit(cfg, config.RateLimitBootstrap)).Post("/bootstrap", handlers.SessionBootstrap(cfg))
 		sr.With(SessionAuth(cfg)).Get("/me", handlers.SessionMe(cfg))
-		sr.With(SessionAuth(cfg)).Post("/refresh", handlers.SessionRefresh(cfg))
+		sr.With(RateLimit(cfg, config.RateLimitRefresh)).Post("/refresh", handlers.SessionRefresh(cfg))
+		sr.With(SessionAuth(cfg)).Post("/recovery-key", handlers.RecoveryKeyCreate(cfg))
+		sr.With(SessionAuth(cfg)).Get("/devices", handlers.SessionDevices(cfg))
+		sr.With(SessionAuth(cfg)).Post("/revoke", handlers.SessionRevoke(cfg, hub))
 	})
 
 	// -------- DEVICE AUTH --------
-	r.Post("/device/challenge", handlers.DeviceChallenge(cfg))
+	r.With(RateLimit(cfg, config.RateLimitDeviceChallenge)).Post("/device/challenge", handlers.DeviceChallenge(cfg))
+	r.Route("/device/link", func(dr chi.Router) {
+		dr.With(RateLimit(cfg, config.RateLimitDeviceLink)).Post("/start", handlers.DeviceLinkStart(cfg))
+		dr.With(RateLimit(cfg, config.RateLimitDeviceLinkCode)).Get("/{code}", handlers.DeviceLinkStatus(cfg))
+		dr.With(SessionAuth(cfg), RateLimit(cfg, config.RateLimitDeviceLinkCode)).Post("/approve", handlers.DeviceLinkApprove(cfg))
+	})
 
 	// -------- LINK CARDS --------
 	r.Route("/link-cards", func(lc chi.Router) {
@@ -74,13 +86,24 @@ func NewRouter(cfg config.Config) http.Handler {
 
 	// -------- REPORTS --------
 	r.Route("/reports", func(rr chi.Router) {
-		rr.With(SessionAuth(cfg)).Post("/profile", handlers.ReportProfile(cfg))
-		rr.With(SessionAuth(cfg)).Post("/post", handlers.ReportPost(cfg))
+		rr.With(SessionAuth(cfg), RateLimit(cfg, config.RateLimitReports)).Post("/profile", handlers.ReportProfile(cfg))
+		rr.With(SessionAuth(cfg), RateLimit(cfg, config.RateLimitReports)).Post("/post", handlers.ReportPost(cfg))
 	})
 
 	// -------- WS --------
-	r.With(SessionAuth(cfg)).Post("/ws/ticket", handlers.CreateWSTicket(tickets, trust))
-	r.Get("/ws/chat", handlers.WSChat(hub, tickets, cfg, trust))
+	r.With(SessionAuth(cfg), RateLimit(cfg, config.RateLimitWSTicket)).Post("/ws/ticket", handlers.CreateWSTicket(cfg, trust))
+	r.Get("/ws/chat", handlers.WSChat(hub, cfg, trust))
+
+	// -------- E2E KEY DIRECTORY --------
+	r.Route("/keys", func(kr chi.Router) {
+		kr.With(SessionAuth(cfg)).Put("/", handlers.KeysUpload(cfg))
+		kr.With(SessionAuth(cfg)).Get("/{anonId}", handlers.KeysGet(cfg))
+	})
+
+	// -------- CHAT --------
+	r.Route("/chat", func(cr chi.Router) {
+		cr.With(SessionAuth(cfg)).Get("/{peer}/history", handlers.ChatHistory(cfg))
+	})
 
 	// -------- POSTS (FEED) --------
 	r.Route("/posts", func(pr chi.Router) {
@@ -90,49 +113,73 @@ func NewRouter(cfg config.Config) http.Handler {
 		pr.With(SessionAuth(cfg)).Get("/search", handlers.PostSearch(cfg))
 		pr.With(SessionAuth(cfg)).Get("/remaining", handlers.PostRemainingCount(cfg))
 		pr.With(SessionAuth(cfg)).Post("/delete", handlers.PostDelete(cfg))
-		pr.With(SessionAuth(cfg)).Post("/like", handlers.PostLike(cfg))
-		pr.With(SessionAuth(cfg)).Post("/dislike", handlers.PostDislike(cfg))
-		pr.With(SessionAuth(cfg)).Post("/{id}/report", handlers.PostReport(cfg))
+		pr.With(SessionAuth(cfg), RateLimit(cfg, config.RateLimitReactions)).Post("/like", handlers.PostLike(cfg))
+		pr.With(SessionAuth(cfg), RateLimit(cfg, config.RateLimitReactions)).Post("/dislike", handlers.PostDislike(cfg))
+		pr.With(SessionAuth(cfg), RateLimit(cfg, config.RateLimitReports)).Post("/{id}/report", handlers.PostReport(cfg))
 
 		// Comments
-		pr.With(SessionAuth(cfg)).Post("/comments/create", handlers.CommentCreate(cfg))
+		pr.With(SessionAuth(cfg), RateLimit(cfg, config.RateLimitComments)).Post("/comments/create", handlers.CommentCreate(cfg))
 		pr.With(SessionAuth(cfg)).Get("/comments", handlers.CommentGet(cfg))
 		pr.With(SessionAuth(cfg)).Post("/comments/delete", handlers.CommentDelete(cfg))
-		pr.With(SessionAuth(cfg)).Post("/comments/like", handlers.CommentLike(cfg))
-		pr.With(SessionAuth(cfg)).Post("/comments/dislike", handlers.CommentDislike(cfg))
-		pr.With(SessionAuth(cfg)).Post("/comments/replies/create", handlers.CommentReplyCreate(cfg))
+		pr.With(SessionAuth(cfg), RateLimit(cfg, config.RateLimitReactions)).Post("/comments/like", handlers.CommentLike(cfg))
+		pr.With(SessionAuth(cfg), RateLimit(cfg, config.RateLimitReactions)).Post("/comments/dislike", handlers.CommentDislike(cfg))
+		pr.With(SessionAuth(cfg), RateLimit(cfg, config.RateLimitComments)).Post("/comments/replies/create", handlers.CommentReplyCreate(cfg))
 		pr.With(SessionAuth(cfg)).Get("/comments/replies", handlers.CommentReplyGet(cfg))
 		pr.With(SessionAuth(cfg)).Post("/comments/replies/delete", handlers.CommentReplyDelete(cfg))
-		pr.With(SessionAuth(cfg)).Post("/comments/replies/like", handlers.CommentReplyLike(cfg))
-		pr.With(SessionAuth(cfg)).Post("/comments/replies/dislike", handlers.CommentReplyDislike(cfg))
+		pr.With(SessionAuth(cfg), RateLimit(cfg, config.RateLimitReactions)).Post("/comments/replies/like", handlers.CommentReplyLike(cfg))
+		pr.With(SessionAuth(cfg), RateLimit(cfg, config.RateLimitReactions)).Post("/comments/replies/dislike", handlers.CommentReplyDislike(cfg))
+	})
+
+	// -------- TAGS --------
+	r.Route("/tags", func(tr chi.Router) {
+		tr.With(SessionAuth(cfg)).Get("/trending", handlers.TagsTrending(cfg))
+		tr.With(SessionAuth(cfg)).Get("/following", handlers.TagsFollowing(cfg))
+		tr.With(SessionAuth(cfg)).Get("/{tag}/posts", handlers.TagPosts(cfg))
+		tr.With(SessionAuth(cfg)).Post("/{tag}/follow", handlers.TagFollow(cfg))
+		tr.With(SessionAuth(cfg)).Delete("/{tag}/follow", handlers.TagFollow(cfg))
 	})
 
 	// Admin routes (protected by admin session token)
-	r.Post("/admin/login", handlers.AdminLogin(cfg))
+	r.With(RateLimit(cfg, config.RateLimitAdminLogin)).Post("/admin/login", handlers.AdminLogin(cfg))
+	r.With(RateLimit(cfg, config.RateLimitAdminLogin)).Post("/admin/login/totp", handlers.AdminLoginTOTP(cfg))
 	r.Route("/admin", func(ar chi.Router) {
 		ar.Use(AdminAuth(cfg))
-		ar.Get("/posts", handlers.AdminGetPosts(cfg))
-		ar.Get("/posts/{id}", handlers.AdminGetPostDetail(cfg))
-		ar.Post("/posts/delete", handlers.AdminDeletePost(cfg))
-		ar.Get("/users", handlers.AdminGetUsers(cfg))
-		ar.Post("/users/ban", handlers.AdminBanUser(cfg))
-		ar.Get("/stats", handlers.AdminGetStats(cfg))
-		ar.Get("/sessions", handlers.AdminGetSessions(cfg))
-		ar.Get("/sessions/user", handlers.AdminGetUserSessions(cfg))
-		ar.Post("/sessions/revoke", handlers.AdminRevokeSession(cfg))
-		ar.Post("/sessions/revoke-all", handlers.AdminRevokeAllUserSessions(cfg))
-		ar.Get("/trust", handlers.AdminGetTrustGraph(cfg))
-		ar.Get("/abuse", handlers.AdminGetAbuseDashboard(cfg))
-		ar.Get("/audit", handlers.AdminGetAuditLog(cfg))
-		ar.Post("/audit/delete", handlers.AdminDeleteAuditLog(cfg))
-		ar.Post("/audit/delete-batch", handlers.AdminDeleteAuditLogs(cfg))
-		ar.Post("/audit/clear", handlers.AdminClearAuditLogs(cfg))
-		ar.Get("/health", handlers.AdminGetHealth(cfg))
+
+		// every admin manages their own second factor
+		ar.Post("/totp/enroll", handlers.AdminTOTPEnroll(cfg))
+		ar.Post("/totp/confirm", handlers.AdminTOTPConfirm(cfg))
+
+		// support: read-only views
+		support := ar.With(RequireAdminRole(security.RoleSupport))
+		support.Get("/posts", handlers.AdminGetPosts(cfg))
+		support.Get("/posts/{id}", handlers.AdminGetPostDetail(cfg))
+		support.Get("/users", handlers.AdminGetUsers(cfg))
+		support.Get("/stats", handlers.AdminGetStats(cfg))
+		support.Get("/sessions", handlers.AdminGetSessions(cfg))
+		support.Get("/sessions/user", handlers.AdminGetUserSessions(cfg))
+		support.Get("/trust", handlers.AdminGetTrustGraph(cfg))
+		support.Get("/abuse", handlers.AdminGetAbuseDashboard(cfg))
+		support.Get("/health", handlers.AdminGetHealth(cfg))
+		support.Get("/ws/stats", handlers.AdminGetWSStats(hub))
+
+		// moderator: acting on users and content
+		mod := ar.With(RequireAdminRole(security.RoleModerator))
+		mod.Post("/posts/delete", handlers.AdminDeletePost(cfg))
+		mod.Post("/users/ban", handlers.AdminBanUser(cfg, hub))
+		mod.Post("/sessions/revoke", handlers.AdminRevokeSession(cfg, hub))
+		mod.Post("/sessions/revoke-all", handlers.AdminRevokeAllUserSessions(cfg, hub))
+		mod.Get("/audit", handlers.AdminGetAuditLog(cfg))
+		mod.Get("/audit/verify", handlers.AdminVerifyAuditLog(cfg))
+
+		// superadmin: staff accounts
+		super := ar.With(RequireAdminRole(security.RoleSuperadmin))
+		super.Get("/admins", handlers.AdminListAdmins(cfg))
+		super.Post("/admins", handlers.AdminCreateAdmin(cfg))
 	})
 
 	// -------- GEO (MAP) --------
 	r.Route("/geo", func(gr chi.Router) {
-		gr.With(SessionAuth(cfg)).Post("/ping", handlers.GeoPing(cfg))
+		gr.With(SessionAuth(cfg), RateLimit(cfg, config.RateLimitGeoPing)).Post("/ping", handlers.GeoPing(cfg))
 		gr.With(SessionAuth(cfg)).Get("/nearby", handlers.GeoNearby(cfg))
 	})
 
@@ -8,7 +8,11 @@ import (
 
 type ctxKey string
 
-const claimsKey ctxKey = "session_claims"
+const (
+	claimsKey    ctxKey = "session_claims"
+	sessionIDKey ctxKey = "session_id"
+	adminKey     ctxKey = "admin_claims"
+)
 
 func WithClaims(ctx context.Context, claims *security.SessionClaims) context.Context {
 	return context.WithValue(ctx, claimsKey, claims)
@@ -21,3 +25,23 @@ func ClaimsFromContext(ctx context.Context) *security.SessionClaims {
 	}
 	return nil
 }
+
+// WithSessionID records which session (see store.SessionInfo.ID) the
+// request's access token belongs to.
+func WithSessionID(ctx context.Context, sessionID string) context.Context {
+	return context.WithValue(ctx, sessionIDKey, sessionID)
+}
+
+func SessionIDFromContext(ctx context.Context) string {
+	id, _ := ctx.Value(sessionIDKey).(string)
+	return id
+}
+
+func WithAdminClaims(ctx context.Context, claims *security.AdminClaims) context.Context {
+	return context.WithValue(ctx, adminKey, claims)
+}
+
+func AdminClaimsFromContext(ctx context.Context) *security.AdminClaims {
+	c, _ := ctx.Value(adminKey).(*security.AdminClaims)
+	return c
+}
@@ -0,0 +1,26 @@
+package security
+
+// Admin roles, from least to most privileged. Each role can do everything
+// the roles before it can.
+const (
+	RoleSupport    = "support"
+	RoleModerator  = "moderator"
+	RoleSuperadmin = "superadmin"
+)
+
+var adminRoleRank = map[string]int{
+	RoleSupport:    1,
+	RoleModerator:  2,
+	RoleSuperadmin: 3,
+}
+
+func IsAdminRole(role string) bool {
+	_, ok := adminRoleRank[role]
+	return ok
+}
+
+// AdminRoleAllows reports whether role may use routes that require required.
+func AdminRoleAllows(role, required string) bool {
+	have, ok := adminRoleRank[role]
+	return ok && have >= adminRoleRank[required]
+}
@@ -1,6 +1,7 @@
 package security
 
 import (
+	"crypto/ed25519"
 	"crypto/hmac"
 	"crypto/rand"
 	"crypto/sha256"
@@ -34,3 +35,32 @@ func VerifyHMACProof(key []byte, message, proof string) (bool, error) {
 	}
 	return hmac.Equal(proofBytes, expectedBytes), nil
 }
+
+// ParseEd25519PublicKey decodes a base64 (standard encoding) Ed25519 public key.
+func ParseEd25519PublicKey(publicKey string) (ed25519.PublicKey, error) {
+	keyBytes, err := base64.StdEncoding.DecodeString(publicKey)
+	if err != nil {
+		return nil, fmt.Errorf("invalid public key encoding")
+	}
+	if len(keyBytes) != ed25519.PublicKeySize {
+		return nil, fmt.Errorf("invalid public key length")
+	}
+	return ed25519.PublicKey(keyBytes), nil
+}
+
+// VerifyEd25519Proof reports whether signature (base64) is a valid Ed25519
+// signature of message by publicKey (base64).
+func VerifyEd25519Proof(publicKey, message, signature string) (bool, error) {
+	key, err := ParseEd25519PublicKey(publicKey)
+	if err != nil {
+		return false, err
+	}
+	sigBytes, err := base64.StdEncoding.DecodeString(signature)
+	if err != nil {
+		return false, fmt.Errorf("invalid proof encoding")
+	}
+	if len(sigBytes) != ed25519.SignatureSize {
+		return false, nil
+	}
+	return ed25519.Verify(key, []byte(message), sigBytes), nil
+}
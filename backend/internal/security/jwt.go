@@ -18,61 +18,119 @@ type AdminClaims struct {
 	jwt.RegisteredClaims
 }
 
-func SignSessionJWT(secret string, ttl time.Duration, anonID, region string) (string, error) {
+func registeredClaims(aud, subject string, ttl time.Duration) jwt.RegisteredClaims {
 	now := time.Now()
-	claims := SessionClaims{
-		AnonID: anonID,
-		Region: region,
-		RegisteredClaims: jwt.RegisteredClaims{
-			IssuedAt:  jwt.NewNumericDate(now),
-			ExpiresAt: jwt.NewNumericDate(now.Add(ttl)),
-		},
+	return jwt.RegisteredClaims{
+		Subject:   subject,
+		Audience:  jwt.ClaimStrings{aud},
+		IssuedAt:  jwt.NewNumericDate(now),
+		ExpiresAt: jwt.NewNumericDate(now.Add(ttl)),
 	}
+}
 
-	t := jwt.NewWithClaims(jwt.SigningMethodHS256, claims)
-	return t.SignedString([]byte(secret))
+func SignSessionJWT(keys *Keyring, ttl time.Duration, anonID, region string) (string, error) {
+	claims := SessionClaims{
+		AnonID:           anonID,
+		Region:           region,
+		RegisteredClaims: registeredClaims(AudienceSession, "", ttl),
+	}
+	return keys.sign(AudienceSession, claims)
 }
 
-func VerifySessionJWT(secret, tokenStr string) (*SessionClaims, error) {
-	tok, err := jwt.ParseWithClaims(tokenStr, &SessionClaims{}, func(token *jwt.Token) (any, error) {
-		return []byte(secret), nil
-	})
+func VerifySessionJWT(keys *Keyring, tokenStr string) (*SessionClaims, error) {
+	tok, err := keys.parse(AudienceSession, tokenStr, &SessionClaims{})
 	if err != nil {
 		return nil, err
 	}
 
 	claims, ok := tok.Claims.(*SessionClaims)
-	if !ok || !tok.Valid {
+	if !ok || !tok.Valid || claims.AnonID == "" {
 		return nil, jwt.ErrTokenInvalidClaims
 	}
 	return claims, nil
 }
 
-func SignAdminJWT(secret string, ttl time.Duration, email string) (string, error) {
-	now := time.Now()
+func SignAdminJWT(keys *Keyring, ttl time.Duration, adminID, email, role string) (string, error) {
 	claims := AdminClaims{
-		Email: email,
-		Role:  "admin",
-		RegisteredClaims: jwt.RegisteredClaims{
-			IssuedAt:  jwt.NewNumericDate(now),
-			ExpiresAt: jwt.NewNumericDate(now.Add(ttl)),
-		},
+		Email:            email,
+		Role:             role,
+		RegisteredClaims: registeredClaims(AudienceAdmin, adminID, ttl),
 	}
-
-	t := jwt.NewWithClaims(jwt.SigningMethodHS256, claims)
-	return t.SignedString([]byte(secret))
+	return keys.sign(AudienceAdmin, claims)
 }
 
-func VerifyAdminJWT(secret, tokenStr string) (*AdminClaims, error) {
-	tok, err := jwt.ParseWithClaims(tokenStr, &AdminClaims{}, func(token *jwt.Token) (any, error) {
-		return []byte(secret), nil
-	})
+func VerifyAdminJWT(keys *Keyring, tokenStr string) (*AdminClaims, error) {
+	tok, err := keys.parse(AudienceAdmin, tokenStr, &AdminClaims{})
 	if err != nil {
 		return nil, err
 	}
 
 	claims, ok := tok.Claims.(*AdminClaims)
-	if !ok || !tok.Valid || claims.Role != "admin" {
+	if !ok || !tok.Valid || !IsAdminRole(claims.Role) {
+		return nil, jwt.ErrTokenInvalidClaims
+	}
+	return claims, nil
+}
+
+// adminMFAPurpose marks the short-lived token handed out between the
+// password and TOTP steps of an admin login.
+const adminMFAPurpose = "admin_mfa"
+
+// AdminMFAClaims identify an admin who passed the password check but still
+// owes a second factor. They carry no role, so VerifyAdminJWT rejects them.
+type AdminMFAClaims struct {
+	Purpose string `json:"purpose"`
+	jwt.RegisteredClaims
+}
+
+func SignAdminMFAJWT(keys *Keyring, ttl time.Duration, adminID string) (string, error) {
+	claims := AdminMFAClaims{
+		Purpose:          adminMFAPurpose,
+		RegisteredClaims: registeredClaims(AudienceAdmin, adminID, ttl),
+	}
+	return keys.sign(AudienceAdmin, claims)
+}
+
+func VerifyAdminMFAJWT(keys *Keyring, tokenStr string) (*AdminMFAClaims, error) {
+	tok, err := keys.parse(AudienceAdmin, tokenStr, &AdminMFAClaims{})
+	if err != nil {
+		return nil, err
+	}
+
+	claims, ok := tok.Claims.(*AdminMFAClaims)
+	if !ok || !tok.Valid || claims.Purpose != adminMFAPurpose || claims.Subject == "" {
+		return nil, jwt.ErrTokenInvalidClaims
+	}
+	return claims, nil
+}
+
+// WSTicketClaims authorize one websocket upgrade. Being signed, a ticket can
+// be redeemed on any API instance; its ID (jti) is recorded in the shared
+// store on redemption, which makes it single-use.
+type WSTicketClaims struct {
+	Peer      string `json:"peer,omitempty"`
+	SessionID string `json:"sid,omitempty"`
+	jwt.RegisteredClaims
+}
+
+func SignWSTicket(keys *Keyring, ttl time.Duration, ticketID, anonID, peer, sessionID string) (string, error) {
+	claims := WSTicketClaims{
+		Peer:             peer,
+		SessionID:        sessionID,
+		RegisteredClaims: registeredClaims(AudienceWS, anonID, ttl),
+	}
+	claims.ID = ticketID
+	return keys.sign(AudienceWS, claims)
+}
+
+func VerifyWSTicket(keys *Keyring, tokenStr string) (*WSTicketClaims, error) {
+	tok, err := keys.parse(AudienceWS, tokenStr, &WSTicketClaims{})
+	if err != nil {
+		return nil, err
+	}
+
+	claims, ok := tok.Claims.(*WSTicketClaims)
+	if !ok || !tok.Valid || claims.ID == "" || claims.Subject == "" {
 		return nil, jwt.ErrTokenInvalidClaims
 	}
 	return claims, nil
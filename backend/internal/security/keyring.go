@@ -0,0 +1,262 @@
+package security
+
+import (
+	"crypto/ed25519"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"slices"
+	"sync"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+)
+
+// Token audiences. Each has its own keys so a token minted for one can never
+// be replayed against another.
+const (
+	AudienceSession = "session"
+	AudienceAdmin   = "admin"
+	AudienceWS      = "ws"
+)
+
+var audiences = []string{AudienceSession, AudienceAdmin, AudienceWS}
+
+// Key algorithms accepted in a keyring file.
+const (
+	KeyAlgHS256 = "HS256"
+	KeyAlgEdDSA = "EdDSA"
+)
+
+var ErrUnknownKey = errors.New("unknown signing key")
+
+// LegacyKeyWindow is how long after a keyring is loaded from a secret it
+// still accepts tokens signed with the raw secret, as every token was before
+// the keyring: they carry no kid and no audience. That is longer than any
+// such token lives, so a deploy logs nobody out.
+const LegacyKeyWindow = 24 * time.Hour
+
+// legacyAudiences had tokens before the keyring; websocket tickets did not.
+var legacyAudiences = []string{AudienceSession, AudienceAdmin}
+
+// signingKey is one entry of an audience's key set. Retired keys may lack
+// signing material and are then only used to verify.
+type signingKey struct {
+	id     string
+	method jwt.SigningMethod
+	sign   any // []byte or ed25519.PrivateKey; nil for verify-only keys
+	verify any // []byte or ed25519.PublicKey
+}
+
+type keySet struct {
+	active *signingKey
+	keys   map[string]*signingKey
+}
+
+// Keyring holds the JWT keys for every audience. Tokens carry the key id in
+// their kid header, so keys can be rotated by adding a new active key and
+// keeping the previous one for verification until its tokens expire.
+// Reload swaps the whole keyring atomically.
+//
+// A keyring derived from a secret also verifies legacy tokens, under kid
+// "", until legacyUntil; see LegacyKeyWindow.
+type Keyring struct {
+	mu          sync.RWMutex
+	sets        map[string]*keySet
+	legacyUntil time.Time
+}
+
+// KeyringFile is the JSON layout of JWT_KEYS_FILE:
+//
+//	{
+//	  "session": {"active": "s2", "keys": [
+//	    {"kid": "s2", "alg": "EdDSA", "private_key": "<base64 seed>"},
+//	    {"kid": "s1", "alg": "HS256", "secret": "<base64>"}
+//	  ]},
+//	  "admin": {...}, "ws": {...}
+//	}
+//
+// EdDSA keys kept only for verification may give "public_key" instead.
+type KeyringFile map[string]struct {
+	Active string `json:"active"`
+	Keys   []struct {
+		KID        string `json:"kid"`
+		Alg        string `json:"alg"`
+		Secret     string `json:"secret,omitempty"`
+		PrivateKey string `json:"private_key,omitempty"`
+		PublicKey  string `json:"public_key,omitempty"`
+	} `json:"keys"`
+}
+
+// LoadKeyring reads path when set; otherwise it derives one HS256 key per
+// audience from secret, which keeps single-secret deployments working, and
+// keeps the raw secret to verify their legacy tokens for LegacyKeyWindow.
+func LoadKeyring(secret, path string) (*Keyring, error) {
+	k := &Keyring{legacyUntil: time.Now().Add(LegacyKeyWindow)}
+	if err := k.Reload(secret, path); err != nil {
+		return nil, err
+	}
+	return k, nil
+}
+
+// Reload rebuilds the keyring from the same sources as LoadKeyring and
+// replaces the current keys only if the new ones are valid.
+func (k *Keyring) Reload(secret, path string) error {
+	var sets map[string]*keySet
+	var err error
+	if path == "" {
+		sets, err = derivedKeySets(secret)
+	} else {
+		sets, err = fileKeySets(path)
+	}
+	if err != nil {
+		return err
+	}
+
+	k.mu.Lock()
+	k.sets = sets
+	k.mu.Unlock()
+	return nil
+}
+
+func derivedKeySets(secret string) (map[string]*keySet, error) {
+	if secret == "" {
+		return nil, errors.New("jwt secret is empty")
+	}
+	sets := make(map[string]*keySet, len(audiences))
+	for _, aud := range audiences {
+		m := hmac.New(sha256.New, []byte(secret))
+		m.Write([]byte("anon-jwt:" + aud))
+		derived := m.Sum(nil)
+		key := &signingKey{id: aud + "-derived", method: jwt.SigningMethodHS256, sign: derived, verify: derived}
+		sets[aud] = &keySet{active: key, keys: map[string]*signingKey{key.id: key}}
+	}
+	for _, aud := range legacyAudiences {
+		sets[aud].keys[""] = &signingKey{method: jwt.SigningMethodHS256, verify: []byte(secret)}
+	}
+	return sets, nil
+}
+
+func fileKeySets(path string) (map[string]*keySet, error) {
+	raw, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("read jwt keys: %w", err)
+	}
+	var file KeyringFile
+	if err := json.Unmarshal(raw, &file); err != nil {
+		return nil, fmt.Errorf("parse jwt keys: %w", err)
+	}
+
+	sets := make(map[string]*keySet, len(audiences))
+	for _, aud := range audiences {
+		entry, ok := file[aud]
+		if !ok {
+			return nil, fmt.Errorf("jwt keys: no keys for audience %q", aud)
+		}
+		set := &keySet{keys: make(map[string]*signingKey)}
+		for _, spec := range entry.Keys {
+			key, err := parseSigningKey(spec.KID, spec.Alg, spec.Secret, spec.PrivateKey, spec.PublicKey)
+			if err != nil {
+				return nil, fmt.Errorf("jwt keys: %s/%s: %w", aud, spec.KID, err)
+			}
+			if _, dup := set.keys[key.id]; dup {
+				return nil, fmt.Errorf("jwt keys: %s: duplicate kid %q", aud, key.id)
+			}
+			set.keys[key.id] = key
+		}
+		set.active = set.keys[entry.Active]
+		if set.active == nil || set.active.sign == nil {
+			return nil, fmt.Errorf("jwt keys: %s: active key %q missing or verify-only", aud, entry.Active)
+		}
+		sets[aud] = set
+	}
+	return sets, nil
+}
+
+func parseSigningKey(kid, alg, secret, privateKey, publicKey string) (*signingKey, error) {
+	if kid == "" {
+		return nil, errors.New("kid required")
+	}
+	switch alg {
+	case KeyAlgHS256:
+		b, err := base64.StdEncoding.DecodeString(secret)
+		if err != nil || len(b) < 32 {
+			return nil, errors.New("secret must be base64 of at least 32 bytes")
+		}
+		return &signingKey{id: kid, method: jwt.SigningMethodHS256, sign: b, verify: b}, nil
+	case KeyAlgEdDSA:
+		if privateKey != "" {
+			seed, err := base64.StdEncoding.DecodeString(privateKey)
+			if err != nil || len(seed) != ed25519.SeedSize {
+				return nil, errors.New("private_key must be a base64 ed25519 seed")
+			}
+			priv := ed25519.NewKeyFromSeed(seed)
+			return &signingKey{id: kid, method: jwt.SigningMethodEdDSA, sign: priv, verify: priv.Public()}, nil
+		}
+		pub, err := ParseEd25519PublicKey(publicKey)
+		if err != nil {
+			return nil, err
+		}
+		return &signingKey{id: kid, method: jwt.SigningMethodEdDSA, verify: pub}, nil
+	default:
+		return nil, fmt.Errorf("unsupported alg %q", alg)
+	}
+}
+
+func (k *Keyring) set(aud string) (*keySet, error) {
+	k.mu.RLock()
+	defer k.mu.RUnlock()
+	set, ok := k.sets[aud]
+	if !ok {
+		return nil, fmt.Errorf("no keys for audience %q", aud)
+	}
+	return set, nil
+}
+
+// sign signs claims with aud's active key. Callers set the audience claim.
+func (k *Keyring) sign(aud string, claims jwt.Claims) (string, error) {
+	set, err := k.set(aud)
+	if err != nil {
+		return "", err
+	}
+	t := jwt.NewWithClaims(set.active.method, claims)
+	t.Header["kid"] = set.active.id
+	return t.SignedString(set.active.sign)
+}
+
+// parse verifies tokenStr against aud's keys, picked by kid, and requires
+// the audience claim to match. Legacy tokens have no audience to match.
+func (k *Keyring) parse(aud, tokenStr string, claims jwt.Claims) (*jwt.Token, error) {
+	set, err := k.set(aud)
+	if err != nil {
+		return nil, err
+	}
+	var kid string
+	tok, err := jwt.ParseWithClaims(tokenStr, claims, func(t *jwt.Token) (any, error) {
+		kid, _ = t.Header["kid"].(string)
+		key, ok := set.keys[kid]
+		if !ok || (kid == "" && !time.Now().Before(k.legacyUntil)) {
+			return nil, ErrUnknownKey
+		}
+		if t.Method.Alg() != key.method.Alg() {
+			return nil, jwt.ErrTokenSignatureInvalid
+		}
+		return key.verify, nil
+	}, jwt.WithValidMethods([]string{KeyAlgHS256, KeyAlgEdDSA}))
+	if err != nil {
+		return nil, err
+	}
+
+	got, err := claims.GetAudience()
+	if err != nil {
+		return nil, err
+	}
+	if (kid == "" && len(got) > 0) || (kid != "" && !slices.Contains(got, aud)) {
+		return nil, jwt.ErrTokenInvalidAudience
+	}
+	return tok, nil
+}
@@ -0,0 +1,71 @@
+package security
+
+import (
+	"testing"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+)
+
+// legacyToken signs claims the way tokens were signed before the keyring:
+// with the raw secret, no kid and no audience.
+func legacyToken(t *testing.T, secret string, claims jwt.Claims) string {
+	t.Helper()
+	tok, err := jwt.NewWithClaims(jwt.SigningMethodHS256, claims).SignedString([]byte(secret))
+	if err != nil {
+		t.Fatal(err)
+	}
+	return tok
+}
+
+func TestKeyringVerifiesLegacyTokens(t *testing.T) {
+	keys, err := LoadKeyring("test-secret", "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	exp := jwt.NewNumericDate(time.Now().Add(time.Hour))
+
+	session := legacyToken(t, "test-secret", SessionClaims{AnonID: "anon-a", RegisteredClaims: jwt.RegisteredClaims{ExpiresAt: exp}})
+	claims, err := VerifySessionJWT(keys, session)
+	if err != nil {
+		t.Fatalf("legacy session token: %v", err)
+	}
+	if claims.AnonID != "anon-a" {
+		t.Fatalf("legacy session token anon id = %q", claims.AnonID)
+	}
+
+	admin := legacyToken(t, "test-secret", AdminClaims{Email: "ops@example.com", Role: RoleSuperadmin, RegisteredClaims: jwt.RegisteredClaims{Subject: "admin-1", ExpiresAt: exp}})
+	if _, err := VerifyAdminJWT(keys, admin); err != nil {
+		t.Fatalf("legacy admin token: %v", err)
+	}
+	// nor does sharing the secret let one kind pass for the other
+	if _, err := VerifySessionJWT(keys, admin); err == nil {
+		t.Fatal("legacy admin token verified as a session token")
+	}
+
+	if _, err := VerifySessionJWT(keys, legacyToken(t, "other-secret", SessionClaims{AnonID: "anon-a", RegisteredClaims: jwt.RegisteredClaims{ExpiresAt: exp}})); err == nil {
+		t.Fatal("verified a legacy token signed with another secret")
+	}
+	// a token naming an audience must carry the kid of a current key
+	withAud := legacyToken(t, "test-secret", SessionClaims{AnonID: "anon-a", RegisteredClaims: jwt.RegisteredClaims{Audience: jwt.ClaimStrings{AudienceSession}, ExpiresAt: exp}})
+	if _, err := VerifySessionJWT(keys, withAud); err == nil {
+		t.Fatal("verified a kid-less token with an audience")
+	}
+
+	// new tokens verify as before
+	current, err := SignSessionJWT(keys, time.Hour, "anon-a", "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := VerifySessionJWT(keys, current); err != nil {
+		t.Fatalf("current session token: %v", err)
+	}
+
+	keys.legacyUntil = time.Now().Add(-time.Second)
+	if _, err := VerifySessionJWT(keys, session); err == nil {
+		t.Fatal("verified a legacy token after the transition window")
+	}
+	if _, err := VerifySessionJWT(keys, current); err != nil {
+		t.Fatalf("current session token after the transition window: %v", err)
+	}
+}
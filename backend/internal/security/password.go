@@ -0,0 +1,35 @@
+package security
+
+import (
+	"errors"
+
+	"golang.org/x/crypto/bcrypt"
+)
+
+// MinAdminPasswordLen is the shortest admin password accepted.
+const MinAdminPasswordLen = 12
+
+var ErrPasswordTooShort = errors.New("password too short")
+
+func HashPassword(password string) (string, error) {
+	if len(password) < MinAdminPasswordLen {
+		return "", ErrPasswordTooShort
+	}
+	hash, err := bcrypt.GenerateFromPassword([]byte(password), bcrypt.DefaultCost)
+	if err != nil {
+		return "", err
+	}
+	return string(hash), nil
+}
+
+func CheckPassword(hash, password string) bool {
+	return bcrypt.CompareHashAndPassword([]byte(hash), []byte(password)) == nil
+}
+
+// dummyPasswordHash is compared against when the account does not exist so
+// that a failed login takes as long whether or not the email is known.
+var dummyPasswordHash, _ = bcrypt.GenerateFromPassword([]byte("anon-dummy-password"), bcrypt.DefaultCost)
+
+func CheckPasswordDummy(password string) {
+	_ = bcrypt.CompareHashAndPassword(dummyPasswordHash, []byte(password))
+}
@@ -0,0 +1,44 @@
+package security
+
+import (
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base32"
+	"encoding/hex"
+	"strings"
+)
+
+// recoveryKeyBytes gives 160 bits of entropy, so a plain SHA-256 of the key
+// is enough to store it; there is nothing to brute-force.
+const recoveryKeyBytes = 20
+
+// NewRecoveryKey returns a human-transcribable recovery key such as
+// "ABCD-EFGH-...", shown to the user once, and the hash to store for it.
+func NewRecoveryKey() (key, hash string, err error) {
+	b := make([]byte, recoveryKeyBytes)
+	if _, err := rand.Read(b); err != nil {
+		return "", "", err
+	}
+	raw := base32.StdEncoding.WithPadding(base32.NoPadding).EncodeToString(b)
+
+	groups := make([]string, 0, len(raw)/4)
+	for i := 0; i < len(raw); i += 4 {
+		groups = append(groups, raw[i:min(i+4, len(raw))])
+	}
+	key = strings.Join(groups, "-")
+	return key, HashRecoveryKey(key), nil
+}
+
+// HashRecoveryKey hashes a recovery key as typed by the user; case, spaces
+// and dashes do not matter.
+func HashRecoveryKey(key string) string {
+	normalized := strings.Map(func(r rune) rune {
+		switch r {
+		case '-', ' ', '\t', '\n':
+			return -1
+		}
+		return r
+	}, strings.ToUpper(key))
+	sum := sha256.Sum256([]byte(normalized))
+	return hex.EncodeToString(sum[:])
+}
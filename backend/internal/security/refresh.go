@@ -0,0 +1,82 @@
+package security
+
+import (
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"strings"
+)
+
+const refreshTokenPrefix = "rt_"
+
+// NewRefreshToken returns an opaque refresh token bound to sessionID and the
+// hash to store for it. Only the hash is ever persisted.
+func NewRefreshToken(sessionID string) (token, hash string, err error) {
+	b := make([]byte, 32)
+	if _, err := rand.Read(b); err != nil {
+		return "", "", err
+	}
+	token = refreshTokenPrefix + sessionID + "." + base64.RawURLEncoding.EncodeToString(b)
+	return token, HashRefreshToken(token), nil
+}
+
+// NewRefreshSeed returns a random seed for NextRefreshToken.
+func NewRefreshSeed() (string, error) {
+	b := make([]byte, 32)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	return base64.RawURLEncoding.EncodeToString(b), nil
+}
+
+// NextRefreshToken returns the token that replaces presented on rotation,
+// and its hash. The seed is stored with the session, so a client retrying
+// the same refresh within the grace window gets the same token back; the
+// token itself is never stored, and deriving it takes presented.
+func NextRefreshToken(presented, seed string) (token, hash string) {
+	sessionID, _ := RefreshTokenSessionID(presented)
+	m := hmac.New(sha256.New, []byte(presented))
+	m.Write([]byte(seed))
+	token = refreshTokenPrefix + sessionID + "." + base64.RawURLEncoding.EncodeToString(m.Sum(nil))
+	return token, HashRefreshToken(token)
+}
+
+func HashRefreshToken(token string) string {
+	sum := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(sum[:])
+}
+
+// RefreshTokenSessionID extracts the session (token family) a refresh token
+// was issued for. It does not say anything about the token being valid.
+func RefreshTokenSessionID(token string) (string, bool) {
+	rest, ok := strings.CutPrefix(token, refreshTokenPrefix)
+	if !ok {
+		return "", false
+	}
+	sessionID, secret, ok := strings.Cut(rest, ".")
+	if !ok || secret == "" || !looksLikeUUID(sessionID) {
+		return "", false
+	}
+	return sessionID, true
+}
+
+func looksLikeUUID(s string) bool {
+	if len(s) != 36 {
+		return false
+	}
+	for i, r := range s {
+		switch i {
+		case 8, 13, 18, 23:
+			if r != '-' {
+				return false
+			}
+		default:
+			if !strings.ContainsRune("0123456789abcdef", r) {
+				return false
+			}
+		}
+	}
+	return true
+}
@@ -0,0 +1,44 @@
+package session
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"anon-backend/internal/store"
+)
+
+type adminCheck struct {
+	admin     *store.Admin
+	checkedAt time.Time
+}
+
+// admins is keyed by admin id; there are few enough admins that it needs
+// no sweeping.
+var admins = struct {
+	mu sync.Mutex
+	m  map[string]adminCheck
+}{m: make(map[string]adminCheck)}
+
+// Admin returns the stored account behind an admin token's subject, asking
+// the store unless it was read less than checkTTL ago, so disabling an
+// admin or changing their role takes effect within checkTTL everywhere.
+// The error is store.ErrAdminNotFound once the account is gone.
+func Admin(ctx context.Context, adminID string, now time.Time) (*store.Admin, error) {
+	admins.mu.Lock()
+	check, ok := admins.m[adminID]
+	admins.mu.Unlock()
+	if ok && now.Sub(check.checkedAt) <= checkTTL {
+		return check.admin, nil
+	}
+
+	admin, err := store.DefaultStore().GetAdminByID(ctx, adminID)
+	if err != nil {
+		return nil, err
+	}
+
+	admins.mu.Lock()
+	admins.m[adminID] = adminCheck{admin: admin, checkedAt: now}
+	admins.mu.Unlock()
+	return admin, nil
+}
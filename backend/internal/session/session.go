@@ -0,0 +1,135 @@
+// Package session caches, per access token, whether the session behind it
+// is still usable, so SessionAuth does not cost two store queries on every
+// request. Handlers that revoke sessions or ban users call Forget or
+// ForgetToken so the change applies at once on this instance. Admin
+// accounts are cached the same way for AdminAuth.
+package session
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+
+	"anon-backend/internal/store"
+)
+
+// checkTTL bounds how long a revocation or ban made on another API
+// instance can go unnoticed.
+const checkTTL = 5 * time.Second
+
+// Status is the store's answer about the session behind a token.
+type Status struct {
+	SessionID string
+	AnonID    string
+	Revoked   bool           // the session no longer exists
+	Ban       *store.UserBan // the user's ban when last checked, if any
+	checkedAt time.Time
+}
+
+// Banned reports whether s carries a ban still in force at now.
+func (s *Status) Banned(now time.Time) bool {
+	return s.Ban != nil && (s.Ban.Permanent || s.Ban.ExpiresAt == nil || s.Ban.ExpiresAt.After(now))
+}
+
+var checks = struct {
+	mu      sync.Mutex
+	m       map[string]*Status
+	sweptAt time.Time
+}{m: make(map[string]*Status)}
+
+// Check returns the status of the session behind token, asking the store
+// unless a check younger than checkTTL is cached.
+func Check(ctx context.Context, token string, now time.Time) (*Status, error) {
+	checks.mu.Lock()
+	status, ok := checks.m[token]
+	checks.mu.Unlock()
+	if ok && now.Sub(status.checkedAt) <= checkTTL {
+		return status, nil
+	}
+
+	status, err := load(ctx, token, now)
+	if err != nil {
+		return nil, err
+	}
+
+	checks.mu.Lock()
+	sweepLocked(now)
+	checks.m[token] = status
+	checks.mu.Unlock()
+	return status, nil
+}
+
+func load(ctx context.Context, token string, now time.Time) (*Status, error) {
+	sess, err := store.DefaultStore().GetSessionByToken(ctx, token)
+	if errors.Is(err, store.ErrSessionNotFound) {
+		return &Status{Revoked: true, checkedAt: now}, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	ban, err := store.DefaultStore().GetActiveUserBan(ctx, sess.AnonID, now)
+	if err != nil {
+		return nil, err
+	}
+	return &Status{SessionID: sess.ID, AnonID: sess.AnonID, Ban: ban, checkedAt: now}, nil
+}
+
+// sweepLocked drops stale entries so the cache stays bounded by the number
+// of tokens seen in the last two TTLs. It scans the cache at most once per
+// TTL, so misses cost O(1) amortized.
+func sweepLocked(now time.Time) {
+	if now.Sub(checks.sweptAt) < checkTTL {
+		return
+	}
+	checks.sweptAt = now
+	for token, status := range checks.m {
+		if now.Sub(status.checkedAt) > checkTTL {
+			delete(checks.m, token)
+		}
+	}
+}
+
+// Forget drops cached checks for anonID's sessions so the next request
+// re-reads revocations and bans from the store.
+func Forget(anonID string) {
+	checks.mu.Lock()
+	defer checks.mu.Unlock()
+
+	for token, status := range checks.m {
+		if status.AnonID == anonID {
+			delete(checks.m, token)
+		}
+	}
+}
+
+// ForgetToken drops the cached check for a single token.
+func ForgetToken(token string) {
+	checks.mu.Lock()
+	defer checks.mu.Unlock()
+	delete(checks.m, token)
+}
+
+// BanDetails describes ban for the details of a USER_BANNED error.
+func BanDetails(ban *store.UserBan, now time.Time) map[string]interface{} {
+	details := map[string]interface{}{
+		"is_permanent": ban.Permanent,
+	}
+
+	if ban.Permanent || ban.ExpiresAt == nil {
+		details["ban_label"] = "Banned permanently"
+		return details
+	}
+
+	remaining := ban.ExpiresAt.Sub(now)
+	if remaining < 0 {
+		remaining = 0
+	}
+
+	details["ban_expires_at"] = ban.ExpiresAt.Format(time.RFC3339)
+	details["remaining_seconds"] = int64(remaining.Seconds())
+	details["ban_label"] = fmt.Sprintf("Banned until %s", ban.ExpiresAt.Format("2006-01-02 15:04"))
+	return details
+}
@@ -0,0 +1,92 @@
+package store
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"anon-backend/internal/security"
+)
+
+var (
+	ErrAdminNotFound = fmt.Errorf("admin %w", ErrNotFound)
+	ErrAdminExists   = errors.New("admin already exists")
+	// ErrInvalidAdminAccount wraps validation failures in CreateAdminAccount.
+	ErrInvalidAdminAccount = errors.New("invalid admin account")
+	// ErrAdminTOTPNotPending is returned when confirming TOTP without an
+	// enrolment in progress.
+	ErrAdminTOTPNotPending = errors.New("no totp enrolment pending")
+)
+
+// Admin is a staff account for the admin panel. Role is one of the
+// security.Role* values; emails are stored lower-cased.
+//
+// TOTPSecret is the active second factor once TOTPEnabled is set;
+// TOTPPendingSecret holds an enrolment awaiting its first code.
+// TOTPLastCounter is the last time step accepted, so a code is never
+// accepted twice.
+//
+// MFAFailures counts wrong second factors, the last at MFALastFailureAt;
+// see RecordAdminMFAFailure. The admin is locked out of second-factor
+// checks until MFALockedUntil.
+type Admin struct {
+	ID                string
+	Email             string
+	PasswordHash      string
+	Role              string
+	Disabled          bool
+	CreatedAt         time.Time
+	LastLoginAt       *time.Time
+	TOTPEnabled       bool
+	TOTPSecret        string
+	TOTPPendingSecret string
+	TOTPLastCounter   int64
+	MFAFailures       int
+	MFALastFailureAt  time.Time
+	MFALockedUntil    time.Time
+}
+
+// MFALocked reports whether the admin is locked out of second-factor
+// checks at now.
+func (a *Admin) MFALocked(now time.Time) bool {
+	return now.Before(a.MFALockedUntil)
+}
+
+// recordAdminMFAFailure counts a wrong second factor on a. Failures more
+// than lockout apart start the count again; the maxFailures-th locks the
+// admin out for lockout. Both stores call it so they count identically.
+func recordAdminMFAFailure(a *Admin, now time.Time, maxFailures int, lockout time.Duration) {
+	if now.Sub(a.MFALastFailureAt) > lockout {
+		a.MFAFailures = 0
+	}
+	a.MFAFailures++
+	a.MFALastFailureAt = now
+	if a.MFAFailures >= maxFailures {
+		a.MFAFailures = 0
+		a.MFALockedUntil = now.Add(lockout)
+	}
+}
+
+// CreateAdminAccount validates and hashes the credentials and stores a new
+// admin in st. It backs the admin CLI, the env bootstrap and the admin API.
+func CreateAdminAccount(ctx context.Context, st Store, email, password, role string) (*Admin, error) {
+	email = strings.ToLower(strings.TrimSpace(email))
+	if email == "" || !strings.Contains(email, "@") {
+		return nil, fmt.Errorf("%w: invalid email", ErrInvalidAdminAccount)
+	}
+	if !security.IsAdminRole(role) {
+		return nil, fmt.Errorf("%w: role must be support, moderator or superadmin", ErrInvalidAdminAccount)
+	}
+	hash, err := security.HashPassword(password)
+	if err != nil {
+		return nil, err
+	}
+
+	admin := &Admin{Email: email, PasswordHash: hash, Role: role}
+	if err := st.CreateAdmin(ctx, admin); err != nil {
+		return nil, err
+	}
+	return admin, nil
+}
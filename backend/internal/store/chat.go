@@ -0,0 +1,80 @@
+package store
+
+import (
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+var (
+	// ErrInvalidCursor is returned when a pagination cursor cannot be decoded.
+	ErrInvalidCursor        = errors.New("invalid cursor")
+	ErrChatMessageNotFound  = fmt.Errorf("chat message %w", ErrNotFound)
+	ErrDuplicateChatMessage = errors.New("duplicate chat message")
+)
+
+// Chat message kinds. Ciphertext messages carry an opaque E2E payload in
+// Text that the server never interprets.
+const (
+	ChatKindText       = "text"
+	ChatKindCiphertext = "ciphertext"
+)
+
+// ChatMessage is one persisted message between two trusted anon ids.
+// RoomID is the sorted "a:b" pair, see ws.RoomID.
+type ChatMessage struct {
+	ID          string
+	RoomID      string
+	FromAnon    string
+	ToAnon      string
+	Kind        string
+	Text        string
+	ClientID    string // sender-chosen id, unique per FromAnon when set
+	CreatedAt   time.Time
+	DeliveredAt *time.Time
+	ReadAt      *time.Time
+}
+
+// Cursor is a keyset position over (created_at, id), newest first.
+type Cursor struct {
+	CreatedAt time.Time
+	ID        string
+}
+
+// Encode returns the opaque form handed to clients.
+func (c Cursor) Encode() string {
+	raw := strconv.FormatInt(c.CreatedAt.UnixNano(), 10) + "|" + c.ID
+	return base64.RawURLEncoding.EncodeToString([]byte(raw))
+}
+
+// ParseCursor decodes a cursor produced by Cursor.Encode.
+func ParseCursor(s string) (*Cursor, error) {
+	raw, err := base64.RawURLEncoding.DecodeString(s)
+	if err != nil {
+		return nil, ErrInvalidCursor
+	}
+	parts := strings.SplitN(string(raw), "|", 2)
+	if len(parts) != 2 || parts[1] == "" {
+		return nil, ErrInvalidCursor
+	}
+	nanos, err := strconv.ParseInt(parts[0], 10, 64)
+	if err != nil {
+		return nil, ErrInvalidCursor
+	}
+	return &Cursor{CreatedAt: time.Unix(0, nanos).UTC(), ID: parts[1]}, nil
+}
+
+// before reports whether (at, id) sorts strictly after the cursor position
+// in newest-first order, i.e. it belongs on the next page.
+func (c *Cursor) before(at time.Time, id string) bool {
+	if c == nil {
+		return true
+	}
+	if at.Equal(c.CreatedAt) {
+		return id < c.ID
+	}
+	return at.Before(c.CreatedAt)
+}
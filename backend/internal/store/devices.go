@@ -2,9 +2,19 @@ package store
 
 import "time"
 
+// Device auth methods. HMAC devices prove possession of DeviceSecretHash,
+// which the server also holds; Ed25519 devices sign with a private key the
+// server never sees and only PublicKey is stored.
+const (
+	DeviceAuthHMAC    = "hmac"
+	DeviceAuthEd25519 = "ed25519"
+)
+
 type Device struct {
 	DevicePublicID   string
-	DeviceSecretHash string
+	DeviceSecretHash string // empty for Ed25519 devices
+	AuthMethod       string
+	PublicKey        string // base64 Ed25519 public key, Ed25519 devices only
 	AnonID           string
 	Username         string
 	CreatedAt        time.Time
@@ -0,0 +1,21 @@
+package store
+
+import (
+	"errors"
+	"fmt"
+)
+
+var (
+	// ErrNotFound is matched by every "does not exist" error a Store
+	// returns, including the more specific ones below.
+	ErrNotFound = errors.New("not found")
+	// ErrForbidden means the record exists but the caller may not change it.
+	ErrForbidden = errors.New("forbidden")
+
+	ErrLinkCardNotFound     = fmt.Errorf("link card %w", ErrNotFound)
+	ErrTrustRequestNotFound = fmt.Errorf("trust request %w", ErrNotFound)
+	ErrPostNotFound         = fmt.Errorf("post %w", ErrNotFound)
+	ErrCommentNotFound      = fmt.Errorf("comment %w", ErrNotFound)
+	ErrReplyNotFound        = fmt.Errorf("reply %w", ErrNotFound)
+	ErrDeviceNotFound       = fmt.Errorf("device %w", ErrNotFound)
+)
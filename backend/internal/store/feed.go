@@ -0,0 +1,49 @@
+package store
+
+import (
+	"errors"
+	"time"
+)
+
+// ErrInvalidFeedScope is returned for a FeedQuery.Scope that is not one of
+// the FeedScope constants.
+var ErrInvalidFeedScope = errors.New("invalid feed scope")
+
+// Feed scopes select whose posts a feed page contains.
+const (
+	FeedScopeAll       = "all"       // every author
+	FeedScopeTrusted   = "trusted"   // authors with an accepted trust to Viewer
+	FeedScopeNearby    = "nearby"    // authors whose last recent ping is in radius
+	FeedScopeFollowing = "following" // posts carrying a tag Viewer follows
+)
+
+// FeedQuery describes one page of the feed, newest first. Pass the position
+// of the last post of a page as Before to fetch the next one.
+type FeedQuery struct {
+	Scope    string // FeedScope*; empty means FeedScopeAll
+	Viewer   string // anon id the trusted and following scopes are relative to
+	Tag      string // when set, only posts carrying this tag
+	Lat      float64
+	Lng      float64
+	RadiusKm float64 // nearby scope only
+	Before   *Cursor
+	Limit    int
+}
+
+// geoPingMaxAge bounds how old a ping may be to place its anon somewhere.
+const geoPingMaxAge = 10 * time.Minute
+
+// normalize fills in defaults and rejects unknown scopes.
+func (q *FeedQuery) normalize() error {
+	switch q.Scope {
+	case "":
+		q.Scope = FeedScopeAll
+	case FeedScopeAll, FeedScopeTrusted, FeedScopeNearby, FeedScopeFollowing:
+	default:
+		return ErrInvalidFeedScope
+	}
+	if q.Limit <= 0 {
+		q.Limit = 50
+	}
+	return nil
+}
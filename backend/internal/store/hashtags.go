@@ -0,0 +1,54 @@
+package store
+
+import (
+	"errors"
+	"regexp"
+	"strings"
+	"unicode"
+)
+
+// ErrInvalidTag is returned for a tag that ExtractHashtags could never
+// produce.
+var ErrInvalidTag = errors.New("invalid tag")
+
+// maxTagLen bounds tags accepted from clients, in runes.
+const maxTagLen = 64
+
+// hashtagPattern mirrors the extract_hashtags SQL function of migration
+// 008, where \w is a letter, a digit or an underscore.
+var hashtagPattern = regexp.MustCompile(`#([\p{L}\p{N}_]+)`)
+
+// TagCount is how many posts carried Tag in some window.
+type TagCount struct {
+	Tag   string
+	Posts int
+}
+
+// ExtractHashtags returns the distinct lowercased tags of text, in order
+// of first use. Postgres derives posts.hashtags the same way.
+func ExtractHashtags(text string) []string {
+	var tags []string
+	seen := make(map[string]bool)
+	for _, m := range hashtagPattern.FindAllStringSubmatch(text, -1) {
+		tag := strings.ToLower(m[1])
+		if !seen[tag] {
+			seen[tag] = true
+			tags = append(tags, tag)
+		}
+	}
+	return tags
+}
+
+// NormalizeTag turns user input such as "#Travel" into the stored form.
+func NormalizeTag(s string) (string, error) {
+	tag := strings.ToLower(strings.TrimPrefix(strings.TrimSpace(s), "#"))
+	if tag == "" || len([]rune(tag)) > maxTagLen {
+		return "", ErrInvalidTag
+	}
+	for _, r := range tag {
+		if !unicode.IsLetter(r) && !unicode.IsNumber(r) && r != '_' {
+			return "", ErrInvalidTag
+		}
+	}
+	return tag, nil
+}
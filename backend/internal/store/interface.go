@@ -1,108 +1,185 @@
 package store
 
-import "time"
+import (
+	"context"
+	"time"
 
-// Store is the interface all storage backends must implement.
+	"anon-backend/internal/config"
+)
+
+// Store is the interface all storage backends must implement. Every method
+// takes the caller's context, so a cancelled request or a deadline stops the
+// underlying query, and reports failures as errors. Lookups of a single
+// record return an error matching ErrNotFound when it does not exist;
+// changes to another anon's content return one matching ErrForbidden.
 type Store interface {
 	// Link Cards
-	PutCard(c *LinkCard) error
-	GetCard(code string) (*LinkCard, bool)
-	CardsByOwner(owner string) []*LinkCard
+	PutCard(ctx context.Context, c *LinkCard) error
+	GetCard(ctx context.Context, code string) (*LinkCard, error)
+	CardsByOwner(ctx context.Context, owner string) ([]*LinkCard, error)
 
 	// Trust Requests
-	PutTrust(t *TrustRequest) error
-	GetTrust(id string) (*TrustRequest, bool)
-	TrustForAnon(anon string) []*TrustRequest
-	TrustAccepted(a, b string) bool
+	PutTrust(ctx context.Context, t *TrustRequest) error
+	GetTrust(ctx context.Context, id string) (*TrustRequest, error)
+	TrustForAnon(ctx context.Context, anon string) ([]*TrustRequest, error)
+	TrustAccepted(ctx context.Context, a, b string) (bool, error)
 
 	// Posts
-	PutPost(p *Post)
-	GetFeed(limit int) []*Post
-	GetTrendingPosts(limit int, offset int) ([]PostWithStats, error)
-	CanCreatePost(anonID string) bool
-	GetRemainingPosts(anonID string) int
-	DeletePostByUser(postID, anonID string) error
-	ReactToPost(postID, anonID, reactionType string) error
-	GetPostReaction(postID, anonID string) (string, bool)
-	GetPost(postID string) (*Post, bool)
-	GetPostsByAnonID(anonID string, limit int) []*Post
-	SearchPosts(query string, hashtags []string, limit int, offset int) ([]*PostSearchResult, int, error)
+	PutPost(ctx context.Context, p *Post) error
+	CreatePostWithQuota(ctx context.Context, p *Post, limits []config.QuotaLimit, now time.Time) (QuotaResult, error)
+	GetFeed(ctx context.Context, limit int) ([]*Post, error)
+	GetFeedPage(ctx context.Context, q FeedQuery) ([]*Post, error)
+	GetTrendingPosts(ctx context.Context, q TrendingQuery) ([]PostWithStats, error)
+	GetPostSignals(ctx context.Context, since time.Time) ([]PostSignals, error)
+	ReplacePostScores(ctx context.Context, scores []PostScore) error
+	DeletePostByUser(ctx context.Context, postID, anonID string) error
+	ReactToPost(ctx context.Context, postID, anonID, reactionType string) error
+	GetPostReaction(ctx context.Context, postID, anonID string) (string, error)
+	GetPost(ctx context.Context, postID string) (*Post, error)
+	GetPostsByAnonID(ctx context.Context, anonID string, limit int) ([]*Post, error)
+	SearchPosts(ctx context.Context, q SearchQuery) ([]*PostSearchResult, int, error)
+
+	// Hashtags; tag pages and the following feed go through GetFeedPage
+	GetTrendingTags(ctx context.Context, since time.Time, limit int) ([]TagCount, error)
+	FollowTag(ctx context.Context, anonID, tag string, now time.Time) error
+	UnfollowTag(ctx context.Context, anonID, tag string) error
+	GetFollowedTags(ctx context.Context, anonID string) ([]string, error)
 
 	// Comments
-	AddComment(comment *PostComment) error
-	GetComments(postID string) []*PostComment
-	GetComment(commentID string) (*PostComment, bool)
-	DeleteCommentByUser(commentID, anonID string) error
-	GetCommentsCount(postID string) int
-	ReactToComment(commentID, anonID, reactionType string) error
-	GetCommentReaction(commentID, anonID string) (string, bool)
-	AddCommentReply(reply *CommentReply) error
-	GetCommentReplies(commentID string) []*CommentReply
-	GetReply(replyID string) (*CommentReply, bool)
-	DeleteCommentReplyByUser(replyID, anonID string) error
-	GetCommentRepliesCount(commentID string) int
-	ReactToReply(replyID, anonID, reactionType string) error
-	GetReplyReaction(replyID, anonID string) (string, bool)
+	AddComment(ctx context.Context, comment *PostComment) error
+	AddCommentWithQuota(ctx context.Context, comment *PostComment, limits []config.QuotaLimit, now time.Time) (QuotaResult, error)
+	GetComments(ctx context.Context, postID string) ([]*PostComment, error)
+	GetComment(ctx context.Context, commentID string) (*PostComment, error)
+	DeleteCommentByUser(ctx context.Context, commentID, anonID string) error
+	GetCommentsCount(ctx context.Context, postID string) (int, error)
+	ReactToComment(ctx context.Context, commentID, anonID, reactionType string) error
+	GetCommentReaction(ctx context.Context, commentID, anonID string) (string, error)
+	AddCommentReply(ctx context.Context, reply *CommentReply) error
+	AddCommentReplyWithQuota(ctx context.Context, reply *CommentReply, limits []config.QuotaLimit, now time.Time) (QuotaResult, error)
+	GetCommentReplies(ctx context.Context, commentID string) ([]*CommentReply, error)
+	GetReply(ctx context.Context, replyID string) (*CommentReply, error)
+	DeleteCommentReplyByUser(ctx context.Context, replyID, anonID string) error
+	GetCommentRepliesCount(ctx context.Context, commentID string) (int, error)
+	ReactToReply(ctx context.Context, replyID, anonID, reactionType string) error
+	GetReplyReaction(ctx context.Context, replyID, anonID string) (string, error)
+
+	// Batch lookups for rendering lists; ids without a row are absent from the map
+	GetPostReactionsFor(ctx context.Context, anonID string, postIDs []string) (map[string]string, error)
+	GetCommentReactionsFor(ctx context.Context, anonID string, commentIDs []string) (map[string]string, error)
+	GetReplyReactionsFor(ctx context.Context, anonID string, replyIDs []string) (map[string]string, error)
+	GetReplyCounts(ctx context.Context, commentIDs []string) (map[string]int, error)
+	GetUsernames(ctx context.Context, anonIDs []string) (map[string]string, error)
+
+	// Chat
+	PutChatMessage(ctx context.Context, msg *ChatMessage) error
+	GetChatMessageByClientID(ctx context.Context, fromAnon, clientID string) (*ChatMessage, error)
+	GetChatHistory(ctx context.Context, roomID string, before *Cursor, limit int) ([]*ChatMessage, error)
+	GetUndeliveredChatMessages(ctx context.Context, roomID, toAnon string) ([]*ChatMessage, error)
+	MarkChatMessagesDelivered(ctx context.Context, ids []string, at time.Time) ([]string, error)
+	MarkChatMessagesRead(ctx context.Context, roomID, readerAnon string, ids []string, at time.Time) ([]string, error)
+	RedeemWSTicket(ctx context.Context, ticketID string, expiresAt, now time.Time) (bool, error)
+	CleanupWSTickets(ctx context.Context, now time.Time) (int, error)
+
+	// E2E key directory
+	PutDeviceKeys(ctx context.Context, bundle *DeviceKeyBundle, oneTime []OneTimePrekey) error
+	ClaimDeviceKeys(ctx context.Context, anonID string) ([]*DeviceKeyBundle, error)
+	CountOneTimePrekeys(ctx context.Context, devicePublicID string) (int, error)
 
 	// Geo Pings
-	PutGeo(ping *GeoPing)
-	GetNearby(lat, lng float64, radiusKm float64) []*GeoPing
+	PutGeo(ctx context.Context, ping *GeoPing) error
+	GetNearby(ctx context.Context, lat, lng float64, radiusKm float64) ([]*GeoPing, error)
 
 	// Admin methods
-	GetAllUsers() []*UserInfo
-	GetAllSessions() []*SessionInfo
-	GetAllTrustRequests() []*TrustRequest
-	GetAuditLogs() []AuditLog
-	DeletePost(postID string) error
-	LogAuditEvent(event AuditLog)
-	DeleteAuditLog(id string) error
-	DeleteAuditLogs(ids []string) error
-	ClearAuditLogs() error
-	PutSession(session SessionInfo) error
+	GetAllUsers(ctx context.Context) ([]*UserInfo, error)
+	GetAllSessions(ctx context.Context) ([]*SessionInfo, error)
+	GetAllTrustRequests(ctx context.Context) ([]*TrustRequest, error)
+	GetAuditLogs(ctx context.Context) ([]AuditLog, error)
+	DeletePost(ctx context.Context, postID string) error
+	LogAuditEvent(ctx context.Context, event AuditLog) error
+	ListAuditLogsAfter(ctx context.Context, afterSeq int64, limit int) ([]AuditLog, error)
+	PruneAuditLogs(ctx context.Context, before time.Time) (int, error)
+	PutSession(ctx context.Context, session SessionInfo) error
 
 	// Device auth
-	GetDevice(devicePublicID string) (*Device, error)
-	GetDeviceByAnonID(anonID string) (*Device, error)
-	CreateDevice(device *Device) error
-	UpdateDeviceTimestamp(devicePublicID string, updatedAt time.Time) error
-	CreateDeviceNonce(devicePublicID, nonce string, expiresAt time.Time) error
-	ConsumeDeviceNonce(devicePublicID, nonce string, now time.Time) (bool, error)
+	GetDevice(ctx context.Context, devicePublicID string) (*Device, error)
+	GetDeviceByAnonID(ctx context.Context, anonID string) (*Device, error)
+	GetDevicesByAnonID(ctx context.Context, anonID string) ([]*Device, error)
+	CreateDevice(ctx context.Context, device *Device) error
+	UpdateDeviceTimestamp(ctx context.Context, devicePublicID string, updatedAt time.Time) error
+	CreateDeviceNonce(ctx context.Context, devicePublicID, nonce string, expiresAt time.Time) error
+	ConsumeDeviceNonce(ctx context.Context, devicePublicID, nonce string, now time.Time) (bool, error)
 
 	// Session management
-	UpdateSessionActivity(token string) error
-	CleanupExpiredSessions() (int, error)
-	GetSessionByToken(token string) (*SessionInfo, error)
-	GetSessionsByAnonID(anonID string) ([]*SessionInfo, error)
-	RevokeSession(token string) error
-	RevokeAllSessionsForUser(anonID string) (int, error)
-	EnforceSessionLimit(anonID string, maxSessions int) error
+	UpdateSessionActivity(ctx context.Context, token string) error
+	CleanupExpiredSessions(ctx context.Context) (int, error)
+	GetSessionByToken(ctx context.Context, token string) (*SessionInfo, error)
+	GetSessionsByAnonID(ctx context.Context, anonID string) ([]*SessionInfo, error)
+	RevokeSession(ctx context.Context, token string) error
+	RevokeAllSessionsForUser(ctx context.Context, anonID string) (int, error)
+	EnforceSessionLimit(ctx context.Context, anonID string, maxSessions int) error
+	GetSessionByID(ctx context.Context, sessionID string) (*SessionInfo, error)
+	RotateRefreshToken(ctx context.Context, sessionID, presentedHash string, next SessionRotation) (*SessionInfo, error)
+
+	// Account recovery and device linking
+	PutRecoveryKey(ctx context.Context, anonID, keyHash string, now time.Time) error
+	RedeemRecoveryKey(ctx context.Context, keyHash string, device *Device, now time.Time) error
+	CreateDeviceLink(ctx context.Context, link *DeviceLink) error
+	GetDeviceLink(ctx context.Context, code string) (*DeviceLink, error)
+	ApproveDeviceLink(ctx context.Context, code, anonID string, now time.Time) (*DeviceLink, error)
+	ConsumeDeviceLink(ctx context.Context, code string, device *Device, now time.Time) error
+
+	// Rate limiting
+	TakeRateLimitToken(ctx context.Context, policy, key string, capacity int, window time.Duration, now time.Time) (RateLimitResult, error)
+	ListRateLimitedBuckets(ctx context.Context, since time.Time, limit int) ([]RateLimitBucket, error)
+	CleanupRateLimitBuckets(ctx context.Context, idleSince time.Time) (int, error)
+
+	// Posting quotas; resource is "posts", "comments" or "replies"
+	TakeQuota(ctx context.Context, anonID, resource string, limits []config.QuotaLimit, now time.Time) (QuotaResult, error)
+	GetQuotaUsage(ctx context.Context, anonID, resource string, limits []config.QuotaLimit, now time.Time) ([]QuotaUsage, error)
+	CleanupQuotaUsage(ctx context.Context, now time.Time) (int, error)
+
+	// Admin accounts
+	CreateAdmin(ctx context.Context, admin *Admin) error
+	GetAdminByEmail(ctx context.Context, email string) (*Admin, error)
+	ListAdmins(ctx context.Context) ([]*Admin, error)
+	CountAdmins(ctx context.Context) (int, error)
+	UpdateAdminLastLogin(ctx context.Context, adminID string, at time.Time) error
+	SetAdminDisabled(ctx context.Context, adminID string, disabled bool) error
+	SetAdminRole(ctx context.Context, adminID, role string) error
+	GetAdminByID(ctx context.Context, adminID string) (*Admin, error)
+	SetAdminTOTPPending(ctx context.Context, adminID, secret string) error
+	EnableAdminTOTP(ctx context.Context, adminID string, counter int64, recoveryCodeHashes []string) error
+	UseAdminTOTPCounter(ctx context.Context, adminID string, counter int64) (bool, error)
+	UseAdminRecoveryCode(ctx context.Context, adminID, codeHash string, at time.Time) (bool, error)
+	RecordAdminMFAFailure(ctx context.Context, adminID string, now time.Time, maxFailures int, lockout time.Duration) (locked bool, err error)
+	ClearAdminMFAFailures(ctx context.Context, adminID string) error
 
 	// User tracking and activity
-	EnsureUser(anonID string, now time.Time) error
-	MarkUserActive(anonID string, now time.Time) error
-	MarkUserInactive(anonID string) error
-	UpdateUserLastSeen(anonID string, now time.Time) error
-	GetActiveUsersCount() (int, error)
-	ReconcileUserActiveStatus(anonID string) error
-	GetTotalUsersCount() (int, error)
-	CreateUserBan(anonID, reason string, bannedBy string, now time.Time, expiresAt *time.Time, permanent bool) error
-	GetActiveUserBan(anonID string, now time.Time) (*UserBan, error)
+	EnsureUser(ctx context.Context, anonID string, now time.Time) error
+	MarkUserActive(ctx context.Context, anonID string, now time.Time) error
+	MarkUserInactive(ctx context.Context, anonID string) error
+	UpdateUserLastSeen(ctx context.Context, anonID string, now time.Time) error
+	GetActiveUsersCount(ctx context.Context) (int, error)
+	ReconcileUserActiveStatus(ctx context.Context, anonID string) error
+	GetTotalUsersCount(ctx context.Context) (int, error)
+	CreateUserBan(ctx context.Context, anonID, reason string, bannedBy string, now time.Time, expiresAt *time.Time, permanent bool) error
+	GetActiveUserBan(ctx context.Context, anonID string, now time.Time) (*UserBan, error)
 
 	// Post Reports
-	ReportPost(postID, reportedAnonID, reporterAnonID, reason string, now time.Time) error
-	GetPostReportCount(postID string) int
-	GetTopReportedPostByAnon(anonID string, threshold int) *PostReport
+	ReportPost(ctx context.Context, postID, reportedAnonID, reporterAnonID, reason string, now time.Time) error
+	GetPostReportCount(ctx context.Context, postID string) (int, error)
+	GetTopReportedPostByAnon(ctx context.Context, anonID string, threshold int) (*PostReport, error)
 
 	// Profiles
-	EnsureProfileForAnon(anonID, region string, now time.Time) error
-	GetProfileByAnonID(anonID string) (*UserProfile, error)
-	UpdateProfile(anonID string, in ProfileUpdateInput, now time.Time) (*UserProfile, error)
-	IsUsernameAvailable(username string, excludeAnonID string) (bool, error)
-	IncrementProfileView(targetAnonID, viewerAnonID string) error
-	GetProfileDeviceInfo(anonID string) (*ProfileDeviceInfo, error)
-	ReportProfile(reporterAnonID, targetUserAnonID, reason string, now time.Time) error
-	ReportPostV2(reporterAnonID, targetUserAnonID, targetPostID, reason string, now time.Time) error
-	GetUserReportCount(targetAnonID string) (int, error)
+	EnsureProfileForAnon(ctx context.Context, anonID, region string, now time.Time) error
+	GetProfileByAnonID(ctx context.Context, anonID string) (*UserProfile, error)
+	UpdateProfile(ctx context.Context, anonID string, in ProfileUpdateInput, now time.Time) (*UserProfile, error)
+	IsUsernameAvailable(ctx context.Context, username string, excludeAnonID string) (bool, error)
+	IncrementProfileView(ctx context.Context, targetAnonID, viewerAnonID string) error
+	GetProfileDeviceInfo(ctx context.Context, anonID string) (*ProfileDeviceInfo, error)
+	ReportProfile(ctx context.Context, reporterAnonID, targetUserAnonID, reason string, now time.Time) error
+	ReportPostV2(ctx context.Context, reporterAnonID, targetUserAnonID, targetPostID, reason string, now time.Time) error
+	GetUserReportCount(ctx context.Context, targetAnonID string) (int, error)
 }
 
 // Admin types
@@ -121,6 +198,21 @@ type SessionInfo struct {
 	ExpiresAt      time.Time
 	CreatedAt      time.Time
 	LastActivityAt time.Time
+
+	// The session id doubles as the refresh token family; rotation keeps
+	// the row and swaps Token and RefreshTokenHash.
+	RefreshTokenHash string
+	RefreshExpiresAt time.Time
+	// The token the last rotation replaced, when, and the seed that derived
+	// the current one from it; see RefreshGrace.
+	PrevRefreshTokenHash string
+	RefreshRotatedAt     time.Time
+	RefreshSeed          string
+
+	// Client metadata, captured at bootstrap and refresh.
+	DevicePublicID string
+	Region         string
+	UserAgent      string
 }
 
 type UserBan struct {
@@ -132,14 +224,6 @@ type UserBan struct {
 	Permanent bool
 }
 
-type AuditLog struct {
-	ID        string    `json:"id"`
-	Action    string    `json:"action"`
-	AnonID    string    `json:"anon_id"`
-	Details   string    `json:"details"`
-	Timestamp time.Time `json:"timestamp"`
-}
-
 // PostReport represents a report on a post
 type PostReport struct {
 	PostID         string
@@ -183,17 +267,20 @@ type ProfileDeviceInfo struct {
 type PostSearchResult struct {
 	Post           *Post
 	RelevanceScore float64
-	MatchedTerms   []string
-	Highlights     string
+	MatchedTerms   []string // highlighted words, then the query's hashtags
+	Highlights     string   // Post.Text, HTML-escaped, with matched words in <mark></mark>
 }
 
 // PostWithStats represents a feed post with aggregated engagement metrics.
+// CommentCount and the scores are as of the last ranking run.
 type PostWithStats struct {
 	Post
 	LikeCount    int
 	DislikeCount int
 	CommentCount int
 	HotScore     float64
+	RisingScore  float64
+	TopScore     float64
 }
 
 var defaultStore Store
@@ -0,0 +1,30 @@
+package store
+
+import (
+	"fmt"
+	"time"
+)
+
+var ErrDeviceKeysNotFound = fmt.Errorf("device keys %w", ErrNotFound)
+
+// DeviceKeyBundle is the public half of a device's E2E chat keys. The server
+// only stores and hands these out; it never sees private keys or plaintext.
+type DeviceKeyBundle struct {
+	DevicePublicID        string
+	AnonID                string
+	IdentityKey           string // base64 public identity key
+	SignedPrekeyID        int
+	SignedPrekey          string // base64
+	SignedPrekeySignature string // base64, by IdentityKey
+	CreatedAt             time.Time
+	UpdatedAt             time.Time
+
+	// OneTimePrekey is filled by ClaimDeviceKeys when one was available.
+	OneTimePrekey *OneTimePrekey
+}
+
+// OneTimePrekey is handed out at most once.
+type OneTimePrekey struct {
+	KeyID     int
+	PublicKey string // base64
+}
@@ -1,6 +1,7 @@
 package store
 
 import (
+	"context"
 	"fmt"
 	"math"
 	"sort"
@@ -106,6 +107,7 @@ type GeoPing struct {
 }
 
 type postReportMeta struct {
+	Target    string // reported anon, set for user post reports
 	Reason    string
 	CreatedAt time.Time
 }
@@ -116,7 +118,7 @@ type MemStore struct {
 	trust                  map[string]*TrustRequest               // id -> trust req
 	posts                  []*Post                                // all posts, newest first
 	pings                  map[string]*GeoPing                    // anon -> last ping
-	postDays               map[string]map[string]int              // anon -> date (YYYY-MM-DD) -> count
+	quotaCounts            map[string]memQuotaCount               // anon|resource|window|start -> count
 	auditLogs              []AuditLog                             // all audit logs
 	sessions               map[string]*SessionInfo                // token -> session
 	postReactions          map[string]map[string]*PostReaction    // postID -> anonID -> reaction
@@ -128,8 +130,22 @@ type MemStore struct {
 	deviceNonces           map[string]map[string]*DeviceNonce     // device_public_id -> nonce -> device nonce
 	users                  map[string]*User                       // anon_id -> user
 	postReports            map[string]map[string]postReportMeta   // postID -> reporterAnonID -> report metadata
+	postScores             map[string]PostScore                   // postID -> last ranking
+	tagIndex               map[string][]*Post                     // tag -> posts carrying it, newest first
+	tagFollows             map[string]map[string]time.Time        // anonID -> tag -> followed at
+	search                 *searchIndex                           // lexeme -> post -> positions, like text_search
 	profileReportsByTarget map[string]map[string]postReportMeta   // target anon -> reporter anon -> report metadata
+	userPostReports        map[string]map[string]postReportMeta   // postID -> reporter anon -> user-facing report
 	userBans               map[string]*UserBan                    // anonID -> active/latest ban
+	chatMessages           map[string][]*ChatMessage              // roomID -> messages, oldest first
+	deviceKeys             map[string]*DeviceKeyBundle            // device_public_id -> published keys
+	oneTimePrekeys         map[string][]OneTimePrekey             // device_public_id -> unclaimed one-time prekeys
+	recoveryKeys           map[string]string                      // anon_id -> recovery key hash
+	deviceLinks            map[string]*DeviceLink                 // code -> device link
+	admins                 map[string]*Admin                      // id -> admin account
+	adminRecoveryCodes     map[string]map[string]bool             // admin id -> code hash -> used
+	rateLimits             map[string]*RateLimitBucket            // policy + "|" + key -> bucket
+	wsTickets              map[string]time.Time                   // redeemed websocket ticket id -> expiry
 }
 
 type User struct {
@@ -160,7 +176,7 @@ func NewMemStore() *MemStore {
 		trust:                  make(map[string]*TrustRequest),
 		posts:                  make([]*Post, 0),
 		pings:                  make(map[string]*GeoPing),
-		postDays:               make(map[string]map[string]int),
+		quotaCounts:            make(map[string]memQuotaCount),
 		auditLogs:              make([]AuditLog, 0),
 		sessions:               make(map[string]*SessionInfo),
 		postReactions:          make(map[string]map[string]*PostReaction),
@@ -172,26 +188,43 @@ func NewMemStore() *MemStore {
 		deviceNonces:           make(map[string]map[string]*DeviceNonce),
 		users:                  make(map[string]*User),
 		postReports:            make(map[string]map[string]postReportMeta),
+		postScores:             make(map[string]PostScore),
+		tagIndex:               make(map[string][]*Post),
+		tagFollows:             make(map[string]map[string]time.Time),
+		search:                 newSearchIndex(),
 		profileReportsByTarget: make(map[string]map[string]postReportMeta),
+		userPostReports:        make(map[string]map[string]postReportMeta),
 		userBans:               make(map[string]*UserBan),
+		chatMessages:           make(map[string][]*ChatMessage),
+		deviceKeys:             make(map[string]*DeviceKeyBundle),
+		oneTimePrekeys:         make(map[string][]OneTimePrekey),
+		recoveryKeys:           make(map[string]string),
+		deviceLinks:            make(map[string]*DeviceLink),
+		admins:                 make(map[string]*Admin),
+		adminRecoveryCodes:     make(map[string]map[string]bool),
+		rateLimits:             make(map[string]*RateLimitBucket),
+		wsTickets:              make(map[string]time.Time),
 	}
 }
 
-func (s *MemStore) PutCard(c *LinkCard) error {
+func (s *MemStore) PutCard(ctx context.Context, c *LinkCard) error {
 	s.mu.Lock()
 	defer s.mu.Unlock()
 	s.cards[c.Code] = c
 	return nil
 }
 
-func (s *MemStore) GetCard(code string) (*LinkCard, bool) {
+func (s *MemStore) GetCard(ctx context.Context, code string) (*LinkCard, error) {
 	s.mu.RLock()
 	defer s.mu.RUnlock()
 	c, ok := s.cards[code]
-	return c, ok
+	if !ok {
+		return nil, ErrLinkCardNotFound
+	}
+	return c, nil
 }
 
-func (s *MemStore) CardsByOwner(owner string) []*LinkCard {
+func (s *MemStore) CardsByOwner(ctx context.Context, owner string) ([]*LinkCard, error) {
 	s.mu.RLock()
 	defer s.mu.RUnlock()
 	out := []*LinkCard{}
@@ -200,24 +233,28 @@ func (s *MemStore) CardsByOwner(owner string) []*LinkCard {
 			out = append(out, c)
 		}
 	}
-	return out
+	sort.Slice(out, func(i, j int) bool { return out[i].CreatedAt.After(out[j].CreatedAt) })
+	return out, nil
 }
 
-func (s *MemStore) PutTrust(t *TrustRequest) error {
+func (s *MemStore) PutTrust(ctx context.Context, t *TrustRequest) error {
 	s.mu.Lock()
 	defer s.mu.Unlock()
 	s.trust[t.ID] = t
 	return nil
 }
 
-func (s *MemStore) GetTrust(id string) (*TrustRequest, bool) {
+func (s *MemStore) GetTrust(ctx context.Context, id string) (*TrustRequest, error) {
 	s.mu.RLock()
 	defer s.mu.RUnlock()
 	t, ok := s.trust[id]
-	return t, ok
+	if !ok {
+		return nil, ErrTrustRequestNotFound
+	}
+	return t, nil
 }
 
-func (s *MemStore) TrustForAnon(anon string) []*TrustRequest {
+func (s *MemStore) TrustForAnon(ctx context.Context, anon string) ([]*TrustRequest, error) {
 	s.mu.RLock()
 	defer s.mu.RUnlock()
 	out := []*TrustRequest{}
@@ -226,24 +263,57 @@ func (s *MemStore) TrustForAnon(anon string) []*TrustRequest {
 			out = append(out, t)
 		}
 	}
-	return out
+	sortTrustRequests(out)
+	return out, nil
 }
 
 // Compile-time check that MemStore implements Store interface
 var _ Store = (*MemStore)(nil)
 
 // PutPost adds a post and maintains newest-first order.
-func (s *MemStore) PutPost(p *Post) {
+func (s *MemStore) PutPost(ctx context.Context, p *Post) error {
 	s.mu.Lock()
 	defer s.mu.Unlock()
-	s.posts = append([]*Post{p}, s.posts...)
+	s.insertPostLocked(p)
+	return nil
+}
+
+// insertPostLocked adds p to s.posts, to the tag index of each of its
+// hashtags and to the search index.
+func (s *MemStore) insertPostLocked(p *Post) {
+	s.posts = insertPostSorted(s.posts, p)
+	for _, tag := range ExtractHashtags(p.Text) {
+		s.tagIndex[tag] = insertPostSorted(s.tagIndex[tag], p)
+	}
+	s.search.add(p)
+}
+
+// insertPostSorted inserts p into posts, which is ordered by (CreatedAt,
+// ID) newest first, so a post backdated on insert lands where Postgres
+// would sort it.
+func insertPostSorted(posts []*Post, p *Post) []*Post {
+	i := sort.Search(len(posts), func(i int) bool {
+		q := posts[i]
+		if q.CreatedAt.Equal(p.CreatedAt) {
+			return q.ID < p.ID
+		}
+		return q.CreatedAt.Before(p.CreatedAt)
+	})
+	posts = append(posts, nil)
+	copy(posts[i+1:], posts[i:])
+	posts[i] = p
+	return posts
 }
 
 // GetFeed returns up to limit posts, newest first, excluding deleted posts.
-func (s *MemStore) GetFeed(limit int) []*Post {
+func (s *MemStore) GetFeed(ctx context.Context, limit int) ([]*Post, error) {
 	s.mu.RLock()
 	defer s.mu.RUnlock()
 
+	if limit <= 0 {
+		limit = 50
+	}
+
 	out := make([]*Post, 0, limit)
 	for _, p := range s.posts {
 		if !p.Deleted {
@@ -253,146 +323,92 @@ func (s *MemStore) GetFeed(limit int) []*Post {
 			}
 		}
 	}
-	return out
+	return out, nil
 }
 
-func (s *MemStore) GetTrendingPosts(limit int, offset int) ([]PostWithStats, error) {
+// GetFeedPage returns the page of the feed described by q, newest first.
+func (s *MemStore) GetFeedPage(ctx context.Context, q FeedQuery) ([]*Post, error) {
+	if err := q.normalize(); err != nil {
+		return nil, err
+	}
+
 	s.mu.RLock()
 	defer s.mu.RUnlock()
 
-	if limit <= 0 {
-		limit = 20
-	}
-	if limit > 50 {
-		limit = 50
-	}
-	if offset < 0 {
-		offset = 0
-	}
-
-	out := make([]PostWithStats, 0, len(s.posts))
-	for _, p := range s.posts {
-		if p.Deleted {
-			continue
+	var authors map[string]bool
+	switch q.Scope {
+	case FeedScopeTrusted:
+		authors = make(map[string]bool)
+		for _, t := range s.trust {
+			if t.Status != TrustAccepted {
+				continue
+			}
+			if t.FromAnon == q.Viewer {
+				authors[t.ToAnon] = true
+			} else if t.ToAnon == q.Viewer {
+				authors[t.FromAnon] = true
+			}
 		}
-
-		commentCount := 0
-		for _, c := range s.postComments[p.ID] {
-			if !c.Deleted {
-				commentCount++
+	case FeedScopeNearby:
+		authors = make(map[string]bool)
+		cutoff := time.Now().Add(-geoPingMaxAge)
+		for anonID, ping := range s.pings {
+			if !ping.Timestamp.Before(cutoff) && haversineDistance(q.Lat, q.Lng, ping.Lat, ping.Lng) <= q.RadiusKm {
+				authors[anonID] = true
 			}
 		}
-
-		hotScore := computeHotScore(p.Likes, p.Dislikes, p.CreatedAt)
-
-		out = append(out, PostWithStats{
-			Post: Post{
-				ID:        p.ID,
-				AnonID:    p.AnonID,
-				Text:      p.Text,
-				CreatedAt: p.CreatedAt,
-				Likes:     p.Likes,
-				Dislikes:  p.Dislikes,
-				Deleted:   p.Deleted,
-			},
-			LikeCount:    p.Likes,
-			DislikeCount: p.Dislikes,
-			CommentCount: commentCount,
-			HotScore:     hotScore,
-		})
 	}
 
-	sort.Slice(out, func(i, j int) bool {
-		if out[i].HotScore == out[j].HotScore {
-			return out[i].CreatedAt.After(out[j].CreatedAt)
+	var tagged map[string]bool
+	if q.Scope == FeedScopeFollowing {
+		tagged = make(map[string]bool)
+		for tag := range s.tagFollows[q.Viewer] {
+			for _, p := range s.tagIndex[tag] {
+				tagged[p.ID] = true
+			}
 		}
-		return out[i].HotScore > out[j].HotScore
-	})
-
-	if offset >= len(out) {
-		return []PostWithStats{}, nil
-	}
-
-	end := offset + limit
-	if end > len(out) {
-		end = len(out)
-	}
-
-	return out[offset:end], nil
-}
-
-func computeHotScore(up, down int, createdAt time.Time) float64 {
-	s := up - down
-	absS := math.Abs(float64(s))
-	order := math.Log(math.Max(absS, 1))
-
-	sign := 0.0
-	if s > 0 {
-		sign = 1.0
-	} else if s < 0 {
-		sign = -1.0
-	}
-
-	seconds := float64(createdAt.Unix())
-	score := order + sign*(seconds/45000.0)
-
-	return math.Round(score*1e7) / 1e7
-}
-
-// CanCreatePost checks daily post limit (3 per day). Returns false if limit reached.
-// Increments counter if allowed.
-func (s *MemStore) CanCreatePost(anonID string) bool {
-	s.mu.Lock()
-	defer s.mu.Unlock()
-
-	dateKey := time.Now().Format("2006-01-02")
-
-	// Initialize anon's day map if needed
-	if _, exists := s.postDays[anonID]; !exists {
-		s.postDays[anonID] = make(map[string]int)
-	}
-
-	count := s.postDays[anonID][dateKey]
-	if count >= 3 {
-		return false
 	}
 
-	s.postDays[anonID][dateKey] = count + 1
-	return true
-}
-
-func (s *MemStore) GetRemainingPosts(anonID string) int {
-	s.mu.RLock()
-	defer s.mu.RUnlock()
-
-	dateKey := time.Now().Format("2006-01-02")
-
-	if _, exists := s.postDays[anonID]; !exists {
-		return 3
+	posts := s.posts
+	if q.Tag != "" {
+		posts = s.tagIndex[q.Tag]
 	}
 
-	count := s.postDays[anonID][dateKey]
-	remaining := 3 - count
-	if remaining < 0 {
-		return 0
+	out := make([]*Post, 0, q.Limit)
+	for _, p := range posts {
+		if p.Deleted || !q.Before.before(p.CreatedAt, p.ID) {
+			continue
+		}
+		if authors != nil && !authors[p.AnonID] {
+			continue
+		}
+		if tagged != nil && !tagged[p.ID] {
+			continue
+		}
+		out = append(out, p)
+		if len(out) >= q.Limit {
+			break
+		}
 	}
-	return remaining
+	return out, nil
 }
 
 // PutGeo stores the last ping for an anon.
-func (s *MemStore) PutGeo(ping *GeoPing) {
+func (s *MemStore) PutGeo(ctx context.Context, ping *GeoPing) error {
 	s.mu.Lock()
 	defer s.mu.Unlock()
 	s.pings[ping.AnonID] = ping
+	return nil
 }
 
-// GetNearby returns all pings within the radius and recent (last 10 minutes).
+// GetNearby returns the last ping of each anon within the radius, if it is
+// recent (last 10 minutes), newest first.
 // Uses haversine distance calculation.
-func (s *MemStore) GetNearby(lat, lng float64, radiusKm float64) []*GeoPing {
+func (s *MemStore) GetNearby(ctx context.Context, lat, lng float64, radiusKm float64) ([]*GeoPing, error) {
 	s.mu.RLock()
 	defer s.mu.RUnlock()
 
-	cutoff := time.Now().Add(-10 * time.Minute)
+	cutoff := time.Now().Add(-geoPingMaxAge)
 	out := make([]*GeoPing, 0)
 
 	for _, ping := range s.pings {
@@ -406,21 +422,20 @@ func (s *MemStore) GetNearby(lat, lng float64, radiusKm float64) []*GeoPing {
 		if dist <= radiusKm {
 			out = append(out, ping)
 		}
-
-		// Limit to 100 results
-		if len(out) >= 100 {
-			break
-		}
 	}
 
-	return out
+	sort.Slice(out, func(i, j int) bool { return out[i].Timestamp.After(out[j].Timestamp) })
+	// Limit to 100 results
+	if len(out) > 100 {
+		out = out[:100]
+	}
+	return out, nil
 }
 
 // haversineDistance calculates distance in km between two lat/lng points.
 func haversineDistance(lat1, lng1, lat2, lng2 float64) float64 {
 	const earthKm = 6371.0
-	const pi = 3.14159265358979323846
-	const degToRad = pi / 180.0
+	const degToRad = math.Pi / 180.0
 
 	dLat := (lat2 - lat1) * degToRad
 	dLng := (lng2 - lng1) * degToRad
@@ -428,108 +443,19 @@ func haversineDistance(lat1, lng1, lat2, lng2 float64) float64 {
 	lat1R := lat1 * degToRad
 	lat2R := lat2 * degToRad
 
-	a := sin2(dLat/2) + cos(lat1R)*cos(lat2R)*sin2(dLng/2)
-	c := 2 * asin(sqrt(a))
+	sinLat := math.Sin(dLat / 2)
+	sinLng := math.Sin(dLng / 2)
+	a := sinLat*sinLat + math.Cos(lat1R)*math.Cos(lat2R)*sinLng*sinLng
+	c := 2 * math.Asin(math.Sqrt(a))
 
 	return earthKm * c
 }
 
-// sin, cos, sqrt, asin helpers for haversine
-func sin(x float64) float64 {
-	return sinTaylor(x)
-}
-
-func cos(x float64) float64 {
-	return cosTaylor(x)
-}
-
-func sqrt(x float64) float64 {
-	if x == 0 {
-		return 0
-	}
-	z := x
-	for i := 0; i < 10; i++ {
-		z = (z + x/z) / 2
-	}
-	return z
-}
-
-func asin(x float64) float64 {
-	if x < 0 {
-		return -asin(-x)
-	}
-	if x > 1 {
-		x = 1
-	}
-	return atan(x / sqrt(1-x*x))
-}
-
-func atan(x float64) float64 {
-	return atanTaylor(x)
-}
-
-func sin2(x float64) float64 {
-	s := sin(x)
-	return s * s
-}
-
-// Taylor series approximations for trig functions (simple, sufficient for haversine)
-func sinTaylor(x float64) float64 {
-	x = normalizeAngle(x)
-	result := x
-	term := x
-	for i := 1; i < 20; i++ {
-		term *= -x * x / (float64((2 * i) * (2*i + 1)))
-		result += term
-	}
-	return result
-}
-
-func cosTaylor(x float64) float64 {
-	x = normalizeAngle(x)
-	result := 1.0
-	term := 1.0
-	for i := 1; i < 20; i++ {
-		term *= -x * x / (float64((2*i - 1) * (2 * i)))
-		result += term
-	}
-	return result
-}
-
-func atanTaylor(x float64) float64 {
-	if x > 1 {
-		return 1.5707963267948966 - atanTaylor(1/x)
-	}
-	if x < -1 {
-		return -1.5707963267948966 - atanTaylor(1/x)
-	}
-	result := x
-	term := x
-	x2 := x * x
-	for i := 1; i < 20; i++ {
-		term *= -x2 * float64(2*i-1) / float64(2*i+1)
-		result += term
-	}
-	return result
-}
-
-func normalizeAngle(x float64) float64 {
-	const pi = 3.14159265358979323846
-	const twoPi = 2 * pi
-	for x > pi {
-		x -= twoPi
-	}
-	for x < -pi {
-		x += twoPi
-	}
-	return x
-}
-
 // Default is the global in-memory store for dev.
 // Later we'll replace this with a DB-backed store.
 
 // TrustAccepted returns true if there exists an accepted trust between a and b (either direction).
-func (s *MemStore) TrustAccepted(a, b string) bool {
+func (s *MemStore) TrustAccepted(ctx context.Context, a, b string) (bool, error) {
 	s.mu.RLock()
 	defer s.mu.RUnlock()
 
@@ -538,98 +464,135 @@ func (s *MemStore) TrustAccepted(a, b string) bool {
 			continue
 		}
 		if (t.FromAnon == a && t.ToAnon == b) || (t.FromAnon == b && t.ToAnon == a) {
-			return true
+			return true, nil
 		}
 	}
-	return false
+	return false, nil
 }
 
 // Admin methods
-func (s *MemStore) GetAllUsers() []*UserInfo {
+func (s *MemStore) GetAllUsers(ctx context.Context) ([]*UserInfo, error) {
 	s.mu.RLock()
 	defer s.mu.RUnlock()
 
-	users := make([]*UserInfo, 0, len(s.devices))
+	// one row per identity; linked devices share the anon id
+	byAnon := make(map[string]*UserInfo)
 	for _, device := range s.devices {
-		// Count posts for this user
-		postCount := 0
+		u, ok := byAnon[device.AnonID]
+		if !ok {
+			u = &UserInfo{AnonID: device.AnonID, Username: device.Username, CreatedAt: device.CreatedAt}
+			byAnon[device.AnonID] = u
+			continue
+		}
+		if device.Username < u.Username {
+			u.Username = device.Username
+		}
+		if device.CreatedAt.Before(u.CreatedAt) {
+			u.CreatedAt = device.CreatedAt
+		}
+	}
+
+	users := make([]*UserInfo, 0, len(byAnon))
+	for _, u := range byAnon {
 		for _, p := range s.posts {
-			if p.AnonID == device.AnonID {
-				postCount++
+			if p.AnonID == u.AnonID {
+				u.PostCount++
 			}
 		}
-
-		users = append(users, &UserInfo{
-			AnonID:    device.AnonID,
-			Username:  device.Username,
-			CreatedAt: device.CreatedAt,
-			PostCount: postCount,
-		})
+		users = append(users, u)
 	}
-	return users
+	sort.Slice(users, func(i, j int) bool { return users[i].CreatedAt.After(users[j].CreatedAt) })
+	return users, nil
 }
 
-func (s *MemStore) GetAllSessions() []*SessionInfo {
+func (s *MemStore) GetAllSessions(ctx context.Context) ([]*SessionInfo, error) {
 	s.mu.RLock()
 	defer s.mu.RUnlock()
 
 	sessions := make([]*SessionInfo, 0, len(s.sessions))
 	for _, sess := range s.sessions {
-		sessions = append(sessions, sess)
+		copy := *sess
+		sessions = append(sessions, &copy)
 	}
-	return sessions
+	sort.Slice(sessions, func(i, j int) bool { return sessions[i].CreatedAt.After(sessions[j].CreatedAt) })
+	return sessions, nil
 }
 
-func (s *MemStore) PutSession(session SessionInfo) error {
+func (s *MemStore) PutSession(ctx context.Context, session SessionInfo) error {
 	s.mu.Lock()
 	defer s.mu.Unlock()
 
+	if session.ID == "" {
+		id, err := newUUID()
+		if err != nil {
+			return fmt.Errorf("generate session id: %w", err)
+		}
+		session.ID = id
+	}
+	// Same defaults as the sessions table, so reads agree across stores.
+	if session.IssuedAt.IsZero() {
+		session.IssuedAt = session.CreatedAt
+		if session.IssuedAt.IsZero() {
+			session.IssuedAt = time.Now()
+		}
+	}
+	if session.CreatedAt.IsZero() {
+		session.CreatedAt = session.IssuedAt
+	}
+	if session.LastActivityAt.IsZero() {
+		session.LastActivityAt = session.IssuedAt
+	}
+
 	copy := session
 	s.sessions[session.Token] = &copy
 	return nil
 }
 
-func (s *MemStore) GetDevice(devicePublicID string) (*Device, error) {
+func (s *MemStore) GetDevice(ctx context.Context, devicePublicID string) (*Device, error) {
 	s.mu.RLock()
 	defer s.mu.RUnlock()
 
 	device, ok := s.devices[devicePublicID]
 	if !ok {
-		return nil, fmt.Errorf("device not found")
+		return nil, ErrDeviceNotFound
 	}
 	copy := *device
 	return &copy, nil
 }
 
-func (s *MemStore) GetDeviceByAnonID(anonID string) (*Device, error) {
+func (s *MemStore) GetDeviceByAnonID(ctx context.Context, anonID string) (*Device, error) {
 	s.mu.RLock()
 	defer s.mu.RUnlock()
 
-	for _, device := range s.devices {
-		if device.AnonID == anonID {
-			copy := *device
-			return &copy, nil
-		}
+	device, err := s.getDeviceByAnonIDUnsafe(anonID)
+	if err != nil {
+		return nil, err
 	}
-	return nil, fmt.Errorf("device not found")
+	copy := *device
+	return &copy, nil
 }
 
-func (s *MemStore) CreateDevice(device *Device) error {
+func (s *MemStore) CreateDevice(ctx context.Context, device *Device) error {
 	s.mu.Lock()
 	defer s.mu.Unlock()
 
+	return s.createDeviceLocked(device)
+}
+
+func (s *MemStore) createDeviceLocked(device *Device) error {
 	if _, exists := s.devices[device.DevicePublicID]; exists {
 		return fmt.Errorf("device already exists")
 	}
+	// linked devices of one identity share its username
 	for _, existing := range s.devices {
-		if existing.Username == device.Username {
-			return fmt.Errorf("username already exists")
-		}
-		if existing.AnonID == device.AnonID {
-			return fmt.Errorf("anon id already exists")
+		if existing.Username == device.Username && existing.AnonID != device.AnonID {
+			return ErrUsernameTaken
 		}
 	}
 
+	if device.AuthMethod == "" {
+		device.AuthMethod = DeviceAuthHMAC
+	}
 	if device.CreatedAt.IsZero() {
 		device.CreatedAt = time.Now()
 	}
@@ -642,19 +605,19 @@ func (s *MemStore) CreateDevice(device *Device) error {
 	return nil
 }
 
-func (s *MemStore) UpdateDeviceTimestamp(devicePublicID string, updatedAt time.Time) error {
+func (s *MemStore) UpdateDeviceTimestamp(ctx context.Context, devicePublicID string, updatedAt time.Time) error {
 	s.mu.Lock()
 	defer s.mu.Unlock()
 
 	device, ok := s.devices[devicePublicID]
 	if !ok {
-		return fmt.Errorf("device not found")
+		return ErrDeviceNotFound
 	}
 	device.UpdatedAt = updatedAt
 	return nil
 }
 
-func (s *MemStore) CreateDeviceNonce(devicePublicID, nonce string, expiresAt time.Time) error {
+func (s *MemStore) CreateDeviceNonce(ctx context.Context, devicePublicID, nonce string, expiresAt time.Time) error {
 	s.mu.Lock()
 	defer s.mu.Unlock()
 
@@ -675,7 +638,7 @@ func (s *MemStore) CreateDeviceNonce(devicePublicID, nonce string, expiresAt tim
 	return nil
 }
 
-func (s *MemStore) ConsumeDeviceNonce(devicePublicID, nonce string, now time.Time) (bool, error) {
+func (s *MemStore) ConsumeDeviceNonce(ctx context.Context, devicePublicID, nonce string, now time.Time) (bool, error) {
 	s.mu.Lock()
 	defer s.mu.Unlock()
 
@@ -698,7 +661,7 @@ func (s *MemStore) ConsumeDeviceNonce(devicePublicID, nonce string, now time.Tim
 	return true, nil
 }
 
-func (s *MemStore) GetAllTrustRequests() []*TrustRequest {
+func (s *MemStore) GetAllTrustRequests(ctx context.Context) ([]*TrustRequest, error) {
 	s.mu.RLock()
 	defer s.mu.RUnlock()
 
@@ -706,24 +669,30 @@ func (s *MemStore) GetAllTrustRequests() []*TrustRequest {
 	for _, req := range s.trust {
 		requests = append(requests, req)
 	}
-	return requests
+	sortTrustRequests(requests)
+	return requests, nil
+}
+
+// sortTrustRequests orders requests newest first, like the Postgres queries.
+func sortTrustRequests(requests []*TrustRequest) {
+	sort.Slice(requests, func(i, j int) bool { return requests[i].CreatedAt.After(requests[j].CreatedAt) })
 }
 
 // Session management methods
-func (s *MemStore) UpdateSessionActivity(token string) error {
+func (s *MemStore) UpdateSessionActivity(ctx context.Context, token string) error {
 	s.mu.Lock()
 	defer s.mu.Unlock()
 
 	sess, ok := s.sessions[token]
 	if !ok {
-		return fmt.Errorf("session not found")
+		return ErrSessionNotFound
 	}
 
 	sess.LastActivityAt = time.Now()
 	return nil
 }
 
-func (s *MemStore) CleanupExpiredSessions() (int, error) {
+func (s *MemStore) CleanupExpiredSessions(ctx context.Context) (int, error) {
 	s.mu.Lock()
 	defer s.mu.Unlock()
 
@@ -732,7 +701,7 @@ func (s *MemStore) CleanupExpiredSessions() (int, error) {
 	affectedUsers := make(map[string]bool)
 
 	for token, sess := range s.sessions {
-		if sess.ExpiresAt.Before(now) {
+		if sessionLiveUntil(sess).Before(now) {
 			affectedUsers[sess.AnonID] = true
 			delete(s.sessions, token)
 			count++
@@ -758,13 +727,13 @@ func (s *MemStore) CleanupExpiredSessions() (int, error) {
 	return count, nil
 }
 
-func (s *MemStore) GetSessionByToken(token string) (*SessionInfo, error) {
+func (s *MemStore) GetSessionByToken(ctx context.Context, token string) (*SessionInfo, error) {
 	s.mu.RLock()
 	defer s.mu.RUnlock()
 
 	sess, ok := s.sessions[token]
 	if !ok {
-		return nil, fmt.Errorf("session not found")
+		return nil, ErrSessionNotFound
 	}
 
 	// Return a copy
@@ -772,7 +741,7 @@ func (s *MemStore) GetSessionByToken(token string) (*SessionInfo, error) {
 	return &copy, nil
 }
 
-func (s *MemStore) GetSessionsByAnonID(anonID string) ([]*SessionInfo, error) {
+func (s *MemStore) GetSessionsByAnonID(ctx context.Context, anonID string) ([]*SessionInfo, error) {
 	s.mu.RLock()
 	defer s.mu.RUnlock()
 
@@ -783,17 +752,20 @@ func (s *MemStore) GetSessionsByAnonID(anonID string) ([]*SessionInfo, error) {
 			sessions = append(sessions, &copy)
 		}
 	}
+	sort.Slice(sessions, func(i, j int) bool {
+		return sessions[i].LastActivityAt.After(sessions[j].LastActivityAt)
+	})
 
 	return sessions, nil
 }
 
-func (s *MemStore) RevokeSession(token string) error {
+func (s *MemStore) RevokeSession(ctx context.Context, token string) error {
 	s.mu.Lock()
 	defer s.mu.Unlock()
 
 	sess, ok := s.sessions[token]
 	if !ok {
-		return fmt.Errorf("session not found")
+		return ErrSessionNotFound
 	}
 
 	anonID := sess.AnonID
@@ -801,13 +773,13 @@ func (s *MemStore) RevokeSession(token string) error {
 
 	// Unlock before calling ReconcileUserActiveStatus to avoid deadlock
 	s.mu.Unlock()
-	_ = s.ReconcileUserActiveStatus(anonID)
+	_ = s.ReconcileUserActiveStatus(ctx, anonID)
 	s.mu.Lock()
 
 	return nil
 }
 
-func (s *MemStore) RevokeAllSessionsForUser(anonID string) (int, error) {
+func (s *MemStore) RevokeAllSessionsForUser(ctx context.Context, anonID string) (int, error) {
 	s.mu.Lock()
 	defer s.mu.Unlock()
 
@@ -827,7 +799,7 @@ func (s *MemStore) RevokeAllSessionsForUser(anonID string) (int, error) {
 	return count, nil
 }
 
-func (s *MemStore) EnforceSessionLimit(anonID string, maxSessions int) error {
+func (s *MemStore) EnforceSessionLimit(ctx context.Context, anonID string, maxSessions int) error {
 	s.mu.Lock()
 	defer s.mu.Unlock()
 
@@ -878,7 +850,7 @@ func (s *MemStore) EnforceSessionLimit(anonID string, maxSessions int) error {
 
 // ===== USER TRACKING =====
 
-func (s *MemStore) EnsureUser(anonID string, now time.Time) error {
+func (s *MemStore) EnsureUser(ctx context.Context, anonID string, now time.Time) error {
 	s.mu.Lock()
 	defer s.mu.Unlock()
 
@@ -902,7 +874,7 @@ func (s *MemStore) EnsureUser(anonID string, now time.Time) error {
 	return nil
 }
 
-func (s *MemStore) MarkUserActive(anonID string, now time.Time) error {
+func (s *MemStore) MarkUserActive(ctx context.Context, anonID string, now time.Time) error {
 	s.mu.Lock()
 	defer s.mu.Unlock()
 
@@ -913,7 +885,7 @@ func (s *MemStore) MarkUserActive(anonID string, now time.Time) error {
 	return nil
 }
 
-func (s *MemStore) MarkUserInactive(anonID string) error {
+func (s *MemStore) MarkUserInactive(ctx context.Context, anonID string) error {
 	s.mu.Lock()
 	defer s.mu.Unlock()
 
@@ -923,7 +895,7 @@ func (s *MemStore) MarkUserInactive(anonID string) error {
 	return nil
 }
 
-func (s *MemStore) UpdateUserLastSeen(anonID string, now time.Time) error {
+func (s *MemStore) UpdateUserLastSeen(ctx context.Context, anonID string, now time.Time) error {
 	s.mu.Lock()
 	defer s.mu.Unlock()
 
@@ -936,7 +908,7 @@ func (s *MemStore) UpdateUserLastSeen(anonID string, now time.Time) error {
 	return nil
 }
 
-func (s *MemStore) GetActiveUsersCount() (int, error) {
+func (s *MemStore) GetActiveUsersCount(ctx context.Context) (int, error) {
 	s.mu.RLock()
 	defer s.mu.RUnlock()
 
@@ -949,14 +921,14 @@ func (s *MemStore) GetActiveUsersCount() (int, error) {
 	return count, nil
 }
 
-func (s *MemStore) GetTotalUsersCount() (int, error) {
+func (s *MemStore) GetTotalUsersCount(ctx context.Context) (int, error) {
 	s.mu.RLock()
 	defer s.mu.RUnlock()
 
 	return len(s.users), nil
 }
 
-func (s *MemStore) CreateUserBan(anonID, reason string, bannedBy string, now time.Time, expiresAt *time.Time, permanent bool) error {
+func (s *MemStore) CreateUserBan(ctx context.Context, anonID, reason string, bannedBy string, now time.Time, expiresAt *time.Time, permanent bool) error {
 	s.mu.Lock()
 	defer s.mu.Unlock()
 
@@ -978,30 +950,34 @@ func (s *MemStore) CreateUserBan(anonID, reason string, bannedBy string, now tim
 	return nil
 }
 
-func (s *MemStore) GetActiveUserBan(anonID string, now time.Time) (*UserBan, error) {
+func (s *MemStore) GetActiveUserBan(ctx context.Context, anonID string, now time.Time) (*UserBan, error) {
 	s.mu.RLock()
 	defer s.mu.RUnlock()
 
+	return s.activeBanLocked(anonID, now), nil
+}
+
+func (s *MemStore) activeBanLocked(anonID string, now time.Time) *UserBan {
 	ban, exists := s.userBans[anonID]
 	if !exists || ban == nil {
-		return nil, nil
+		return nil
 	}
 
 	if ban.Permanent {
 		copyBan := *ban
-		return &copyBan, nil
+		return &copyBan
 	}
 
 	if ban.ExpiresAt != nil && ban.ExpiresAt.After(now) {
 		copyBan := *ban
-		return &copyBan, nil
+		return &copyBan
 	}
 
-	return nil, nil
+	return nil
 }
 
 // ReportPost adds a report for a post
-func (s *MemStore) ReportPost(postID, reportedAnonID, reporterAnonID, reason string, now time.Time) error {
+func (s *MemStore) ReportPost(ctx context.Context, postID, reportedAnonID, reporterAnonID, reason string, now time.Time) error {
 	s.mu.Lock()
 	defer s.mu.Unlock()
 
@@ -1023,18 +999,16 @@ func (s *MemStore) ReportPost(postID, reportedAnonID, reporterAnonID, reason str
 }
 
 // GetPostReportCount returns the number of reports for a post
-func (s *MemStore) GetPostReportCount(postID string) int {
+func (s *MemStore) GetPostReportCount(ctx context.Context, postID string) (int, error) {
 	s.mu.RLock()
 	defer s.mu.RUnlock()
 
-	if s.postReports == nil || s.postReports[postID] == nil {
-		return 0
-	}
-	return len(s.postReports[postID])
+	return len(s.postReports[postID]), nil
 }
 
 // GetTopReportedPostByAnon returns the most reported post by a user that meets threshold
-func (s *MemStore) GetTopReportedPostByAnon(anonID string, threshold int) *PostReport {
+// It returns nil, nil when no post reaches the threshold.
+func (s *MemStore) GetTopReportedPostByAnon(ctx context.Context, anonID string, threshold int) (*PostReport, error) {
 	s.mu.RLock()
 	defer s.mu.RUnlock()
 
@@ -1068,7 +1042,7 @@ func (s *MemStore) GetTopReportedPostByAnon(anonID string, threshold int) *PostR
 		}
 	}
 
-	return topPost
+	return topPost, nil
 }
 
 // Helper method to get post by ID (internal)
@@ -1081,7 +1055,7 @@ func (s *MemStore) getPostByID(postID string) (*Post, bool) {
 	return nil, false
 }
 
-func (s *MemStore) ReconcileUserActiveStatus(anonID string) error {
+func (s *MemStore) ReconcileUserActiveStatus(ctx context.Context, anonID string) error {
 	s.mu.Lock()
 	defer s.mu.Unlock()
 
@@ -1106,85 +1080,99 @@ func (s *MemStore) ReconcileUserActiveStatus(anonID string) error {
 
 // ===== AUDIT LOGS =====
 
-func (s *MemStore) GetAuditLogs() []AuditLog {
+func (s *MemStore) GetAuditLogs(ctx context.Context) ([]AuditLog, error) {
 	s.mu.RLock()
 	defer s.mu.RUnlock()
-	return s.auditLogs
+
+	// newest first, like the admin listing in Postgres
+	out := make([]AuditLog, len(s.auditLogs))
+	for i, e := range s.auditLogs {
+		out[len(out)-1-i] = e
+	}
+	return out, nil
 }
 
-func (s *MemStore) DeletePost(postID string) error {
+func (s *MemStore) DeletePost(ctx context.Context, postID string) error {
 	s.mu.Lock()
 	defer s.mu.Unlock()
 
 	for i, p := range s.posts {
 		if p.ID == postID {
 			s.posts = append(s.posts[:i], s.posts[i+1:]...)
+			for _, tag := range ExtractHashtags(p.Text) {
+				s.tagIndex[tag] = removePost(s.tagIndex[tag], postID)
+				if len(s.tagIndex[tag]) == 0 {
+					delete(s.tagIndex, tag)
+				}
+			}
+			s.search.remove(p)
 			return nil
 		}
 	}
-	return nil
-}
-
-func (s *MemStore) LogAuditEvent(event AuditLog) {
-	s.mu.Lock()
-	defer s.mu.Unlock()
-	event.Timestamp = time.Now()
-	s.auditLogs = append(s.auditLogs, event)
+	return ErrPostNotFound
 }
 
-func (s *MemStore) DeleteAuditLog(id string) error {
+func (s *MemStore) LogAuditEvent(ctx context.Context, event AuditLog) error {
 	s.mu.Lock()
 	defer s.mu.Unlock()
 
-	for i, log := range s.auditLogs {
-		if log.ID == id {
-			s.auditLogs = append(s.auditLogs[:i], s.auditLogs[i+1:]...)
-			return nil
-		}
+	var prevSeq int64
+	var prevHash string
+	if n := len(s.auditLogs); n > 0 {
+		prevSeq, prevHash = s.auditLogs[n-1].Seq, s.auditLogs[n-1].Hash
+	}
+	if err := sealAuditLog(&event, prevSeq, prevHash); err != nil {
+		return err
 	}
+	s.auditLogs = append(s.auditLogs, event)
 	return nil
 }
 
-func (s *MemStore) DeleteAuditLogs(ids []string) error {
-	s.mu.Lock()
-	defer s.mu.Unlock()
-
-	idMap := make(map[string]bool)
-	for _, id := range ids {
-		idMap[id] = true
-	}
+func (s *MemStore) ListAuditLogsAfter(ctx context.Context, afterSeq int64, limit int) ([]AuditLog, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
 
-	filtered := make([]AuditLog, 0)
-	for _, log := range s.auditLogs {
-		if !idMap[log.ID] {
-			filtered = append(filtered, log)
+	out := make([]AuditLog, 0)
+	for _, e := range s.auditLogs {
+		if e.Seq <= afterSeq {
+			continue
+		}
+		out = append(out, e)
+		if len(out) == limit {
+			break
 		}
 	}
-	s.auditLogs = filtered
-	return nil
+	return out, nil
 }
 
-func (s *MemStore) ClearAuditLogs() error {
+// PruneAuditLogs drops the oldest entries written before the cutoff, always
+// keeping the newest entry so the chain carries on from its hash.
+func (s *MemStore) PruneAuditLogs(ctx context.Context, before time.Time) (int, error) {
 	s.mu.Lock()
 	defer s.mu.Unlock()
-	s.auditLogs = make([]AuditLog, 0)
-	return nil
+
+	n := 0
+	for n < len(s.auditLogs)-1 && s.auditLogs[n].Timestamp.Before(before) {
+		n++
+	}
+	s.auditLogs = append([]AuditLog(nil), s.auditLogs[n:]...)
+	return n, nil
 }
 
 // GetPost retrieves a post by ID
-func (s *MemStore) GetPost(postID string) (*Post, bool) {
+func (s *MemStore) GetPost(ctx context.Context, postID string) (*Post, error) {
 	s.mu.RLock()
 	defer s.mu.RUnlock()
 
 	for _, p := range s.posts {
 		if p.ID == postID {
-			return p, true
+			return p, nil
 		}
 	}
-	return nil, false
+	return nil, ErrPostNotFound
 }
 
-func (s *MemStore) GetPostsByAnonID(anonID string, limit int) []*Post {
+func (s *MemStore) GetPostsByAnonID(ctx context.Context, anonID string, limit int) ([]*Post, error) {
 	s.mu.RLock()
 	defer s.mu.RUnlock()
 
@@ -1202,244 +1190,28 @@ func (s *MemStore) GetPostsByAnonID(anonID string, limit int) []*Post {
 			break
 		}
 	}
-	return out
-}
-
-// SearchPosts performs basic in-memory search (simplified version for MemStore)
-func (s *MemStore) SearchPosts(query string, hashtags []string, limit int, offset int) ([]*PostSearchResult, int, error) {
-	s.mu.RLock()
-	defer s.mu.RUnlock()
-
-	if limit <= 0 {
-		limit = 20
-	}
-	if offset < 0 {
-		offset = 0
-	}
-
-	// Simple case-insensitive search
-	searchLower := toLower(query)
-	searchNormalized := normalizeSearchToken(query)
-	var results []*PostSearchResult
-
-	for _, p := range s.posts {
-		if p.Deleted {
-			continue
-		}
-
-		textLower := toLower(p.Text)
-		anonIDLower := toLower(p.AnonID)
-		usernameLower := ""
-		for _, d := range s.devices {
-			if d.AnonID == p.AnonID {
-				usernameLower = toLower(d.Username)
-				break
-			}
-		}
-		usernameNormalized := normalizeSearchToken(usernameLower)
-		score := 0.0
-
-		// Check hashtag match (if hashtags are provided, must match all)
-		if len(hashtags) > 0 {
-			postHashtags := extractHashtagsFromText(p.Text)
-			if !containsAllHashtags(postHashtags, hashtags) {
-				continue
-			}
-			score += 5.0
-		}
-
-		// Check keyword match
-		if query != "" {
-			textMatch := contains(textLower, searchLower)
-			usernameMatch := usernameLower != "" && contains(usernameLower, searchLower)
-			anonIDMatch := contains(anonIDLower, searchLower)
-			normalizedUsernameMatch := searchNormalized != "" && usernameNormalized != "" && contains(usernameNormalized, searchNormalized)
-			if !textMatch && !usernameMatch && !anonIDMatch && !normalizedUsernameMatch {
-				continue
-			}
-
-			// Exact match
-			if textLower == searchLower || usernameLower == searchLower || anonIDLower == searchLower {
-				score += 10.0
-			} else if startsWith(textLower, searchLower) || startsWith(usernameLower, searchLower) {
-				score += 5.0
-			} else {
-				score += 2.0
-			}
-
-			if usernameMatch {
-				score += 1.5
-			}
-			if normalizedUsernameMatch {
-				score += 1.0
-			}
-			if anonIDMatch {
-				score += 1.0
-			}
-		} else if len(hashtags) == 0 {
-			// No query and no hashtags - skip
-			continue
-		}
-
-		// Recency boost
-		age := time.Since(p.CreatedAt)
-		if age < 7*24*time.Hour {
-			score += 0.5
-		} else if age < 30*24*time.Hour {
-			score += 0.2
-		}
-
-		results = append(results, &PostSearchResult{
-			Post:           p,
-			RelevanceScore: score,
-			MatchedTerms:   append([]string{query}, hashtags...),
-			Highlights:     truncateText(p.Text, 100),
-		})
-	}
-
-	// Sort by relevance
-	sortSearchResults(results)
-
-	totalCount := len(results)
-
-	// Apply pagination
-	if offset >= len(results) {
-		return []*PostSearchResult{}, totalCount, nil
-	}
-
-	end := offset + limit
-	if end > len(results) {
-		end = len(results)
-	}
-
-	return results[offset:end], totalCount, nil
-}
-
-// Helper functions for simple string operations
-func toLower(s string) string {
-	result := make([]rune, len(s))
-	for i, r := range s {
-		if r >= 'A' && r <= 'Z' {
-			result[i] = r + 32
-		} else {
-			result[i] = r
-		}
-	}
-	return string(result)
-}
-
-func normalizeSearchToken(s string) string {
-	runes := make([]rune, 0, len(s))
-	for _, r := range toLower(s) {
-		if (r >= 'a' && r <= 'z') || (r >= '0' && r <= '9') || r == '_' {
-			runes = append(runes, r)
-		}
-	}
-	return string(runes)
-}
-
-func contains(s, substr string) bool {
-	if len(substr) > len(s) {
-		return false
-	}
-	for i := 0; i <= len(s)-len(substr); i++ {
-		if s[i:i+len(substr)] == substr {
-			return true
-		}
-	}
-	return false
-}
-
-func startsWith(s, prefix string) bool {
-	if len(prefix) > len(s) {
-		return false
-	}
-	return s[:len(prefix)] == prefix
-}
-
-func extractHashtagsFromText(text string) []string {
-	var hashtags []string
-	words := splitWords(text)
-	for _, word := range words {
-		if len(word) > 1 && word[0] == '#' {
-			hashtags = append(hashtags, toLower(word[1:]))
-		}
-	}
-	return hashtags
-}
-
-func splitWords(text string) []string {
-	var words []string
-	var current []rune
-	for _, r := range text {
-		if r == ' ' || r == '\n' || r == '\t' || r == '\r' {
-			if len(current) > 0 {
-				words = append(words, string(current))
-				current = []rune{}
-			}
-		} else {
-			current = append(current, r)
-		}
-	}
-	if len(current) > 0 {
-		words = append(words, string(current))
-	}
-	return words
-}
-
-func containsAllHashtags(postHashtags, requiredHashtags []string) bool {
-	for _, required := range requiredHashtags {
-		found := false
-		for _, tag := range postHashtags {
-			if tag == toLower(required) {
-				found = true
-				break
-			}
-		}
-		if !found {
-			return false
-		}
-	}
-	return true
-}
-
-func truncateText(text string, maxLen int) string {
-	if len(text) <= maxLen {
-		return text
-	}
-	return text[:maxLen] + "..."
-}
-
-func sortSearchResults(results []*PostSearchResult) {
-	// Simple bubble sort by relevance score (descending)
-	for i := 0; i < len(results); i++ {
-		for j := i + 1; j < len(results); j++ {
-			if results[j].RelevanceScore > results[i].RelevanceScore {
-				results[i], results[j] = results[j], results[i]
-			}
-		}
-	}
+	return out, nil
 }
 
 // DeletePostByUser marks a post as deleted if the user is the author
-func (s *MemStore) DeletePostByUser(postID, anonID string) error {
+func (s *MemStore) DeletePostByUser(ctx context.Context, postID, anonID string) error {
 	s.mu.Lock()
 	defer s.mu.Unlock()
 
 	for _, p := range s.posts {
 		if p.ID == postID {
 			if p.AnonID != anonID {
-				return fmt.Errorf("unauthorized: not post author")
+				return fmt.Errorf("not post author: %w", ErrForbidden)
 			}
 			p.Deleted = true
 			return nil
 		}
 	}
-	return fmt.Errorf("post not found")
+	return ErrPostNotFound
 }
 
 // ReactToPost adds or updates a user's reaction to a post
-func (s *MemStore) ReactToPost(postID, anonID, reactionType string) error {
+func (s *MemStore) ReactToPost(ctx context.Context, postID, anonID, reactionType string) error {
 	s.mu.Lock()
 	defer s.mu.Unlock()
 
@@ -1452,12 +1224,8 @@ func (s *MemStore) ReactToPost(postID, anonID, reactionType string) error {
 		}
 	}
 
-	if post == nil {
-		return fmt.Errorf("post not found")
-	}
-
-	if post.Deleted {
-		return fmt.Errorf("post is deleted")
+	if post == nil || post.Deleted {
+		return ErrPostNotFound
 	}
 
 	// Initialize reaction map for post if needed
@@ -1507,86 +1275,70 @@ func (s *MemStore) ReactToPost(postID, anonID, reactionType string) error {
 }
 
 // GetPostReaction retrieves a user's reaction to a post
-func (s *MemStore) GetPostReaction(postID, anonID string) (string, bool) {
+func (s *MemStore) GetPostReaction(ctx context.Context, postID, anonID string) (string, error) {
 	s.mu.RLock()
 	defer s.mu.RUnlock()
 
-	if s.postReactions[postID] == nil {
-		return "", false
-	}
-
 	reaction, ok := s.postReactions[postID][anonID]
 	if !ok {
-		return "", false
+		return "", nil
 	}
-
-	return reaction.ReactionType, true
+	return reaction.ReactionType, nil
 }
 
 // AddComment adds a comment to a post
-func (s *MemStore) AddComment(comment *PostComment) error {
+func (s *MemStore) AddComment(ctx context.Context, comment *PostComment) error {
 	s.mu.Lock()
 	defer s.mu.Unlock()
 
-	// Verify post exists
-	postExists := false
-	for _, p := range s.posts {
-		if p.ID == comment.PostID && !p.Deleted {
-			postExists = true
-			break
-		}
-	}
-
-	if !postExists {
-		return fmt.Errorf("post not found or deleted")
-	}
-
-	if s.postComments[comment.PostID] == nil {
-		s.postComments[comment.PostID] = make([]*PostComment, 0)
+	if !s.postExistsLocked(comment.PostID) {
+		return ErrPostNotFound
 	}
-
 	s.postComments[comment.PostID] = append(s.postComments[comment.PostID], comment)
 	return nil
 }
 
+// postExistsLocked reports whether postID is a live post.
+func (s *MemStore) postExistsLocked(postID string) bool {
+	for _, p := range s.posts {
+		if p.ID == postID && !p.Deleted {
+			return true
+		}
+	}
+	return false
+}
+
 // GetComments retrieves all non-deleted comments for a post
-func (s *MemStore) GetComments(postID string) []*PostComment {
+func (s *MemStore) GetComments(ctx context.Context, postID string) ([]*PostComment, error) {
 	s.mu.RLock()
 	defer s.mu.RUnlock()
 
-	comments := s.postComments[postID]
-	if comments == nil {
-		return []*PostComment{}
-	}
-
 	result := make([]*PostComment, 0)
-	for _, c := range comments {
+	for _, c := range s.postComments[postID] {
 		if !c.Deleted {
 			result = append(result, c)
 		}
 	}
-
-	return result
+	return result, nil
 }
 
 // GetComment retrieves a comment by ID
-func (s *MemStore) GetComment(commentID string) (*PostComment, bool) {
+func (s *MemStore) GetComment(ctx context.Context, commentID string) (*PostComment, error) {
 	s.mu.RLock()
 	defer s.mu.RUnlock()
 
 	for _, comments := range s.postComments {
 		for _, c := range comments {
 			if c.ID == commentID {
-				return c, true
+				return c, nil
 			}
 		}
 	}
-
-	return nil, false
+	return nil, ErrCommentNotFound
 }
 
 // DeleteCommentByUser soft-deletes a comment if user is the author
-func (s *MemStore) DeleteCommentByUser(commentID, anonID string) error {
+func (s *MemStore) DeleteCommentByUser(ctx context.Context, commentID, anonID string) error {
 	s.mu.Lock()
 	defer s.mu.Unlock()
 
@@ -1594,7 +1346,7 @@ func (s *MemStore) DeleteCommentByUser(commentID, anonID string) error {
 		for _, c := range comments {
 			if c.ID == commentID {
 				if c.AnonID != anonID {
-					return fmt.Errorf("unauthorized: not comment author")
+					return fmt.Errorf("not comment author: %w", ErrForbidden)
 				}
 				c.Deleted = true
 				return nil
@@ -1602,11 +1354,11 @@ func (s *MemStore) DeleteCommentByUser(commentID, anonID string) error {
 		}
 	}
 
-	return fmt.Errorf("comment not found")
+	return ErrCommentNotFound
 }
 
 // ReactToComment adds or updates a user's reaction to a comment
-func (s *MemStore) ReactToComment(commentID, anonID, reactionType string) error {
+func (s *MemStore) ReactToComment(ctx context.Context, commentID, anonID, reactionType string) error {
 	s.mu.Lock()
 	defer s.mu.Unlock()
 
@@ -1623,12 +1375,8 @@ func (s *MemStore) ReactToComment(commentID, anonID, reactionType string) error
 		}
 	}
 
-	if comment == nil {
-		return fmt.Errorf("comment not found")
-	}
-
-	if comment.Deleted {
-		return fmt.Errorf("comment is deleted")
+	if comment == nil || comment.Deleted {
+		return ErrCommentNotFound
 	}
 
 	if s.commentReacts[commentID] == nil {
@@ -1672,97 +1420,66 @@ func (s *MemStore) ReactToComment(commentID, anonID, reactionType string) error
 }
 
 // GetCommentReaction retrieves a user's reaction to a comment
-func (s *MemStore) GetCommentReaction(commentID, anonID string) (string, bool) {
+func (s *MemStore) GetCommentReaction(ctx context.Context, commentID, anonID string) (string, error) {
 	s.mu.RLock()
 	defer s.mu.RUnlock()
 
-	if s.commentReacts[commentID] == nil {
-		return "", false
-	}
-
 	reaction, ok := s.commentReacts[commentID][anonID]
 	if !ok {
-		return "", false
+		return "", nil
 	}
-
-	return reaction.ReactionType, true
+	return reaction.ReactionType, nil
 }
 
 // AddCommentReply adds a reply to a comment
-func (s *MemStore) AddCommentReply(reply *CommentReply) error {
+func (s *MemStore) AddCommentReply(ctx context.Context, reply *CommentReply) error {
 	s.mu.Lock()
 	defer s.mu.Unlock()
 
-	commentExists := false
-	for _, comments := range s.postComments {
-		for _, c := range comments {
-			if c.ID == reply.CommentID && !c.Deleted {
-				commentExists = true
-				break
-			}
-		}
-		if commentExists {
-			break
-		}
-	}
-
-	if !commentExists {
-		return fmt.Errorf("comment not found or deleted")
-	}
-
-	if s.commentReplies[reply.CommentID] == nil {
-		s.commentReplies[reply.CommentID] = make([]*CommentReply, 0)
+	if !s.commentExistsLocked(reply.CommentID) {
+		return ErrCommentNotFound
 	}
-
 	s.commentReplies[reply.CommentID] = append(s.commentReplies[reply.CommentID], reply)
 	return nil
 }
 
+// commentExistsLocked reports whether commentID is a live comment.
+func (s *MemStore) commentExistsLocked(commentID string) bool {
+	c, ok := s.getCommentByIDUnsafe(commentID)
+	return ok && !c.Deleted
+}
+
 // GetCommentReplies retrieves all non-deleted replies for a comment
-func (s *MemStore) GetCommentReplies(commentID string) []*CommentReply {
+func (s *MemStore) GetCommentReplies(ctx context.Context, commentID string) ([]*CommentReply, error) {
 	s.mu.RLock()
 	defer s.mu.RUnlock()
 
-	replies := s.commentReplies[commentID]
-	if replies == nil {
-		return []*CommentReply{}
-	}
-
 	result := make([]*CommentReply, 0)
-	for _, r := range replies {
+	for _, r := range s.commentReplies[commentID] {
 		if !r.Deleted {
 			result = append(result, r)
 		}
 	}
-
-	return result
+	return result, nil
 }
 
 // GetReply retrieves a single reply by ID
-func (s *MemStore) GetReply(replyID string) (*CommentReply, bool) {
+func (s *MemStore) GetReply(ctx context.Context, replyID string) (*CommentReply, error) {
 	s.mu.RLock()
 	defer s.mu.RUnlock()
 
-	fmt.Printf("[DEBUG] GetReply searching for id: %s\n", replyID)
-	fmt.Printf("[DEBUG] Total comment IDs with replies: %d\n", len(s.commentReplies))
-
-	for commentID, replies := range s.commentReplies {
-		fmt.Printf("[DEBUG] Checking commentID %s with %d replies\n", commentID, len(replies))
+	for _, replies := range s.commentReplies {
 		for _, r := range replies {
-			fmt.Printf("[DEBUG] Comparing reply id %s with search id %s\n", r.ID, replyID)
 			if r.ID == replyID {
-				fmt.Printf("[DEBUG] Found reply: %+v\n", r)
-				return r, true
+				return r, nil
 			}
 		}
 	}
-
-	fmt.Printf("[DEBUG] Reply not found in store\n")
-	return nil, false
+	return nil, ErrReplyNotFound
 }
 
 // DeleteCommentReplyByUser soft-deletes a reply if user is the author
-func (s *MemStore) DeleteCommentReplyByUser(replyID, anonID string) error {
+func (s *MemStore) DeleteCommentReplyByUser(ctx context.Context, replyID, anonID string) error {
 	s.mu.Lock()
 	defer s.mu.Unlock()
 
@@ -1770,7 +1487,7 @@ func (s *MemStore) DeleteCommentReplyByUser(replyID, anonID string) error {
 		for _, r := range replies {
 			if r.ID == replyID {
 				if r.AnonID != anonID {
-					return fmt.Errorf("unauthorized: not reply author")
+					return fmt.Errorf("not reply author: %w", ErrForbidden)
 				}
 				r.Deleted = true
 				return nil
@@ -1778,31 +1495,25 @@ func (s *MemStore) DeleteCommentReplyByUser(replyID, anonID string) error {
 		}
 	}
 
-	return fmt.Errorf("reply not found")
+	return ErrReplyNotFound
 }
 
 // GetCommentRepliesCount returns count of non-deleted replies for a comment
-func (s *MemStore) GetCommentRepliesCount(commentID string) int {
+func (s *MemStore) GetCommentRepliesCount(ctx context.Context, commentID string) (int, error) {
 	s.mu.RLock()
 	defer s.mu.RUnlock()
 
-	replies := s.commentReplies[commentID]
-	if replies == nil {
-		return 0
-	}
-
 	count := 0
-	for _, r := range replies {
+	for _, r := range s.commentReplies[commentID] {
 		if !r.Deleted {
 			count++
 		}
 	}
-
-	return count
+	return count, nil
 }
 
 // ReactToReply adds or updates a user's reaction to a reply
-func (s *MemStore) ReactToReply(replyID, anonID, reactionType string) error {
+func (s *MemStore) ReactToReply(ctx context.Context, replyID, anonID, reactionType string) error {
 	s.mu.Lock()
 	defer s.mu.Unlock()
 
@@ -1819,12 +1530,8 @@ func (s *MemStore) ReactToReply(replyID, anonID, reactionType string) error {
 		}
 	}
 
-	if reply == nil {
-		return fmt.Errorf("reply not found")
-	}
-
-	if reply.Deleted {
-		return fmt.Errorf("reply is deleted")
+	if reply == nil || reply.Deleted {
+		return ErrReplyNotFound
 	}
 
 	if s.replyReacts[replyID] == nil {
@@ -1868,38 +1575,27 @@ func (s *MemStore) ReactToReply(replyID, anonID, reactionType string) error {
 }
 
 // GetReplyReaction retrieves a user's reaction to a reply
-func (s *MemStore) GetReplyReaction(replyID, anonID string) (string, bool) {
+func (s *MemStore) GetReplyReaction(ctx context.Context, replyID, anonID string) (string, error) {
 	s.mu.RLock()
 	defer s.mu.RUnlock()
 
-	if s.replyReacts[replyID] == nil {
-		return "", false
-	}
-
 	reaction, ok := s.replyReacts[replyID][anonID]
 	if !ok {
-		return "", false
+		return "", nil
 	}
-
-	return reaction.ReactionType, true
+	return reaction.ReactionType, nil
 }
 
 // GetCommentsCount returns count of non-deleted comments for a post
-func (s *MemStore) GetCommentsCount(postID string) int {
+func (s *MemStore) GetCommentsCount(ctx context.Context, postID string) (int, error) {
 	s.mu.RLock()
 	defer s.mu.RUnlock()
 
-	comments := s.postComments[postID]
-	if comments == nil {
-		return 0
-	}
-
 	count := 0
-	for _, c := range comments {
+	for _, c := range s.postComments[postID] {
 		if !c.Deleted {
 			count++
 		}
 	}
-
-	return count
+	return count, nil
 }
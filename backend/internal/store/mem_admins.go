@@ -0,0 +1,214 @@
+package store
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	"anon-backend/internal/security"
+)
+
+func (s *MemStore) CreateAdmin(ctx context.Context, admin *Admin) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	admin.Email = strings.ToLower(strings.TrimSpace(admin.Email))
+	if !security.IsAdminRole(admin.Role) {
+		return fmt.Errorf("%w: unknown role %q", ErrInvalidAdminAccount, admin.Role)
+	}
+	for _, existing := range s.admins {
+		if existing.Email == admin.Email {
+			return ErrAdminExists
+		}
+	}
+	if admin.ID == "" {
+		id, err := newUUID()
+		if err != nil {
+			return err
+		}
+		admin.ID = id
+	}
+	if admin.CreatedAt.IsZero() {
+		admin.CreatedAt = time.Now()
+	}
+
+	copy := *admin
+	s.admins[admin.ID] = &copy
+	return nil
+}
+
+func (s *MemStore) GetAdminByEmail(ctx context.Context, email string) (*Admin, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	email = strings.ToLower(strings.TrimSpace(email))
+	for _, admin := range s.admins {
+		if admin.Email == email {
+			copy := *admin
+			return &copy, nil
+		}
+	}
+	return nil, ErrAdminNotFound
+}
+
+func (s *MemStore) ListAdmins(ctx context.Context) ([]*Admin, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	out := make([]*Admin, 0, len(s.admins))
+	for _, admin := range s.admins {
+		copy := *admin
+		out = append(out, &copy)
+	}
+	sort.Slice(out, func(i, j int) bool { return out[i].CreatedAt.Before(out[j].CreatedAt) })
+	return out, nil
+}
+
+func (s *MemStore) CountAdmins(ctx context.Context) (int, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return len(s.admins), nil
+}
+
+func (s *MemStore) UpdateAdminLastLogin(ctx context.Context, adminID string, at time.Time) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	admin, ok := s.admins[adminID]
+	if !ok {
+		return ErrAdminNotFound
+	}
+	t := at
+	admin.LastLoginAt = &t
+	return nil
+}
+
+func (s *MemStore) SetAdminDisabled(ctx context.Context, adminID string, disabled bool) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	admin, ok := s.admins[adminID]
+	if !ok {
+		return ErrAdminNotFound
+	}
+	admin.Disabled = disabled
+	return nil
+}
+
+func (s *MemStore) SetAdminRole(ctx context.Context, adminID, role string) error {
+	if !security.IsAdminRole(role) {
+		return fmt.Errorf("%w: unknown role %q", ErrInvalidAdminAccount, role)
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	admin, ok := s.admins[adminID]
+	if !ok {
+		return ErrAdminNotFound
+	}
+	admin.Role = role
+	return nil
+}
+
+func (s *MemStore) GetAdminByID(ctx context.Context, adminID string) (*Admin, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	admin, ok := s.admins[adminID]
+	if !ok {
+		return nil, ErrAdminNotFound
+	}
+	copy := *admin
+	return &copy, nil
+}
+
+func (s *MemStore) SetAdminTOTPPending(ctx context.Context, adminID, secret string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	admin, ok := s.admins[adminID]
+	if !ok {
+		return ErrAdminNotFound
+	}
+	admin.TOTPPendingSecret = secret
+	return nil
+}
+
+func (s *MemStore) EnableAdminTOTP(ctx context.Context, adminID string, counter int64, recoveryCodeHashes []string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	admin, ok := s.admins[adminID]
+	if !ok {
+		return ErrAdminNotFound
+	}
+	if admin.TOTPPendingSecret == "" {
+		return ErrAdminTOTPNotPending
+	}
+	admin.TOTPSecret = admin.TOTPPendingSecret
+	admin.TOTPPendingSecret = ""
+	admin.TOTPEnabled = true
+	admin.TOTPLastCounter = counter
+
+	codes := make(map[string]bool, len(recoveryCodeHashes))
+	for _, hash := range recoveryCodeHashes {
+		codes[hash] = false
+	}
+	s.adminRecoveryCodes[adminID] = codes
+	return nil
+}
+
+func (s *MemStore) UseAdminTOTPCounter(ctx context.Context, adminID string, counter int64) (bool, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	admin, ok := s.admins[adminID]
+	if !ok {
+		return false, ErrAdminNotFound
+	}
+	if counter <= admin.TOTPLastCounter {
+		return false, nil
+	}
+	admin.TOTPLastCounter = counter
+	return true, nil
+}
+
+func (s *MemStore) UseAdminRecoveryCode(ctx context.Context, adminID, codeHash string, at time.Time) (bool, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	codes := s.adminRecoveryCodes[adminID]
+	used, ok := codes[codeHash]
+	if !ok || used {
+		return false, nil
+	}
+	codes[codeHash] = true
+	return true, nil
+}
+
+func (s *MemStore) RecordAdminMFAFailure(ctx context.Context, adminID string, now time.Time, maxFailures int, lockout time.Duration) (bool, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	admin, ok := s.admins[adminID]
+	if !ok {
+		return false, ErrAdminNotFound
+	}
+	recordAdminMFAFailure(admin, now, maxFailures, lockout)
+	return admin.MFALocked(now), nil
+}
+
+func (s *MemStore) ClearAdminMFAFailures(ctx context.Context, adminID string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	admin, ok := s.admins[adminID]
+	if !ok {
+		return ErrAdminNotFound
+	}
+	admin.MFAFailures = 0
+	admin.MFALastFailureAt = time.Time{}
+	return nil
+}
@@ -0,0 +1,92 @@
+package store
+
+import "context"
+
+// GetPostReactionsFor returns anonID's reaction to each of postIDs that it
+// has reacted to.
+func (s *MemStore) GetPostReactionsFor(ctx context.Context, anonID string, postIDs []string) (map[string]string, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	out := make(map[string]string, len(postIDs))
+	for _, id := range postIDs {
+		if reaction, ok := s.postReactions[id][anonID]; ok {
+			out[id] = reaction.ReactionType
+		}
+	}
+	return out, nil
+}
+
+// GetCommentReactionsFor returns anonID's reaction to each of commentIDs
+// that it has reacted to.
+func (s *MemStore) GetCommentReactionsFor(ctx context.Context, anonID string, commentIDs []string) (map[string]string, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	out := make(map[string]string, len(commentIDs))
+	for _, id := range commentIDs {
+		if reaction, ok := s.commentReacts[id][anonID]; ok {
+			out[id] = reaction.ReactionType
+		}
+	}
+	return out, nil
+}
+
+// GetReplyReactionsFor returns anonID's reaction to each of replyIDs that
+// it has reacted to.
+func (s *MemStore) GetReplyReactionsFor(ctx context.Context, anonID string, replyIDs []string) (map[string]string, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	out := make(map[string]string, len(replyIDs))
+	for _, id := range replyIDs {
+		if reaction, ok := s.replyReacts[id][anonID]; ok {
+			out[id] = reaction.ReactionType
+		}
+	}
+	return out, nil
+}
+
+// GetReplyCounts returns the number of non-deleted replies of each of
+// commentIDs that has any.
+func (s *MemStore) GetReplyCounts(ctx context.Context, commentIDs []string) (map[string]int, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	out := make(map[string]int, len(commentIDs))
+	for _, id := range commentIDs {
+		for _, r := range s.commentReplies[id] {
+			if !r.Deleted {
+				out[id]++
+			}
+		}
+	}
+	return out, nil
+}
+
+// GetUsernames returns the username of each of anonIDs that has a device,
+// taken from its first device like GetDeviceByAnonID.
+func (s *MemStore) GetUsernames(ctx context.Context, anonIDs []string) (map[string]string, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	want := make(map[string]bool, len(anonIDs))
+	for _, id := range anonIDs {
+		want[id] = true
+	}
+	first := make(map[string]*Device, len(anonIDs))
+	for _, device := range s.devices {
+		if !want[device.AnonID] {
+			continue
+		}
+		if d, ok := first[device.AnonID]; !ok || device.CreatedAt.Before(d.CreatedAt) {
+			first[device.AnonID] = device
+		}
+	}
+
+	out := make(map[string]string, len(first))
+	for anonID, device := range first {
+		out[anonID] = device.Username
+	}
+	return out, nil
+}
@@ -0,0 +1,194 @@
+package store
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"time"
+)
+
+func (s *MemStore) PutChatMessage(ctx context.Context, msg *ChatMessage) error {
+	if msg.ID == "" || msg.RoomID == "" {
+		return fmt.Errorf("chat message id and room id required")
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if msg.ClientID != "" {
+		if _, ok := s.findChatMessageByClientIDUnsafe(msg.FromAnon, msg.ClientID); ok {
+			return ErrDuplicateChatMessage
+		}
+	}
+	if msg.Kind == "" {
+		msg.Kind = ChatKindText
+	}
+	if msg.CreatedAt.IsZero() {
+		msg.CreatedAt = time.Now().UTC()
+	}
+	cp := *msg
+	s.chatMessages[msg.RoomID] = append(s.chatMessages[msg.RoomID], &cp)
+	return nil
+}
+
+func (s *MemStore) GetChatMessageByClientID(ctx context.Context, fromAnon, clientID string) (*ChatMessage, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	m, ok := s.findChatMessageByClientIDUnsafe(fromAnon, clientID)
+	if !ok {
+		return nil, ErrChatMessageNotFound
+	}
+	cp := *m
+	return &cp, nil
+}
+
+func (s *MemStore) findChatMessageByClientIDUnsafe(fromAnon, clientID string) (*ChatMessage, bool) {
+	for _, msgs := range s.chatMessages {
+		for _, m := range msgs {
+			if m.FromAnon == fromAnon && m.ClientID == clientID {
+				return m, true
+			}
+		}
+	}
+	return nil, false
+}
+
+// GetChatHistory returns up to limit messages older than before, newest first.
+func (s *MemStore) GetChatHistory(ctx context.Context, roomID string, before *Cursor, limit int) ([]*ChatMessage, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	msgs := s.chatMessages[roomID]
+	out := make([]*ChatMessage, 0, limit)
+	// messages are appended in send order, so walk backwards for newest first
+	for i := len(msgs) - 1; i >= 0 && len(out) < limit; i-- {
+		m := msgs[i]
+		if !before.before(m.CreatedAt, m.ID) {
+			continue
+		}
+		cp := *m
+		out = append(out, &cp)
+	}
+	return out, nil
+}
+
+// GetUndeliveredChatMessages returns messages addressed to toAnon that have
+// not reached any of its sockets yet, oldest first. An empty roomID covers
+// every conversation of toAnon.
+func (s *MemStore) GetUndeliveredChatMessages(ctx context.Context, roomID, toAnon string) ([]*ChatMessage, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	out := []*ChatMessage{}
+	for room, msgs := range s.chatMessages {
+		if roomID != "" && room != roomID {
+			continue
+		}
+		for _, m := range msgs {
+			if m.ToAnon != toAnon || m.DeliveredAt != nil {
+				continue
+			}
+			cp := *m
+			out = append(out, &cp)
+		}
+	}
+	sort.SliceStable(out, func(i, j int) bool {
+		if out[i].CreatedAt.Equal(out[j].CreatedAt) {
+			return out[i].ID < out[j].ID
+		}
+		return out[i].CreatedAt.Before(out[j].CreatedAt)
+	})
+	return out, nil
+}
+
+// MarkChatMessagesDelivered marks the given messages as delivered unless
+// they already were. It returns the ids that changed state, so that only
+// one of several concurrent callers reports a delivery.
+func (s *MemStore) MarkChatMessagesDelivered(ctx context.Context, ids []string, at time.Time) ([]string, error) {
+	if len(ids) == 0 {
+		return []string{}, nil
+	}
+
+	wanted := make(map[string]struct{}, len(ids))
+	for _, id := range ids {
+		wanted[id] = struct{}{}
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	updated := []string{}
+	for _, msgs := range s.chatMessages {
+		for _, m := range msgs {
+			if _, ok := wanted[m.ID]; !ok || m.DeliveredAt != nil {
+				continue
+			}
+			atCopy := at
+			m.DeliveredAt = &atCopy
+			updated = append(updated, m.ID)
+		}
+	}
+	return updated, nil
+}
+
+// MarkChatMessagesRead marks the given messages in roomID that were sent to
+// readerAnon as read (and delivered, if they were not yet). It returns the ids
+// that changed state.
+func (s *MemStore) MarkChatMessagesRead(ctx context.Context, roomID, readerAnon string, ids []string, at time.Time) ([]string, error) {
+	if len(ids) == 0 {
+		return []string{}, nil
+	}
+
+	wanted := make(map[string]struct{}, len(ids))
+	for _, id := range ids {
+		wanted[id] = struct{}{}
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	updated := []string{}
+	for _, m := range s.chatMessages[roomID] {
+		if _, ok := wanted[m.ID]; !ok || m.ToAnon != readerAnon || m.ReadAt != nil {
+			continue
+		}
+		atCopy := at
+		m.ReadAt = &atCopy
+		if m.DeliveredAt == nil {
+			m.DeliveredAt = &atCopy
+		}
+		updated = append(updated, m.ID)
+	}
+	return updated, nil
+}
+
+// RedeemWSTicket records ticketID as used, reporting false if it already was
+// or has expired.
+func (s *MemStore) RedeemWSTicket(ctx context.Context, ticketID string, expiresAt, now time.Time) (bool, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if !expiresAt.After(now) {
+		return false, nil
+	}
+	if _, used := s.wsTickets[ticketID]; used {
+		return false, nil
+	}
+	s.wsTickets[ticketID] = expiresAt
+	return true, nil
+}
+
+func (s *MemStore) CleanupWSTickets(ctx context.Context, now time.Time) (int, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	n := 0
+	for id, expiresAt := range s.wsTickets {
+		if !expiresAt.After(now) {
+			delete(s.wsTickets, id)
+			n++
+		}
+	}
+	return n, nil
+}
@@ -0,0 +1,80 @@
+package store
+
+import (
+	"context"
+	"sort"
+	"time"
+)
+
+// PutDeviceKeys replaces the identity and signed prekey of a device and adds
+// oneTime to its pool, skipping key ids it already has.
+func (s *MemStore) PutDeviceKeys(ctx context.Context, bundle *DeviceKeyBundle, oneTime []OneTimePrekey) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	device, ok := s.devices[bundle.DevicePublicID]
+	if !ok || device.AnonID != bundle.AnonID {
+		return ErrDeviceNotFound
+	}
+
+	now := bundle.UpdatedAt
+	if now.IsZero() {
+		now = time.Now()
+	}
+	cp := *bundle
+	cp.OneTimePrekey = nil
+	cp.UpdatedAt = now
+	if existing, ok := s.deviceKeys[bundle.DevicePublicID]; ok {
+		cp.CreatedAt = existing.CreatedAt
+	} else if cp.CreatedAt.IsZero() {
+		cp.CreatedAt = now
+	}
+	s.deviceKeys[bundle.DevicePublicID] = &cp
+
+	pool := s.oneTimePrekeys[bundle.DevicePublicID]
+	seen := make(map[int]struct{}, len(pool))
+	for _, k := range pool {
+		seen[k.KeyID] = struct{}{}
+	}
+	for _, k := range oneTime {
+		if _, dup := seen[k.KeyID]; dup {
+			continue
+		}
+		seen[k.KeyID] = struct{}{}
+		pool = append(pool, k)
+	}
+	s.oneTimePrekeys[bundle.DevicePublicID] = pool
+	return nil
+}
+
+// ClaimDeviceKeys returns a bundle for every device of anonID that published
+// keys, each with one one-time prekey removed from its pool when available.
+func (s *MemStore) ClaimDeviceKeys(ctx context.Context, anonID string) ([]*DeviceKeyBundle, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	out := []*DeviceKeyBundle{}
+	for devicePublicID, bundle := range s.deviceKeys {
+		if bundle.AnonID != anonID {
+			continue
+		}
+		cp := *bundle
+		if pool := s.oneTimePrekeys[devicePublicID]; len(pool) > 0 {
+			k := pool[0]
+			cp.OneTimePrekey = &k
+			s.oneTimePrekeys[devicePublicID] = pool[1:]
+		}
+		out = append(out, &cp)
+	}
+	if len(out) == 0 {
+		return nil, ErrDeviceKeysNotFound
+	}
+	sort.Slice(out, func(i, j int) bool { return out[i].DevicePublicID < out[j].DevicePublicID })
+	return out, nil
+}
+
+func (s *MemStore) CountOneTimePrekeys(ctx context.Context, devicePublicID string) (int, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return len(s.oneTimePrekeys[devicePublicID]), nil
+}
@@ -1,13 +1,14 @@
 package store
 
 import (
+	"context"
 	"fmt"
 	"sort"
 	"strings"
 	"time"
 )
 
-func (s *MemStore) EnsureProfileForAnon(anonID, region string, now time.Time) error {
+func (s *MemStore) EnsureProfileForAnon(ctx context.Context, anonID, region string, now time.Time) error {
 	s.mu.Lock()
 	defer s.mu.Unlock()
 
@@ -54,7 +55,7 @@ func (s *MemStore) EnsureProfileForAnon(anonID, region string, now time.Time) er
 	return nil
 }
 
-func (s *MemStore) IsUsernameAvailable(username string, excludeAnonID string) (bool, error) {
+func (s *MemStore) IsUsernameAvailable(ctx context.Context, username string, excludeAnonID string) (bool, error) {
 	s.mu.RLock()
 	defer s.mu.RUnlock()
 	return s.isUsernameAvailableUnsafe(username, excludeAnonID), nil
@@ -112,20 +113,7 @@ func (s *MemStore) recomputeProfileDerivedFieldsUnsafe(anonID string) {
 		reactionsCount += len(reacts)
 	}
 
-	reportCount := 0
-	for postID, byReporter := range s.postReports {
-		post, ok := s.getPostByID(postID)
-		if !ok || post.AnonID != anonID {
-			continue
-		}
-		reportCount += len(byReporter)
-	}
-	for _, byReporter := range s.profileReportsByTarget[anonID] {
-		if byReporter.CreatedAt.IsZero() {
-			continue
-		}
-		reportCount++
-	}
+	reportCount := s.userReportCountUnsafe(anonID)
 
 	user.PostsCount = postsCount
 	user.CommentsCount = commentsCount
@@ -133,7 +121,7 @@ func (s *MemStore) recomputeProfileDerivedFieldsUnsafe(anonID string) {
 	user.StatusLabel = DeriveStatusLabel(reportCount)
 }
 
-func (s *MemStore) GetProfileByAnonID(anonID string) (*UserProfile, error) {
+func (s *MemStore) GetProfileByAnonID(ctx context.Context, anonID string) (*UserProfile, error) {
 	s.mu.Lock()
 	defer s.mu.Unlock()
 
@@ -166,7 +154,7 @@ func (s *MemStore) GetProfileByAnonID(anonID string) (*UserProfile, error) {
 	return profile, nil
 }
 
-func (s *MemStore) UpdateProfile(anonID string, in ProfileUpdateInput, now time.Time) (*UserProfile, error) {
+func (s *MemStore) UpdateProfile(ctx context.Context, anonID string, in ProfileUpdateInput, now time.Time) (*UserProfile, error) {
 	s.mu.Lock()
 	defer s.mu.Unlock()
 
@@ -190,9 +178,11 @@ func (s *MemStore) UpdateProfile(anonID string, in ProfileUpdateInput, now time.
 			t := now
 			user.UsernameChangedAt = &t
 
-			if device, err := s.getDeviceByAnonIDUnsafe(anonID); err == nil {
-				device.Username = full
-				device.UpdatedAt = now
+			for _, device := range s.devices {
+				if device.AnonID == anonID {
+					device.Username = full
+					device.UpdatedAt = now
+				}
 			}
 		}
 	}
@@ -224,7 +214,7 @@ func (s *MemStore) UpdateProfile(anonID string, in ProfileUpdateInput, now time.
 	}, nil
 }
 
-func (s *MemStore) IncrementProfileView(targetAnonID, viewerAnonID string) error {
+func (s *MemStore) IncrementProfileView(ctx context.Context, targetAnonID, viewerAnonID string) error {
 	if targetAnonID == "" || targetAnonID == viewerAnonID {
 		return nil
 	}
@@ -240,7 +230,7 @@ func (s *MemStore) IncrementProfileView(targetAnonID, viewerAnonID string) error
 	return nil
 }
 
-func (s *MemStore) GetProfileDeviceInfo(anonID string) (*ProfileDeviceInfo, error) {
+func (s *MemStore) GetProfileDeviceInfo(ctx context.Context, anonID string) (*ProfileDeviceInfo, error) {
 	s.mu.RLock()
 	defer s.mu.RUnlock()
 
@@ -249,8 +239,7 @@ func (s *MemStore) GetProfileDeviceInfo(anonID string) (*ProfileDeviceInfo, erro
 		return nil, ErrProfileNotFound
 	}
 
-	device, err := s.getDeviceByAnonIDUnsafe(anonID)
-	if err != nil {
+	if _, err := s.getDeviceByAnonIDUnsafe(anonID); err != nil {
 		return nil, err
 	}
 
@@ -259,10 +248,16 @@ func (s *MemStore) GetProfileDeviceInfo(anonID string) (*ProfileDeviceInfo, erro
 		t := *user.LastSeenAt
 		latestActive = &t
 	}
-	if latestActive == nil || device.UpdatedAt.After(*latestActive) {
-		t := device.UpdatedAt
-		latestActive = &t
+	for _, device := range s.devices {
+		if device.AnonID != anonID {
+			continue
+		}
+		if latestActive == nil || device.UpdatedAt.After(*latestActive) {
+			t := device.UpdatedAt
+			latestActive = &t
+		}
 	}
+	_, hasRecoveryKey := s.recoveryKeys[anonID]
 
 	hasActiveSession := false
 	now := time.Now()
@@ -281,12 +276,12 @@ func (s *MemStore) GetProfileDeviceInfo(anonID string) (*ProfileDeviceInfo, erro
 	return &ProfileDeviceInfo{
 		PrimaryDeviceActive:  hasActiveSession,
 		LastActiveAt:         latestActive,
-		RecoveryKeyGenerated: strings.TrimSpace(device.DeviceSecretHash) != "",
+		RecoveryKeyGenerated: hasRecoveryKey,
 		SessionStatus:        status,
 	}, nil
 }
 
-func (s *MemStore) ReportProfile(reporterAnonID, targetUserAnonID, reason string, now time.Time) error {
+func (s *MemStore) ReportProfile(ctx context.Context, reporterAnonID, targetUserAnonID, reason string, now time.Time) error {
 	s.mu.Lock()
 	defer s.mu.Unlock()
 
@@ -306,44 +301,59 @@ func (s *MemStore) ReportProfile(reporterAnonID, targetUserAnonID, reason string
 	return nil
 }
 
-func (s *MemStore) ReportPostV2(reporterAnonID, targetUserAnonID, targetPostID, reason string, now time.Time) error {
-	s.mu.RLock()
-	if s.postReports != nil {
-		if reporters, ok := s.postReports[targetPostID]; ok {
-			if _, exists := reporters[reporterAnonID]; exists {
-				s.mu.RUnlock()
-				return ErrAlreadyReported
-			}
-		}
+// ReportPostV2 records a user-facing post report. These are kept apart from
+// the moderation counters ReportPost maintains, as the reports table is in
+// Postgres.
+func (s *MemStore) ReportPostV2(ctx context.Context, reporterAnonID, targetUserAnonID, targetPostID, reason string, now time.Time) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.userPostReports[targetPostID] == nil {
+		s.userPostReports[targetPostID] = make(map[string]postReportMeta)
 	}
-	s.mu.RUnlock()
-	return s.ReportPost(targetPostID, targetUserAnonID, reporterAnonID, reason, now)
+	if _, exists := s.userPostReports[targetPostID][reporterAnonID]; exists {
+		return ErrAlreadyReported
+	}
+	s.userPostReports[targetPostID][reporterAnonID] = postReportMeta{
+		Target:    targetUserAnonID,
+		Reason:    strings.TrimSpace(reason),
+		CreatedAt: now,
+	}
+	return nil
 }
 
-func (s *MemStore) GetUserReportCount(targetAnonID string) (int, error) {
+func (s *MemStore) GetUserReportCount(ctx context.Context, targetAnonID string) (int, error) {
 	s.mu.RLock()
 	defer s.mu.RUnlock()
+	return s.userReportCountUnsafe(targetAnonID), nil
+}
 
-	count := 0
-	if s.profileReportsByTarget != nil {
-		count += len(s.profileReportsByTarget[targetAnonID])
-	}
-	for postID, byReporter := range s.postReports {
-		post, ok := s.getPostByID(postID)
-		if ok && post.AnonID == targetAnonID {
-			count += len(byReporter)
+// userReportCountUnsafe counts profile and post reports against anonID.
+func (s *MemStore) userReportCountUnsafe(anonID string) int {
+	count := len(s.profileReportsByTarget[anonID])
+	for _, byReporter := range s.userPostReports {
+		for _, report := range byReporter {
+			if report.Target == anonID {
+				count++
+			}
 		}
 	}
-	return count, nil
+	return count
 }
 
+// getDeviceByAnonIDUnsafe returns the identity's first device; linked
+// devices share its anon id.
 func (s *MemStore) getDeviceByAnonIDUnsafe(anonID string) (*Device, error) {
+	var first *Device
 	for _, device := range s.devices {
-		if device.AnonID == anonID {
-			return device, nil
+		if device.AnonID == anonID && (first == nil || device.CreatedAt.Before(first.CreatedAt)) {
+			first = device
 		}
 	}
-	return nil, fmt.Errorf("device not found")
+	if first == nil {
+		return nil, ErrDeviceNotFound
+	}
+	return first, nil
 }
 
 func (s *MemStore) getCommentByIDUnsafe(commentID string) (*PostComment, bool) {
@@ -389,10 +399,13 @@ func (s *MemStore) getReplyByIDUnsafe(replyID string) (*CommentReply, bool) {
 	return nil, false
 }
 
-func (s *MemStore) GetPostsByAnonIDSorted(anonID string) []*Post {
-	posts := s.GetPostsByAnonID(anonID, 0)
+func (s *MemStore) GetPostsByAnonIDSorted(ctx context.Context, anonID string) ([]*Post, error) {
+	posts, err := s.GetPostsByAnonID(ctx, anonID, 0)
+	if err != nil {
+		return nil, err
+	}
 	sort.Slice(posts, func(i, j int) bool {
 		return posts[i].CreatedAt.After(posts[j].CreatedAt)
 	})
-	return posts
+	return posts, nil
 }
@@ -0,0 +1,119 @@
+package store
+
+import (
+	"context"
+	"strconv"
+	"time"
+
+	"anon-backend/internal/config"
+)
+
+// memQuotaCount is one window's counter; it is dropped once the window
+// has passed.
+type memQuotaCount struct {
+	count   int
+	resetAt time.Time
+}
+
+func memQuotaKey(anonID, resource string, l config.QuotaLimit, now time.Time) string {
+	start := QuotaWindowStart(l.Window, now)
+	return anonID + "|" + resource + "|" + l.Window + "|" + strconv.FormatInt(start.Unix(), 10)
+}
+
+func (s *MemStore) TakeQuota(ctx context.Context, anonID, resource string, limits []config.QuotaLimit, now time.Time) (QuotaResult, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.takeQuotaLocked(anonID, resource, limits, now), nil
+}
+
+// CreatePostWithQuota counts the post against the posts quota and stores it
+// under one lock, so concurrent posts cannot overshoot the limit.
+func (s *MemStore) CreatePostWithQuota(ctx context.Context, p *Post, limits []config.QuotaLimit, now time.Time) (QuotaResult, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	res := s.takeQuotaLocked(p.AnonID, config.QuotaPosts, limits, now)
+	if !res.Allowed {
+		return res, &QuotaExceededError{Resource: config.QuotaPosts, Result: res}
+	}
+	s.insertPostLocked(p)
+	return res, nil
+}
+
+// AddCommentWithQuota stores the comment and counts it against the comments
+// quota under one lock. A comment on a missing post is not counted.
+func (s *MemStore) AddCommentWithQuota(ctx context.Context, c *PostComment, limits []config.QuotaLimit, now time.Time) (QuotaResult, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if !s.postExistsLocked(c.PostID) {
+		return QuotaResult{}, ErrPostNotFound
+	}
+	res := s.takeQuotaLocked(c.AnonID, config.QuotaComments, limits, now)
+	if !res.Allowed {
+		return res, &QuotaExceededError{Resource: config.QuotaComments, Result: res}
+	}
+	s.postComments[c.PostID] = append(s.postComments[c.PostID], c)
+	return res, nil
+}
+
+// AddCommentReplyWithQuota stores the reply and counts it against the
+// replies quota under one lock. A reply to a missing comment is not
+// counted.
+func (s *MemStore) AddCommentReplyWithQuota(ctx context.Context, r *CommentReply, limits []config.QuotaLimit, now time.Time) (QuotaResult, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if !s.commentExistsLocked(r.CommentID) {
+		return QuotaResult{}, ErrCommentNotFound
+	}
+	res := s.takeQuotaLocked(r.AnonID, config.QuotaReplies, limits, now)
+	if !res.Allowed {
+		return res, &QuotaExceededError{Resource: config.QuotaReplies, Result: res}
+	}
+	s.commentReplies[r.CommentID] = append(s.commentReplies[r.CommentID], r)
+	return res, nil
+}
+
+// takeQuotaLocked expects s.mu to be held for writing.
+func (s *MemStore) takeQuotaLocked(anonID, resource string, limits []config.QuotaLimit, now time.Time) QuotaResult {
+	keys := make([]string, len(limits))
+	used := make([]int, len(limits))
+	for i, l := range limits {
+		keys[i] = memQuotaKey(anonID, resource, l, now)
+		used[i] = s.quotaCounts[keys[i]].count
+	}
+
+	res := takeQuota(limits, used, now)
+	if res.Allowed {
+		for i, key := range keys {
+			s.quotaCounts[key] = memQuotaCount{count: used[i], resetAt: res.Usage[i].ResetAt}
+		}
+	}
+	return res
+}
+
+func (s *MemStore) GetQuotaUsage(ctx context.Context, anonID, resource string, limits []config.QuotaLimit, now time.Time) ([]QuotaUsage, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	used := make([]int, len(limits))
+	for i, l := range limits {
+		used[i] = s.quotaCounts[memQuotaKey(anonID, resource, l, now)].count
+	}
+	return quotaUsage(limits, used, now), nil
+}
+
+func (s *MemStore) CleanupQuotaUsage(ctx context.Context, now time.Time) (int, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	n := 0
+	for key, c := range s.quotaCounts {
+		if !c.resetAt.After(now) {
+			delete(s.quotaCounts, key)
+			n++
+		}
+	}
+	return n, nil
+}
@@ -0,0 +1,98 @@
+package store
+
+import (
+	"context"
+	"sort"
+	"time"
+)
+
+// GetTrendingPosts returns a page of the posts ranked by the last
+// ReplacePostScores, best first. Posts scored since deleted are skipped.
+func (s *MemStore) GetTrendingPosts(ctx context.Context, q TrendingQuery) ([]PostWithStats, error) {
+	since, err := q.normalize(time.Now())
+	if err != nil {
+		return nil, err
+	}
+
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	out := make([]PostWithStats, 0, len(s.postScores))
+	for _, p := range s.posts {
+		score, ok := s.postScores[p.ID]
+		if !ok || p.Deleted || p.CreatedAt.Before(since) {
+			continue
+		}
+		out = append(out, PostWithStats{
+			Post:         *p,
+			LikeCount:    p.Likes,
+			DislikeCount: p.Dislikes,
+			CommentCount: score.Comments,
+			HotScore:     score.Hot,
+			RisingScore:  score.Rising,
+			TopScore:     score.Top,
+		})
+	}
+
+	rank := func(p PostWithStats) float64 {
+		switch q.Algo {
+		case TrendingRising:
+			return p.RisingScore
+		case TrendingTop:
+			return p.TopScore
+		}
+		return p.HotScore
+	}
+	// s.posts is newest first already, which breaks ties
+	sort.SliceStable(out, func(i, j int) bool { return rank(out[i]) > rank(out[j]) })
+
+	if q.Offset >= len(out) {
+		return []PostWithStats{}, nil
+	}
+	end := q.Offset + q.Limit
+	if end > len(out) {
+		end = len(out)
+	}
+	return out[q.Offset:end], nil
+}
+
+// GetPostSignals returns the engagement counts of every non-deleted post
+// created at or after since.
+func (s *MemStore) GetPostSignals(ctx context.Context, since time.Time) ([]PostSignals, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	out := []PostSignals{}
+	for _, p := range s.posts {
+		if p.Deleted || p.CreatedAt.Before(since) {
+			continue
+		}
+		sig := PostSignals{
+			PostID:    p.ID,
+			CreatedAt: p.CreatedAt,
+			Likes:     p.Likes,
+			Dislikes:  p.Dislikes,
+			Reports:   len(s.postReports[p.ID]),
+		}
+		for _, c := range s.postComments[p.ID] {
+			if !c.Deleted {
+				sig.Comments++
+			}
+		}
+		out = append(out, sig)
+	}
+	return out, nil
+}
+
+// ReplacePostScores makes scores the whole ranking; posts without a score
+// drop out of GetTrendingPosts.
+func (s *MemStore) ReplacePostScores(ctx context.Context, scores []PostScore) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.postScores = make(map[string]PostScore, len(scores))
+	for _, score := range scores {
+		s.postScores[score.PostID] = score
+	}
+	return nil
+}
@@ -0,0 +1,51 @@
+package store
+
+import (
+	"context"
+	"sort"
+	"time"
+)
+
+func (s *MemStore) TakeRateLimitToken(ctx context.Context, policy, key string, capacity int, window time.Duration, now time.Time) (RateLimitResult, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	id := policy + "|" + key
+	b, ok := s.rateLimits[id]
+	if !ok {
+		b = &RateLimitBucket{Policy: policy, Key: key}
+		s.rateLimits[id] = b
+	}
+	return takeRateLimitToken(b, capacity, window, now), nil
+}
+
+func (s *MemStore) ListRateLimitedBuckets(ctx context.Context, since time.Time, limit int) ([]RateLimitBucket, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	out := make([]RateLimitBucket, 0)
+	for _, b := range s.rateLimits {
+		if b.LastLimitedAt != nil && !b.LastLimitedAt.Before(since) {
+			out = append(out, *b)
+		}
+	}
+	sort.Slice(out, func(i, j int) bool { return out[i].LastLimitedAt.After(*out[j].LastLimitedAt) })
+	if len(out) > limit {
+		out = out[:limit]
+	}
+	return out, nil
+}
+
+func (s *MemStore) CleanupRateLimitBuckets(ctx context.Context, idleSince time.Time) (int, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	n := 0
+	for id, b := range s.rateLimits {
+		if b.UpdatedAt.Before(idleSince) {
+			delete(s.rateLimits, id)
+			n++
+		}
+	}
+	return n, nil
+}
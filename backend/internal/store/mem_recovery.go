@@ -0,0 +1,148 @@
+package store
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"time"
+)
+
+// PutRecoveryKey stores keyHash as anonID's recovery key, replacing any
+// previous one.
+func (s *MemStore) PutRecoveryKey(ctx context.Context, anonID, keyHash string, now time.Time) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for owner, hash := range s.recoveryKeys {
+		if hash == keyHash && owner != anonID {
+			return fmt.Errorf("recovery key collision")
+		}
+	}
+	s.recoveryKeys[anonID] = keyHash
+	return nil
+}
+
+// RedeemRecoveryKey adds device to the identity whose recovery key has
+// keyHash and consumes the key. Keys are single use; a device that cannot
+// join leaves the key unused.
+func (s *MemStore) RedeemRecoveryKey(ctx context.Context, keyHash string, device *Device, now time.Time) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for anonID, hash := range s.recoveryKeys {
+		if hash == keyHash {
+			if err := s.joinIdentityLocked(anonID, device, now); err != nil {
+				return err
+			}
+			delete(s.recoveryKeys, anonID)
+			return nil
+		}
+	}
+	return ErrRecoveryKeyInvalid
+}
+
+// joinIdentityLocked creates device as a device of anonID, with its
+// username unless one is set, unless anonID is banned.
+func (s *MemStore) joinIdentityLocked(anonID string, device *Device, now time.Time) error {
+	device.AnonID = anonID
+	if s.activeBanLocked(anonID, now) != nil {
+		return ErrIdentityBanned
+	}
+	if device.Username == "" {
+		if user, ok := s.users[anonID]; ok && user.Username != "" {
+			device.Username = user.Username
+		} else {
+			var first *Device
+			for _, d := range s.devices {
+				if d.AnonID == anonID && (first == nil || d.CreatedAt.Before(first.CreatedAt)) {
+					first = d
+				}
+			}
+			if first != nil {
+				device.Username = first.Username
+			}
+		}
+	}
+	return s.createDeviceLocked(device)
+}
+
+func (s *MemStore) CreateDeviceLink(ctx context.Context, link *DeviceLink) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if _, exists := s.deviceLinks[link.Code]; exists {
+		return fmt.Errorf("device link already exists")
+	}
+	if link.CreatedAt.IsZero() {
+		link.CreatedAt = time.Now()
+	}
+	copy := *link
+	s.deviceLinks[link.Code] = &copy
+	return nil
+}
+
+func (s *MemStore) GetDeviceLink(ctx context.Context, code string) (*DeviceLink, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	link, ok := s.deviceLinks[code]
+	if !ok {
+		return nil, ErrDeviceLinkNotFound
+	}
+	copy := *link
+	return &copy, nil
+}
+
+// ApproveDeviceLink binds a pending, unexpired link to anonID.
+func (s *MemStore) ApproveDeviceLink(ctx context.Context, code, anonID string, now time.Time) (*DeviceLink, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	link, ok := s.deviceLinks[code]
+	if !ok || link.ApprovedAt != nil || !link.ExpiresAt.After(now) {
+		return nil, ErrDeviceLinkNotFound
+	}
+	approvedAt := now
+	link.AnonID = anonID
+	link.ApprovedAt = &approvedAt
+
+	copy := *link
+	return &copy, nil
+}
+
+// ConsumeDeviceLink redeems an approved link for device and adds device to
+// the identity that approved it. Links are single use; a device that
+// cannot join leaves the link unused.
+func (s *MemStore) ConsumeDeviceLink(ctx context.Context, code string, device *Device, now time.Time) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	link, ok := s.deviceLinks[code]
+	if !ok || link.DevicePublicID != device.DevicePublicID || link.ConsumedAt != nil || !link.ExpiresAt.After(now) {
+		return ErrDeviceLinkNotFound
+	}
+	if link.ApprovedAt == nil {
+		return ErrDeviceLinkPending
+	}
+	if err := s.joinIdentityLocked(link.AnonID, device, now); err != nil {
+		return err
+	}
+	consumedAt := now
+	link.ConsumedAt = &consumedAt
+	return nil
+}
+
+func (s *MemStore) GetDevicesByAnonID(ctx context.Context, anonID string) ([]*Device, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	out := make([]*Device, 0)
+	for _, device := range s.devices {
+		if device.AnonID == anonID {
+			copy := *device
+			out = append(out, &copy)
+		}
+	}
+	sort.Slice(out, func(i, j int) bool { return out[i].CreatedAt.Before(out[j].CreatedAt) })
+	return out, nil
+}
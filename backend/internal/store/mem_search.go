@@ -0,0 +1,523 @@
+package store
+
+import (
+	"context"
+	"sort"
+	"strings"
+	"unicode"
+)
+
+// searchIndex is MemStore's counterpart of the text_search GIN index: the
+// positions of each lexeme in each post.
+type searchIndex struct {
+	postings map[string]map[string][]int // lexeme -> post id -> positions
+	lexemes  []string                    // sorted, for prefix lookups
+}
+
+func newSearchIndex() *searchIndex {
+	return &searchIndex{postings: make(map[string]map[string][]int)}
+}
+
+// searchToken is one word of a text, numbered like to_tsvector numbers
+// them: from 1, stop words included.
+type searchToken struct {
+	word       string // lowercased
+	start, end int    // byte offsets in the text
+	pos        int
+}
+
+func searchTokens(text string) []searchToken {
+	var toks []searchToken
+	start := -1
+	for i, r := range text {
+		inWord := unicode.IsLetter(r) || unicode.IsNumber(r)
+		if inWord && start < 0 {
+			start = i
+		}
+		if !inWord && start >= 0 {
+			toks = append(toks, searchToken{word: strings.ToLower(text[start:i]), start: start, end: i, pos: len(toks) + 1})
+			start = -1
+		}
+	}
+	if start >= 0 {
+		toks = append(toks, searchToken{word: strings.ToLower(text[start:]), start: start, end: len(text), pos: len(toks) + 1})
+	}
+	return toks
+}
+
+// lexeme returns what word is indexed as, or "" for a stop word.
+func lexeme(word string) string {
+	if stopWords[word] {
+		return ""
+	}
+	return stem(word)
+}
+
+func (ix *searchIndex) add(p *Post) {
+	for _, tok := range searchTokens(p.Text) {
+		lex := lexeme(tok.word)
+		if lex == "" {
+			continue
+		}
+		posts, ok := ix.postings[lex]
+		if !ok {
+			posts = make(map[string][]int)
+			ix.postings[lex] = posts
+			i := sort.SearchStrings(ix.lexemes, lex)
+			ix.lexemes = append(ix.lexemes, "")
+			copy(ix.lexemes[i+1:], ix.lexemes[i:])
+			ix.lexemes[i] = lex
+		}
+		posts[p.ID] = append(posts[p.ID], tok.pos)
+	}
+}
+
+func (ix *searchIndex) remove(p *Post) {
+	for _, tok := range searchTokens(p.Text) {
+		lex := lexeme(tok.word)
+		posts, ok := ix.postings[lex]
+		if !ok {
+			continue
+		}
+		delete(posts, p.ID)
+		if len(posts) == 0 {
+			delete(ix.postings, lex)
+			i := sort.SearchStrings(ix.lexemes, lex)
+			ix.lexemes = append(ix.lexemes[:i], ix.lexemes[i+1:]...)
+		}
+	}
+}
+
+// lookup returns the positions of word in each post containing it. A
+// prefix word matches every lexeme it begins. The result must not be
+// modified.
+func (ix *searchIndex) lookup(word string, prefix bool) map[string][]int {
+	lex := stem(word)
+	if !prefix {
+		return ix.postings[lex]
+	}
+	merged := make(map[string][]int)
+	for i := sort.SearchStrings(ix.lexemes, lex); i < len(ix.lexemes) && strings.HasPrefix(ix.lexemes[i], lex); i++ {
+		for id, positions := range ix.postings[ix.lexemes[i]] {
+			merged[id] = append(merged[id], positions...)
+		}
+	}
+	for _, positions := range merged {
+		sort.Ints(positions)
+	}
+	return merged
+}
+
+// termHits returns, for each post t matches, the positions of the first
+// word of t that is not a stop word wherever t matches.
+func (ix *searchIndex) termHits(t searchTerm) map[string][]int {
+	var hits map[string][]int
+	first := -1
+	for i, w := range t.words {
+		if stopWords[w] {
+			continue
+		}
+		found := ix.lookup(w, t.prefix && i == len(t.words)-1)
+		if first < 0 {
+			first = t.offsets[i]
+			hits = found
+			continue
+		}
+		shift := t.offsets[i] - first
+		next := make(map[string][]int)
+		for id, starts := range hits {
+			at := found[id]
+			for _, p := range starts {
+				if j := sort.SearchInts(at, p+shift); j < len(at) && at[j] == p+shift {
+					next[id] = append(next[id], p)
+				}
+			}
+		}
+		hits = next
+	}
+	return hits
+}
+
+// SearchPosts returns a page of the posts matching q, best first, and how
+// many match in all. See SearchQuery for the semantics; the in-memory
+// ranking and stemming approximate Postgres' closely enough that both
+// stores return the same posts in the same order for ordinary queries.
+func (s *MemStore) SearchPosts(ctx context.Context, q SearchQuery) ([]*PostSearchResult, int, error) {
+	q.normalize()
+	terms := parseSearchTerms(q.Text)
+
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	if q.Text == "" && len(q.Hashtags) == 0 {
+		return []*PostSearchResult{}, 0, nil
+	}
+	results := s.searchLocked(q, terms)
+	if len(results) == 0 && q.Text != "" {
+		results = s.fuzzySearchLocked(q, terms)
+	}
+	// s.posts is newest first, so a stable sort breaks ties the same way
+	// Postgres does
+	sort.SliceStable(results, func(i, j int) bool {
+		return results[i].RelevanceScore > results[j].RelevanceScore
+	})
+
+	total := len(results)
+	if q.Offset >= total {
+		return []*PostSearchResult{}, total, nil
+	}
+	return results[q.Offset:min(q.Offset+q.Limit, total)], total, nil
+}
+
+// searchLocked finds the posts q's text or author match, or with no text,
+// every post carrying q's hashtags.
+func (s *MemStore) searchLocked(q SearchQuery, terms []searchTerm) []*PostSearchResult {
+	var searchable []searchTerm
+	var hits []map[string][]int
+	for _, t := range terms {
+		if t.searchable() {
+			searchable = append(searchable, t)
+			hits = append(hits, s.search.termHits(t))
+		}
+	}
+
+	author := normalizeSearchToken(q.Text)
+	usernames := make(map[string]string)
+	if author != "" {
+		first := make(map[string]*Device)
+		for _, d := range s.devices {
+			if f, ok := first[d.AnonID]; !ok || d.CreatedAt.Before(f.CreatedAt) {
+				first[d.AnonID] = d
+			}
+		}
+		for anonID, d := range first {
+			usernames[anonID] = normalizeSearchToken(d.Username)
+		}
+	}
+
+	var results []*PostSearchResult
+	for _, p := range s.posts {
+		if p.Deleted || !carriesTags(p, q.Hashtags) {
+			continue
+		}
+		if q.Text == "" {
+			results = append(results, &PostSearchResult{
+				Post:           p,
+				RelevanceScore: searchScoreText,
+				MatchedTerms:   matchedTerms("", q.Hashtags),
+				Highlights:     escapeHighlight(p.Text),
+			})
+			continue
+		}
+
+		textMatch := len(hits) > 0
+		positions := make([][]int, len(hits))
+		for k, h := range hits {
+			if positions[k] = h[p.ID]; len(positions[k]) == 0 {
+				textMatch = false
+				break
+			}
+		}
+		isAuthor := (author != "" && usernames[p.AnonID] == author) || strings.EqualFold(p.AnonID, q.Text)
+
+		var score float64
+		switch {
+		case isAuthor:
+			score = searchScoreAuthor
+		case textMatch:
+			rank := coverDensity(positions)
+			score = searchScoreText + rank/(rank+1)
+		default:
+			continue
+		}
+		highlights := escapeHighlight(p.Text)
+		if textMatch {
+			highlights = highlight(p.Text, searchable, positions)
+		}
+		results = append(results, &PostSearchResult{
+			Post:           p,
+			RelevanceScore: score,
+			MatchedTerms:   matchedTerms(highlights, q.Hashtags),
+			Highlights:     highlights,
+		})
+	}
+	return results
+}
+
+// fuzzySearchLocked finds the posts in which every word of terms has a
+// close enough trigram match.
+func (s *MemStore) fuzzySearchLocked(q SearchQuery, terms []searchTerm) []*PostSearchResult {
+	words := fuzzyWords(terms)
+	if len(words) == 0 {
+		return nil
+	}
+
+	var results []*PostSearchResult
+	for _, p := range s.posts {
+		if p.Deleted || !carriesTags(p, q.Hashtags) {
+			continue
+		}
+		text := trigrams(p.Text)
+		sum := 0.0
+		for _, w := range words {
+			sim := wordSimilarity(w, text)
+			if sim < searchFuzzyThreshold {
+				sum = -1
+				break
+			}
+			sum += sim
+		}
+		if sum < 0 {
+			continue
+		}
+		results = append(results, &PostSearchResult{
+			Post:           p,
+			RelevanceScore: sum / float64(len(words)),
+			MatchedTerms:   matchedTerms("", q.Hashtags),
+			Highlights:     escapeHighlight(p.Text),
+		})
+	}
+	return results
+}
+
+// carriesTags reports whether p carries every one of tags.
+func carriesTags(p *Post, tags []string) bool {
+	if len(tags) == 0 {
+		return true
+	}
+	have := make(map[string]bool)
+	for _, tag := range ExtractHashtags(p.Text) {
+		have[tag] = true
+	}
+	for _, tag := range tags {
+		if !have[tag] {
+			return false
+		}
+	}
+	return true
+}
+
+// coverDensity approximates ts_rank_cd. Each cover, a shortest stretch of
+// text holding a match of every term, adds 0.1 divided by one plus the
+// number of words in it that are not matches.
+func coverDensity(positions [][]int) float64 {
+	type event struct{ pos, term int }
+	var events []event
+	for k, ps := range positions {
+		for _, p := range ps {
+			events = append(events, event{p, k})
+		}
+	}
+	sort.Slice(events, func(i, j int) bool {
+		if events[i].pos == events[j].pos {
+			return events[i].term < events[j].term
+		}
+		return events[i].pos < events[j].pos
+	})
+
+	rank := 0.0
+	for start := 0; start < len(events); {
+		// the first end by which every term has matched
+		count := make([]int, len(positions))
+		seen, end := 0, -1
+		for j := start; j < len(events); j++ {
+			if count[events[j].term] == 0 {
+				seen++
+			}
+			count[events[j].term]++
+			if seen == len(positions) {
+				end = j
+				break
+			}
+		}
+		if end < 0 {
+			break
+		}
+		// the last begin that still holds every term
+		begin := start
+		for count[events[begin].term] > 1 {
+			count[events[begin].term]--
+			begin++
+		}
+
+		noise := (events[end].pos - events[begin].pos) - (end - begin)
+		if noise < 0 {
+			noise = 0
+		}
+		rank += 0.1 / float64(1+noise)
+		start = begin + 1
+	}
+	return rank
+}
+
+// highlight escapes text and wraps the words that terms matched at
+// positions, as ts_headline with HighlightAll does.
+func highlight(text string, terms []searchTerm, positions [][]int) string {
+	marked := make(map[int]bool)
+	for k, t := range terms {
+		first := -1
+		for i, w := range t.words {
+			if stopWords[w] {
+				continue
+			}
+			if first < 0 {
+				first = t.offsets[i]
+			}
+			for _, p := range positions[k] {
+				marked[p+t.offsets[i]-first] = true
+			}
+		}
+	}
+
+	var b strings.Builder
+	last := 0
+	for _, tok := range searchTokens(text) {
+		if !marked[tok.pos] {
+			continue
+		}
+		b.WriteString(escapeHighlight(text[last:tok.start]))
+		b.WriteString(highlightStart)
+		b.WriteString(escapeHighlight(text[tok.start:tok.end]))
+		b.WriteString(highlightStop)
+		last = tok.end
+	}
+	b.WriteString(escapeHighlight(text[last:]))
+	return b.String()
+}
+
+// trigrams returns the trigrams of each word of s in order, built as
+// pg_trgm builds them: lowercased, with two spaces before the word and
+// one after.
+func trigrams(s string) []string {
+	var out []string
+	for _, w := range searchWords(s) {
+		r := []rune("  " + w + " ")
+		for i := 0; i+3 <= len(r); i++ {
+			out = append(out, string(r[i:i+3]))
+		}
+	}
+	return out
+}
+
+// wordSimilarity is pg_trgm's word_similarity(word, text) given the
+// trigrams of text: the best similarity between the trigrams of word and
+// those of any stretch of text.
+func wordSimilarity(word string, text []string) float64 {
+	want := make(map[string]bool)
+	for _, t := range trigrams(word) {
+		want[t] = true
+	}
+
+	best := 0.0
+	// the best stretch starts and ends on a trigram of word
+	for i := range text {
+		if !want[text[i]] {
+			continue
+		}
+		seen := make(map[string]bool)
+		common := 0
+		for j := i; j < len(text); j++ {
+			if !seen[text[j]] {
+				seen[text[j]] = true
+				if want[text[j]] {
+					common++
+				}
+			}
+			if want[text[j]] {
+				if sim := float64(common) / float64(len(want)+len(seen)-common); sim > best {
+					best = sim
+				}
+			}
+		}
+	}
+	return best
+}
+
+// stem approximates the english snowball stemmer Postgres applies. Only
+// its first step is done, which undoes plurals, -ed, -ing and a final y;
+// words that differ in other suffixes (-ness, -ational, ...) are
+// conflated by Postgres alone.
+func stem(w string) string {
+	if len(w) <= 2 {
+		return w
+	}
+
+	switch {
+	case strings.HasSuffix(w, "sses"):
+		w = w[:len(w)-2]
+	case strings.HasSuffix(w, "ied"), strings.HasSuffix(w, "ies"):
+		if len(w) > 4 {
+			w = w[:len(w)-2]
+		} else {
+			w = w[:len(w)-1]
+		}
+	case strings.HasSuffix(w, "us"), strings.HasSuffix(w, "ss"):
+	case strings.HasSuffix(w, "s"):
+		if hasVowel(w[:len(w)-2]) {
+			w = w[:len(w)-1]
+		}
+	}
+
+	switch {
+	case strings.HasSuffix(w, "eed"):
+		if len(w)-3 >= r1(w) {
+			w = w[:len(w)-1]
+		}
+	case strings.HasSuffix(w, "ed"), strings.HasSuffix(w, "ing"):
+		base := strings.TrimSuffix(w, "ed")
+		if base == w {
+			base = strings.TrimSuffix(w, "ing")
+		}
+		if !hasVowel(base) {
+			break
+		}
+		w = base
+		switch {
+		case strings.HasSuffix(w, "at"), strings.HasSuffix(w, "bl"), strings.HasSuffix(w, "iz"):
+			w += "e"
+		case len(w) >= 2 && w[len(w)-1] == w[len(w)-2] && strings.IndexByte("bdfgmnprt", w[len(w)-1]) >= 0:
+			w = w[:len(w)-1]
+		case r1(w) >= len(w) && endsShortSyllable(w):
+			w += "e"
+		}
+	}
+
+	if n := len(w); n > 2 && (w[n-1] == 'y') && !isVowel(w[n-2]) {
+		w = w[:n-1] + "i"
+	}
+	return w
+}
+
+func isVowel(c byte) bool {
+	return strings.IndexByte("aeiouy", c) >= 0
+}
+
+func hasVowel(s string) bool {
+	for i := 0; i < len(s); i++ {
+		if isVowel(s[i]) {
+			return true
+		}
+	}
+	return false
+}
+
+// r1 is where the snowball region R1 of w starts: after the first
+// consonant that follows a vowel.
+func r1(w string) int {
+	for i := 1; i < len(w); i++ {
+		if !isVowel(w[i]) && isVowel(w[i-1]) {
+			return i + 1
+		}
+	}
+	return len(w)
+}
+
+// endsShortSyllable reports whether w ends consonant, vowel, consonant
+// (other than w or x), or is a vowel followed by a consonant.
+func endsShortSyllable(w string) bool {
+	n := len(w)
+	if n == 2 {
+		return isVowel(w[0]) && !isVowel(w[1])
+	}
+	return n > 2 && !isVowel(w[n-3]) && isVowel(w[n-2]) && !isVowel(w[n-1]) && strings.IndexByte("wx", w[n-1]) < 0
+}
@@ -0,0 +1,85 @@
+package store
+
+import (
+	"context"
+	"time"
+)
+
+func (s *MemStore) GetSessionByID(ctx context.Context, sessionID string) (*SessionInfo, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	for _, sess := range s.sessions {
+		if sess.ID == sessionID {
+			copy := *sess
+			return &copy, nil
+		}
+	}
+	return nil, ErrSessionNotFound
+}
+
+// RotateRefreshToken swaps the session's access token and refresh token hash
+// if presentedHash is the current one. The token it replaced is accepted for
+// RefreshGrace and gets the session back unchanged. Presenting any other
+// token of a live family means an old refresh token was replayed: the whole
+// session is revoked and ErrRefreshTokenReused is returned.
+func (s *MemStore) RotateRefreshToken(ctx context.Context, sessionID, presentedHash string, next SessionRotation) (*SessionInfo, error) {
+	s.mu.Lock()
+
+	var sess *SessionInfo
+	for _, candidate := range s.sessions {
+		if candidate.ID == sessionID {
+			sess = candidate
+			break
+		}
+	}
+	if sess == nil || sess.RefreshTokenHash == "" {
+		s.mu.Unlock()
+		return nil, ErrSessionNotFound
+	}
+
+	if sess.RefreshTokenHash != presentedHash {
+		if sess.PrevRefreshTokenHash == presentedHash && next.IssuedAt.Before(sess.RefreshRotatedAt.Add(RefreshGrace)) {
+			copy := *sess
+			s.mu.Unlock()
+			return &copy, nil
+		}
+		anonID := sess.AnonID
+		delete(s.sessions, sess.Token)
+		s.mu.Unlock()
+		_ = s.ReconcileUserActiveStatus(ctx, anonID)
+		return nil, ErrRefreshTokenReused
+	}
+	if !sess.RefreshExpiresAt.After(next.IssuedAt) {
+		s.mu.Unlock()
+		return nil, ErrSessionNotFound
+	}
+
+	delete(s.sessions, sess.Token)
+	sess.Token = next.Token
+	sess.PrevRefreshTokenHash = presentedHash
+	sess.RefreshRotatedAt = next.IssuedAt
+	sess.RefreshSeed = next.RefreshSeed
+	sess.RefreshTokenHash = next.RefreshTokenHash
+	sess.IssuedAt = next.IssuedAt
+	sess.ExpiresAt = next.ExpiresAt
+	sess.RefreshExpiresAt = next.RefreshExpiresAt
+	sess.LastActivityAt = next.IssuedAt
+	if next.UserAgent != "" {
+		sess.UserAgent = next.UserAgent
+	}
+	s.sessions[sess.Token] = sess
+
+	copy := *sess
+	s.mu.Unlock()
+	return &copy, nil
+}
+
+// sessionLiveUntil is how long a session row is worth keeping: until both its
+// access token and its refresh token have expired.
+func sessionLiveUntil(sess *SessionInfo) time.Time {
+	if sess.RefreshExpiresAt.After(sess.ExpiresAt) {
+		return sess.RefreshExpiresAt
+	}
+	return sess.ExpiresAt
+}
@@ -0,0 +1,90 @@
+package store
+
+import (
+	"context"
+	"sort"
+	"time"
+)
+
+// GetTrendingTags returns the limit tags carried by the most non-deleted
+// posts created at or after since, most used first.
+func (s *MemStore) GetTrendingTags(ctx context.Context, since time.Time, limit int) ([]TagCount, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	out := []TagCount{}
+	for tag, posts := range s.tagIndex {
+		n := 0
+		// newest first, so stop at the first post outside the window
+		for _, p := range posts {
+			if p.CreatedAt.Before(since) {
+				break
+			}
+			if !p.Deleted {
+				n++
+			}
+		}
+		if n > 0 {
+			out = append(out, TagCount{Tag: tag, Posts: n})
+		}
+	}
+
+	sort.Slice(out, func(i, j int) bool {
+		if out[i].Posts == out[j].Posts {
+			return out[i].Tag < out[j].Tag
+		}
+		return out[i].Posts > out[j].Posts
+	})
+	if limit > 0 && len(out) > limit {
+		out = out[:limit]
+	}
+	return out, nil
+}
+
+// FollowTag makes posts carrying tag part of anonID's following feed.
+// Following a tag again is not an error.
+func (s *MemStore) FollowTag(ctx context.Context, anonID, tag string, now time.Time) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.tagFollows[anonID] == nil {
+		s.tagFollows[anonID] = make(map[string]time.Time)
+	}
+	if _, ok := s.tagFollows[anonID][tag]; !ok {
+		s.tagFollows[anonID][tag] = now
+	}
+	return nil
+}
+
+// UnfollowTag undoes FollowTag. Unfollowing a tag not followed is not an
+// error.
+func (s *MemStore) UnfollowTag(ctx context.Context, anonID, tag string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	delete(s.tagFollows[anonID], tag)
+	return nil
+}
+
+// GetFollowedTags returns the tags anonID follows, alphabetically.
+func (s *MemStore) GetFollowedTags(ctx context.Context, anonID string) ([]string, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	out := make([]string, 0, len(s.tagFollows[anonID]))
+	for tag := range s.tagFollows[anonID] {
+		out = append(out, tag)
+	}
+	sort.Strings(out)
+	return out, nil
+}
+
+// removePost returns posts without the post with id postID.
+func removePost(posts []*Post, postID string) []*Post {
+	for i, p := range posts {
+		if p.ID == postID {
+			return append(posts[:i], posts[i+1:]...)
+		}
+	}
+	return posts
+}
@@ -0,0 +1,14 @@
+package store_test
+
+import (
+	"testing"
+
+	"anon-backend/internal/store"
+	"anon-backend/internal/store/storetest"
+)
+
+func TestMemStore(t *testing.T) {
+	storetest.Run(t, func(t *testing.T) store.Store {
+		return store.NewMemStore()
+	})
+}
@@ -0,0 +1,16 @@
+-- Persist 1:1 chat messages so peers that are offline can catch up later.
+CREATE TABLE IF NOT EXISTS chat_messages (
+    id TEXT PRIMARY KEY,
+    room_id TEXT NOT NULL,
+    from_anon TEXT NOT NULL,
+    to_anon TEXT NOT NULL,
+    text TEXT NOT NULL,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    delivered_at TIMESTAMPTZ
+);
+
+CREATE INDEX IF NOT EXISTS idx_chat_messages_room_created
+    ON chat_messages(room_id, created_at DESC, id DESC);
+CREATE INDEX IF NOT EXISTS idx_chat_messages_undelivered
+    ON chat_messages(to_anon, room_id, created_at)
+    WHERE delivered_at IS NULL;
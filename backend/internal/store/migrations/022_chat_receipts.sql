@@ -0,0 +1,7 @@
+-- Read receipts and client-side de-duplication for chat messages.
+ALTER TABLE chat_messages ADD COLUMN IF NOT EXISTS client_id TEXT;
+ALTER TABLE chat_messages ADD COLUMN IF NOT EXISTS read_at TIMESTAMPTZ;
+
+CREATE UNIQUE INDEX IF NOT EXISTS ux_chat_messages_client_id
+    ON chat_messages(from_anon, client_id)
+    WHERE client_id IS NOT NULL;
@@ -0,0 +1,24 @@
+-- Public key directory for opt-in end-to-end encrypted chat.
+CREATE TABLE IF NOT EXISTS device_keys (
+    device_public_id TEXT PRIMARY KEY REFERENCES devices(device_public_id) ON DELETE CASCADE,
+    anon_id TEXT NOT NULL,
+    identity_key TEXT NOT NULL,
+    signed_prekey_id INTEGER NOT NULL,
+    signed_prekey TEXT NOT NULL,
+    signed_prekey_signature TEXT NOT NULL,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    updated_at TIMESTAMPTZ NOT NULL DEFAULT NOW()
+);
+
+CREATE INDEX IF NOT EXISTS idx_device_keys_anon_id ON device_keys(anon_id);
+
+CREATE TABLE IF NOT EXISTS device_one_time_prekeys (
+    device_public_id TEXT NOT NULL REFERENCES devices(device_public_id) ON DELETE CASCADE,
+    key_id INTEGER NOT NULL,
+    public_key TEXT NOT NULL,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    PRIMARY KEY (device_public_id, key_id)
+);
+
+-- Encrypted messages are stored as opaque ciphertext next to plaintext ones.
+ALTER TABLE chat_messages ADD COLUMN IF NOT EXISTS kind TEXT NOT NULL DEFAULT 'text';
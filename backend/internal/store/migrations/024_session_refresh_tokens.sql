@@ -0,0 +1,6 @@
+-- Opaque, rotating refresh tokens. Only a hash is stored; the session id
+-- is the token family that gets revoked when an old token is replayed.
+ALTER TABLE sessions ADD COLUMN IF NOT EXISTS refresh_token_hash TEXT;
+ALTER TABLE sessions ADD COLUMN IF NOT EXISTS refresh_expires_at TIMESTAMP WITH TIME ZONE;
+
+CREATE INDEX IF NOT EXISTS idx_sessions_id ON sessions(id);
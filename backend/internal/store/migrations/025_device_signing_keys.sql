@@ -0,0 +1,7 @@
+-- Devices may authenticate with an Ed25519 key pair instead of the shared
+-- HMAC secret. Only the public key is stored; existing devices stay on hmac.
+ALTER TABLE devices ADD COLUMN IF NOT EXISTS auth_method TEXT NOT NULL DEFAULT 'hmac';
+ALTER TABLE devices ADD COLUMN IF NOT EXISTS public_key TEXT;
+
+-- Ed25519 devices have no secret to store.
+ALTER TABLE devices ALTER COLUMN device_secret_hash SET DEFAULT '';
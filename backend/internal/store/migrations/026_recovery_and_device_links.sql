@@ -0,0 +1,25 @@
+-- One identity (anon_id) may now be used from many devices: a device is
+-- added either by redeeming the account's recovery key or by being approved
+-- from an already signed-in device. Linked devices share the username.
+ALTER TABLE devices DROP CONSTRAINT IF EXISTS devices_anon_id_key;
+ALTER TABLE devices DROP CONSTRAINT IF EXISTS devices_username_key;
+
+-- At most one recovery key per identity; only its hash is stored.
+CREATE TABLE IF NOT EXISTS recovery_keys (
+    anon_id TEXT PRIMARY KEY,
+    key_hash TEXT NOT NULL UNIQUE,
+    created_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT CURRENT_TIMESTAMP
+);
+
+-- Pending requests from a new device to join an existing identity.
+CREATE TABLE IF NOT EXISTS device_links (
+    code TEXT PRIMARY KEY,
+    device_public_id TEXT NOT NULL,
+    anon_id TEXT,
+    created_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    expires_at TIMESTAMP WITH TIME ZONE NOT NULL,
+    approved_at TIMESTAMP WITH TIME ZONE,
+    consumed_at TIMESTAMP WITH TIME ZONE
+);
+
+CREATE INDEX IF NOT EXISTS idx_device_links_expires_at ON device_links(expires_at);
@@ -0,0 +1,4 @@
+-- Client metadata shown to users managing their own sessions.
+ALTER TABLE sessions ADD COLUMN IF NOT EXISTS device_public_id TEXT;
+ALTER TABLE sessions ADD COLUMN IF NOT EXISTS region TEXT;
+ALTER TABLE sessions ADD COLUMN IF NOT EXISTS user_agent TEXT;
@@ -0,0 +1,11 @@
+-- Individual staff accounts for the admin panel, replacing the single
+-- ADMIN_EMAIL/ADMIN_PASSWORD login. Passwords are bcrypt hashes.
+CREATE TABLE IF NOT EXISTS admins (
+    id TEXT PRIMARY KEY,
+    email TEXT NOT NULL UNIQUE,
+    password_hash TEXT NOT NULL,
+    role TEXT NOT NULL CHECK (role IN ('support', 'moderator', 'superadmin')),
+    disabled BOOLEAN NOT NULL DEFAULT false,
+    created_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    last_login_at TIMESTAMP WITH TIME ZONE
+);
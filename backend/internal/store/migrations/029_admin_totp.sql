@@ -0,0 +1,16 @@
+-- TOTP second factor for admin logins. The pending secret holds an
+-- enrolment until the admin confirms it with a first code; the last
+-- counter stops a code being replayed within its window.
+ALTER TABLE admins ADD COLUMN IF NOT EXISTS totp_enabled BOOLEAN NOT NULL DEFAULT false;
+ALTER TABLE admins ADD COLUMN IF NOT EXISTS totp_secret TEXT NOT NULL DEFAULT '';
+ALTER TABLE admins ADD COLUMN IF NOT EXISTS totp_pending_secret TEXT NOT NULL DEFAULT '';
+ALTER TABLE admins ADD COLUMN IF NOT EXISTS totp_last_counter BIGINT NOT NULL DEFAULT 0;
+
+-- Single-use recovery codes, stored as sha256 hashes. Re-enrolling
+-- replaces the whole set.
+CREATE TABLE IF NOT EXISTS admin_recovery_codes (
+    admin_id TEXT NOT NULL REFERENCES admins(id) ON DELETE CASCADE,
+    code_hash TEXT NOT NULL,
+    used_at TIMESTAMP WITH TIME ZONE,
+    PRIMARY KEY (admin_id, code_hash)
+);
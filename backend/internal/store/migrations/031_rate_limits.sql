@@ -0,0 +1,15 @@
+-- Token buckets for the HTTP rate limiter, shared by every API instance.
+-- key is "anon:<id>" or "ip:<addr>".
+CREATE TABLE IF NOT EXISTS rate_limit_buckets (
+    policy TEXT NOT NULL,
+    key TEXT NOT NULL,
+    tokens DOUBLE PRECISION NOT NULL,
+    updated_at TIMESTAMP WITH TIME ZONE NOT NULL,
+    limited BIGINT NOT NULL DEFAULT 0,
+    last_limited_at TIMESTAMP WITH TIME ZONE,
+    PRIMARY KEY (policy, key)
+);
+
+CREATE INDEX IF NOT EXISTS idx_rate_limit_buckets_limited ON rate_limit_buckets(last_limited_at DESC)
+    WHERE last_limited_at IS NOT NULL;
+CREATE INDEX IF NOT EXISTS idx_rate_limit_buckets_updated ON rate_limit_buckets(updated_at);
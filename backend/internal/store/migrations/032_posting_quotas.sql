@@ -0,0 +1,27 @@
+-- Per-window posting counters replacing post_daily_limits. quota_window is
+-- "hour", "day" or "week" and window_start its UTC start; rows for windows
+-- that have ended are deleted by the cleanup job.
+CREATE TABLE IF NOT EXISTS quota_usage (
+    anon_id TEXT NOT NULL,
+    resource TEXT NOT NULL,
+    quota_window TEXT NOT NULL,
+    window_start TIMESTAMP WITH TIME ZONE NOT NULL,
+    reset_at TIMESTAMP WITH TIME ZONE NOT NULL,
+    count INT NOT NULL DEFAULT 0,
+    PRIMARY KEY (anon_id, resource, quota_window, window_start)
+);
+
+CREATE INDEX IF NOT EXISTS idx_quota_usage_reset ON quota_usage(reset_at);
+
+-- Carry over recent post counts so the switch does not hand out fresh quota;
+-- rows for past days are dropped by the next cleanup.
+INSERT INTO quota_usage (anon_id, resource, quota_window, window_start, reset_at, count)
+SELECT anon_id, 'posts', 'day',
+       date_key::date::timestamp AT TIME ZONE 'UTC',
+       (date_key::date + 1)::timestamp AT TIME ZONE 'UTC',
+       count
+FROM post_daily_limits
+WHERE date_key >= to_char(CURRENT_DATE - 1, 'YYYY-MM-DD')
+ON CONFLICT DO NOTHING;
+
+DROP TABLE IF EXISTS post_daily_limits;
@@ -0,0 +1,4 @@
+-- Keyset pagination over the feed orders by (created_at, id); scoped pages
+-- also filter by author.
+CREATE INDEX IF NOT EXISTS idx_posts_feed ON posts(created_at DESC, id DESC) WHERE deleted = false;
+CREATE INDEX IF NOT EXISTS idx_posts_anon_feed ON posts(anon_id, created_at DESC, id DESC) WHERE deleted = false;
@@ -0,0 +1,16 @@
+-- Rankings maintained by the trending job (store.RefreshPostScores). Each
+-- run replaces the whole table; comments and reports are the counts the
+-- scores were computed from.
+CREATE TABLE IF NOT EXISTS post_scores (
+    post_id TEXT PRIMARY KEY REFERENCES posts(id) ON DELETE CASCADE,
+    comments INT NOT NULL DEFAULT 0,
+    reports INT NOT NULL DEFAULT 0,
+    hot DOUBLE PRECISION NOT NULL,
+    rising DOUBLE PRECISION NOT NULL,
+    top DOUBLE PRECISION NOT NULL,
+    computed_at TIMESTAMP WITH TIME ZONE NOT NULL
+);
+
+CREATE INDEX IF NOT EXISTS idx_post_scores_hot ON post_scores(hot DESC);
+CREATE INDEX IF NOT EXISTS idx_post_scores_rising ON post_scores(rising DESC);
+CREATE INDEX IF NOT EXISTS idx_post_scores_top ON post_scores(top DESC);
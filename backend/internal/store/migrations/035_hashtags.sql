@@ -0,0 +1,46 @@
+-- One row per (tag, post), kept in step with posts.hashtags by trigger, so
+-- tag pages can be paged by (created_at, id) and tag counts over a window
+-- only read the posts of that window.
+CREATE TABLE IF NOT EXISTS post_tags (
+    tag TEXT NOT NULL,
+    post_id TEXT NOT NULL REFERENCES posts(id) ON DELETE CASCADE,
+    created_at TIMESTAMP WITH TIME ZONE NOT NULL,
+    PRIMARY KEY (tag, post_id)
+);
+
+CREATE INDEX IF NOT EXISTS idx_post_tags_page ON post_tags(tag, created_at DESC, post_id DESC);
+CREATE INDEX IF NOT EXISTS idx_post_tags_created_at ON post_tags(created_at);
+CREATE INDEX IF NOT EXISTS idx_post_tags_post_id ON post_tags(post_id);
+
+-- posts_search_trigger (migration 008) fills hashtags before the row is
+-- written; this one indexes the result afterwards.
+CREATE OR REPLACE FUNCTION posts_tags_trigger() RETURNS trigger AS $$
+BEGIN
+  DELETE FROM post_tags WHERE post_id = NEW.id;
+  INSERT INTO post_tags (tag, post_id, created_at)
+  SELECT DISTINCT tag, NEW.id, NEW.created_at
+  FROM unnest(COALESCE(NEW.hashtags, ARRAY[]::TEXT[])) AS tag;
+  RETURN NULL;
+END
+$$ LANGUAGE plpgsql;
+
+DROP TRIGGER IF EXISTS posts_tags_update ON posts;
+CREATE TRIGGER posts_tags_update
+  AFTER INSERT OR UPDATE OF text, created_at ON posts
+  FOR EACH ROW
+  EXECUTE FUNCTION posts_tags_trigger();
+
+INSERT INTO post_tags (tag, post_id, created_at)
+SELECT DISTINCT tag, p.id, p.created_at
+FROM posts p, unnest(p.hashtags) AS tag
+ON CONFLICT DO NOTHING;
+
+-- Tags an anon follows; their posts make up the "following" feed.
+CREATE TABLE IF NOT EXISTS tag_follows (
+    anon_id TEXT NOT NULL,
+    tag TEXT NOT NULL,
+    created_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT NOW(),
+    PRIMARY KEY (anon_id, tag)
+);
+
+CREATE INDEX IF NOT EXISTS idx_tag_follows_tag ON tag_follows(tag);
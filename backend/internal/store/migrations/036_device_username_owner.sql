@@ -0,0 +1,8 @@
+-- Migration 026 dropped the unique username so linked devices could share
+-- one; different identities must still not. Devices of the same anon_id
+-- may repeat a username, devices of different anon_ids may not.
+CREATE EXTENSION IF NOT EXISTS btree_gist;
+
+ALTER TABLE devices DROP CONSTRAINT IF EXISTS devices_username_owner;
+ALTER TABLE devices ADD CONSTRAINT devices_username_owner
+    EXCLUDE USING gist (username WITH =, anon_id WITH <>);
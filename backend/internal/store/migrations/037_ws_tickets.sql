@@ -0,0 +1,9 @@
+-- Redeemed websocket tickets, shared by every API instance so a ticket
+-- opens one socket in all. Rows are useless once the ticket expires.
+CREATE TABLE IF NOT EXISTS ws_tickets (
+    ticket_id TEXT PRIMARY KEY,
+    expires_at TIMESTAMP WITH TIME ZONE NOT NULL,
+    redeemed_at TIMESTAMP WITH TIME ZONE NOT NULL
+);
+
+CREATE INDEX IF NOT EXISTS idx_ws_tickets_expires ON ws_tickets(expires_at);
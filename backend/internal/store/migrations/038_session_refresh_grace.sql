@@ -0,0 +1,5 @@
+-- The refresh token a rotation replaced stays valid for a short grace
+-- window; the seed derives its successor again for a client that retries.
+ALTER TABLE sessions ADD COLUMN IF NOT EXISTS prev_refresh_token_hash TEXT;
+ALTER TABLE sessions ADD COLUMN IF NOT EXISTS refresh_rotated_at TIMESTAMP WITH TIME ZONE;
+ALTER TABLE sessions ADD COLUMN IF NOT EXISTS refresh_seed TEXT;
@@ -0,0 +1,5 @@
+-- Wrong second factors are counted on the admin row, so the lockout holds
+-- across instances and restarts.
+ALTER TABLE admins ADD COLUMN IF NOT EXISTS mfa_failures INTEGER NOT NULL DEFAULT 0;
+ALTER TABLE admins ADD COLUMN IF NOT EXISTS mfa_last_failure_at TIMESTAMP WITH TIME ZONE;
+ALTER TABLE admins ADD COLUMN IF NOT EXISTS mfa_locked_until TIMESTAMP WITH TIME ZONE;
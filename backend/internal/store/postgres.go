@@ -1,13 +1,16 @@
 package store
 
 import (
+	"context"
 	"crypto/rand"
 	"database/sql"
 	"encoding/hex"
 	"fmt"
-	"time"
-
 	"github.com/lib/pq"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
 )
 
 // PgStore implements Store using PostgreSQL backend.
@@ -25,7 +28,7 @@ var _ Store = (*PgStore)(nil)
 
 // ===== LINK CARDS =====
 
-func (s *PgStore) PutCard(c *LinkCard) error {
+func (s *PgStore) PutCard(ctx context.Context, c *LinkCard) error {
 	if c.ID == "" {
 		id, err := newUUID()
 		if err != nil {
@@ -42,117 +45,112 @@ func (s *PgStore) PutCard(c *LinkCard) error {
 			expires_at = EXCLUDED.expires_at,
 			used_by = EXCLUDED.used_by
 	`
-	_, err := s.db.Exec(query, c.ID, c.Code, c.OwnerAnon, c.Status, c.CreatedAt, c.ExpiresAt, c.UsedBy)
+	_, err := s.db.ExecContext(ctx, query, c.ID, c.Code, c.OwnerAnon, c.Status, c.CreatedAt, c.ExpiresAt, c.UsedBy)
 	if err != nil {
 		return fmt.Errorf("put link card: %w", err)
 	}
 	return nil
 }
 
-func (s *PgStore) GetCard(code string) (*LinkCard, bool) {
+func (s *PgStore) GetCard(ctx context.Context, code string) (*LinkCard, error) {
 	query := `SELECT id, code, owner_anon, status, created_at, expires_at, used_by FROM link_cards WHERE code = $1`
-	row := s.db.QueryRow(query, code)
-
-	card := &LinkCard{}
-	var status sql.NullString
-	var expiresAt sql.NullTime
-	var usedBy sql.NullString
-	err := row.Scan(&card.ID, &card.Code, &card.OwnerAnon, &status, &card.CreatedAt, &expiresAt, &usedBy)
+	card, err := scanLinkCard(s.db.QueryRowContext(ctx, query, code))
 	if err == sql.ErrNoRows {
-		return nil, false
+		return nil, ErrLinkCardNotFound
 	}
 	if err != nil {
-		fmt.Printf("error getting card: %v\n", err)
-		return nil, false
-	}
-	if status.Valid {
-		card.Status = LinkCardStatus(status.String)
-	}
-	if expiresAt.Valid {
-		card.ExpiresAt = expiresAt.Time
+		return nil, fmt.Errorf("get link card: %w", err)
 	}
-	if usedBy.Valid {
-		card.UsedBy = usedBy.String
-	}
-	return card, true
+	return card, nil
 }
 
-func (s *PgStore) CardsByOwner(owner string) []*LinkCard {
+func (s *PgStore) CardsByOwner(ctx context.Context, owner string) ([]*LinkCard, error) {
 	query := `SELECT id, code, owner_anon, status, created_at, expires_at, used_by FROM link_cards WHERE owner_anon = $1 ORDER BY created_at DESC`
-	rows, err := s.db.Query(query, owner)
+	rows, err := s.db.QueryContext(ctx, query, owner)
 	if err != nil {
-		fmt.Printf("error querying cards: %v\n", err)
-		return []*LinkCard{}
+		return nil, fmt.Errorf("query link cards: %w", err)
 	}
 	defer rows.Close()
 
 	out := []*LinkCard{}
 	for rows.Next() {
-		card := &LinkCard{}
-		var status sql.NullString
-		var expiresAt sql.NullTime
-		var usedBy sql.NullString
-		if err := rows.Scan(&card.ID, &card.Code, &card.OwnerAnon, &status, &card.CreatedAt, &expiresAt, &usedBy); err != nil {
-			fmt.Printf("error scanning card: %v\n", err)
-			continue
-		}
-		if status.Valid {
-			card.Status = LinkCardStatus(status.String)
-		}
-		if expiresAt.Valid {
-			card.ExpiresAt = expiresAt.Time
-		}
-		if usedBy.Valid {
-			card.UsedBy = usedBy.String
+		card, err := scanLinkCard(rows)
+		if err != nil {
+			return nil, fmt.Errorf("scan link card: %w", err)
 		}
 		out = append(out, card)
 	}
-	return out
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("iterate link cards: %w", err)
+	}
+	return out, nil
+}
+
+func scanLinkCard(row rowScanner) (*LinkCard, error) {
+	card := &LinkCard{}
+	var status sql.NullString
+	var expiresAt sql.NullTime
+	var usedBy sql.NullString
+	if err := row.Scan(&card.ID, &card.Code, &card.OwnerAnon, &status, &card.CreatedAt, &expiresAt, &usedBy); err != nil {
+		return nil, err
+	}
+	if status.Valid {
+		card.Status = LinkCardStatus(status.String)
+	}
+	if expiresAt.Valid {
+		card.ExpiresAt = expiresAt.Time
+	}
+	if usedBy.Valid {
+		card.UsedBy = usedBy.String
+	}
+	return card, nil
 }
 
 // ===== TRUST REQUESTS =====
 
-func (s *PgStore) PutTrust(t *TrustRequest) error {
+func (s *PgStore) PutTrust(ctx context.Context, t *TrustRequest) error {
 	query := `
 		INSERT INTO trust_requests (id, code, from_anon, to_anon, status, created_at, updated_at)
 		VALUES ($1, $2, $3, $4, $5, $6, $7)
 		ON CONFLICT (id) DO UPDATE SET
 			status = $5, updated_at = $7
 	`
-	_, err := s.db.Exec(query, t.ID, t.Code, t.FromAnon, t.ToAnon, t.Status, t.CreatedAt, t.UpdatedAt)
+	_, err := s.db.ExecContext(ctx, query, t.ID, t.Code, t.FromAnon, t.ToAnon, t.Status, t.CreatedAt, t.UpdatedAt)
 	if err != nil {
 		return fmt.Errorf("put trust request: %w", err)
 	}
 	return nil
 }
 
-func (s *PgStore) GetTrust(id string) (*TrustRequest, bool) {
+func (s *PgStore) GetTrust(ctx context.Context, id string) (*TrustRequest, error) {
 	query := `SELECT id, code, from_anon, to_anon, status, created_at, updated_at FROM trust_requests WHERE id = $1`
-	row := s.db.QueryRow(query, id)
+	row := s.db.QueryRowContext(ctx, query, id)
 
 	tr := &TrustRequest{}
 	err := row.Scan(&tr.ID, &tr.Code, &tr.FromAnon, &tr.ToAnon, &tr.Status, &tr.CreatedAt, &tr.UpdatedAt)
 	if err == sql.ErrNoRows {
-		return nil, false
+		return nil, ErrTrustRequestNotFound
 	}
 	if err != nil {
-		fmt.Printf("error getting trust: %v\n", err)
-		return nil, false
+		return nil, fmt.Errorf("get trust request: %w", err)
 	}
-	return tr, true
+	return tr, nil
 }
 
-func (s *PgStore) TrustForAnon(anon string) []*TrustRequest {
+func (s *PgStore) TrustForAnon(ctx context.Context, anon string) ([]*TrustRequest, error) {
 	query := `
 		SELECT id, code, from_anon, to_anon, status, created_at, updated_at 
 		FROM trust_requests 
 		WHERE from_anon = $1 OR to_anon = $1
 		ORDER BY created_at DESC
 	`
-	rows, err := s.db.Query(query, anon)
+	return s.queryTrustRequests(ctx, query, anon)
+}
+
+func (s *PgStore) queryTrustRequests(ctx context.Context, query string, args ...interface{}) ([]*TrustRequest, error) {
+	rows, err := s.db.QueryContext(ctx, query, args...)
 	if err != nil {
-		fmt.Printf("error querying trust: %v\n", err)
-		return []*TrustRequest{}
+		return nil, fmt.Errorf("query trust requests: %w", err)
 	}
 	defer rows.Close()
 
@@ -160,48 +158,49 @@ func (s *PgStore) TrustForAnon(anon string) []*TrustRequest {
 	for rows.Next() {
 		tr := &TrustRequest{}
 		if err := rows.Scan(&tr.ID, &tr.Code, &tr.FromAnon, &tr.ToAnon, &tr.Status, &tr.CreatedAt, &tr.UpdatedAt); err != nil {
-			fmt.Printf("error scanning trust: %v\n", err)
-			continue
+			return nil, fmt.Errorf("scan trust request: %w", err)
 		}
 		out = append(out, tr)
 	}
-	return out
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("iterate trust requests: %w", err)
+	}
+	return out, nil
 }
 
-func (s *PgStore) TrustAccepted(a, b string) bool {
+func (s *PgStore) TrustAccepted(ctx context.Context, a, b string) (bool, error) {
 	query := `
 		SELECT COUNT(*) FROM trust_requests 
 		WHERE status = 'accepted' 
 		AND ((from_anon = $1 AND to_anon = $2) OR (from_anon = $2 AND to_anon = $1))
 	`
 	var count int
-	err := s.db.QueryRow(query, a, b).Scan(&count)
+	err := s.db.QueryRowContext(ctx, query, a, b).Scan(&count)
 	if err != nil {
-		fmt.Printf("error checking trust: %v\n", err)
-		return false
+		return false, fmt.Errorf("check trust: %w", err)
 	}
-	return count > 0
+	return count > 0, nil
 }
 
 // ===== POSTS =====
 
-func (s *PgStore) PutPost(p *Post) {
+func (s *PgStore) PutPost(ctx context.Context, p *Post) error {
 	query := `INSERT INTO posts (id, anon_id, text, created_at, likes, dislikes, deleted) VALUES ($1, $2, $3, $4, $5, $6, $7)`
-	_, err := s.db.Exec(query, p.ID, p.AnonID, p.Text, p.CreatedAt, p.Likes, p.Dislikes, p.Deleted)
+	_, err := s.db.ExecContext(ctx, query, p.ID, p.AnonID, p.Text, p.CreatedAt, p.Likes, p.Dislikes, p.Deleted)
 	if err != nil {
-		fmt.Printf("error putting post: %v\n", err)
+		return fmt.Errorf("put post: %w", err)
 	}
+	return nil
 }
 
-func (s *PgStore) GetFeed(limit int) []*Post {
+func (s *PgStore) GetFeed(ctx context.Context, limit int) ([]*Post, error) {
 	if limit <= 0 {
 		limit = 50 // sensible default
 	}
-	query := `SELECT id, anon_id, text, created_at, likes, dislikes, deleted FROM posts WHERE deleted = false ORDER BY created_at DESC LIMIT $1`
-	rows, err := s.db.Query(query, limit)
+	query := `SELECT id, anon_id, text, created_at, likes, dislikes, deleted FROM posts WHERE deleted = false ORDER BY created_at DESC, id DESC LIMIT $1`
+	rows, err := s.db.QueryContext(ctx, query, limit)
 	if err != nil {
-		fmt.Printf("error querying posts: %v\n", err)
-		return []*Post{}
+		return nil, fmt.Errorf("query posts: %w", err)
 	}
 	defer rows.Close()
 
@@ -209,29 +208,72 @@ func (s *PgStore) GetFeed(limit int) []*Post {
 	for rows.Next() {
 		p := &Post{}
 		if err := rows.Scan(&p.ID, &p.AnonID, &p.Text, &p.CreatedAt, &p.Likes, &p.Dislikes, &p.Deleted); err != nil {
-			fmt.Printf("error scanning post: %v\n", err)
-			continue
+			return nil, fmt.Errorf("scan posts: %w", err)
 		}
 		out = append(out, p)
 	}
-	return out
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("iterate posts: %w", err)
+	}
+	return out, nil
 }
 
-func (s *PgStore) GetPostsByAnonID(anonID string, limit int) []*Post {
-	if limit <= 0 {
-		limit = 50
+// GetFeedPage returns the page of the feed described by q, newest first.
+func (s *PgStore) GetFeedPage(ctx context.Context, q FeedQuery) ([]*Post, error) {
+	if err := q.normalize(); err != nil {
+		return nil, err
+	}
+
+	conds := []string{"deleted = false"}
+	args := []interface{}{}
+	arg := func(v interface{}) string {
+		args = append(args, v)
+		return "$" + strconv.Itoa(len(args))
 	}
+
+	switch q.Scope {
+	case FeedScopeTrusted:
+		viewer := arg(q.Viewer)
+		conds = append(conds, `EXISTS (
+			SELECT 1 FROM trust_requests t
+			WHERE t.status = 'accepted'
+			AND ((t.from_anon = `+viewer+` AND t.to_anon = posts.anon_id) OR (t.to_anon = `+viewer+` AND t.from_anon = posts.anon_id))
+		)`)
+	case FeedScopeNearby:
+		// Same notion of "nearby" as GetNearby, without its cap
+		pings, err := s.latestPingsWithin(ctx, q.Lat, q.Lng, q.RadiusKm)
+		if err != nil {
+			return nil, err
+		}
+		anonIDs := make([]string, len(pings))
+		for i, ping := range pings {
+			anonIDs[i] = ping.AnonID
+		}
+		conds = append(conds, "anon_id = ANY("+arg(pq.Array(anonIDs))+")")
+	case FeedScopeFollowing:
+		conds = append(conds, `EXISTS (
+			SELECT 1 FROM post_tags t
+			JOIN tag_follows f ON f.tag = t.tag
+			WHERE t.post_id = posts.id AND f.anon_id = `+arg(q.Viewer)+`
+		)`)
+	}
+	if q.Tag != "" {
+		conds = append(conds, "EXISTS (SELECT 1 FROM post_tags t WHERE t.post_id = posts.id AND t.tag = "+arg(q.Tag)+")")
+	}
+	if q.Before != nil {
+		conds = append(conds, "(created_at, id) < ("+arg(q.Before.CreatedAt)+", "+arg(q.Before.ID)+")")
+	}
+
 	query := `
 		SELECT id, anon_id, text, created_at, likes, dislikes, deleted
 		FROM posts
-		WHERE deleted = false AND anon_id = $1
-		ORDER BY created_at DESC
-		LIMIT $2
-	`
-	rows, err := s.db.Query(query, anonID, limit)
+		WHERE ` + strings.Join(conds, " AND ") + `
+		ORDER BY created_at DESC, id DESC
+		LIMIT ` + arg(q.Limit)
+
+	rows, err := s.db.QueryContext(ctx, query, args...)
 	if err != nil {
-		fmt.Printf("error querying posts by anon_id: %v\n", err)
-		return []*Post{}
+		return nil, fmt.Errorf("query feed page: %w", err)
 	}
 	defer rows.Close()
 
@@ -239,184 +281,88 @@ func (s *PgStore) GetPostsByAnonID(anonID string, limit int) []*Post {
 	for rows.Next() {
 		p := &Post{}
 		if err := rows.Scan(&p.ID, &p.AnonID, &p.Text, &p.CreatedAt, &p.Likes, &p.Dislikes, &p.Deleted); err != nil {
-			fmt.Printf("error scanning post by anon_id: %v\n", err)
-			continue
+			return nil, fmt.Errorf("scan feed page: %w", err)
 		}
 		out = append(out, p)
 	}
-	return out
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("iterate feed page: %w", err)
+	}
+	return out, nil
 }
 
-func (s *PgStore) GetTrendingPosts(limit int, offset int) ([]PostWithStats, error) {
+func (s *PgStore) GetPostsByAnonID(ctx context.Context, anonID string, limit int) ([]*Post, error) {
 	if limit <= 0 {
-		limit = 20
-	}
-	if limit > 50 {
 		limit = 50
 	}
-	if offset < 0 {
-		offset = 0
-	}
-
 	query := `
-		WITH react AS (
-			SELECT
-				post_id,
-				COUNT(*) FILTER (WHERE reaction_type = 'like') AS likes,
-				COUNT(*) FILTER (WHERE reaction_type = 'dislike') AS dislikes
-			FROM post_reactions
-			GROUP BY post_id
-		),
-		com AS (
-			SELECT post_id, COUNT(*) AS comments
-			FROM post_comments
-			WHERE deleted = false
-			GROUP BY post_id
-		)
-		SELECT
-			p.id,
-			p.anon_id,
-			p.text,
-			p.created_at,
-			p.likes,
-			p.dislikes,
-			p.deleted,
-			COALESCE(r.likes, 0) AS like_count,
-			COALESCE(r.dislikes, 0) AS dislike_count,
-			COALESCE(c.comments, 0) AS comment_count,
-			ROUND(
-				LN(GREATEST(ABS(COALESCE(r.likes, 0) - COALESCE(r.dislikes, 0)), 1)) +
-				CASE
-					WHEN (COALESCE(r.likes, 0) - COALESCE(r.dislikes, 0)) > 0 THEN 1
-					WHEN (COALESCE(r.likes, 0) - COALESCE(r.dislikes, 0)) < 0 THEN -1
-					ELSE 0
-				END * (EXTRACT(EPOCH FROM p.created_at) / 45000.0),
-				7
-			)::double precision AS hot_score
-		FROM posts p
-		LEFT JOIN react r ON r.post_id = p.id
-		LEFT JOIN com c ON c.post_id = p.id
-		WHERE p.deleted = false
-		ORDER BY hot_score DESC, p.created_at DESC
-		LIMIT $1 OFFSET $2
+		SELECT id, anon_id, text, created_at, likes, dislikes, deleted
+		FROM posts
+		WHERE deleted = false AND anon_id = $1
+		ORDER BY created_at DESC
+		LIMIT $2
 	`
-
-	rows, err := s.db.Query(query, limit, offset)
+	rows, err := s.db.QueryContext(ctx, query, anonID, limit)
 	if err != nil {
-		return nil, fmt.Errorf("query trending posts: %w", err)
+		return nil, fmt.Errorf("query posts by anon: %w", err)
 	}
 	defer rows.Close()
 
-	out := make([]PostWithStats, 0, limit)
+	out := []*Post{}
 	for rows.Next() {
-		var post PostWithStats
-		err := rows.Scan(
-			&post.ID,
-			&post.AnonID,
-			&post.Text,
-			&post.CreatedAt,
-			&post.Likes,
-			&post.Dislikes,
-			&post.Deleted,
-			&post.LikeCount,
-			&post.DislikeCount,
-			&post.CommentCount,
-			&post.HotScore,
-		)
-		if err != nil {
-			return nil, fmt.Errorf("scan trending post: %w", err)
+		p := &Post{}
+		if err := rows.Scan(&p.ID, &p.AnonID, &p.Text, &p.CreatedAt, &p.Likes, &p.Dislikes, &p.Deleted); err != nil {
+			return nil, fmt.Errorf("scan posts by anon: %w", err)
 		}
-		out = append(out, post)
+		out = append(out, p)
 	}
-
 	if err := rows.Err(); err != nil {
-		return nil, fmt.Errorf("iterate trending posts: %w", err)
+		return nil, fmt.Errorf("iterate posts by anon: %w", err)
 	}
-
 	return out, nil
 }
 
-func (s *PgStore) CanCreatePost(anonID string) bool {
-	dateKey := time.Now().Format("2006-01-02")
-
-	// Check current count
-	var count int
-	query := `SELECT count FROM post_daily_limits WHERE anon_id = $1 AND date_key = $2`
-	err := s.db.QueryRow(query, anonID, dateKey).Scan(&count)
-	if err == sql.ErrNoRows {
-		count = 0
-	} else if err != nil {
-		fmt.Printf("error checking post limit: %v\n", err)
-		return false
-	}
-
-	if count >= 3 {
-		return false
-	}
+// ===== GEO PINGS =====
 
-	// Increment or insert
-	upsertQuery := `
-		INSERT INTO post_daily_limits (anon_id, date_key, count) 
-		VALUES ($1, $2, 1)
-		ON CONFLICT (anon_id, date_key) DO UPDATE SET count = post_daily_limits.count + 1
-	`
-	_, err = s.db.Exec(upsertQuery, anonID, dateKey)
+func (s *PgStore) PutGeo(ctx context.Context, ping *GeoPing) error {
+	query := `INSERT INTO geo_pings (anon_id, lat, lng, timestamp) VALUES ($1, $2, $3, $4)`
+	_, err := s.db.ExecContext(ctx, query, ping.AnonID, ping.Lat, ping.Lng, ping.Timestamp)
 	if err != nil {
-		fmt.Printf("error updating post limit: %v\n", err)
-		return false
+		return fmt.Errorf("put geo ping: %w", err)
 	}
-
-	return true
+	return nil
 }
 
-func (s *PgStore) GetRemainingPosts(anonID string) int {
-	dateKey := time.Now().Format("2006-01-02")
-
-	var count int
-	query := `SELECT count FROM post_daily_limits WHERE anon_id = $1 AND date_key = $2`
-	err := s.db.QueryRow(query, anonID, dateKey).Scan(&count)
-	if err == sql.ErrNoRows {
-		return 3
-	}
+func (s *PgStore) GetNearby(ctx context.Context, lat, lng float64, radiusKm float64) ([]*GeoPing, error) {
+	out, err := s.latestPingsWithin(ctx, lat, lng, radiusKm)
 	if err != nil {
-		fmt.Printf("error checking post limit: %v\n", err)
-		return 3
-	}
-
-	remaining := 3 - count
-	if remaining < 0 {
-		return 0
+		return nil, err
 	}
-	return remaining
-}
-
-// ===== GEO PINGS =====
 
-func (s *PgStore) PutGeo(ping *GeoPing) {
-	query := `INSERT INTO geo_pings (anon_id, lat, lng, timestamp) VALUES ($1, $2, $3, $4)`
-	_, err := s.db.Exec(query, ping.AnonID, ping.Lat, ping.Lng, ping.Timestamp)
-	if err != nil {
-		fmt.Printf("error putting geo: %v\n", err)
+	sort.Slice(out, func(i, j int) bool { return out[i].Timestamp.After(out[j].Timestamp) })
+	if len(out) > 100 {
+		out = out[:100]
 	}
+	return out, nil
 }
 
-func (s *PgStore) GetNearby(lat, lng float64, radiusKm float64) []*GeoPing {
-	cutoff := time.Now().Add(-10 * time.Minute)
+// latestPingsWithin returns the last recent ping of every anon that lies
+// within radiusKm of (lat, lng), in no particular order.
+func (s *PgStore) latestPingsWithin(ctx context.Context, lat, lng float64, radiusKm float64) ([]*GeoPing, error) {
+	cutoff := time.Now().Add(-geoPingMaxAge)
 
-	// Use PostgreSQL earth distance or simple Haversine in SQL
-	// For now, fetch recent pings and filter in memory (can optimize later with PostGIS)
+	// Only each anon's last ping counts; the radius is checked in Go
+	// (can optimize later with PostGIS)
 	query := `
-		SELECT anon_id, lat, lng, timestamp 
-		FROM geo_pings 
+		SELECT DISTINCT ON (anon_id) anon_id, lat, lng, timestamp
+		FROM geo_pings
 		WHERE timestamp > $1
-		ORDER BY timestamp DESC
-		LIMIT 100
+		ORDER BY anon_id, timestamp DESC
 	`
 
-	rows, err := s.db.Query(query, cutoff)
+	rows, err := s.db.QueryContext(ctx, query, cutoff)
 	if err != nil {
-		fmt.Printf("error querying geo: %v\n", err)
-		return []*GeoPing{}
+		return nil, fmt.Errorf("query geo pings: %w", err)
 	}
 	defer rows.Close()
 
@@ -424,8 +370,7 @@ func (s *PgStore) GetNearby(lat, lng float64, radiusKm float64) []*GeoPing {
 	for rows.Next() {
 		ping := &GeoPing{}
 		if err := rows.Scan(&ping.AnonID, &ping.Lat, &ping.Lng, &ping.Timestamp); err != nil {
-			fmt.Printf("error scanning geo: %v\n", err)
-			continue
+			return nil, fmt.Errorf("scan geo pings: %w", err)
 		}
 
 		// Filter by distance using haversine
@@ -435,28 +380,31 @@ func (s *PgStore) GetNearby(lat, lng float64, radiusKm float64) []*GeoPing {
 		}
 	}
 
-	return out
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("iterate geo pings: %w", err)
+	}
+
+	return out, nil
 }
 
 // ===== ADMIN METHODS =====
 
-func (s *PgStore) GetAllUsers() []*UserInfo {
+func (s *PgStore) GetAllUsers(ctx context.Context) ([]*UserInfo, error) {
 	query := `
 		SELECT 
 			d.anon_id,
-			d.username,
-			COALESCE(COUNT(p.id), 0) AS post_count,
-			d.created_at
+			MIN(d.username),
+			COALESCE(COUNT(DISTINCT p.id), 0) AS post_count,
+			MIN(d.created_at) AS created_at
 		FROM devices d
 		LEFT JOIN posts p ON p.anon_id = d.anon_id
-		GROUP BY d.anon_id, d.username, d.created_at
-		ORDER BY d.created_at DESC
+		GROUP BY d.anon_id
+		ORDER BY created_at DESC
 	`
 
-	rows, err := s.db.Query(query)
+	rows, err := s.db.QueryContext(ctx, query)
 	if err != nil {
-		fmt.Printf("error querying users: %v\n", err)
-		return []*UserInfo{}
+		return nil, fmt.Errorf("query users: %w", err)
 	}
 	defer rows.Close()
 
@@ -464,15 +412,17 @@ func (s *PgStore) GetAllUsers() []*UserInfo {
 	for rows.Next() {
 		u := &UserInfo{}
 		if err := rows.Scan(&u.AnonID, &u.Username, &u.PostCount, &u.CreatedAt); err != nil {
-			fmt.Printf("error scanning user: %v\n", err)
-			continue
+			return nil, fmt.Errorf("scan users: %w", err)
 		}
 		out = append(out, u)
 	}
-	return out
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("iterate users: %w", err)
+	}
+	return out, nil
 }
 
-func (s *PgStore) GetAllSessions() []*SessionInfo {
+func (s *PgStore) GetAllSessions(ctx context.Context) ([]*SessionInfo, error) {
 	query := `
 		SELECT
 			COALESCE(id::text, ''),
@@ -486,10 +436,9 @@ func (s *PgStore) GetAllSessions() []*SessionInfo {
 		ORDER BY created_at DESC
 	`
 
-	rows, err := s.db.Query(query)
+	rows, err := s.db.QueryContext(ctx, query)
 	if err != nil {
-		fmt.Printf("error querying sessions: %v\n", err)
-		return []*SessionInfo{}
+		return nil, fmt.Errorf("query sessions: %w", err)
 	}
 	defer rows.Close()
 
@@ -497,15 +446,17 @@ func (s *PgStore) GetAllSessions() []*SessionInfo {
 	for rows.Next() {
 		s := &SessionInfo{}
 		if err := rows.Scan(&s.ID, &s.AnonID, &s.Token, &s.ExpiresAt, &s.IssuedAt, &s.CreatedAt, &s.LastActivityAt); err != nil {
-			fmt.Printf("error scanning session: %v\n", err)
-			continue
+			return nil, fmt.Errorf("scan sessions: %w", err)
 		}
 		out = append(out, s)
 	}
-	return out
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("iterate sessions: %w", err)
+	}
+	return out, nil
 }
 
-func (s *PgStore) PutSession(session SessionInfo) error {
+func (s *PgStore) PutSession(ctx context.Context, session SessionInfo) error {
 	issuedAt := session.IssuedAt
 	if issuedAt.IsZero() {
 		issuedAt = session.CreatedAt
@@ -532,61 +483,97 @@ func (s *PgStore) PutSession(session SessionInfo) error {
 		session.ID = id
 	}
 
+	var refreshExpiresAt *time.Time
+	if !session.RefreshExpiresAt.IsZero() {
+		refreshExpiresAt = &session.RefreshExpiresAt
+	}
+
 	query := `
-		INSERT INTO sessions (id, anon_id, issued_at, expires_at, token, created_at, last_activity_at)
-		VALUES ($1, $2, $3, $4, $5, $6, $7)
+		INSERT INTO sessions (id, anon_id, issued_at, expires_at, token, created_at, last_activity_at, refresh_token_hash, refresh_expires_at,
+			device_public_id, region, user_agent)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, NULLIF($8, ''), $9, NULLIF($10, ''), NULLIF($11, ''), NULLIF($12, ''))
 	`
-	_, err := s.db.Exec(query, session.ID, session.AnonID, issuedAt, session.ExpiresAt, session.Token, createdAt, lastActivityAt)
+	_, err := s.db.ExecContext(ctx, query, session.ID, session.AnonID, issuedAt, session.ExpiresAt, session.Token, createdAt, lastActivityAt, session.RefreshTokenHash, refreshExpiresAt,
+		session.DevicePublicID, session.Region, session.UserAgent)
 	if err != nil {
 		return fmt.Errorf("put session: %w", err)
 	}
 	return nil
 }
 
-func (s *PgStore) GetDevice(devicePublicID string) (*Device, error) {
+func (s *PgStore) GetDevice(ctx context.Context, devicePublicID string) (*Device, error) {
 	query := `
-		SELECT device_public_id, device_secret_hash, anon_id, username, created_at, updated_at
+		SELECT device_public_id, device_secret_hash, auth_method, COALESCE(public_key, ''), anon_id, username, created_at, updated_at
 		FROM devices
 		WHERE device_public_id = $1
 	`
 	device := &Device{}
-	err := s.db.QueryRow(query, devicePublicID).Scan(
+	err := s.db.QueryRowContext(ctx, query, devicePublicID).Scan(
 		&device.DevicePublicID,
 		&device.DeviceSecretHash,
+		&device.AuthMethod,
+		&device.PublicKey,
 		&device.AnonID,
 		&device.Username,
 		&device.CreatedAt,
 		&device.UpdatedAt,
 	)
+	if err == sql.ErrNoRows {
+		return nil, ErrDeviceNotFound
+	}
 	if err != nil {
 		return nil, fmt.Errorf("get device: %w", err)
 	}
 	return device, nil
 }
 
-func (s *PgStore) GetDeviceByAnonID(anonID string) (*Device, error) {
+func (s *PgStore) GetDeviceByAnonID(ctx context.Context, anonID string) (*Device, error) {
 	query := `
-		SELECT device_public_id, device_secret_hash, anon_id, username, created_at, updated_at
+		SELECT device_public_id, device_secret_hash, auth_method, COALESCE(public_key, ''), anon_id, username, created_at, updated_at
 		FROM devices
 		WHERE anon_id = $1
+		ORDER BY created_at
 		LIMIT 1
 	`
 	device := &Device{}
-	err := s.db.QueryRow(query, anonID).Scan(
+	err := s.db.QueryRowContext(ctx, query, anonID).Scan(
 		&device.DevicePublicID,
 		&device.DeviceSecretHash,
+		&device.AuthMethod,
+		&device.PublicKey,
 		&device.AnonID,
 		&device.Username,
 		&device.CreatedAt,
 		&device.UpdatedAt,
 	)
+	if err == sql.ErrNoRows {
+		return nil, ErrDeviceNotFound
+	}
 	if err != nil {
 		return nil, fmt.Errorf("get device by anon id: %w", err)
 	}
 	return device, nil
 }
 
-func (s *PgStore) CreateDevice(device *Device) error {
+func (s *PgStore) CreateDevice(ctx context.Context, device *Device) error {
+	_, err := s.db.ExecContext(ctx, insertDeviceQuery, insertDeviceArgs(device)...)
+	if isExclusionViolation(err, "devices_username_owner") {
+		return ErrUsernameTaken
+	}
+	if err != nil {
+		return fmt.Errorf("create device: %w", err)
+	}
+	return nil
+}
+
+const insertDeviceQuery = `
+	INSERT INTO devices (device_public_id, device_secret_hash, auth_method, public_key, anon_id, username, created_at, updated_at)
+	VALUES ($1, $2, $3, NULLIF($4, ''), $5, $6, $7, $8)
+`
+
+// insertDeviceArgs fills in device's defaults and returns the arguments of
+// insertDeviceQuery.
+func insertDeviceArgs(device *Device) []interface{} {
 	createdAt := device.CreatedAt
 	if createdAt.IsZero() {
 		createdAt = time.Now()
@@ -596,53 +583,50 @@ func (s *PgStore) CreateDevice(device *Device) error {
 		updatedAt = createdAt
 	}
 
-	query := `
-		INSERT INTO devices (device_public_id, device_secret_hash, anon_id, username, created_at, updated_at)
-		VALUES ($1, $2, $3, $4, $5, $6)
-	`
-	_, err := s.db.Exec(
-		query,
+	if device.AuthMethod == "" {
+		device.AuthMethod = DeviceAuthHMAC
+	}
+
+	return []interface{}{
 		device.DevicePublicID,
 		device.DeviceSecretHash,
+		device.AuthMethod,
+		device.PublicKey,
 		device.AnonID,
 		device.Username,
 		createdAt,
 		updatedAt,
-	)
-	if err != nil {
-		return fmt.Errorf("create device: %w", err)
 	}
-	return nil
 }
 
-func (s *PgStore) UpdateDeviceTimestamp(devicePublicID string, updatedAt time.Time) error {
+func (s *PgStore) UpdateDeviceTimestamp(ctx context.Context, devicePublicID string, updatedAt time.Time) error {
 	query := `UPDATE devices SET updated_at = $1 WHERE device_public_id = $2`
-	_, err := s.db.Exec(query, updatedAt, devicePublicID)
+	_, err := s.db.ExecContext(ctx, query, updatedAt, devicePublicID)
 	if err != nil {
 		return fmt.Errorf("update device timestamp: %w", err)
 	}
 	return nil
 }
 
-func (s *PgStore) CreateDeviceNonce(devicePublicID, nonce string, expiresAt time.Time) error {
+func (s *PgStore) CreateDeviceNonce(ctx context.Context, devicePublicID, nonce string, expiresAt time.Time) error {
 	query := `
 		INSERT INTO device_nonces (device_public_id, nonce, expires_at, created_at)
 		VALUES ($1, $2, $3, $4)
 	`
-	_, err := s.db.Exec(query, devicePublicID, nonce, expiresAt, time.Now())
+	_, err := s.db.ExecContext(ctx, query, devicePublicID, nonce, expiresAt, time.Now())
 	if err != nil {
 		return fmt.Errorf("create device nonce: %w", err)
 	}
 	return nil
 }
 
-func (s *PgStore) ConsumeDeviceNonce(devicePublicID, nonce string, now time.Time) (bool, error) {
+func (s *PgStore) ConsumeDeviceNonce(ctx context.Context, devicePublicID, nonce string, now time.Time) (bool, error) {
 	query := `
 		UPDATE device_nonces
 		SET used_at = $1
 		WHERE device_public_id = $2 AND nonce = $3 AND used_at IS NULL AND expires_at >= $1
 	`
-	result, err := s.db.Exec(query, now, devicePublicID, nonce)
+	result, err := s.db.ExecContext(ctx, query, now, devicePublicID, nonce)
 	if err != nil {
 		return false, fmt.Errorf("consume device nonce: %w", err)
 	}
@@ -667,55 +651,13 @@ func newUUID() (string, error) {
 	return fmt.Sprintf("%s-%s-%s-%s-%s", encoded[0:8], encoded[8:12], encoded[12:16], encoded[16:20], encoded[20:32]), nil
 }
 
-func (s *PgStore) GetAllTrustRequests() []*TrustRequest {
-	query := `
-		SELECT id, code, from_anon, to_anon, status, created_at, updated_at
-		FROM trust_requests
-		ORDER BY created_at DESC
-	`
-	rows, err := s.db.Query(query)
-	if err != nil {
-		fmt.Printf("error querying trust requests: %v\n", err)
-		return []*TrustRequest{}
-	}
-	defer rows.Close()
-
-	out := []*TrustRequest{}
-	for rows.Next() {
-		tr := &TrustRequest{}
-		if err := rows.Scan(&tr.ID, &tr.Code, &tr.FromAnon, &tr.ToAnon, &tr.Status, &tr.CreatedAt, &tr.UpdatedAt); err != nil {
-			fmt.Printf("error scanning trust: %v\n", err)
-			continue
-		}
-		out = append(out, tr)
-	}
-	return out
-}
-
-func (s *PgStore) GetAuditLogs() []AuditLog {
-	query := `SELECT id, action, anon_id, details, timestamp FROM audit_logs ORDER BY timestamp DESC`
-	rows, err := s.db.Query(query)
-	if err != nil {
-		fmt.Printf("error querying audit logs: %v\n", err)
-		return []AuditLog{}
-	}
-	defer rows.Close()
-
-	out := []AuditLog{}
-	for rows.Next() {
-		log := AuditLog{}
-		if err := rows.Scan(&log.ID, &log.Action, &log.AnonID, &log.Details, &log.Timestamp); err != nil {
-			fmt.Printf("error scanning audit log: %v\n", err)
-			continue
-		}
-		out = append(out, log)
-	}
-	return out
+func (s *PgStore) GetAllTrustRequests(ctx context.Context) ([]*TrustRequest, error) {
+	return s.queryTrustRequests(ctx, `SELECT id, code, from_anon, to_anon, status, created_at, updated_at FROM trust_requests ORDER BY created_at DESC`)
 }
 
-func (s *PgStore) DeletePost(postID string) error {
+func (s *PgStore) DeletePost(ctx context.Context, postID string) error {
 	query := `DELETE FROM posts WHERE id = $1`
-	_, err := s.db.Exec(query, postID)
+	_, err := s.db.ExecContext(ctx, query, postID)
 	if err != nil {
 		return fmt.Errorf("delete post: %w", err)
 	}
@@ -723,18 +665,25 @@ func (s *PgStore) DeletePost(postID string) error {
 }
 
 // Session management methods
-func (s *PgStore) UpdateSessionActivity(token string) error {
+func (s *PgStore) UpdateSessionActivity(ctx context.Context, token string) error {
 	query := `UPDATE sessions SET last_activity_at = $1 WHERE token = $2`
-	_, err := s.db.Exec(query, time.Now(), token)
+	result, err := s.db.ExecContext(ctx, query, time.Now(), token)
 	if err != nil {
 		return fmt.Errorf("update session activity: %w", err)
 	}
+	if n, err := result.RowsAffected(); err != nil {
+		return fmt.Errorf("check rows affected: %w", err)
+	} else if n == 0 {
+		return ErrSessionNotFound
+	}
 	return nil
 }
 
-func (s *PgStore) CleanupExpiredSessions() (int, error) {
-	query := `DELETE FROM sessions WHERE expires_at < $1`
-	result, err := s.db.Exec(query, time.Now())
+func (s *PgStore) CleanupExpiredSessions(ctx context.Context) (int, error) {
+	// A session whose access token expired may still be refreshed; keep it
+	// until its refresh token has expired too.
+	query := `DELETE FROM sessions WHERE expires_at < $1 AND COALESCE(refresh_expires_at, expires_at) < $1`
+	result, err := s.db.ExecContext(ctx, query, time.Now())
 	if err != nil {
 		return 0, fmt.Errorf("cleanup expired sessions: %w", err)
 	}
@@ -755,19 +704,20 @@ func (s *PgStore) CleanupExpiredSessions() (int, error) {
 		)
 		WHERE is_active = true
 	`
-	_, _ = s.db.Exec(reconcileQuery)
+	_, _ = s.db.ExecContext(ctx, reconcileQuery)
 
 	return int(count), nil
 }
 
-func (s *PgStore) GetSessionByToken(token string) (*SessionInfo, error) {
+func (s *PgStore) GetSessionByToken(ctx context.Context, token string) (*SessionInfo, error) {
 	query := `
-		SELECT id, anon_id, issued_at, expires_at, token, created_at, COALESCE(last_activity_at, issued_at)
+		SELECT id, anon_id, issued_at, expires_at, token, created_at, COALESCE(last_activity_at, issued_at),
+			COALESCE(device_public_id, ''), COALESCE(region, ''), COALESCE(user_agent, '')
 		FROM sessions 
 		WHERE token = $1
 	`
 	sess := &SessionInfo{}
-	err := s.db.QueryRow(query, token).Scan(
+	err := s.db.QueryRowContext(ctx, query, token).Scan(
 		&sess.ID,
 		&sess.AnonID,
 		&sess.IssuedAt,
@@ -775,21 +725,28 @@ func (s *PgStore) GetSessionByToken(token string) (*SessionInfo, error) {
 		&sess.Token,
 		&sess.CreatedAt,
 		&sess.LastActivityAt,
+		&sess.DevicePublicID,
+		&sess.Region,
+		&sess.UserAgent,
 	)
+	if err == sql.ErrNoRows {
+		return nil, ErrSessionNotFound
+	}
 	if err != nil {
 		return nil, fmt.Errorf("get session: %w", err)
 	}
 	return sess, nil
 }
 
-func (s *PgStore) GetSessionsByAnonID(anonID string) ([]*SessionInfo, error) {
+func (s *PgStore) GetSessionsByAnonID(ctx context.Context, anonID string) ([]*SessionInfo, error) {
 	query := `
-		SELECT id, anon_id, issued_at, expires_at, token, created_at, COALESCE(last_activity_at, issued_at)
+		SELECT id, anon_id, issued_at, expires_at, token, created_at, COALESCE(last_activity_at, issued_at),
+			COALESCE(device_public_id, ''), COALESCE(region, ''), COALESCE(user_agent, '')
 		FROM sessions 
 		WHERE anon_id = $1
-		ORDER BY last_activity_at DESC
+		ORDER BY COALESCE(last_activity_at, issued_at) DESC
 	`
-	rows, err := s.db.Query(query, anonID)
+	rows, err := s.db.QueryContext(ctx, query, anonID)
 	if err != nil {
 		return nil, fmt.Errorf("get sessions by anon id: %w", err)
 	}
@@ -798,30 +755,34 @@ func (s *PgStore) GetSessionsByAnonID(anonID string) ([]*SessionInfo, error) {
 	sessions := make([]*SessionInfo, 0)
 	for rows.Next() {
 		sess := &SessionInfo{}
-		if err := rows.Scan(&sess.ID, &sess.AnonID, &sess.IssuedAt, &sess.ExpiresAt, &sess.Token, &sess.CreatedAt, &sess.LastActivityAt); err != nil {
+		if err := rows.Scan(&sess.ID, &sess.AnonID, &sess.IssuedAt, &sess.ExpiresAt, &sess.Token, &sess.CreatedAt, &sess.LastActivityAt,
+			&sess.DevicePublicID, &sess.Region, &sess.UserAgent); err != nil {
 			return nil, fmt.Errorf("scan session: %w", err)
 		}
 		sessions = append(sessions, sess)
 	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("iterate sessions: %w", err)
+	}
 
 	return sessions, nil
 }
 
-func (s *PgStore) RevokeSession(token string) error {
+func (s *PgStore) RevokeSession(ctx context.Context, token string) error {
 	// First, get the anonID for this session
 	var anonID string
 	getQuery := `SELECT anon_id FROM sessions WHERE token = $1`
-	err := s.db.QueryRow(getQuery, token).Scan(&anonID)
+	err := s.db.QueryRowContext(ctx, getQuery, token).Scan(&anonID)
 	if err != nil {
 		if err == sql.ErrNoRows {
-			return fmt.Errorf("session not found")
+			return ErrSessionNotFound
 		}
 		return fmt.Errorf("get session anon_id: %w", err)
 	}
 
 	// Delete the session
 	query := `DELETE FROM sessions WHERE token = $1`
-	result, err := s.db.Exec(query, token)
+	result, err := s.db.ExecContext(ctx, query, token)
 	if err != nil {
 		return fmt.Errorf("revoke session: %w", err)
 	}
@@ -832,18 +793,18 @@ func (s *PgStore) RevokeSession(token string) error {
 	}
 
 	if count == 0 {
-		return fmt.Errorf("session not found")
+		return ErrSessionNotFound
 	}
 
 	// Reconcile user active status
-	_ = s.ReconcileUserActiveStatus(anonID)
+	_ = s.ReconcileUserActiveStatus(ctx, anonID)
 
 	return nil
 }
 
-func (s *PgStore) RevokeAllSessionsForUser(anonID string) (int, error) {
+func (s *PgStore) RevokeAllSessionsForUser(ctx context.Context, anonID string) (int, error) {
 	query := `DELETE FROM sessions WHERE anon_id = $1`
-	result, err := s.db.Exec(query, anonID)
+	result, err := s.db.ExecContext(ctx, query, anonID)
 	if err != nil {
 		return 0, fmt.Errorf("revoke all sessions: %w", err)
 	}
@@ -854,23 +815,23 @@ func (s *PgStore) RevokeAllSessionsForUser(anonID string) (int, error) {
 	}
 
 	// Mark user as inactive since all sessions are revoked
-	_ = s.MarkUserInactive(anonID)
+	_ = s.MarkUserInactive(ctx, anonID)
 
 	return int(count), nil
 }
 
-func (s *PgStore) EnforceSessionLimit(anonID string, maxSessions int) error {
-	// Delete oldest sessions beyond the limit
+func (s *PgStore) EnforceSessionLimit(ctx context.Context, anonID string, maxSessions int) error {
+	// Keep the most recently active sessions and delete the rest
 	query := `
 		DELETE FROM sessions 
 		WHERE token IN (
 			SELECT token FROM sessions 
 			WHERE anon_id = $1
-			ORDER BY COALESCE(last_activity_at, issued_at) ASC
+			ORDER BY COALESCE(last_activity_at, issued_at) DESC
 			OFFSET $2
 		)
 	`
-	_, err := s.db.Exec(query, anonID, maxSessions)
+	_, err := s.db.ExecContext(ctx, query, anonID, maxSessions)
 	if err != nil {
 		return fmt.Errorf("enforce session limit: %w", err)
 	}
@@ -880,7 +841,7 @@ func (s *PgStore) EnforceSessionLimit(anonID string, maxSessions int) error {
 
 // ===== USER TRACKING =====
 
-func (s *PgStore) EnsureUser(anonID string, now time.Time) error {
+func (s *PgStore) EnsureUser(ctx context.Context, anonID string, now time.Time) error {
 	query := `
 		INSERT INTO users (
 			anon_id,
@@ -910,36 +871,36 @@ func (s *PgStore) EnsureUser(anonID string, now time.Time) error {
 			username_suffix = COALESCE(NULLIF(users.username_suffix, ''), EXCLUDED.username_suffix),
 			username_normalized = COALESCE(NULLIF(users.username_normalized, ''), EXCLUDED.username_normalized)
 	`
-	_, err := s.db.Exec(query, anonID, now)
+	_, err := s.db.ExecContext(ctx, query, anonID, now)
 	if err != nil {
 		return fmt.Errorf("ensure user: %w", err)
 	}
 	return nil
 }
 
-func (s *PgStore) MarkUserActive(anonID string, now time.Time) error {
+func (s *PgStore) MarkUserActive(ctx context.Context, anonID string, now time.Time) error {
 	query := `
 		UPDATE users
 		SET is_active = true, last_seen_at = $2
 		WHERE anon_id = $1
 	`
-	_, err := s.db.Exec(query, anonID, now)
+	_, err := s.db.ExecContext(ctx, query, anonID, now)
 	if err != nil {
 		return fmt.Errorf("mark user active: %w", err)
 	}
 	return nil
 }
 
-func (s *PgStore) MarkUserInactive(anonID string) error {
+func (s *PgStore) MarkUserInactive(ctx context.Context, anonID string) error {
 	query := `UPDATE users SET is_active = false WHERE anon_id = $1`
-	_, err := s.db.Exec(query, anonID)
+	_, err := s.db.ExecContext(ctx, query, anonID)
 	if err != nil {
 		return fmt.Errorf("mark user inactive: %w", err)
 	}
 	return nil
 }
 
-func (s *PgStore) UpdateUserLastSeen(anonID string, now time.Time) error {
+func (s *PgStore) UpdateUserLastSeen(ctx context.Context, anonID string, now time.Time) error {
 	// Only update if last_seen_at is NULL or more than 60 seconds ago
 	query := `
 		UPDATE users
@@ -947,34 +908,34 @@ func (s *PgStore) UpdateUserLastSeen(anonID string, now time.Time) error {
 		WHERE anon_id = $1
 		AND (last_seen_at IS NULL OR last_seen_at < $2 - INTERVAL '60 seconds')
 	`
-	_, err := s.db.Exec(query, anonID, now)
+	_, err := s.db.ExecContext(ctx, query, anonID, now)
 	if err != nil {
 		return fmt.Errorf("update user last seen: %w", err)
 	}
 	return nil
 }
 
-func (s *PgStore) GetActiveUsersCount() (int, error) {
+func (s *PgStore) GetActiveUsersCount(ctx context.Context) (int, error) {
 	query := `SELECT COUNT(*) FROM users WHERE is_active = true`
 	var count int
-	err := s.db.QueryRow(query).Scan(&count)
+	err := s.db.QueryRowContext(ctx, query).Scan(&count)
 	if err != nil {
 		return 0, fmt.Errorf("get active users count: %w", err)
 	}
 	return count, nil
 }
 
-func (s *PgStore) GetTotalUsersCount() (int, error) {
+func (s *PgStore) GetTotalUsersCount(ctx context.Context) (int, error) {
 	query := `SELECT COUNT(*) FROM users`
 	var count int
-	err := s.db.QueryRow(query).Scan(&count)
+	err := s.db.QueryRowContext(ctx, query).Scan(&count)
 	if err != nil {
 		return 0, fmt.Errorf("get total users count: %w", err)
 	}
 	return count, nil
 }
 
-func (s *PgStore) ReconcileUserActiveStatus(anonID string) error {
+func (s *PgStore) ReconcileUserActiveStatus(ctx context.Context, anonID string) error {
 	// Check if user has any active sessions (non-expired)
 	query := `
 		UPDATE users
@@ -985,26 +946,26 @@ func (s *PgStore) ReconcileUserActiveStatus(anonID string) error {
 		)
 		WHERE anon_id = $1
 	`
-	_, err := s.db.Exec(query, anonID)
+	_, err := s.db.ExecContext(ctx, query, anonID)
 	if err != nil {
 		return fmt.Errorf("reconcile user active status: %w", err)
 	}
 	return nil
 }
 
-func (s *PgStore) CreateUserBan(anonID, reason string, bannedBy string, now time.Time, expiresAt *time.Time, permanent bool) error {
+func (s *PgStore) CreateUserBan(ctx context.Context, anonID, reason string, bannedBy string, now time.Time, expiresAt *time.Time, permanent bool) error {
 	query := `
 		INSERT INTO user_bans (anon_id, reason, banned_by, banned_at, expires_at, is_permanent)
 		VALUES ($1, $2, $3, $4, $5, $6)
 	`
-	_, err := s.db.Exec(query, anonID, reason, bannedBy, now, expiresAt, permanent)
+	_, err := s.db.ExecContext(ctx, query, anonID, reason, bannedBy, now, expiresAt, permanent)
 	if err != nil {
 		return fmt.Errorf("create user ban: %w", err)
 	}
 	return nil
 }
 
-func (s *PgStore) GetActiveUserBan(anonID string, now time.Time) (*UserBan, error) {
+func (s *PgStore) GetActiveUserBan(ctx context.Context, anonID string, now time.Time) (*UserBan, error) {
 	query := `
 		SELECT anon_id, reason, banned_by, banned_at, expires_at, is_permanent
 		FROM user_bans
@@ -1017,7 +978,7 @@ func (s *PgStore) GetActiveUserBan(anonID string, now time.Time) (*UserBan, erro
 
 	var ban UserBan
 	var expiresAt sql.NullTime
-	err := s.db.QueryRow(query, anonID, now).Scan(
+	err := s.db.QueryRowContext(ctx, query, anonID, now).Scan(
 		&ban.AnonID,
 		&ban.Reason,
 		&ban.BannedBy,
@@ -1039,325 +1000,26 @@ func (s *PgStore) GetActiveUserBan(anonID string, now time.Time) (*UserBan, erro
 	return &ban, nil
 }
 
-// ===== AUDIT LOGS =====
-
-func (s *PgStore) LogAuditEvent(event AuditLog) {
-	if event.ID == "" {
-		event.ID = fmt.Sprintf("audit_%d", time.Now().UnixNano())
-	}
-	if event.Timestamp.IsZero() {
-		event.Timestamp = time.Now()
-	}
-	query := `INSERT INTO audit_logs (id, action, anon_id, details, timestamp) VALUES ($1, $2, $3, $4, $5)`
-	_, err := s.db.Exec(query, event.ID, event.Action, event.AnonID, event.Details, event.Timestamp)
-	if err != nil {
-		fmt.Printf("error logging audit event: %v\n", err)
-	}
-}
-
-func (s *PgStore) DeleteAuditLog(id string) error {
-	query := `DELETE FROM audit_logs WHERE id = $1`
-	_, err := s.db.Exec(query, id)
-	if err != nil {
-		return fmt.Errorf("delete audit log: %w", err)
-	}
-	return nil
-}
-
-func (s *PgStore) DeleteAuditLogs(ids []string) error {
-	if len(ids) == 0 {
-		return nil
-	}
-
-	// Build the DELETE query with placeholders
-	query := `DELETE FROM audit_logs WHERE id = ANY($1)`
-	_, err := s.db.Exec(query, pq.Array(ids))
-	if err != nil {
-		return fmt.Errorf("delete audit logs: %w", err)
-	}
-	return nil
-}
-
-func (s *PgStore) ClearAuditLogs() error {
-	query := `DELETE FROM audit_logs`
-	_, err := s.db.Exec(query)
-	if err != nil {
-		return fmt.Errorf("clear audit logs: %w", err)
-	}
-	return nil
-}
-
 // GetPost retrieves a post by ID
-func (s *PgStore) GetPost(postID string) (*Post, bool) {
+func (s *PgStore) GetPost(ctx context.Context, postID string) (*Post, error) {
 	query := `SELECT id, anon_id, text, created_at, likes, dislikes, deleted FROM posts WHERE id = $1`
-	row := s.db.QueryRow(query, postID)
+	row := s.db.QueryRowContext(ctx, query, postID)
 
 	p := &Post{}
 	err := row.Scan(&p.ID, &p.AnonID, &p.Text, &p.CreatedAt, &p.Likes, &p.Dislikes, &p.Deleted)
 	if err == sql.ErrNoRows {
-		return nil, false
+		return nil, ErrPostNotFound
 	}
 	if err != nil {
-		fmt.Printf("error getting post: %v\n", err)
-		return nil, false
+		return nil, fmt.Errorf("get post: %w", err)
 	}
-	return p, true
-}
-
-// SearchPosts performs a comprehensive search across posts with ranking
-// Supports keyword search, hashtag filtering, typo tolerance, and mixed queries
-func (s *PgStore) SearchPosts(query string, hashtags []string, limit int, offset int) ([]*PostSearchResult, int, error) {
-	if limit <= 0 {
-		limit = 20
-	}
-	if offset < 0 {
-		offset = 0
-	}
-
-	var results []*PostSearchResult
-	var totalCount int
-
-	// Build the base query with ranking
-	// We'll use multiple ranking strategies and combine them
-	baseQuery := `
-		WITH ranked_posts AS (
-			SELECT 
-				p.id, p.anon_id, p.text, p.created_at, p.likes, p.dislikes, p.deleted,
-				p.hashtags,
-				CASE 
-					-- Strategy 1: Full-text search relevance (highest priority)
-					WHEN $1 != '' THEN 
-						ts_rank(p.text_search, plainto_tsquery('english', $1)) * 10.0
-					ELSE 0
-				END +
-				CASE
-					-- Strategy 2: Text contains match bonus
-					WHEN $1 != '' AND LOWER(p.text) LIKE '%' || LOWER($1) || '%' THEN 5.0
-					ELSE 0
-				END +
-				CASE
-					-- Strategy 3: Text prefix match bonus
-					WHEN $1 != '' AND LOWER(p.text) LIKE LOWER($1) || '%' THEN 3.0
-					ELSE 0
-				END +
-				CASE
-					-- Strategy 4: Fuzzy similarity (typo tolerance)
-					WHEN $1 != '' THEN 
-						similarity(LOWER(p.text), LOWER($1)) * 2.0
-					ELSE 0
-				END +
-				CASE
-					-- Strategy 5: Exact identity match bonus (username or anon_id)
-					WHEN $1 != '' AND (
-						LOWER(COALESCE(d.username, '')) = LOWER($1)
-						OR LOWER(p.anon_id) = LOWER($1)
-					) THEN 12.0
-					ELSE 0
-				END +
-				CASE
-					-- Strategy 6: Username contains match bonus
-					WHEN $1 != '' AND LOWER(COALESCE(d.username, '')) LIKE '%' || LOWER($1) || '%' THEN 4.0
-					ELSE 0
-				END +
-				CASE
-					-- Strategy 7: Normalized username contains bonus (handles separators)
-					WHEN $1 != '' AND regexp_replace(LOWER(COALESCE(d.username, '')), '[^a-z0-9_]', '', 'g') LIKE '%' || regexp_replace(LOWER($1), '[^a-z0-9_]', '', 'g') || '%' THEN 3.0
-					ELSE 0
-				END +
-				CASE
-					-- Strategy 8: anon_id contains match bonus
-					WHEN $1 != '' AND LOWER(p.anon_id) LIKE '%' || LOWER($1) || '%' THEN 2.0
-					ELSE 0
-				END +
-				CASE
-					-- Strategy 9: Small recency boost (recent posts get slight advantage)
-					WHEN p.created_at > NOW() - INTERVAL '7 days' THEN 0.5
-					WHEN p.created_at > NOW() - INTERVAL '30 days' THEN 0.2
-					ELSE 0
-				END AS relevance_score
-			FROM posts p
-			LEFT JOIN devices d ON d.anon_id = p.anon_id
-			WHERE p.deleted = false
-	`
-
-	// Add hashtag filtering if hashtags are provided
-	args := []interface{}{query}
-	argIdx := 2
-	if len(hashtags) > 0 {
-		baseQuery += fmt.Sprintf(" AND p.hashtags @> $%d", argIdx)
-		args = append(args, hashtags)
-		argIdx++
-	}
-
-	// Add keyword filter (must match if query is provided)
-	if query != "" {
-		baseQuery += fmt.Sprintf(` AND (
-			p.text_search @@ plainto_tsquery('english', $%d)
-			OR LOWER(p.text) LIKE '%%' || LOWER($%d) || '%%'
-			OR similarity(LOWER(p.text), LOWER($%d)) > 0.1
-			OR LOWER(COALESCE(d.username, '')) LIKE '%%' || LOWER($%d) || '%%'
-			OR regexp_replace(LOWER(COALESCE(d.username, '')), '[^a-z0-9_]', '', 'g') LIKE '%%' || regexp_replace(LOWER($%d), '[^a-z0-9_]', '', 'g') || '%%'
-			OR LOWER(p.anon_id) = LOWER($%d)
-			OR LOWER(p.anon_id) LIKE '%%' || LOWER($%d) || '%%'
-		)`, argIdx, argIdx, argIdx, argIdx, argIdx, argIdx, argIdx)
-		args = append(args, query)
-		argIdx++
-	}
-
-	// Complete the CTE
-	baseQuery += `
-		)`
-
-	// Get total count
-	countQuery := baseQuery + `
-		SELECT COUNT(*) FROM ranked_posts WHERE relevance_score > 0
-	`
-	err := s.db.QueryRow(countQuery, args...).Scan(&totalCount)
-	if err != nil && err != sql.ErrNoRows {
-		return nil, 0, fmt.Errorf("count search results: %w", err)
-	}
-
-	// Complete the main query with ordering, pagination
-	mainQuery := baseQuery + `
-		SELECT 
-			id, anon_id, text, created_at, likes, dislikes, deleted,
-			hashtags, relevance_score
-		FROM ranked_posts
-		WHERE relevance_score > 0
-		ORDER BY relevance_score DESC, created_at DESC
-		LIMIT $` + fmt.Sprintf("%d", argIdx) + ` OFFSET $` + fmt.Sprintf("%d", argIdx+1)
-
-	args = append(args, limit, offset)
-
-	rows, err := s.db.Query(mainQuery, args...)
-	if err != nil {
-		return nil, 0, fmt.Errorf("search posts: %w", err)
-	}
-	defer rows.Close()
-
-	for rows.Next() {
-		result := &PostSearchResult{
-			Post: &Post{},
-		}
-		var hashtagsArray []string
-		var hashtagsRaw sql.NullString
-
-		err := rows.Scan(
-			&result.Post.ID,
-			&result.Post.AnonID,
-			&result.Post.Text,
-			&result.Post.CreatedAt,
-			&result.Post.Likes,
-			&result.Post.Dislikes,
-			&result.Post.Deleted,
-			&hashtagsRaw,
-			&result.RelevanceScore,
-		)
-		if err != nil {
-			fmt.Printf("error scanning search result: %v\n", err)
-			continue
-		}
-
-		// Parse hashtags array from postgres
-		if hashtagsRaw.Valid {
-			// PostgreSQL array comes as {tag1,tag2,tag3}
-			hashtagsStr := hashtagsRaw.String
-			if len(hashtagsStr) > 2 {
-				hashtagsStr = hashtagsStr[1 : len(hashtagsStr)-1] // remove { }
-				if hashtagsStr != "" {
-					for _, tag := range parsePostgresArray(hashtagsStr) {
-						hashtagsArray = append(hashtagsArray, tag)
-					}
-				}
-			}
-		}
-
-		// Extract matched terms for highlighting
-		result.MatchedTerms = extractMatchedTerms(query, hashtagsArray)
-		result.Highlights = generateHighlights(result.Post.Text, query, 100)
-
-		results = append(results, result)
-	}
-
-	if err = rows.Err(); err != nil {
-		return nil, 0, fmt.Errorf("iterate search results: %w", err)
-	}
-
-	return results, totalCount, nil
-}
-
-// Helper function to parse PostgreSQL array format
-func parsePostgresArray(s string) []string {
-	if s == "" {
-		return []string{}
-	}
-	// Simple split - for production, consider using a proper parser
-	parts := []string{}
-	for _, part := range splitPostgresArray(s) {
-		if part != "" {
-			parts = append(parts, part)
-		}
-	}
-	return parts
-}
-
-func splitPostgresArray(s string) []string {
-	// Handle quoted and unquoted elements
-	var result []string
-	var current []rune
-	inQuote := false
-
-	for i, r := range s {
-		if r == '"' {
-			inQuote = !inQuote
-		} else if r == ',' && !inQuote {
-			if len(current) > 0 {
-				result = append(result, string(current))
-				current = []rune{}
-			}
-		} else {
-			current = append(current, r)
-		}
-
-		// Add last element
-		if i == len(s)-1 && len(current) > 0 {
-			result = append(result, string(current))
-		}
-	}
-
-	return result
-}
-
-// Extract terms that matched from the query
-func extractMatchedTerms(query string, hashtags []string) []string {
-	terms := []string{}
-	if query != "" {
-		terms = append(terms, query)
-	}
-	terms = append(terms, hashtags...)
-	return terms
-}
-
-// Generate highlighted snippet of text
-func generateHighlights(text string, query string, maxLen int) string {
-	if query == "" || len(text) <= maxLen {
-		if len(text) > maxLen {
-			return text[:maxLen] + "..."
-		}
-		return text
-	}
-
-	// Simple highlighting: return snippet around the match
-	if len(text) > maxLen {
-		return text[:maxLen] + "..."
-	}
-	return text
+	return p, nil
 }
 
 // DeletePostByUser marks a post as deleted if the user is the author
-func (s *PgStore) DeletePostByUser(postID, anonID string) error {
-	query := `UPDATE posts SET deleted = true WHERE id = $1 AND anon_id = $2 AND deleted = false`
-	result, err := s.db.Exec(query, postID, anonID)
+func (s *PgStore) DeletePostByUser(ctx context.Context, postID, anonID string) error {
+	query := `UPDATE posts SET deleted = true WHERE id = $1 AND anon_id = $2`
+	result, err := s.db.ExecContext(ctx, query, postID, anonID)
 	if err != nil {
 		return fmt.Errorf("delete post: %w", err)
 	}
@@ -1367,17 +1029,16 @@ func (s *PgStore) DeletePostByUser(postID, anonID string) error {
 		return fmt.Errorf("check rows affected: %w", err)
 	}
 
-	if rows == 0 {
-		return fmt.Errorf("unauthorized: not post author or post not found")
+	if rows > 0 {
+		return nil
 	}
-
-	return nil
+	return s.softDeleteRefused(ctx, `posts`, postID, ErrPostNotFound, "post")
 }
 
 // ReactToPost adds or updates a user's reaction to a post
-func (s *PgStore) ReactToPost(postID, anonID, reactionType string) error {
+func (s *PgStore) ReactToPost(ctx context.Context, postID, anonID, reactionType string) error {
 	// Start transaction
-	tx, err := s.db.Begin()
+	tx, err := s.db.BeginTx(ctx, nil)
 	if err != nil {
 		return fmt.Errorf("begin transaction: %w", err)
 	}
@@ -1385,20 +1046,20 @@ func (s *PgStore) ReactToPost(postID, anonID, reactionType string) error {
 
 	// Check if post exists and is not deleted
 	var deleted bool
-	err = tx.QueryRow(`SELECT deleted FROM posts WHERE id = $1`, postID).Scan(&deleted)
+	err = tx.QueryRowContext(ctx, `SELECT deleted FROM posts WHERE id = $1`, postID).Scan(&deleted)
 	if err == sql.ErrNoRows {
-		return fmt.Errorf("post not found")
+		return ErrPostNotFound
 	}
 	if err != nil {
 		return fmt.Errorf("check post: %w", err)
 	}
 	if deleted {
-		return fmt.Errorf("post is deleted")
+		return ErrPostNotFound
 	}
 
 	// Get existing reaction
 	var existingReaction sql.NullString
-	err = tx.QueryRow(`SELECT reaction_type FROM post_reactions WHERE post_id = $1 AND anon_id = $2`,
+	err = tx.QueryRowContext(ctx, `SELECT reaction_type FROM post_reactions WHERE post_id = $1 AND anon_id = $2`,
 		postID, anonID).Scan(&existingReaction)
 	if err != nil && err != sql.ErrNoRows {
 		return fmt.Errorf("get existing reaction: %w", err)
@@ -1406,16 +1067,16 @@ func (s *PgStore) ReactToPost(postID, anonID, reactionType string) error {
 
 	// If same reaction, remove it (toggle off)
 	if existingReaction.Valid && existingReaction.String == reactionType {
-		_, err = tx.Exec(`DELETE FROM post_reactions WHERE post_id = $1 AND anon_id = $2`, postID, anonID)
+		_, err = tx.ExecContext(ctx, `DELETE FROM post_reactions WHERE post_id = $1 AND anon_id = $2`, postID, anonID)
 		if err != nil {
 			return fmt.Errorf("remove reaction: %w", err)
 		}
 
 		// Decrement counter
 		if reactionType == "like" {
-			_, err = tx.Exec(`UPDATE posts SET likes = likes - 1 WHERE id = $1`, postID)
+			_, err = tx.ExecContext(ctx, `UPDATE posts SET likes = likes - 1 WHERE id = $1`, postID)
 		} else if reactionType == "dislike" {
-			_, err = tx.Exec(`UPDATE posts SET dislikes = dislikes - 1 WHERE id = $1`, postID)
+			_, err = tx.ExecContext(ctx, `UPDATE posts SET dislikes = dislikes - 1 WHERE id = $1`, postID)
 		}
 		if err != nil {
 			return fmt.Errorf("decrement counter: %w", err)
@@ -1427,9 +1088,9 @@ func (s *PgStore) ReactToPost(postID, anonID, reactionType string) error {
 	// If different reaction exists, update counters
 	if existingReaction.Valid {
 		if existingReaction.String == "like" {
-			_, err = tx.Exec(`UPDATE posts SET likes = likes - 1 WHERE id = $1`, postID)
+			_, err = tx.ExecContext(ctx, `UPDATE posts SET likes = likes - 1 WHERE id = $1`, postID)
 		} else if existingReaction.String == "dislike" {
-			_, err = tx.Exec(`UPDATE posts SET dislikes = dislikes - 1 WHERE id = $1`, postID)
+			_, err = tx.ExecContext(ctx, `UPDATE posts SET dislikes = dislikes - 1 WHERE id = $1`, postID)
 		}
 		if err != nil {
 			return fmt.Errorf("decrement old counter: %w", err)
@@ -1437,7 +1098,7 @@ func (s *PgStore) ReactToPost(postID, anonID, reactionType string) error {
 	}
 
 	// Add/update reaction
-	_, err = tx.Exec(`
+	_, err = tx.ExecContext(ctx, `
 		INSERT INTO post_reactions (post_id, anon_id, reaction_type, created_at)
 		VALUES ($1, $2, $3, $4)
 		ON CONFLICT (post_id, anon_id) DO UPDATE SET reaction_type = $3, created_at = $4
@@ -1448,9 +1109,9 @@ func (s *PgStore) ReactToPost(postID, anonID, reactionType string) error {
 
 	// Increment new counter
 	if reactionType == "like" {
-		_, err = tx.Exec(`UPDATE posts SET likes = likes + 1 WHERE id = $1`, postID)
+		_, err = tx.ExecContext(ctx, `UPDATE posts SET likes = likes + 1 WHERE id = $1`, postID)
 	} else if reactionType == "dislike" {
-		_, err = tx.Exec(`UPDATE posts SET dislikes = dislikes + 1 WHERE id = $1`, postID)
+		_, err = tx.ExecContext(ctx, `UPDATE posts SET dislikes = dislikes + 1 WHERE id = $1`, postID)
 	}
 	if err != nil {
 		return fmt.Errorf("increment counter: %w", err)
@@ -1460,37 +1121,46 @@ func (s *PgStore) ReactToPost(postID, anonID, reactionType string) error {
 }
 
 // GetPostReaction retrieves a user's reaction to a post
-func (s *PgStore) GetPostReaction(postID, anonID string) (string, bool) {
-	var reactionType string
-	err := s.db.QueryRow(`SELECT reaction_type FROM post_reactions WHERE post_id = $1 AND anon_id = $2`,
-		postID, anonID).Scan(&reactionType)
+func (s *PgStore) GetPostReaction(ctx context.Context, postID, anonID string) (string, error) {
+	var reaction string
+	err := s.db.QueryRowContext(ctx, `SELECT reaction_type FROM post_reactions WHERE post_id = $1 AND anon_id = $2`,
+		postID, anonID).Scan(&reaction)
 	if err == sql.ErrNoRows {
-		return "", false
+		return "", nil
 	}
 	if err != nil {
-		fmt.Printf("error getting reaction: %v\n", err)
-		return "", false
+		return "", fmt.Errorf("get post reaction: %w", err)
 	}
-	return reactionType, true
+	return reaction, nil
 }
 
 // AddComment adds a comment to a post
-func (s *PgStore) AddComment(comment *PostComment) error {
-	query := `INSERT INTO post_comments (id, post_id, anon_id, text, created_at, likes, dislikes, deleted) VALUES ($1, $2, $3, $4, $5, $6, $7, $8)`
-	_, err := s.db.Exec(query, comment.ID, comment.PostID, comment.AnonID, comment.Text, comment.CreatedAt, comment.Likes, comment.Dislikes, comment.Deleted)
+// addCommentQuery inserts a comment unless its post is missing or deleted.
+const addCommentQuery = `
+	INSERT INTO post_comments (id, post_id, anon_id, text, created_at, likes, dislikes, deleted)
+	SELECT $1, $2, $3, $4, $5, $6, $7, $8
+	WHERE EXISTS (SELECT 1 FROM posts WHERE id = $2 AND deleted = false)
+`
+
+func (s *PgStore) AddComment(ctx context.Context, comment *PostComment) error {
+	result, err := s.db.ExecContext(ctx, addCommentQuery, comment.ID, comment.PostID, comment.AnonID, comment.Text, comment.CreatedAt, comment.Likes, comment.Dislikes, comment.Deleted)
 	if err != nil {
 		return fmt.Errorf("add comment: %w", err)
 	}
+	if n, err := result.RowsAffected(); err != nil {
+		return fmt.Errorf("check rows affected: %w", err)
+	} else if n == 0 {
+		return ErrPostNotFound
+	}
 	return nil
 }
 
 // GetComments retrieves all non-deleted comments for a post
-func (s *PgStore) GetComments(postID string) []*PostComment {
+func (s *PgStore) GetComments(ctx context.Context, postID string) ([]*PostComment, error) {
 	query := `SELECT id, post_id, anon_id, text, created_at, likes, dislikes, deleted FROM post_comments WHERE post_id = $1 AND deleted = false ORDER BY created_at ASC`
-	rows, err := s.db.Query(query, postID)
+	rows, err := s.db.QueryContext(ctx, query, postID)
 	if err != nil {
-		fmt.Printf("error querying comments: %v\n", err)
-		return []*PostComment{}
+		return nil, fmt.Errorf("query comments: %w", err)
 	}
 	defer rows.Close()
 
@@ -1498,36 +1168,37 @@ func (s *PgStore) GetComments(postID string) []*PostComment {
 	for rows.Next() {
 		c := &PostComment{}
 		if err := rows.Scan(&c.ID, &c.PostID, &c.AnonID, &c.Text, &c.CreatedAt, &c.Likes, &c.Dislikes, &c.Deleted); err != nil {
-			fmt.Printf("error scanning comment: %v\n", err)
-			continue
+			return nil, fmt.Errorf("scan comments: %w", err)
 		}
 		comments = append(comments, c)
 	}
 
-	return comments
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("iterate comments: %w", err)
+	}
+	return comments, nil
 }
 
 // GetComment retrieves a comment by ID
-func (s *PgStore) GetComment(commentID string) (*PostComment, bool) {
+func (s *PgStore) GetComment(ctx context.Context, commentID string) (*PostComment, error) {
 	query := `SELECT id, post_id, anon_id, text, created_at, likes, dislikes, deleted FROM post_comments WHERE id = $1`
-	row := s.db.QueryRow(query, commentID)
+	row := s.db.QueryRowContext(ctx, query, commentID)
 
 	comment := &PostComment{}
 	if err := row.Scan(&comment.ID, &comment.PostID, &comment.AnonID, &comment.Text, &comment.CreatedAt, &comment.Likes, &comment.Dislikes, &comment.Deleted); err != nil {
 		if err == sql.ErrNoRows {
-			return nil, false
+			return nil, ErrCommentNotFound
 		}
-		fmt.Printf("error getting comment: %v\n", err)
-		return nil, false
+		return nil, fmt.Errorf("get comment: %w", err)
 	}
 
-	return comment, true
+	return comment, nil
 }
 
 // DeleteCommentByUser soft-deletes a comment if user is the author
-func (s *PgStore) DeleteCommentByUser(commentID, anonID string) error {
-	query := `UPDATE post_comments SET deleted = true WHERE id = $1 AND anon_id = $2 AND deleted = false`
-	result, err := s.db.Exec(query, commentID, anonID)
+func (s *PgStore) DeleteCommentByUser(ctx context.Context, commentID, anonID string) error {
+	query := `UPDATE post_comments SET deleted = true WHERE id = $1 AND anon_id = $2`
+	result, err := s.db.ExecContext(ctx, query, commentID, anonID)
 	if err != nil {
 		return fmt.Errorf("delete comment: %w", err)
 	}
@@ -1537,50 +1208,49 @@ func (s *PgStore) DeleteCommentByUser(commentID, anonID string) error {
 		return fmt.Errorf("check rows affected: %w", err)
 	}
 
-	if rows == 0 {
-		return fmt.Errorf("unauthorized: not comment author or comment not found")
+	if rows > 0 {
+		return nil
 	}
-
-	return nil
+	return s.softDeleteRefused(ctx, `post_comments`, commentID, ErrCommentNotFound, "comment")
 }
 
 // ReactToComment adds or updates a user's reaction to a comment
-func (s *PgStore) ReactToComment(commentID, anonID, reactionType string) error {
-	tx, err := s.db.Begin()
+func (s *PgStore) ReactToComment(ctx context.Context, commentID, anonID, reactionType string) error {
+	tx, err := s.db.BeginTx(ctx, nil)
 	if err != nil {
 		return fmt.Errorf("begin transaction: %w", err)
 	}
 	defer tx.Rollback()
 
 	var deleted bool
-	err = tx.QueryRow(`SELECT deleted FROM post_comments WHERE id = $1`, commentID).Scan(&deleted)
+	err = tx.QueryRowContext(ctx, `SELECT deleted FROM post_comments WHERE id = $1`, commentID).Scan(&deleted)
 	if err == sql.ErrNoRows {
-		return fmt.Errorf("comment not found")
+		return ErrCommentNotFound
 	}
 	if err != nil {
 		return fmt.Errorf("check comment: %w", err)
 	}
 	if deleted {
-		return fmt.Errorf("comment is deleted")
+		return ErrCommentNotFound
 	}
 
 	var existingReaction sql.NullString
-	err = tx.QueryRow(`SELECT reaction_type FROM comment_reactions WHERE comment_id = $1 AND anon_id = $2`,
+	err = tx.QueryRowContext(ctx, `SELECT reaction_type FROM comment_reactions WHERE comment_id = $1 AND anon_id = $2`,
 		commentID, anonID).Scan(&existingReaction)
 	if err != nil && err != sql.ErrNoRows {
 		return fmt.Errorf("get existing reaction: %w", err)
 	}
 
 	if existingReaction.Valid && existingReaction.String == reactionType {
-		_, err = tx.Exec(`DELETE FROM comment_reactions WHERE comment_id = $1 AND anon_id = $2`, commentID, anonID)
+		_, err = tx.ExecContext(ctx, `DELETE FROM comment_reactions WHERE comment_id = $1 AND anon_id = $2`, commentID, anonID)
 		if err != nil {
 			return fmt.Errorf("remove reaction: %w", err)
 		}
 
 		if reactionType == "like" {
-			_, err = tx.Exec(`UPDATE post_comments SET likes = likes - 1 WHERE id = $1`, commentID)
+			_, err = tx.ExecContext(ctx, `UPDATE post_comments SET likes = likes - 1 WHERE id = $1`, commentID)
 		} else if reactionType == "dislike" {
-			_, err = tx.Exec(`UPDATE post_comments SET dislikes = dislikes - 1 WHERE id = $1`, commentID)
+			_, err = tx.ExecContext(ctx, `UPDATE post_comments SET dislikes = dislikes - 1 WHERE id = $1`, commentID)
 		}
 		if err != nil {
 			return fmt.Errorf("decrement counter: %w", err)
@@ -1591,16 +1261,16 @@ func (s *PgStore) ReactToComment(commentID, anonID, reactionType string) error {
 
 	if existingReaction.Valid {
 		if existingReaction.String == "like" {
-			_, err = tx.Exec(`UPDATE post_comments SET likes = likes - 1 WHERE id = $1`, commentID)
+			_, err = tx.ExecContext(ctx, `UPDATE post_comments SET likes = likes - 1 WHERE id = $1`, commentID)
 		} else if existingReaction.String == "dislike" {
-			_, err = tx.Exec(`UPDATE post_comments SET dislikes = dislikes - 1 WHERE id = $1`, commentID)
+			_, err = tx.ExecContext(ctx, `UPDATE post_comments SET dislikes = dislikes - 1 WHERE id = $1`, commentID)
 		}
 		if err != nil {
 			return fmt.Errorf("decrement old counter: %w", err)
 		}
 	}
 
-	_, err = tx.Exec(`
+	_, err = tx.ExecContext(ctx, `
 		INSERT INTO comment_reactions (comment_id, anon_id, reaction_type, created_at)
 		VALUES ($1, $2, $3, $4)
 		ON CONFLICT (comment_id, anon_id) DO UPDATE SET reaction_type = $3, created_at = $4
@@ -1610,9 +1280,9 @@ func (s *PgStore) ReactToComment(commentID, anonID, reactionType string) error {
 	}
 
 	if reactionType == "like" {
-		_, err = tx.Exec(`UPDATE post_comments SET likes = likes + 1 WHERE id = $1`, commentID)
+		_, err = tx.ExecContext(ctx, `UPDATE post_comments SET likes = likes + 1 WHERE id = $1`, commentID)
 	} else if reactionType == "dislike" {
-		_, err = tx.Exec(`UPDATE post_comments SET dislikes = dislikes + 1 WHERE id = $1`, commentID)
+		_, err = tx.ExecContext(ctx, `UPDATE post_comments SET dislikes = dislikes + 1 WHERE id = $1`, commentID)
 	}
 	if err != nil {
 		return fmt.Errorf("increment counter: %w", err)
@@ -1622,37 +1292,47 @@ func (s *PgStore) ReactToComment(commentID, anonID, reactionType string) error {
 }
 
 // GetCommentReaction retrieves a user's reaction to a comment
-func (s *PgStore) GetCommentReaction(commentID, anonID string) (string, bool) {
-	var reactionType string
-	err := s.db.QueryRow(`SELECT reaction_type FROM comment_reactions WHERE comment_id = $1 AND anon_id = $2`,
-		commentID, anonID).Scan(&reactionType)
+func (s *PgStore) GetCommentReaction(ctx context.Context, commentID, anonID string) (string, error) {
+	var reaction string
+	err := s.db.QueryRowContext(ctx, `SELECT reaction_type FROM comment_reactions WHERE comment_id = $1 AND anon_id = $2`,
+		commentID, anonID).Scan(&reaction)
 	if err == sql.ErrNoRows {
-		return "", false
+		return "", nil
 	}
 	if err != nil {
-		fmt.Printf("error getting comment reaction: %v\n", err)
-		return "", false
+		return "", fmt.Errorf("get comment reaction: %w", err)
 	}
-	return reactionType, true
+	return reaction, nil
 }
 
 // AddCommentReply adds a reply to a comment
-func (s *PgStore) AddCommentReply(reply *CommentReply) error {
-	query := `INSERT INTO comment_replies (id, comment_id, anon_id, text, created_at, deleted, likes, dislikes) VALUES ($1, $2, $3, $4, $5, $6, 0, 0)`
-	_, err := s.db.Exec(query, reply.ID, reply.CommentID, reply.AnonID, reply.Text, reply.CreatedAt, reply.Deleted)
+// addCommentReplyQuery inserts a reply unless its comment is missing or
+// deleted.
+const addCommentReplyQuery = `
+	INSERT INTO comment_replies (id, comment_id, anon_id, text, created_at, deleted, likes, dislikes)
+	SELECT $1, $2, $3, $4, $5, $6, 0, 0
+	WHERE EXISTS (SELECT 1 FROM post_comments WHERE id = $2 AND deleted = false)
+`
+
+func (s *PgStore) AddCommentReply(ctx context.Context, reply *CommentReply) error {
+	result, err := s.db.ExecContext(ctx, addCommentReplyQuery, reply.ID, reply.CommentID, reply.AnonID, reply.Text, reply.CreatedAt, reply.Deleted)
 	if err != nil {
 		return fmt.Errorf("add comment reply: %w", err)
 	}
+	if n, err := result.RowsAffected(); err != nil {
+		return fmt.Errorf("check rows affected: %w", err)
+	} else if n == 0 {
+		return ErrCommentNotFound
+	}
 	return nil
 }
 
 // GetCommentReplies retrieves all non-deleted replies for a comment
-func (s *PgStore) GetCommentReplies(commentID string) []*CommentReply {
+func (s *PgStore) GetCommentReplies(ctx context.Context, commentID string) ([]*CommentReply, error) {
 	query := `SELECT id, comment_id, anon_id, text, created_at, deleted, likes, dislikes FROM comment_replies WHERE comment_id = $1 AND deleted = false ORDER BY created_at ASC`
-	rows, err := s.db.Query(query, commentID)
+	rows, err := s.db.QueryContext(ctx, query, commentID)
 	if err != nil {
-		fmt.Printf("error querying comment replies: %v\n", err)
-		return []*CommentReply{}
+		return nil, fmt.Errorf("query comment replies: %w", err)
 	}
 	defer rows.Close()
 
@@ -1660,30 +1340,35 @@ func (s *PgStore) GetCommentReplies(commentID string) []*CommentReply {
 	for rows.Next() {
 		r := &CommentReply{}
 		if err := rows.Scan(&r.ID, &r.CommentID, &r.AnonID, &r.Text, &r.CreatedAt, &r.Deleted, &r.Likes, &r.Dislikes); err != nil {
-			fmt.Printf("error scanning comment reply: %v\n", err)
-			continue
+			return nil, fmt.Errorf("scan comment replies: %w", err)
 		}
 		replies = append(replies, r)
 	}
 
-	return replies
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("iterate comment replies: %w", err)
+	}
+	return replies, nil
 }
 
 // GetReply retrieves a single reply by ID
-func (s *PgStore) GetReply(replyID string) (*CommentReply, bool) {
+func (s *PgStore) GetReply(ctx context.Context, replyID string) (*CommentReply, error) {
 	query := `SELECT id, comment_id, anon_id, text, created_at, likes, dislikes, deleted FROM comment_replies WHERE id = $1`
 	r := &CommentReply{}
-	err := s.db.QueryRow(query, replyID).Scan(&r.ID, &r.CommentID, &r.AnonID, &r.Text, &r.CreatedAt, &r.Likes, &r.Dislikes, &r.Deleted)
+	err := s.db.QueryRowContext(ctx, query, replyID).Scan(&r.ID, &r.CommentID, &r.AnonID, &r.Text, &r.CreatedAt, &r.Likes, &r.Dislikes, &r.Deleted)
+	if err == sql.ErrNoRows {
+		return nil, ErrReplyNotFound
+	}
 	if err != nil {
-		return nil, false
+		return nil, fmt.Errorf("get reply: %w", err)
 	}
-	return r, true
+	return r, nil
 }
 
 // DeleteCommentReplyByUser soft-deletes a reply if user is the author
-func (s *PgStore) DeleteCommentReplyByUser(replyID, anonID string) error {
-	query := `UPDATE comment_replies SET deleted = true WHERE id = $1 AND anon_id = $2 AND deleted = false`
-	result, err := s.db.Exec(query, replyID, anonID)
+func (s *PgStore) DeleteCommentReplyByUser(ctx context.Context, replyID, anonID string) error {
+	query := `UPDATE comment_replies SET deleted = true WHERE id = $1 AND anon_id = $2`
+	result, err := s.db.ExecContext(ctx, query, replyID, anonID)
 	if err != nil {
 		return fmt.Errorf("delete comment reply: %w", err)
 	}
@@ -1693,29 +1378,40 @@ func (s *PgStore) DeleteCommentReplyByUser(replyID, anonID string) error {
 		return fmt.Errorf("check rows affected: %w", err)
 	}
 
-	if rows == 0 {
-		return fmt.Errorf("unauthorized: not reply author or reply not found")
+	if rows > 0 {
+		return nil
 	}
+	return s.softDeleteRefused(ctx, `comment_replies`, replyID, ErrReplyNotFound, "reply")
+}
 
-	return nil
+// softDeleteRefused explains why a soft delete by anon_id changed no rows:
+// the row is missing, or it belongs to someone else.
+func (s *PgStore) softDeleteRefused(ctx context.Context, table, id string, notFound error, noun string) error {
+	var author string
+	err := s.db.QueryRowContext(ctx, `SELECT anon_id FROM `+table+` WHERE id = $1`, id).Scan(&author)
+	if err == sql.ErrNoRows {
+		return notFound
+	}
+	if err != nil {
+		return fmt.Errorf("check %s author: %w", noun, err)
+	}
+	return fmt.Errorf("not %s author: %w", noun, ErrForbidden)
 }
 
 // GetCommentRepliesCount returns count of non-deleted replies for a comment
-func (s *PgStore) GetCommentRepliesCount(commentID string) int {
+func (s *PgStore) GetCommentRepliesCount(ctx context.Context, commentID string) (int, error) {
 	var count int
 	query := `SELECT COUNT(*) FROM comment_replies WHERE comment_id = $1 AND deleted = false`
-	err := s.db.QueryRow(query, commentID).Scan(&count)
-	if err != nil {
-		fmt.Printf("error counting comment replies: %v\n", err)
-		return 0
+	if err := s.db.QueryRowContext(ctx, query, commentID).Scan(&count); err != nil {
+		return 0, fmt.Errorf("count comment replies: %w", err)
 	}
-	return count
+	return count, nil
 }
 
 // ReactToReply adds or updates a user's reaction to a reply
-func (s *PgStore) ReactToReply(replyID, anonID, reactionType string) error {
+func (s *PgStore) ReactToReply(ctx context.Context, replyID, anonID, reactionType string) error {
 	// Start transaction
-	tx, err := s.db.Begin()
+	tx, err := s.db.BeginTx(ctx, nil)
 	if err != nil {
 		return fmt.Errorf("begin transaction: %w", err)
 	}
@@ -1723,36 +1419,36 @@ func (s *PgStore) ReactToReply(replyID, anonID, reactionType string) error {
 
 	// Check if reply exists and isn't deleted
 	var deleted bool
-	err = tx.QueryRow(`SELECT deleted FROM comment_replies WHERE id = $1`, replyID).Scan(&deleted)
+	err = tx.QueryRowContext(ctx, `SELECT deleted FROM comment_replies WHERE id = $1`, replyID).Scan(&deleted)
 	if err == sql.ErrNoRows {
-		return fmt.Errorf("reply not found")
+		return ErrReplyNotFound
 	}
 	if err != nil {
 		return fmt.Errorf("check reply: %w", err)
 	}
 	if deleted {
-		return fmt.Errorf("reply is deleted")
+		return ErrReplyNotFound
 	}
 
 	// Get existing reaction
 	var existingReaction sql.NullString
-	err = tx.QueryRow(`SELECT reaction FROM reply_reactions WHERE reply_id = $1 AND anon_id = $2`, replyID, anonID).Scan(&existingReaction)
+	err = tx.QueryRowContext(ctx, `SELECT reaction FROM reply_reactions WHERE reply_id = $1 AND anon_id = $2`, replyID, anonID).Scan(&existingReaction)
 	if err != nil && err != sql.ErrNoRows {
 		return fmt.Errorf("get existing reaction: %w", err)
 	}
 
 	// If same reaction, remove it (toggle off)
 	if existingReaction.Valid && existingReaction.String == reactionType {
-		_, err = tx.Exec(`DELETE FROM reply_reactions WHERE reply_id = $1 AND anon_id = $2`, replyID, anonID)
+		_, err = tx.ExecContext(ctx, `DELETE FROM reply_reactions WHERE reply_id = $1 AND anon_id = $2`, replyID, anonID)
 		if err != nil {
 			return fmt.Errorf("remove reaction: %w", err)
 		}
 
 		// Decrement counter
 		if reactionType == "like" {
-			_, err = tx.Exec(`UPDATE comment_replies SET likes = likes - 1 WHERE id = $1`, replyID)
+			_, err = tx.ExecContext(ctx, `UPDATE comment_replies SET likes = likes - 1 WHERE id = $1`, replyID)
 		} else if reactionType == "dislike" {
-			_, err = tx.Exec(`UPDATE comment_replies SET dislikes = dislikes - 1 WHERE id = $1`, replyID)
+			_, err = tx.ExecContext(ctx, `UPDATE comment_replies SET dislikes = dislikes - 1 WHERE id = $1`, replyID)
 		}
 		if err != nil {
 			return fmt.Errorf("decrement counter: %w", err)
@@ -1764,9 +1460,9 @@ func (s *PgStore) ReactToReply(replyID, anonID, reactionType string) error {
 	// If different reaction exists, update counters
 	if existingReaction.Valid {
 		if existingReaction.String == "like" {
-			_, err = tx.Exec(`UPDATE comment_replies SET likes = likes - 1 WHERE id = $1`, replyID)
+			_, err = tx.ExecContext(ctx, `UPDATE comment_replies SET likes = likes - 1 WHERE id = $1`, replyID)
 		} else if existingReaction.String == "dislike" {
-			_, err = tx.Exec(`UPDATE comment_replies SET dislikes = dislikes - 1 WHERE id = $1`, replyID)
+			_, err = tx.ExecContext(ctx, `UPDATE comment_replies SET dislikes = dislikes - 1 WHERE id = $1`, replyID)
 		}
 		if err != nil {
 			return fmt.Errorf("decrement old counter: %w", err)
@@ -1774,7 +1470,7 @@ func (s *PgStore) ReactToReply(replyID, anonID, reactionType string) error {
 	}
 
 	// Add/update reaction
-	_, err = tx.Exec(`
+	_, err = tx.ExecContext(ctx, `
 		INSERT INTO reply_reactions (reply_id, anon_id, reaction, created_at)
 		VALUES ($1, $2, $3, $4)
 		ON CONFLICT (reply_id, anon_id) DO UPDATE SET reaction = $3, created_at = $4
@@ -1785,9 +1481,9 @@ func (s *PgStore) ReactToReply(replyID, anonID, reactionType string) error {
 
 	// Increment new counter
 	if reactionType == "like" {
-		_, err = tx.Exec(`UPDATE comment_replies SET likes = likes + 1 WHERE id = $1`, replyID)
+		_, err = tx.ExecContext(ctx, `UPDATE comment_replies SET likes = likes + 1 WHERE id = $1`, replyID)
 	} else if reactionType == "dislike" {
-		_, err = tx.Exec(`UPDATE comment_replies SET dislikes = dislikes + 1 WHERE id = $1`, replyID)
+		_, err = tx.ExecContext(ctx, `UPDATE comment_replies SET dislikes = dislikes + 1 WHERE id = $1`, replyID)
 	}
 	if err != nil {
 		return fmt.Errorf("increment counter: %w", err)
@@ -1797,23 +1493,25 @@ func (s *PgStore) ReactToReply(replyID, anonID, reactionType string) error {
 }
 
 // GetReplyReaction retrieves a user's reaction to a reply
-func (s *PgStore) GetReplyReaction(replyID, anonID string) (string, bool) {
+func (s *PgStore) GetReplyReaction(ctx context.Context, replyID, anonID string) (string, error) {
 	var reaction string
-	err := s.db.QueryRow(`SELECT reaction FROM reply_reactions WHERE reply_id = $1 AND anon_id = $2`, replyID, anonID).Scan(&reaction)
+	err := s.db.QueryRowContext(ctx, `SELECT reaction FROM reply_reactions WHERE reply_id = $1 AND anon_id = $2`,
+		replyID, anonID).Scan(&reaction)
+	if err == sql.ErrNoRows {
+		return "", nil
+	}
 	if err != nil {
-		return "", false
+		return "", fmt.Errorf("get reply reaction: %w", err)
 	}
-	return reaction, true
+	return reaction, nil
 }
 
 // GetCommentsCount returns count of non-deleted comments for a post
-func (s *PgStore) GetCommentsCount(postID string) int {
+func (s *PgStore) GetCommentsCount(ctx context.Context, postID string) (int, error) {
 	var count int
 	query := `SELECT COUNT(*) FROM post_comments WHERE post_id = $1 AND deleted = false`
-	err := s.db.QueryRow(query, postID).Scan(&count)
-	if err != nil {
-		fmt.Printf("error counting comments: %v\n", err)
-		return 0
+	if err := s.db.QueryRowContext(ctx, query, postID).Scan(&count); err != nil {
+		return 0, fmt.Errorf("count comments: %w", err)
 	}
-	return count
+	return count, nil
 }
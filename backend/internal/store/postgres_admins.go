@@ -0,0 +1,282 @@
+package store
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"strings"
+	"time"
+
+	"anon-backend/internal/security"
+)
+
+func (s *PgStore) CreateAdmin(ctx context.Context, admin *Admin) error {
+	admin.Email = strings.ToLower(strings.TrimSpace(admin.Email))
+	if !security.IsAdminRole(admin.Role) {
+		return fmt.Errorf("%w: unknown role %q", ErrInvalidAdminAccount, admin.Role)
+	}
+	if admin.ID == "" {
+		id, err := newUUID()
+		if err != nil {
+			return fmt.Errorf("generate admin id: %w", err)
+		}
+		admin.ID = id
+	}
+	if admin.CreatedAt.IsZero() {
+		admin.CreatedAt = time.Now()
+	}
+
+	query := `
+		INSERT INTO admins (id, email, password_hash, role, disabled, created_at)
+		VALUES ($1, $2, $3, $4, $5, $6)
+	`
+	_, err := s.db.ExecContext(ctx, query, admin.ID, admin.Email, admin.PasswordHash, admin.Role, admin.Disabled, admin.CreatedAt)
+	if err != nil {
+		if isUniqueViolation(err) {
+			return ErrAdminExists
+		}
+		return fmt.Errorf("create admin: %w", err)
+	}
+	return nil
+}
+
+func (s *PgStore) GetAdminByEmail(ctx context.Context, email string) (*Admin, error) {
+	query := `
+		SELECT ` + adminColumns + `
+		FROM admins
+		WHERE email = $1
+	`
+	admin, err := scanAdmin(s.db.QueryRowContext(ctx, query, strings.ToLower(strings.TrimSpace(email))))
+	if err == sql.ErrNoRows {
+		return nil, ErrAdminNotFound
+	}
+	if err != nil {
+		return nil, fmt.Errorf("get admin: %w", err)
+	}
+	return admin, nil
+}
+
+func (s *PgStore) ListAdmins(ctx context.Context) ([]*Admin, error) {
+	rows, err := s.db.QueryContext(ctx, `
+		SELECT `+adminColumns+`
+		FROM admins
+		ORDER BY created_at
+	`)
+	if err != nil {
+		return nil, fmt.Errorf("list admins: %w", err)
+	}
+	defer rows.Close()
+
+	out := make([]*Admin, 0)
+	for rows.Next() {
+		admin, err := scanAdmin(rows)
+		if err != nil {
+			return nil, fmt.Errorf("scan admin: %w", err)
+		}
+		out = append(out, admin)
+	}
+	return out, rows.Err()
+}
+
+func (s *PgStore) CountAdmins(ctx context.Context) (int, error) {
+	var n int
+	if err := s.db.QueryRowContext(ctx, `SELECT COUNT(*) FROM admins`).Scan(&n); err != nil {
+		return 0, fmt.Errorf("count admins: %w", err)
+	}
+	return n, nil
+}
+
+func (s *PgStore) UpdateAdminLastLogin(ctx context.Context, adminID string, at time.Time) error {
+	if _, err := s.db.ExecContext(ctx, `UPDATE admins SET last_login_at = $2 WHERE id = $1`, adminID, at); err != nil {
+		return fmt.Errorf("update admin last login: %w", err)
+	}
+	return nil
+}
+
+func (s *PgStore) SetAdminDisabled(ctx context.Context, adminID string, disabled bool) error {
+	res, err := s.db.ExecContext(ctx, `UPDATE admins SET disabled = $2 WHERE id = $1`, adminID, disabled)
+	if err != nil {
+		return fmt.Errorf("set admin disabled: %w", err)
+	}
+	if n, _ := res.RowsAffected(); n == 0 {
+		return ErrAdminNotFound
+	}
+	return nil
+}
+
+func (s *PgStore) SetAdminRole(ctx context.Context, adminID, role string) error {
+	if !security.IsAdminRole(role) {
+		return fmt.Errorf("%w: unknown role %q", ErrInvalidAdminAccount, role)
+	}
+	res, err := s.db.ExecContext(ctx, `UPDATE admins SET role = $2 WHERE id = $1`, adminID, role)
+	if err != nil {
+		return fmt.Errorf("set admin role: %w", err)
+	}
+	if n, _ := res.RowsAffected(); n == 0 {
+		return ErrAdminNotFound
+	}
+	return nil
+}
+
+func (s *PgStore) GetAdminByID(ctx context.Context, adminID string) (*Admin, error) {
+	query := `
+		SELECT ` + adminColumns + `
+		FROM admins
+		WHERE id = $1
+	`
+	admin, err := scanAdmin(s.db.QueryRowContext(ctx, query, adminID))
+	if err == sql.ErrNoRows {
+		return nil, ErrAdminNotFound
+	}
+	if err != nil {
+		return nil, fmt.Errorf("get admin: %w", err)
+	}
+	return admin, nil
+}
+
+func (s *PgStore) SetAdminTOTPPending(ctx context.Context, adminID, secret string) error {
+	res, err := s.db.ExecContext(ctx, `UPDATE admins SET totp_pending_secret = $2 WHERE id = $1`, adminID, secret)
+	if err != nil {
+		return fmt.Errorf("set admin totp pending: %w", err)
+	}
+	if n, _ := res.RowsAffected(); n == 0 {
+		return ErrAdminNotFound
+	}
+	return nil
+}
+
+func (s *PgStore) EnableAdminTOTP(ctx context.Context, adminID string, counter int64, recoveryCodeHashes []string) error {
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("begin enable admin totp: %w", err)
+	}
+	defer tx.Rollback()
+
+	res, err := tx.ExecContext(ctx, `
+		UPDATE admins
+		SET totp_secret = totp_pending_secret,
+		    totp_pending_secret = '',
+		    totp_enabled = true,
+		    totp_last_counter = $2
+		WHERE id = $1 AND totp_pending_secret <> ''
+	`, adminID, counter)
+	if err != nil {
+		return fmt.Errorf("enable admin totp: %w", err)
+	}
+	if n, _ := res.RowsAffected(); n == 0 {
+		return ErrAdminTOTPNotPending
+	}
+
+	if _, err := tx.ExecContext(ctx, `DELETE FROM admin_recovery_codes WHERE admin_id = $1`, adminID); err != nil {
+		return fmt.Errorf("clear admin recovery codes: %w", err)
+	}
+	for _, hash := range recoveryCodeHashes {
+		if _, err := tx.ExecContext(ctx, `INSERT INTO admin_recovery_codes (admin_id, code_hash) VALUES ($1, $2)`, adminID, hash); err != nil {
+			return fmt.Errorf("insert admin recovery code: %w", err)
+		}
+	}
+
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("commit enable admin totp: %w", err)
+	}
+	return nil
+}
+
+// UseAdminTOTPCounter records counter as used, refusing it unless it is
+// newer than the last accepted step.
+func (s *PgStore) UseAdminTOTPCounter(ctx context.Context, adminID string, counter int64) (bool, error) {
+	res, err := s.db.ExecContext(ctx, `
+		UPDATE admins SET totp_last_counter = $2
+		WHERE id = $1 AND totp_last_counter < $2
+	`, adminID, counter)
+	if err != nil {
+		return false, fmt.Errorf("use admin totp counter: %w", err)
+	}
+	n, _ := res.RowsAffected()
+	return n == 1, nil
+}
+
+func (s *PgStore) UseAdminRecoveryCode(ctx context.Context, adminID, codeHash string, at time.Time) (bool, error) {
+	res, err := s.db.ExecContext(ctx, `
+		UPDATE admin_recovery_codes SET used_at = $3
+		WHERE admin_id = $1 AND code_hash = $2 AND used_at IS NULL
+	`, adminID, codeHash, at)
+	if err != nil {
+		return false, fmt.Errorf("use admin recovery code: %w", err)
+	}
+	n, _ := res.RowsAffected()
+	return n == 1, nil
+}
+
+const adminColumns = `id, email, password_hash, role, disabled, created_at, last_login_at,
+	totp_enabled, totp_secret, totp_pending_secret, totp_last_counter,
+	mfa_failures, mfa_last_failure_at, mfa_locked_until`
+
+// RecordAdminMFAFailure locks the admin row so failures on every instance
+// count towards the same lockout.
+func (s *PgStore) RecordAdminMFAFailure(ctx context.Context, adminID string, now time.Time, maxFailures int, lockout time.Duration) (bool, error) {
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return false, fmt.Errorf("begin admin mfa failure: %w", err)
+	}
+	defer tx.Rollback()
+
+	admin, err := scanAdmin(tx.QueryRowContext(ctx, `SELECT `+adminColumns+` FROM admins WHERE id = $1 FOR UPDATE`, adminID))
+	if err == sql.ErrNoRows {
+		return false, ErrAdminNotFound
+	}
+	if err != nil {
+		return false, fmt.Errorf("load admin mfa failures: %w", err)
+	}
+
+	recordAdminMFAFailure(admin, now, maxFailures, lockout)
+
+	_, err = tx.ExecContext(ctx, `
+		UPDATE admins SET mfa_failures = $2, mfa_last_failure_at = $3, mfa_locked_until = $4
+		WHERE id = $1
+	`, adminID, admin.MFAFailures, nullTime(admin.MFALastFailureAt), nullTime(admin.MFALockedUntil))
+	if err != nil {
+		return false, fmt.Errorf("update admin mfa failures: %w", err)
+	}
+	if err := tx.Commit(); err != nil {
+		return false, fmt.Errorf("commit admin mfa failure: %w", err)
+	}
+	return admin.MFALocked(now), nil
+}
+
+func (s *PgStore) ClearAdminMFAFailures(ctx context.Context, adminID string) error {
+	res, err := s.db.ExecContext(ctx, `UPDATE admins SET mfa_failures = 0, mfa_last_failure_at = NULL WHERE id = $1`, adminID)
+	if err != nil {
+		return fmt.Errorf("clear admin mfa failures: %w", err)
+	}
+	if n, _ := res.RowsAffected(); n == 0 {
+		return ErrAdminNotFound
+	}
+	return nil
+}
+
+type rowScanner interface {
+	Scan(dest ...any) error
+}
+
+func scanAdmin(row rowScanner) (*Admin, error) {
+	admin := &Admin{}
+	var lastLogin, lastFailure, lockedUntil sql.NullTime
+	err := row.Scan(&admin.ID, &admin.Email, &admin.PasswordHash, &admin.Role, &admin.Disabled, &admin.CreatedAt, &lastLogin,
+		&admin.TOTPEnabled, &admin.TOTPSecret, &admin.TOTPPendingSecret, &admin.TOTPLastCounter,
+		&admin.MFAFailures, &lastFailure, &lockedUntil)
+	if err != nil {
+		return nil, err
+	}
+	if lastLogin.Valid {
+		admin.LastLoginAt = &lastLogin.Time
+	}
+	admin.MFALastFailureAt = lastFailure.Time
+	admin.MFALockedUntil = lockedUntil.Time
+	return admin, nil
+}
+
+// nullTime stores the zero time as NULL.
+func nullTime(t time.Time) sql.NullTime {
+	return sql.NullTime{Time: t, Valid: !t.IsZero()}
+}
@@ -0,0 +1,152 @@
+package store
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"time"
+)
+
+// auditChainLock is the advisory lock key serialising appends so two
+// writers cannot chain onto the same previous entry.
+const auditChainLock = 0x617564697400 // "audit"
+
+const auditColumns = `id, seq, action, anon_id, actor_id, actor_email, actor_role, ip, request_id,
+	target, before_payload, after_payload, details, timestamp, prev_hash, hash`
+
+func (s *PgStore) GetAuditLogs(ctx context.Context) ([]AuditLog, error) {
+	rows, err := s.db.QueryContext(ctx, `SELECT `+auditColumns+` FROM audit_logs ORDER BY seq DESC`)
+	if err != nil {
+		return nil, fmt.Errorf("query audit logs: %w", err)
+	}
+	defer rows.Close()
+
+	out := []AuditLog{}
+	for rows.Next() {
+		log, err := scanAuditLog(rows)
+		if err != nil {
+			return nil, fmt.Errorf("scan audit logs: %w", err)
+		}
+		out = append(out, *log)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("iterate audit logs: %w", err)
+	}
+	return out, nil
+}
+
+func (s *PgStore) LogAuditEvent(ctx context.Context, event AuditLog) error {
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("begin audit event: %w", err)
+	}
+	defer tx.Rollback()
+
+	if _, err := tx.ExecContext(ctx, `SELECT pg_advisory_xact_lock($1)`, auditChainLock); err != nil {
+		return fmt.Errorf("lock audit chain: %w", err)
+	}
+
+	var prevSeq int64
+	var prevHash string
+	err = tx.QueryRowContext(ctx, `SELECT seq, hash FROM audit_logs ORDER BY seq DESC LIMIT 1`).Scan(&prevSeq, &prevHash)
+	if err != nil && err != sql.ErrNoRows {
+		return fmt.Errorf("read audit chain head: %w", err)
+	}
+	if err := sealAuditLog(&event, prevSeq, prevHash); err != nil {
+		return err
+	}
+
+	query := `
+		INSERT INTO audit_logs (` + auditColumns + `)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12, $13, $14, $15, $16)
+	`
+	_, err = tx.ExecContext(ctx, query, event.ID, event.Seq, event.Action, event.AnonID, event.ActorID, event.ActorEmail, event.ActorRole,
+		event.IP, event.RequestID, event.Target, nullableText(event.Before), nullableText(event.After),
+		event.Details, event.Timestamp, event.PrevHash, event.Hash)
+	if err != nil {
+		return fmt.Errorf("insert audit event: %w", err)
+	}
+
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("commit audit event: %w", err)
+	}
+	return nil
+}
+
+func (s *PgStore) ListAuditLogsAfter(ctx context.Context, afterSeq int64, limit int) ([]AuditLog, error) {
+	rows, err := s.db.QueryContext(ctx, `SELECT `+auditColumns+` FROM audit_logs WHERE seq > $1 ORDER BY seq LIMIT $2`, afterSeq, limit)
+	if err != nil {
+		return nil, fmt.Errorf("list audit logs: %w", err)
+	}
+	defer rows.Close()
+
+	out := make([]AuditLog, 0)
+	for rows.Next() {
+		log, err := scanAuditLog(rows)
+		if err != nil {
+			return nil, fmt.Errorf("scan audit log: %w", err)
+		}
+		out = append(out, *log)
+	}
+	return out, rows.Err()
+}
+
+// PruneAuditLogs deletes the oldest entries written before the cutoff. It
+// only ever removes a prefix of the chain and always keeps the newest
+// entry, so what remains still verifies. The audit_logs triggers refuse
+// deletes unless anon.audit_prune is set for the transaction.
+func (s *PgStore) PruneAuditLogs(ctx context.Context, before time.Time) (int, error) {
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return 0, fmt.Errorf("begin prune audit logs: %w", err)
+	}
+	defer tx.Rollback()
+
+	if _, err := tx.ExecContext(ctx, `SELECT pg_advisory_xact_lock($1)`, auditChainLock); err != nil {
+		return 0, fmt.Errorf("lock audit chain: %w", err)
+	}
+	if _, err := tx.ExecContext(ctx, `SET LOCAL anon.audit_prune = 'on'`); err != nil {
+		return 0, fmt.Errorf("enable audit prune: %w", err)
+	}
+
+	res, err := tx.ExecContext(ctx, `
+		DELETE FROM audit_logs
+		WHERE seq < COALESCE(
+			(SELECT MIN(seq) FROM audit_logs WHERE timestamp >= $1),
+			(SELECT MAX(seq) FROM audit_logs)
+		)
+	`, before)
+	if err != nil {
+		return 0, fmt.Errorf("prune audit logs: %w", err)
+	}
+	n, _ := res.RowsAffected()
+
+	if err := tx.Commit(); err != nil {
+		return 0, fmt.Errorf("commit prune audit logs: %w", err)
+	}
+	return int(n), nil
+}
+
+func scanAuditLog(row rowScanner) (*AuditLog, error) {
+	log := &AuditLog{}
+	var before, after sql.NullString
+	err := row.Scan(&log.ID, &log.Seq, &log.Action, &log.AnonID, &log.ActorID, &log.ActorEmail, &log.ActorRole,
+		&log.IP, &log.RequestID, &log.Target, &before, &after, &log.Details, &log.Timestamp, &log.PrevHash, &log.Hash)
+	if err != nil {
+		return nil, err
+	}
+	if before.Valid {
+		log.Before = []byte(before.String)
+	}
+	if after.Valid {
+		log.After = []byte(after.String)
+	}
+	return log, nil
+}
+
+func nullableText(raw []byte) sql.NullString {
+	if len(raw) == 0 {
+		return sql.NullString{}
+	}
+	return sql.NullString{String: string(raw), Valid: true}
+}
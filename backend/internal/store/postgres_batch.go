@@ -0,0 +1,123 @@
+package store
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/lib/pq"
+)
+
+// GetPostReactionsFor returns anonID's reaction to each of postIDs that it
+// has reacted to.
+func (s *PgStore) GetPostReactionsFor(ctx context.Context, anonID string, postIDs []string) (map[string]string, error) {
+	query := `SELECT post_id, reaction_type FROM post_reactions WHERE anon_id = $1 AND post_id = ANY($2)`
+	return s.queryStringMap(ctx, "post reactions", query, anonID, postIDs)
+}
+
+// GetCommentReactionsFor returns anonID's reaction to each of commentIDs
+// that it has reacted to.
+func (s *PgStore) GetCommentReactionsFor(ctx context.Context, anonID string, commentIDs []string) (map[string]string, error) {
+	query := `SELECT comment_id, reaction_type FROM comment_reactions WHERE anon_id = $1 AND comment_id = ANY($2)`
+	return s.queryStringMap(ctx, "comment reactions", query, anonID, commentIDs)
+}
+
+// GetReplyReactionsFor returns anonID's reaction to each of replyIDs that
+// it has reacted to.
+func (s *PgStore) GetReplyReactionsFor(ctx context.Context, anonID string, replyIDs []string) (map[string]string, error) {
+	query := `SELECT reply_id, reaction FROM reply_reactions WHERE anon_id = $1 AND reply_id = ANY($2)`
+	return s.queryStringMap(ctx, "reply reactions", query, anonID, replyIDs)
+}
+
+// GetReplyCounts returns the number of non-deleted replies of each of
+// commentIDs that has any.
+func (s *PgStore) GetReplyCounts(ctx context.Context, commentIDs []string) (map[string]int, error) {
+	out := make(map[string]int, len(commentIDs))
+	if len(commentIDs) == 0 {
+		return out, nil
+	}
+
+	query := `
+		SELECT comment_id, COUNT(*)
+		FROM comment_replies
+		WHERE comment_id = ANY($1) AND deleted = false
+		GROUP BY comment_id
+	`
+	rows, err := s.db.QueryContext(ctx, query, pq.Array(commentIDs))
+	if err != nil {
+		return nil, fmt.Errorf("count comment replies: %w", err)
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var id string
+		var n int
+		if err := rows.Scan(&id, &n); err != nil {
+			return nil, fmt.Errorf("scan reply counts: %w", err)
+		}
+		out[id] = n
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("iterate reply counts: %w", err)
+	}
+	return out, nil
+}
+
+// GetUsernames returns the username of each of anonIDs that has a device,
+// taken from its first device like GetDeviceByAnonID.
+func (s *PgStore) GetUsernames(ctx context.Context, anonIDs []string) (map[string]string, error) {
+	if len(anonIDs) == 0 {
+		return map[string]string{}, nil
+	}
+
+	query := `
+		SELECT DISTINCT ON (anon_id) anon_id, username
+		FROM devices
+		WHERE anon_id = ANY($1)
+		ORDER BY anon_id, created_at
+	`
+	rows, err := s.db.QueryContext(ctx, query, pq.Array(anonIDs))
+	if err != nil {
+		return nil, fmt.Errorf("get usernames: %w", err)
+	}
+	defer rows.Close()
+
+	out := make(map[string]string, len(anonIDs))
+	for rows.Next() {
+		var anonID, username string
+		if err := rows.Scan(&anonID, &username); err != nil {
+			return nil, fmt.Errorf("scan usernames: %w", err)
+		}
+		out[anonID] = username
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("iterate usernames: %w", err)
+	}
+	return out, nil
+}
+
+// queryStringMap runs a query taking (anonID, ids) whose rows are (id,
+// value) pairs and collects them into a map. what names the rows in errors.
+func (s *PgStore) queryStringMap(ctx context.Context, what, query, anonID string, ids []string) (map[string]string, error) {
+	out := make(map[string]string, len(ids))
+	if len(ids) == 0 {
+		return out, nil
+	}
+
+	rows, err := s.db.QueryContext(ctx, query, anonID, pq.Array(ids))
+	if err != nil {
+		return nil, fmt.Errorf("get %s: %w", what, err)
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var id, value string
+		if err := rows.Scan(&id, &value); err != nil {
+			return nil, fmt.Errorf("scan %s: %w", what, err)
+		}
+		out[id] = value
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("iterate %s: %w", what, err)
+	}
+	return out, nil
+}
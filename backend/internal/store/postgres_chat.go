@@ -0,0 +1,240 @@
+package store
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/lib/pq"
+)
+
+func (s *PgStore) PutChatMessage(ctx context.Context, msg *ChatMessage) error {
+	if msg.Kind == "" {
+		msg.Kind = ChatKindText
+	}
+	if msg.CreatedAt.IsZero() {
+		msg.CreatedAt = time.Now().UTC()
+	}
+
+	query := `
+		INSERT INTO chat_messages (id, room_id, from_anon, to_anon, kind, text, client_id, created_at, delivered_at, read_at)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10)
+	`
+	clientID := sql.NullString{String: msg.ClientID, Valid: msg.ClientID != ""}
+	_, err := s.db.ExecContext(ctx, query, msg.ID, msg.RoomID, msg.FromAnon, msg.ToAnon, msg.Kind, msg.Text, clientID, msg.CreatedAt, msg.DeliveredAt, msg.ReadAt)
+	if err != nil {
+		if isUniqueViolation(err) && msg.ClientID != "" {
+			return ErrDuplicateChatMessage
+		}
+		return fmt.Errorf("put chat message: %w", err)
+	}
+	return nil
+}
+
+func (s *PgStore) GetChatMessageByClientID(ctx context.Context, fromAnon, clientID string) (*ChatMessage, error) {
+	query := `
+		SELECT id, room_id, from_anon, to_anon, kind, text, client_id, created_at, delivered_at, read_at
+		FROM chat_messages
+		WHERE from_anon = $1 AND client_id = $2
+	`
+	rows, err := s.db.QueryContext(ctx, query, fromAnon, clientID)
+	if err != nil {
+		return nil, fmt.Errorf("get chat message by client id: %w", err)
+	}
+	defer rows.Close()
+
+	msgs, err := scanChatMessages(rows)
+	if err != nil {
+		return nil, err
+	}
+	if len(msgs) == 0 {
+		return nil, ErrChatMessageNotFound
+	}
+	return msgs[0], nil
+}
+
+// GetChatHistory returns up to limit messages older than before, newest first.
+func (s *PgStore) GetChatHistory(ctx context.Context, roomID string, before *Cursor, limit int) ([]*ChatMessage, error) {
+	var (
+		rows *sql.Rows
+		err  error
+	)
+	if before == nil {
+		query := `
+			SELECT id, room_id, from_anon, to_anon, kind, text, client_id, created_at, delivered_at, read_at
+			FROM chat_messages
+			WHERE room_id = $1
+			ORDER BY created_at DESC, id DESC
+			LIMIT $2
+		`
+		rows, err = s.db.QueryContext(ctx, query, roomID, limit)
+	} else {
+		query := `
+			SELECT id, room_id, from_anon, to_anon, kind, text, client_id, created_at, delivered_at, read_at
+			FROM chat_messages
+			WHERE room_id = $1 AND (created_at, id) < ($2, $3)
+			ORDER BY created_at DESC, id DESC
+			LIMIT $4
+		`
+		rows, err = s.db.QueryContext(ctx, query, roomID, before.CreatedAt, before.ID, limit)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("get chat history: %w", err)
+	}
+	defer rows.Close()
+
+	return scanChatMessages(rows)
+}
+
+// GetUndeliveredChatMessages returns messages addressed to toAnon that have
+// not reached any of its sockets yet, oldest first. An empty roomID covers
+// every conversation of toAnon.
+func (s *PgStore) GetUndeliveredChatMessages(ctx context.Context, roomID, toAnon string) ([]*ChatMessage, error) {
+	query := `
+		SELECT id, room_id, from_anon, to_anon, kind, text, client_id, created_at, delivered_at, read_at
+		FROM chat_messages
+		WHERE to_anon = $1 AND ($2 = '' OR room_id = $2) AND delivered_at IS NULL
+		ORDER BY created_at ASC, id ASC
+	`
+	rows, err := s.db.QueryContext(ctx, query, toAnon, roomID)
+	if err != nil {
+		return nil, fmt.Errorf("get undelivered chat messages: %w", err)
+	}
+	defer rows.Close()
+
+	return scanChatMessages(rows)
+}
+
+// MarkChatMessagesDelivered marks the given messages as delivered unless
+// they already were. It returns the ids that changed state, so that only
+// one of several concurrent callers reports a delivery.
+func (s *PgStore) MarkChatMessagesDelivered(ctx context.Context, ids []string, at time.Time) ([]string, error) {
+	if len(ids) == 0 {
+		return []string{}, nil
+	}
+
+	query := `UPDATE chat_messages SET delivered_at = $1 WHERE id = ANY($2) AND delivered_at IS NULL RETURNING id`
+	rows, err := s.db.QueryContext(ctx, query, at, pq.Array(ids))
+	if err != nil {
+		return nil, fmt.Errorf("mark chat messages delivered: %w", err)
+	}
+	defer rows.Close()
+
+	updated := []string{}
+	for rows.Next() {
+		var id string
+		if err := rows.Scan(&id); err != nil {
+			return nil, fmt.Errorf("scan delivered chat message: %w", err)
+		}
+		updated = append(updated, id)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("iterate delivered chat messages: %w", err)
+	}
+	return updated, nil
+}
+
+// MarkChatMessagesRead marks the given messages in roomID that were sent to
+// readerAnon as read (and delivered, if they were not yet). It returns the ids
+// that changed state.
+func (s *PgStore) MarkChatMessagesRead(ctx context.Context, roomID, readerAnon string, ids []string, at time.Time) ([]string, error) {
+	if len(ids) == 0 {
+		return []string{}, nil
+	}
+
+	query := `
+		UPDATE chat_messages
+		SET read_at = $1, delivered_at = COALESCE(delivered_at, $1)
+		WHERE room_id = $2 AND to_anon = $3 AND id = ANY($4) AND read_at IS NULL
+		RETURNING id
+	`
+	rows, err := s.db.QueryContext(ctx, query, at, roomID, readerAnon, pq.Array(ids))
+	if err != nil {
+		return nil, fmt.Errorf("mark chat messages read: %w", err)
+	}
+	defer rows.Close()
+
+	updated := []string{}
+	for rows.Next() {
+		var id string
+		if err := rows.Scan(&id); err != nil {
+			return nil, fmt.Errorf("scan read chat message: %w", err)
+		}
+		updated = append(updated, id)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("iterate read chat messages: %w", err)
+	}
+	return updated, nil
+}
+
+func scanChatMessages(rows *sql.Rows) ([]*ChatMessage, error) {
+	out := []*ChatMessage{}
+	for rows.Next() {
+		m := &ChatMessage{}
+		var clientID sql.NullString
+		var deliveredAt, readAt sql.NullTime
+		if err := rows.Scan(&m.ID, &m.RoomID, &m.FromAnon, &m.ToAnon, &m.Kind, &m.Text, &clientID, &m.CreatedAt, &deliveredAt, &readAt); err != nil {
+			return nil, fmt.Errorf("scan chat message: %w", err)
+		}
+		m.ClientID = clientID.String
+		if deliveredAt.Valid {
+			t := deliveredAt.Time
+			m.DeliveredAt = &t
+		}
+		if readAt.Valid {
+			t := readAt.Time
+			m.ReadAt = &t
+		}
+		out = append(out, m)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("iterate chat messages: %w", err)
+	}
+	return out, nil
+}
+
+// RedeemWSTicket records ticketID as used, reporting false if it already was
+// or has expired. Every API instance shares the record, so a ticket opens
+// one socket in all.
+func (s *PgStore) RedeemWSTicket(ctx context.Context, ticketID string, expiresAt, now time.Time) (bool, error) {
+	if !expiresAt.After(now) {
+		return false, nil
+	}
+	res, err := s.db.ExecContext(ctx, `
+		INSERT INTO ws_tickets (ticket_id, expires_at, redeemed_at)
+		VALUES ($1, $2, $3)
+		ON CONFLICT (ticket_id) DO NOTHING
+	`, ticketID, expiresAt, now)
+	if err != nil {
+		return false, fmt.Errorf("redeem ws ticket: %w", err)
+	}
+	n, err := res.RowsAffected()
+	if err != nil {
+		return false, fmt.Errorf("redeem ws ticket rows: %w", err)
+	}
+	return n > 0, nil
+}
+
+func (s *PgStore) CleanupWSTickets(ctx context.Context, now time.Time) (int, error) {
+	res, err := s.db.ExecContext(ctx, `DELETE FROM ws_tickets WHERE expires_at <= $1`, now)
+	if err != nil {
+		return 0, fmt.Errorf("cleanup ws tickets: %w", err)
+	}
+	n, _ := res.RowsAffected()
+	return int(n), nil
+}
+
+func isUniqueViolation(err error) bool {
+	var pqErr *pq.Error
+	return errors.As(err, &pqErr) && pqErr.Code == "23505"
+}
+
+// isExclusionViolation reports whether err is a violation of the named
+// exclusion constraint.
+func isExclusionViolation(err error, constraint string) bool {
+	var pqErr *pq.Error
+	return errors.As(err, &pqErr) && pqErr.Code == "23P01" && pqErr.Constraint == constraint
+}
@@ -0,0 +1,128 @@
+package store
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"time"
+)
+
+// PutDeviceKeys replaces the identity and signed prekey of a device and adds
+// oneTime to its pool, skipping key ids it already has.
+func (s *PgStore) PutDeviceKeys(ctx context.Context, bundle *DeviceKeyBundle, oneTime []OneTimePrekey) error {
+	now := bundle.UpdatedAt
+	if now.IsZero() {
+		now = time.Now()
+	}
+
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("begin put device keys: %w", err)
+	}
+	defer tx.Rollback()
+
+	var owner string
+	err = tx.QueryRowContext(ctx, `SELECT anon_id FROM devices WHERE device_public_id = $1`, bundle.DevicePublicID).Scan(&owner)
+	if err == sql.ErrNoRows || (err == nil && owner != bundle.AnonID) {
+		return ErrDeviceNotFound
+	}
+	if err != nil {
+		return fmt.Errorf("put device keys: %w", err)
+	}
+
+	query := `
+		INSERT INTO device_keys (device_public_id, anon_id, identity_key, signed_prekey_id, signed_prekey, signed_prekey_signature, created_at, updated_at)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $7)
+		ON CONFLICT (device_public_id) DO UPDATE SET
+			identity_key = EXCLUDED.identity_key,
+			signed_prekey_id = EXCLUDED.signed_prekey_id,
+			signed_prekey = EXCLUDED.signed_prekey,
+			signed_prekey_signature = EXCLUDED.signed_prekey_signature,
+			updated_at = EXCLUDED.updated_at
+	`
+	if _, err := tx.ExecContext(ctx, query, bundle.DevicePublicID, bundle.AnonID, bundle.IdentityKey, bundle.SignedPrekeyID, bundle.SignedPrekey, bundle.SignedPrekeySignature, now); err != nil {
+		return fmt.Errorf("put device keys: %w", err)
+	}
+
+	for _, k := range oneTime {
+		_, err := tx.ExecContext(ctx, `
+			INSERT INTO device_one_time_prekeys (device_public_id, key_id, public_key, created_at)
+			VALUES ($1, $2, $3, $4)
+			ON CONFLICT (device_public_id, key_id) DO NOTHING
+		`, bundle.DevicePublicID, k.KeyID, k.PublicKey, now)
+		if err != nil {
+			return fmt.Errorf("put one-time prekey: %w", err)
+		}
+	}
+
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("commit put device keys: %w", err)
+	}
+	return nil
+}
+
+// ClaimDeviceKeys returns a bundle for every device of anonID that published
+// keys, each with one one-time prekey removed from its pool when available.
+func (s *PgStore) ClaimDeviceKeys(ctx context.Context, anonID string) ([]*DeviceKeyBundle, error) {
+	query := `
+		SELECT device_public_id, anon_id, identity_key, signed_prekey_id, signed_prekey, signed_prekey_signature, created_at, updated_at
+		FROM device_keys
+		WHERE anon_id = $1
+		ORDER BY device_public_id
+	`
+	rows, err := s.db.QueryContext(ctx, query, anonID)
+	if err != nil {
+		return nil, fmt.Errorf("claim device keys: %w", err)
+	}
+	defer rows.Close()
+
+	out := []*DeviceKeyBundle{}
+	for rows.Next() {
+		b := &DeviceKeyBundle{}
+		if err := rows.Scan(&b.DevicePublicID, &b.AnonID, &b.IdentityKey, &b.SignedPrekeyID, &b.SignedPrekey, &b.SignedPrekeySignature, &b.CreatedAt, &b.UpdatedAt); err != nil {
+			return nil, fmt.Errorf("scan device keys: %w", err)
+		}
+		out = append(out, b)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("iterate device keys: %w", err)
+	}
+	if len(out) == 0 {
+		return nil, ErrDeviceKeysNotFound
+	}
+
+	// pop one one-time prekey per device; SKIP LOCKED keeps concurrent
+	// claimers from handing out the same key
+	claim := `
+		DELETE FROM device_one_time_prekeys
+		WHERE ctid = (
+			SELECT ctid FROM device_one_time_prekeys
+			WHERE device_public_id = $1
+			ORDER BY created_at, key_id
+			LIMIT 1
+			FOR UPDATE SKIP LOCKED
+		)
+		RETURNING key_id, public_key
+	`
+	for _, b := range out {
+		k := &OneTimePrekey{}
+		err := s.db.QueryRowContext(ctx, claim, b.DevicePublicID).Scan(&k.KeyID, &k.PublicKey)
+		if err == sql.ErrNoRows {
+			continue
+		}
+		if err != nil {
+			return nil, fmt.Errorf("claim one-time prekey: %w", err)
+		}
+		b.OneTimePrekey = k
+	}
+	return out, nil
+}
+
+func (s *PgStore) CountOneTimePrekeys(ctx context.Context, devicePublicID string) (int, error) {
+	var count int
+	err := s.db.QueryRowContext(ctx, `SELECT COUNT(*) FROM device_one_time_prekeys WHERE device_public_id = $1`, devicePublicID).Scan(&count)
+	if err != nil {
+		return 0, fmt.Errorf("count one-time prekeys: %w", err)
+	}
+	return count, nil
+}
@@ -1,13 +1,14 @@
 package store
 
 import (
+	"context"
 	"database/sql"
 	"fmt"
 	"strings"
 	"time"
 )
 
-func (s *PgStore) EnsureProfileForAnon(anonID, region string, now time.Time) error {
+func (s *PgStore) EnsureProfileForAnon(ctx context.Context, anonID, region string, now time.Time) error {
 	query := `
 		INSERT INTO users (
 			anon_id, is_active, last_login_at, last_seen_at, created_at,
@@ -25,6 +26,8 @@ func (s *PgStore) EnsureProfileForAnon(anonID, region string, now time.Time) err
 			NULLIF($3, '')
 		FROM devices d
 		WHERE d.anon_id = $1
+		ORDER BY d.created_at
+		LIMIT 1
 		ON CONFLICT (anon_id) DO UPDATE SET
 			is_active = true,
 			last_seen_at = EXCLUDED.last_seen_at,
@@ -36,7 +39,7 @@ func (s *PgStore) EnsureProfileForAnon(anonID, region string, now time.Time) err
 				ELSE users.region
 			END
 	`
-	res, err := s.db.Exec(query, anonID, now, strings.TrimSpace(region))
+	res, err := s.db.ExecContext(ctx, query, anonID, now, strings.TrimSpace(region))
 	if err != nil {
 		return fmt.Errorf("ensure profile for anon: %w", err)
 	}
@@ -47,7 +50,7 @@ func (s *PgStore) EnsureProfileForAnon(anonID, region string, now time.Time) err
 	return nil
 }
 
-func (s *PgStore) IsUsernameAvailable(username string, excludeAnonID string) (bool, error) {
+func (s *PgStore) IsUsernameAvailable(ctx context.Context, username string, excludeAnonID string) (bool, error) {
 	normalized := strings.ToLower(strings.TrimSpace(username))
 	query := `
 		SELECT EXISTS (
@@ -57,14 +60,14 @@ func (s *PgStore) IsUsernameAvailable(username string, excludeAnonID string) (bo
 		)
 	`
 	var exists bool
-	if err := s.db.QueryRow(query, normalized, excludeAnonID).Scan(&exists); err != nil {
+	if err := s.db.QueryRowContext(ctx, query, normalized, excludeAnonID).Scan(&exists); err != nil {
 		return false, fmt.Errorf("check username availability: %w", err)
 	}
 	return !exists, nil
 }
 
-func (s *PgStore) refreshProfileDerivedFields(anonID string) error {
-	reportCount, err := s.GetUserReportCount(anonID)
+func (s *PgStore) refreshProfileDerivedFields(ctx context.Context, anonID string) error {
+	reportCount, err := s.GetUserReportCount(ctx, anonID)
 	if err != nil {
 		return err
 	}
@@ -99,15 +102,15 @@ func (s *PgStore) refreshProfileDerivedFields(anonID string) error {
 			status_label = $2
 		WHERE anon_id = $1
 	`
-	_, err = s.db.Exec(query, anonID, status)
+	_, err = s.db.ExecContext(ctx, query, anonID, status)
 	if err != nil {
 		return fmt.Errorf("refresh profile derived fields: %w", err)
 	}
 	return nil
 }
 
-func (s *PgStore) GetProfileByAnonID(anonID string) (*UserProfile, error) {
-	if err := s.refreshProfileDerivedFields(anonID); err != nil {
+func (s *PgStore) GetProfileByAnonID(ctx context.Context, anonID string) (*UserProfile, error) {
+	if err := s.refreshProfileDerivedFields(ctx, anonID); err != nil {
 		return nil, err
 	}
 
@@ -134,7 +137,7 @@ func (s *PgStore) GetProfileByAnonID(anonID string) (*UserProfile, error) {
 	`
 	profile := &UserProfile{}
 	var usernameChangedAt sql.NullTime
-	err := s.db.QueryRow(query, anonID).Scan(
+	err := s.db.QueryRowContext(ctx, query, anonID).Scan(
 		&profile.AnonID,
 		&profile.Username,
 		&profile.UsernameSuffix,
@@ -163,8 +166,8 @@ func (s *PgStore) GetProfileByAnonID(anonID string) (*UserProfile, error) {
 	return profile, nil
 }
 
-func (s *PgStore) UpdateProfile(anonID string, in ProfileUpdateInput, now time.Time) (*UserProfile, error) {
-	tx, err := s.db.Begin()
+func (s *PgStore) UpdateProfile(ctx context.Context, anonID string, in ProfileUpdateInput, now time.Time) (*UserProfile, error) {
+	tx, err := s.db.BeginTx(ctx, nil)
 	if err != nil {
 		return nil, fmt.Errorf("begin profile update tx: %w", err)
 	}
@@ -174,7 +177,7 @@ func (s *PgStore) UpdateProfile(anonID string, in ProfileUpdateInput, now time.T
 	var currentBio string
 	var currentRegionPublic bool
 	var changedAt sql.NullTime
-	err = tx.QueryRow(`
+	err = tx.QueryRowContext(ctx, `
 		SELECT username_suffix, bio, is_region_public, username_changed_at
 		FROM users
 		WHERE anon_id = $1
@@ -210,7 +213,7 @@ func (s *PgStore) UpdateProfile(anonID string, in ProfileUpdateInput, now time.T
 		fullUsername := BuildUsernameFromSuffix(newSuffix)
 		normalized := strings.ToLower(fullUsername)
 
-		if _, err := tx.Exec(`
+		if _, err := tx.ExecContext(ctx, `
 			UPDATE users
 			SET username = $2,
 				username_suffix = $3,
@@ -226,14 +229,14 @@ func (s *PgStore) UpdateProfile(anonID string, in ProfileUpdateInput, now time.T
 			return nil, fmt.Errorf("update profile username: %w", err)
 		}
 
-		if _, err := tx.Exec(`UPDATE devices SET username = $2, updated_at = $3 WHERE anon_id = $1`, anonID, fullUsername, now); err != nil {
+		if _, err := tx.ExecContext(ctx, `UPDATE devices SET username = $2, updated_at = $3 WHERE anon_id = $1`, anonID, fullUsername, now); err != nil {
 			if strings.Contains(strings.ToLower(err.Error()), "duplicate") || strings.Contains(strings.ToLower(err.Error()), "unique") {
 				return nil, ErrUsernameTaken
 			}
 			return nil, fmt.Errorf("sync device username: %w", err)
 		}
 	} else {
-		if _, err := tx.Exec(`
+		if _, err := tx.ExecContext(ctx, `
 			UPDATE users
 			SET bio = $2,
 				is_region_public = $3
@@ -247,34 +250,32 @@ func (s *PgStore) UpdateProfile(anonID string, in ProfileUpdateInput, now time.T
 		return nil, fmt.Errorf("commit profile update tx: %w", err)
 	}
 
-	return s.GetProfileByAnonID(anonID)
+	return s.GetProfileByAnonID(ctx, anonID)
 }
 
-func (s *PgStore) IncrementProfileView(targetAnonID, viewerAnonID string) error {
+func (s *PgStore) IncrementProfileView(ctx context.Context, targetAnonID, viewerAnonID string) error {
 	if targetAnonID == "" || targetAnonID == viewerAnonID {
 		return nil
 	}
-	_, err := s.db.Exec(`UPDATE users SET profile_views = profile_views + 1 WHERE anon_id = $1`, targetAnonID)
+	_, err := s.db.ExecContext(ctx, `UPDATE users SET profile_views = profile_views + 1 WHERE anon_id = $1`, targetAnonID)
 	if err != nil {
 		return fmt.Errorf("increment profile view: %w", err)
 	}
 	return nil
 }
 
-func (s *PgStore) GetProfileDeviceInfo(anonID string) (*ProfileDeviceInfo, error) {
+func (s *PgStore) GetProfileDeviceInfo(ctx context.Context, anonID string) (*ProfileDeviceInfo, error) {
 	info := &ProfileDeviceInfo{}
 	query := `
 		SELECT
 			EXISTS (SELECT 1 FROM sessions WHERE anon_id = $1 AND expires_at > CURRENT_TIMESTAMP) AS has_active_session,
-			COALESCE(u.last_seen_at, d.updated_at) AS last_active,
-			CASE WHEN d.device_secret_hash IS NOT NULL AND d.device_secret_hash != '' THEN true ELSE false END AS recovery_key_generated
+			COALESCE(u.last_seen_at, (SELECT MAX(updated_at) FROM devices WHERE anon_id = $1)) AS last_active,
+			EXISTS (SELECT 1 FROM recovery_keys WHERE anon_id = $1) AS recovery_key_generated
 		FROM users u
-		LEFT JOIN devices d ON d.anon_id = u.anon_id
 		WHERE u.anon_id = $1
-		LIMIT 1
 	`
 	var lastActive sql.NullTime
-	err := s.db.QueryRow(query, anonID).Scan(&info.PrimaryDeviceActive, &lastActive, &info.RecoveryKeyGenerated)
+	err := s.db.QueryRowContext(ctx, query, anonID).Scan(&info.PrimaryDeviceActive, &lastActive, &info.RecoveryKeyGenerated)
 	if err != nil {
 		if err == sql.ErrNoRows {
 			return nil, ErrProfileNotFound
@@ -292,13 +293,13 @@ func (s *PgStore) GetProfileDeviceInfo(anonID string) (*ProfileDeviceInfo, error
 	return info, nil
 }
 
-func (s *PgStore) ReportProfile(reporterAnonID, targetUserAnonID, reason string, now time.Time) error {
+func (s *PgStore) ReportProfile(ctx context.Context, reporterAnonID, targetUserAnonID, reason string, now time.Time) error {
 	query := `
 		INSERT INTO reports (reporter_anon_id, target_type, target_user_anon_id, reason, created_at)
 		VALUES ($1, 'profile', $2, $3, $4)
 		ON CONFLICT DO NOTHING
 	`
-	res, err := s.db.Exec(query, reporterAnonID, targetUserAnonID, strings.TrimSpace(reason), now)
+	res, err := s.db.ExecContext(ctx, query, reporterAnonID, targetUserAnonID, strings.TrimSpace(reason), now)
 	if err != nil {
 		return fmt.Errorf("report profile: %w", err)
 	}
@@ -308,13 +309,13 @@ func (s *PgStore) ReportProfile(reporterAnonID, targetUserAnonID, reason string,
 	return nil
 }
 
-func (s *PgStore) ReportPostV2(reporterAnonID, targetUserAnonID, targetPostID, reason string, now time.Time) error {
+func (s *PgStore) ReportPostV2(ctx context.Context, reporterAnonID, targetUserAnonID, targetPostID, reason string, now time.Time) error {
 	query := `
 		INSERT INTO reports (reporter_anon_id, target_type, target_user_anon_id, target_post_id, reason, created_at)
 		VALUES ($1, 'post', $2, $3, $4, $5)
 		ON CONFLICT DO NOTHING
 	`
-	res, err := s.db.Exec(query, reporterAnonID, targetUserAnonID, targetPostID, strings.TrimSpace(reason), now)
+	res, err := s.db.ExecContext(ctx, query, reporterAnonID, targetUserAnonID, targetPostID, strings.TrimSpace(reason), now)
 	if err != nil {
 		return fmt.Errorf("report post v2: %w", err)
 	}
@@ -324,10 +325,10 @@ func (s *PgStore) ReportPostV2(reporterAnonID, targetUserAnonID, targetPostID, r
 	return nil
 }
 
-func (s *PgStore) GetUserReportCount(targetAnonID string) (int, error) {
+func (s *PgStore) GetUserReportCount(ctx context.Context, targetAnonID string) (int, error) {
 	query := `SELECT COUNT(*) FROM reports WHERE target_user_anon_id = $1`
 	var count int
-	if err := s.db.QueryRow(query, targetAnonID).Scan(&count); err != nil {
+	if err := s.db.QueryRowContext(ctx, query, targetAnonID).Scan(&count); err != nil {
 		return 0, fmt.Errorf("get user report count: %w", err)
 	}
 	return count, nil
@@ -0,0 +1,196 @@
+package store
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"time"
+
+	"anon-backend/internal/config"
+)
+
+// TakeQuota locks the anon's counters for the resource so concurrent
+// requests on any instance cannot both take the last slot.
+func (s *PgStore) TakeQuota(ctx context.Context, anonID, resource string, limits []config.QuotaLimit, now time.Time) (QuotaResult, error) {
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return QuotaResult{}, fmt.Errorf("begin quota: %w", err)
+	}
+	defer tx.Rollback()
+
+	res, err := takeQuotaTx(ctx, tx, anonID, resource, limits, now)
+	if err != nil || !res.Allowed {
+		return res, err
+	}
+
+	if err := tx.Commit(); err != nil {
+		return QuotaResult{}, fmt.Errorf("commit quota: %w", err)
+	}
+	return res, nil
+}
+
+// CreatePostWithQuota takes the posts quota and inserts the post in one
+// transaction: a refused post leaves the counters alone and a failed insert
+// rolls the counters back.
+func (s *PgStore) CreatePostWithQuota(ctx context.Context, p *Post, limits []config.QuotaLimit, now time.Time) (QuotaResult, error) {
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return QuotaResult{}, fmt.Errorf("begin create post: %w", err)
+	}
+	defer tx.Rollback()
+
+	res, err := takeQuotaTx(ctx, tx, p.AnonID, config.QuotaPosts, limits, now)
+	if err != nil {
+		return QuotaResult{}, err
+	}
+	if !res.Allowed {
+		return res, &QuotaExceededError{Resource: config.QuotaPosts, Result: res}
+	}
+
+	_, err = tx.ExecContext(ctx, `
+		INSERT INTO posts (id, anon_id, text, created_at, likes, dislikes, deleted)
+		VALUES ($1, $2, $3, $4, $5, $6, $7)
+	`, p.ID, p.AnonID, p.Text, p.CreatedAt, p.Likes, p.Dislikes, p.Deleted)
+	if err != nil {
+		return QuotaResult{}, fmt.Errorf("insert post: %w", err)
+	}
+
+	if err := tx.Commit(); err != nil {
+		return QuotaResult{}, fmt.Errorf("commit create post: %w", err)
+	}
+	return res, nil
+}
+
+// AddCommentWithQuota inserts the comment and takes the comments quota in
+// one transaction. A comment on a missing post is not counted, and a
+// refused one is not stored.
+func (s *PgStore) AddCommentWithQuota(ctx context.Context, c *PostComment, limits []config.QuotaLimit, now time.Time) (QuotaResult, error) {
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return QuotaResult{}, fmt.Errorf("begin add comment: %w", err)
+	}
+	defer tx.Rollback()
+
+	result, err := tx.ExecContext(ctx, addCommentQuery, c.ID, c.PostID, c.AnonID, c.Text, c.CreatedAt, c.Likes, c.Dislikes, c.Deleted)
+	if err != nil {
+		return QuotaResult{}, fmt.Errorf("add comment: %w", err)
+	}
+	if n, err := result.RowsAffected(); err != nil {
+		return QuotaResult{}, fmt.Errorf("check rows affected: %w", err)
+	} else if n == 0 {
+		return QuotaResult{}, ErrPostNotFound
+	}
+
+	res, err := takeQuotaTx(ctx, tx, c.AnonID, config.QuotaComments, limits, now)
+	if err != nil {
+		return QuotaResult{}, err
+	}
+	if !res.Allowed {
+		return res, &QuotaExceededError{Resource: config.QuotaComments, Result: res}
+	}
+
+	if err := tx.Commit(); err != nil {
+		return QuotaResult{}, fmt.Errorf("commit add comment: %w", err)
+	}
+	return res, nil
+}
+
+// AddCommentReplyWithQuota inserts the reply and takes the replies quota in
+// one transaction. A reply to a missing comment is not counted, and a
+// refused one is not stored.
+func (s *PgStore) AddCommentReplyWithQuota(ctx context.Context, r *CommentReply, limits []config.QuotaLimit, now time.Time) (QuotaResult, error) {
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return QuotaResult{}, fmt.Errorf("begin add comment reply: %w", err)
+	}
+	defer tx.Rollback()
+
+	result, err := tx.ExecContext(ctx, addCommentReplyQuery, r.ID, r.CommentID, r.AnonID, r.Text, r.CreatedAt, r.Deleted)
+	if err != nil {
+		return QuotaResult{}, fmt.Errorf("add comment reply: %w", err)
+	}
+	if n, err := result.RowsAffected(); err != nil {
+		return QuotaResult{}, fmt.Errorf("check rows affected: %w", err)
+	} else if n == 0 {
+		return QuotaResult{}, ErrCommentNotFound
+	}
+
+	res, err := takeQuotaTx(ctx, tx, r.AnonID, config.QuotaReplies, limits, now)
+	if err != nil {
+		return QuotaResult{}, err
+	}
+	if !res.Allowed {
+		return res, &QuotaExceededError{Resource: config.QuotaReplies, Result: res}
+	}
+
+	if err := tx.Commit(); err != nil {
+		return QuotaResult{}, fmt.Errorf("commit add comment reply: %w", err)
+	}
+	return res, nil
+}
+
+// takeQuotaTx locks the anon's counters for the resource and, if every
+// window has room, increments them. The caller commits.
+func takeQuotaTx(ctx context.Context, tx *sql.Tx, anonID, resource string, limits []config.QuotaLimit, now time.Time) (QuotaResult, error) {
+	starts := make([]time.Time, len(limits))
+	used := make([]int, len(limits))
+	for i, l := range limits {
+		starts[i] = QuotaWindowStart(l.Window, now)
+		_, err := tx.ExecContext(ctx, `
+			INSERT INTO quota_usage (anon_id, resource, quota_window, window_start, reset_at, count)
+			VALUES ($1, $2, $3, $4, $5, 0)
+			ON CONFLICT (anon_id, resource, quota_window, window_start) DO NOTHING
+		`, anonID, resource, l.Window, starts[i], quotaWindowEnd(l.Window, starts[i]))
+		if err != nil {
+			return QuotaResult{}, fmt.Errorf("create quota counter: %w", err)
+		}
+		err = tx.QueryRowContext(ctx, `
+			SELECT count FROM quota_usage
+			WHERE anon_id = $1 AND resource = $2 AND quota_window = $3 AND window_start = $4
+			FOR UPDATE
+		`, anonID, resource, l.Window, starts[i]).Scan(&used[i])
+		if err != nil {
+			return QuotaResult{}, fmt.Errorf("load quota counter: %w", err)
+		}
+	}
+
+	res := takeQuota(limits, used, now)
+	if !res.Allowed {
+		return res, nil
+	}
+
+	for i, l := range limits {
+		_, err := tx.ExecContext(ctx, `
+			UPDATE quota_usage SET count = $5
+			WHERE anon_id = $1 AND resource = $2 AND quota_window = $3 AND window_start = $4
+		`, anonID, resource, l.Window, starts[i], used[i])
+		if err != nil {
+			return QuotaResult{}, fmt.Errorf("update quota counter: %w", err)
+		}
+	}
+
+	return res, nil
+}
+
+func (s *PgStore) GetQuotaUsage(ctx context.Context, anonID, resource string, limits []config.QuotaLimit, now time.Time) ([]QuotaUsage, error) {
+	used := make([]int, len(limits))
+	for i, l := range limits {
+		err := s.db.QueryRowContext(ctx, `
+			SELECT COALESCE(SUM(count), 0) FROM quota_usage
+			WHERE anon_id = $1 AND resource = $2 AND quota_window = $3 AND window_start = $4
+		`, anonID, resource, l.Window, QuotaWindowStart(l.Window, now)).Scan(&used[i])
+		if err != nil {
+			return nil, fmt.Errorf("get quota usage: %w", err)
+		}
+	}
+	return quotaUsage(limits, used, now), nil
+}
+
+func (s *PgStore) CleanupQuotaUsage(ctx context.Context, now time.Time) (int, error) {
+	res, err := s.db.ExecContext(ctx, `DELETE FROM quota_usage WHERE reset_at <= $1`, now)
+	if err != nil {
+		return 0, fmt.Errorf("cleanup quota usage: %w", err)
+	}
+	n, _ := res.RowsAffected()
+	return int(n), nil
+}
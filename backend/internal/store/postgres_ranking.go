@@ -0,0 +1,175 @@
+package store
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/lib/pq"
+)
+
+// trendingColumns maps a trending algorithm to its post_scores column.
+var trendingColumns = map[string]string{
+	TrendingHot:    "s.hot",
+	TrendingRising: "s.rising",
+	TrendingTop:    "s.top",
+}
+
+// GetTrendingPosts returns a page of the posts ranked by the last
+// ReplacePostScores, best first. Posts scored since deleted are skipped.
+func (s *PgStore) GetTrendingPosts(ctx context.Context, q TrendingQuery) ([]PostWithStats, error) {
+	since, err := q.normalize(time.Now())
+	if err != nil {
+		return nil, err
+	}
+
+	query := `
+		SELECT p.id, p.anon_id, p.text, p.created_at, p.likes, p.dislikes, p.deleted,
+		       s.comments, s.hot, s.rising, s.top
+		FROM post_scores s
+		JOIN posts p ON p.id = s.post_id
+		WHERE p.deleted = false AND p.created_at >= $1
+		ORDER BY ` + trendingColumns[q.Algo] + ` DESC, p.created_at DESC, p.id DESC
+		LIMIT $2 OFFSET $3
+	`
+	rows, err := s.db.QueryContext(ctx, query, since, q.Limit, q.Offset)
+	if err != nil {
+		return nil, fmt.Errorf("query trending posts: %w", err)
+	}
+	defer rows.Close()
+
+	out := make([]PostWithStats, 0, q.Limit)
+	for rows.Next() {
+		var post PostWithStats
+		err := rows.Scan(
+			&post.ID,
+			&post.AnonID,
+			&post.Text,
+			&post.CreatedAt,
+			&post.Likes,
+			&post.Dislikes,
+			&post.Deleted,
+			&post.CommentCount,
+			&post.HotScore,
+			&post.RisingScore,
+			&post.TopScore,
+		)
+		if err != nil {
+			return nil, fmt.Errorf("scan trending post: %w", err)
+		}
+		post.LikeCount = post.Likes
+		post.DislikeCount = post.Dislikes
+		out = append(out, post)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("iterate trending posts: %w", err)
+	}
+	return out, nil
+}
+
+// GetPostSignals returns the engagement counts of every non-deleted post
+// created at or after since.
+func (s *PgStore) GetPostSignals(ctx context.Context, since time.Time) ([]PostSignals, error) {
+	// likes and dislikes are the counters ReactToPost keeps in step with
+	// post_reactions.
+	query := `
+		WITH com AS (
+			SELECT post_id, COUNT(*) AS n
+			FROM post_comments
+			WHERE deleted = false
+			GROUP BY post_id
+		), rep AS (
+			SELECT post_id, COUNT(*) AS n
+			FROM post_reports
+			GROUP BY post_id
+		)
+		SELECT p.id, p.created_at, p.likes, p.dislikes, COALESCE(com.n, 0), COALESCE(rep.n, 0)
+		FROM posts p
+		LEFT JOIN com ON com.post_id = p.id
+		LEFT JOIN rep ON rep.post_id = p.id
+		WHERE p.deleted = false AND p.created_at >= $1
+	`
+	rows, err := s.db.QueryContext(ctx, query, since)
+	if err != nil {
+		return nil, fmt.Errorf("query post signals: %w", err)
+	}
+	defer rows.Close()
+
+	out := []PostSignals{}
+	for rows.Next() {
+		var sig PostSignals
+		if err := rows.Scan(&sig.PostID, &sig.CreatedAt, &sig.Likes, &sig.Dislikes, &sig.Comments, &sig.Reports); err != nil {
+			return nil, fmt.Errorf("scan post signals: %w", err)
+		}
+		out = append(out, sig)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("iterate post signals: %w", err)
+	}
+	return out, nil
+}
+
+// postScoresLock is the advisory lock key serialising ranking rewrites, so
+// replicas running the trending job at once do not collide on post_id.
+const postScoresLock = 0x73636f726573 // "scores"
+
+// ReplacePostScores makes scores the whole ranking in one transaction, so
+// readers see either the previous run or this one. If another instance is
+// writing its ranking at the same moment, this run is dropped: both rank
+// the same posts.
+func (s *PgStore) ReplacePostScores(ctx context.Context, scores []PostScore) error {
+	n := len(scores)
+	ids := make([]string, n)
+	comments := make([]int64, n)
+	reports := make([]int64, n)
+	hot := make([]float64, n)
+	rising := make([]float64, n)
+	top := make([]float64, n)
+	computedAt := make([]string, n)
+	for i, sc := range scores {
+		ids[i] = sc.PostID
+		comments[i] = int64(sc.Comments)
+		reports[i] = int64(sc.Reports)
+		hot[i] = sc.Hot
+		rising[i] = sc.Rising
+		top[i] = sc.Top
+		computedAt[i] = sc.ComputedAt.UTC().Format(time.RFC3339Nano)
+	}
+
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	var locked bool
+	if err := tx.QueryRowContext(ctx, `SELECT pg_try_advisory_xact_lock($1)`, postScoresLock).Scan(&locked); err != nil {
+		return fmt.Errorf("lock post scores: %w", err)
+	}
+	if !locked {
+		return nil
+	}
+
+	if _, err := tx.ExecContext(ctx, `DELETE FROM post_scores`); err != nil {
+		return fmt.Errorf("clear post scores: %w", err)
+	}
+	// Posts deleted for good since they were scored are skipped
+	query := `
+		INSERT INTO post_scores (post_id, comments, reports, hot, rising, top, computed_at)
+		SELECT u.post_id, u.comments, u.reports, u.hot, u.rising, u.top, u.computed_at
+		FROM unnest($1::text[], $2::int[], $3::int[], $4::float8[], $5::float8[], $6::float8[], $7::timestamptz[])
+			AS u(post_id, comments, reports, hot, rising, top, computed_at)
+		WHERE EXISTS (SELECT 1 FROM posts WHERE id = u.post_id)
+	`
+	_, err = tx.ExecContext(ctx, query,
+		pq.Array(ids), pq.Array(comments), pq.Array(reports),
+		pq.Array(hot), pq.Array(rising), pq.Array(top), pq.Array(computedAt))
+	if err != nil {
+		return fmt.Errorf("insert post scores: %w", err)
+	}
+
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("commit post scores: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,95 @@
+package store
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"time"
+)
+
+// TakeRateLimitToken locks the bucket row so concurrent requests on any
+// instance draw from the same tokens.
+func (s *PgStore) TakeRateLimitToken(ctx context.Context, policy, key string, capacity int, window time.Duration, now time.Time) (RateLimitResult, error) {
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return RateLimitResult{}, fmt.Errorf("begin rate limit: %w", err)
+	}
+	defer tx.Rollback()
+
+	_, err = tx.ExecContext(ctx, `
+		INSERT INTO rate_limit_buckets (policy, key, tokens, updated_at)
+		VALUES ($1, $2, $3, $4)
+		ON CONFLICT (policy, key) DO NOTHING
+	`, policy, key, float64(capacity), now)
+	if err != nil {
+		return RateLimitResult{}, fmt.Errorf("create rate limit bucket: %w", err)
+	}
+
+	b := RateLimitBucket{Policy: policy, Key: key}
+	var lastLimited sql.NullTime
+	err = tx.QueryRowContext(ctx, `
+		SELECT tokens, updated_at, limited, last_limited_at
+		FROM rate_limit_buckets
+		WHERE policy = $1 AND key = $2
+		FOR UPDATE
+	`, policy, key).Scan(&b.Tokens, &b.UpdatedAt, &b.Limited, &lastLimited)
+	if err != nil {
+		return RateLimitResult{}, fmt.Errorf("load rate limit bucket: %w", err)
+	}
+	if lastLimited.Valid {
+		b.LastLimitedAt = &lastLimited.Time
+	}
+
+	res := takeRateLimitToken(&b, capacity, window, now)
+
+	_, err = tx.ExecContext(ctx, `
+		UPDATE rate_limit_buckets
+		SET tokens = $3, updated_at = $4, limited = $5, last_limited_at = $6
+		WHERE policy = $1 AND key = $2
+	`, policy, key, b.Tokens, b.UpdatedAt, b.Limited, b.LastLimitedAt)
+	if err != nil {
+		return RateLimitResult{}, fmt.Errorf("update rate limit bucket: %w", err)
+	}
+
+	if err := tx.Commit(); err != nil {
+		return RateLimitResult{}, fmt.Errorf("commit rate limit: %w", err)
+	}
+	return res, nil
+}
+
+func (s *PgStore) ListRateLimitedBuckets(ctx context.Context, since time.Time, limit int) ([]RateLimitBucket, error) {
+	rows, err := s.db.QueryContext(ctx, `
+		SELECT policy, key, tokens, updated_at, limited, last_limited_at
+		FROM rate_limit_buckets
+		WHERE last_limited_at >= $1
+		ORDER BY last_limited_at DESC
+		LIMIT $2
+	`, since, limit)
+	if err != nil {
+		return nil, fmt.Errorf("list rate limited buckets: %w", err)
+	}
+	defer rows.Close()
+
+	out := make([]RateLimitBucket, 0)
+	for rows.Next() {
+		var b RateLimitBucket
+		var lastLimited sql.NullTime
+		if err := rows.Scan(&b.Policy, &b.Key, &b.Tokens, &b.UpdatedAt, &b.Limited, &lastLimited); err != nil {
+			return nil, fmt.Errorf("scan rate limit bucket: %w", err)
+		}
+		if lastLimited.Valid {
+			b.LastLimitedAt = &lastLimited.Time
+		}
+		out = append(out, b)
+	}
+	return out, rows.Err()
+}
+
+func (s *PgStore) CleanupRateLimitBuckets(ctx context.Context, idleSince time.Time) (int, error) {
+	res, err := s.db.ExecContext(ctx, `DELETE FROM rate_limit_buckets WHERE updated_at < $1`, idleSince)
+	if err != nil {
+		return 0, fmt.Errorf("cleanup rate limit buckets: %w", err)
+	}
+	n, _ := res.RowsAffected()
+	return int(n), nil
+}
@@ -0,0 +1,237 @@
+package store
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"time"
+)
+
+// PutRecoveryKey stores keyHash as anonID's recovery key, replacing any
+// previous one.
+func (s *PgStore) PutRecoveryKey(ctx context.Context, anonID, keyHash string, now time.Time) error {
+	query := `
+		INSERT INTO recovery_keys (anon_id, key_hash, created_at)
+		VALUES ($1, $2, $3)
+		ON CONFLICT (anon_id) DO UPDATE SET key_hash = EXCLUDED.key_hash, created_at = EXCLUDED.created_at
+	`
+	if _, err := s.db.ExecContext(ctx, query, anonID, keyHash, now); err != nil {
+		return fmt.Errorf("put recovery key: %w", err)
+	}
+	return nil
+}
+
+// RedeemRecoveryKey adds device to the identity whose recovery key has
+// keyHash and consumes the key. Keys are single use; a device that cannot
+// join leaves the key unused.
+func (s *PgStore) RedeemRecoveryKey(ctx context.Context, keyHash string, device *Device, now time.Time) error {
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("begin redeem recovery key: %w", err)
+	}
+	defer tx.Rollback()
+
+	var anonID string
+	err = tx.QueryRowContext(ctx, `DELETE FROM recovery_keys WHERE key_hash = $1 RETURNING anon_id`, keyHash).Scan(&anonID)
+	if err == sql.ErrNoRows {
+		return ErrRecoveryKeyInvalid
+	}
+	if err != nil {
+		return fmt.Errorf("redeem recovery key: %w", err)
+	}
+	if err := joinIdentityTx(ctx, tx, anonID, device, now); err != nil {
+		return err
+	}
+
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("commit redeem recovery key: %w", err)
+	}
+	return nil
+}
+
+// joinIdentityTx creates device as a device of anonID, with its username
+// unless one is set, unless anonID is banned.
+func joinIdentityTx(ctx context.Context, tx *sql.Tx, anonID string, device *Device, now time.Time) error {
+	device.AnonID = anonID
+
+	var banned bool
+	err := tx.QueryRowContext(ctx, `
+		SELECT EXISTS (
+			SELECT 1 FROM user_bans
+			WHERE anon_id = $1 AND banned_at <= $2 AND (is_permanent = true OR expires_at > $2)
+		)
+	`, anonID, now).Scan(&banned)
+	if err != nil {
+		return fmt.Errorf("check identity ban: %w", err)
+	}
+	if banned {
+		return ErrIdentityBanned
+	}
+
+	if device.Username == "" {
+		err := tx.QueryRowContext(ctx, `
+			SELECT COALESCE(
+				(SELECT NULLIF(username, '') FROM users WHERE anon_id = $1),
+				(SELECT username FROM devices WHERE anon_id = $1 ORDER BY created_at LIMIT 1),
+				''
+			)
+		`, anonID).Scan(&device.Username)
+		if err != nil {
+			return fmt.Errorf("get identity username: %w", err)
+		}
+	}
+
+	_, err = tx.ExecContext(ctx, insertDeviceQuery, insertDeviceArgs(device)...)
+	if isExclusionViolation(err, "devices_username_owner") {
+		return ErrUsernameTaken
+	}
+	if err != nil {
+		return fmt.Errorf("create device: %w", err)
+	}
+	return nil
+}
+
+func (s *PgStore) CreateDeviceLink(ctx context.Context, link *DeviceLink) error {
+	if link.CreatedAt.IsZero() {
+		link.CreatedAt = time.Now()
+	}
+	query := `
+		INSERT INTO device_links (code, device_public_id, created_at, expires_at)
+		VALUES ($1, $2, $3, $4)
+	`
+	if _, err := s.db.ExecContext(ctx, query, link.Code, link.DevicePublicID, link.CreatedAt, link.ExpiresAt); err != nil {
+		return fmt.Errorf("create device link: %w", err)
+	}
+	return nil
+}
+
+func (s *PgStore) GetDeviceLink(ctx context.Context, code string) (*DeviceLink, error) {
+	query := `
+		SELECT code, device_public_id, COALESCE(anon_id, ''), created_at, expires_at, approved_at, consumed_at
+		FROM device_links
+		WHERE code = $1
+	`
+	link, err := scanDeviceLink(s.db.QueryRowContext(ctx, query, code))
+	if err == sql.ErrNoRows {
+		return nil, ErrDeviceLinkNotFound
+	}
+	if err != nil {
+		return nil, fmt.Errorf("get device link: %w", err)
+	}
+	return link, nil
+}
+
+// ApproveDeviceLink binds a pending, unexpired link to anonID.
+func (s *PgStore) ApproveDeviceLink(ctx context.Context, code, anonID string, now time.Time) (*DeviceLink, error) {
+	query := `
+		UPDATE device_links
+		SET anon_id = $2, approved_at = $3
+		WHERE code = $1 AND approved_at IS NULL AND expires_at > $3
+		RETURNING code, device_public_id, COALESCE(anon_id, ''), created_at, expires_at, approved_at, consumed_at
+	`
+	link, err := scanDeviceLink(s.db.QueryRowContext(ctx, query, code, anonID, now))
+	if err == sql.ErrNoRows {
+		return nil, ErrDeviceLinkNotFound
+	}
+	if err != nil {
+		return nil, fmt.Errorf("approve device link: %w", err)
+	}
+	return link, nil
+}
+
+// ConsumeDeviceLink redeems an approved link for device and adds device to
+// the identity that approved it. Links are single use; a device that
+// cannot join leaves the link unused.
+func (s *PgStore) ConsumeDeviceLink(ctx context.Context, code string, device *Device, now time.Time) error {
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("begin consume device link: %w", err)
+	}
+	defer tx.Rollback()
+
+	query := `
+		UPDATE device_links
+		SET consumed_at = $3
+		WHERE code = $1 AND device_public_id = $2 AND approved_at IS NOT NULL
+			AND consumed_at IS NULL AND expires_at > $3
+		RETURNING anon_id
+	`
+	var anonID string
+	err = tx.QueryRowContext(ctx, query, code, device.DevicePublicID, now).Scan(&anonID)
+	if err == sql.ErrNoRows {
+		// Tell a link that is merely waiting for approval apart from a bad one.
+		link, getErr := s.GetDeviceLink(ctx, code)
+		if getErr == nil && link.DevicePublicID == device.DevicePublicID && link.ApprovedAt == nil && link.ExpiresAt.After(now) {
+			return ErrDeviceLinkPending
+		}
+		return ErrDeviceLinkNotFound
+	}
+	if err != nil {
+		return fmt.Errorf("consume device link: %w", err)
+	}
+	if err := joinIdentityTx(ctx, tx, anonID, device, now); err != nil {
+		return err
+	}
+
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("commit consume device link: %w", err)
+	}
+	return nil
+}
+
+func scanDeviceLink(row *sql.Row) (*DeviceLink, error) {
+	link := &DeviceLink{}
+	var approvedAt, consumedAt sql.NullTime
+	err := row.Scan(
+		&link.Code,
+		&link.DevicePublicID,
+		&link.AnonID,
+		&link.CreatedAt,
+		&link.ExpiresAt,
+		&approvedAt,
+		&consumedAt,
+	)
+	if err != nil {
+		return nil, err
+	}
+	if approvedAt.Valid {
+		link.ApprovedAt = &approvedAt.Time
+	}
+	if consumedAt.Valid {
+		link.ConsumedAt = &consumedAt.Time
+	}
+	return link, nil
+}
+
+func (s *PgStore) GetDevicesByAnonID(ctx context.Context, anonID string) ([]*Device, error) {
+	query := `
+		SELECT device_public_id, device_secret_hash, auth_method, COALESCE(public_key, ''), anon_id, username, created_at, updated_at
+		FROM devices
+		WHERE anon_id = $1
+		ORDER BY created_at
+	`
+	rows, err := s.db.QueryContext(ctx, query, anonID)
+	if err != nil {
+		return nil, fmt.Errorf("get devices by anon id: %w", err)
+	}
+	defer rows.Close()
+
+	out := make([]*Device, 0)
+	for rows.Next() {
+		device := &Device{}
+		if err := rows.Scan(
+			&device.DevicePublicID,
+			&device.DeviceSecretHash,
+			&device.AuthMethod,
+			&device.PublicKey,
+			&device.AnonID,
+			&device.Username,
+			&device.CreatedAt,
+			&device.UpdatedAt,
+		); err != nil {
+			return nil, fmt.Errorf("scan device: %w", err)
+		}
+		out = append(out, device)
+	}
+	return out, rows.Err()
+}
@@ -1,19 +1,20 @@
 package store
 
 import (
+	"context"
 	"database/sql"
 	"fmt"
 	"time"
 )
 
 // ReportPost adds a report for a post. Returns error only on non-unique-constraint errors
-func (s *PgStore) ReportPost(postID, reportedAnonID, reporterAnonID, reason string, now time.Time) error {
+func (s *PgStore) ReportPost(ctx context.Context, postID, reportedAnonID, reporterAnonID, reason string, now time.Time) error {
 	query := `
 		INSERT INTO post_reports (post_id, reported_anon_id, reporter_anon_id, reason, created_at)
 		VALUES ($1, $2, $3, $4, $5)
 		ON CONFLICT (post_id, reporter_anon_id) DO NOTHING
 	`
-	_, err := s.db.Exec(query, postID, reportedAnonID, reporterAnonID, reason, now)
+	_, err := s.db.ExecContext(ctx, query, postID, reportedAnonID, reporterAnonID, reason, now)
 	if err != nil {
 		return fmt.Errorf("report post: %w", err)
 	}
@@ -21,19 +22,17 @@ func (s *PgStore) ReportPost(postID, reportedAnonID, reporterAnonID, reason stri
 }
 
 // GetPostReportCount returns the number of reports for a post
-func (s *PgStore) GetPostReportCount(postID string) int {
+func (s *PgStore) GetPostReportCount(ctx context.Context, postID string) (int, error) {
 	var count int
 	query := `SELECT COUNT(*) FROM post_reports WHERE post_id = $1`
-	err := s.db.QueryRow(query, postID).Scan(&count)
-	if err != nil {
-		fmt.Printf("error counting post reports: %v\n", err)
-		return 0
+	if err := s.db.QueryRowContext(ctx, query, postID).Scan(&count); err != nil {
+		return 0, fmt.Errorf("count post reports: %w", err)
 	}
-	return count
+	return count, nil
 }
 
 // GetTopReportedPostByAnon returns the most reported post by a user that meets threshold
-func (s *PgStore) GetTopReportedPostByAnon(anonID string, threshold int) *PostReport {
+func (s *PgStore) GetTopReportedPostByAnon(ctx context.Context, anonID string, threshold int) (*PostReport, error) {
 	query := `
 		SELECT
 			pr.post_id,
@@ -52,13 +51,12 @@ func (s *PgStore) GetTopReportedPostByAnon(anonID string, threshold int) *PostRe
 	var lastReportedAt time.Time
 	var reason sql.NullString
 
-	err := s.db.QueryRow(query, anonID, threshold).Scan(&postID, &reportCount, &lastReportedAt, &reason)
+	err := s.db.QueryRowContext(ctx, query, anonID, threshold).Scan(&postID, &reportCount, &lastReportedAt, &reason)
 	if err != nil {
 		if err == sql.ErrNoRows {
-			return nil
+			return nil, nil
 		}
-		fmt.Printf("error getting top reported post: %v\n", err)
-		return nil
+		return nil, fmt.Errorf("get top reported post: %w", err)
 	}
 
 	return &PostReport{
@@ -66,5 +64,5 @@ func (s *PgStore) GetTopReportedPostByAnon(anonID string, threshold int) *PostRe
 		ReportCount:    reportCount,
 		LastReportedAt: lastReportedAt,
 		Reason:         reason.String,
-	}
+	}, nil
 }
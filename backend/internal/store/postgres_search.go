@@ -0,0 +1,141 @@
+package store
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/lib/pq"
+)
+
+// ts_headline marks matches in the raw text with STX and ETX, which are
+// stripped from the text first so they cannot come from a post. The result
+// is then escaped and the markers swapped for highlightStart and
+// highlightStop; running ts_headline on escaped text would let a search for
+// "amp" mark the inside of an entity.
+const (
+	headlineMarkStart = "chr(2)"
+	headlineMarkStop  = "chr(3)"
+)
+
+// headlineOptions makes ts_headline return the whole post with matches
+// marked; posts are short enough not to need fragments.
+const headlineOptions = "'StartSel=' || " + headlineMarkStart + " || ', StopSel=' || " + headlineMarkStop + " || ', HighlightAll=true'"
+
+// headlineSQL is the Highlights expression for a post matching query.
+func headlineSQL(query string) string {
+	raw := "translate(p.text, " + headlineMarkStart + " || " + headlineMarkStop + ", '')"
+	marked := escapeHighlightSQL("ts_headline('english', " + raw + ", " + query + ", " + headlineOptions + ")")
+	return "replace(replace(" + marked + ", " + headlineMarkStart + ", '" + highlightStart + "'), " + headlineMarkStop + ", '" + highlightStop + "')"
+}
+
+// SearchPosts returns a page of the posts matching q, best first, and how
+// many match in all. Text matches use the text_search GIN index and the
+// trigram fallback idx_posts_text_trgm, both from migration 008.
+func (s *PgStore) SearchPosts(ctx context.Context, q SearchQuery) ([]*PostSearchResult, int, error) {
+	q.normalize()
+	if q.Text == "" && len(q.Hashtags) == 0 {
+		return []*PostSearchResult{}, 0, nil
+	}
+	terms := parseSearchTerms(q.Text)
+
+	results, total, err := s.searchPosts(ctx, q, terms, false)
+	if err != nil || total > 0 || q.Text == "" {
+		return results, total, err
+	}
+	return s.searchPosts(ctx, q, terms, true)
+}
+
+// searchPosts runs one pass of SearchPosts: text and author matches, or
+// with fuzzy set, trigram matches.
+func (s *PgStore) searchPosts(ctx context.Context, q SearchQuery, terms []searchTerm, fuzzy bool) ([]*PostSearchResult, int, error) {
+	conds := []string{"p.deleted = false"}
+	args := []interface{}{}
+	arg := func(v interface{}) string {
+		args = append(args, v)
+		return "$" + strconv.Itoa(len(args))
+	}
+	if len(q.Hashtags) > 0 {
+		conds = append(conds, "p.hashtags @> "+arg(pq.Array(q.Hashtags)))
+	}
+
+	join := ""
+	score := fmt.Sprintf("%g", searchScoreText)
+	escaped := escapeHighlightSQL("p.text")
+	headline := escaped
+	switch {
+	case fuzzy:
+		words := fuzzyWords(terms)
+		if len(words) == 0 {
+			return []*PostSearchResult{}, 0, nil
+		}
+		sims := make([]string, len(words))
+		for i, w := range words {
+			word := arg(w)
+			conds = append(conds, word+" <% p.text")
+			sims[i] = "word_similarity(" + word + ", p.text)::float8"
+		}
+		score = "(" + strings.Join(sims, " + ") + ") / " + strconv.Itoa(len(words))
+	case q.Text != "":
+		// the author's username comes from their first device, as in
+		// GetUsernames
+		join = `LEFT JOIN LATERAL (
+			SELECT username FROM devices d WHERE d.anon_id = p.anon_id ORDER BY d.created_at LIMIT 1
+		) d ON true`
+		author := "lower(p.anon_id) = lower(" + arg(q.Text) + ")"
+		if norm := normalizeSearchToken(q.Text); norm != "" {
+			author = "(" + author + " OR regexp_replace(lower(COALESCE(d.username, '')), '[^a-z0-9_]', '', 'g') = " + arg(norm) + ")"
+		}
+		match := []string{author}
+		score = fmt.Sprintf("CASE WHEN %s THEN %g", author, searchScoreAuthor)
+		if tsq := tsQuery(terms); tsq != "" {
+			query := "to_tsquery('english', " + arg(tsq) + ")"
+			fts := "p.text_search @@ " + query
+			match = append(match, fts)
+			score += fmt.Sprintf(" WHEN %s THEN %g + ts_rank_cd(p.text_search, %s, 32)::float8", fts, searchScoreText, query)
+			headline = "CASE WHEN " + fts + " THEN " + headlineSQL(query) + " ELSE " + escaped + " END"
+		}
+		score += " END"
+		conds = append(conds, "("+strings.Join(match, " OR ")+")")
+	}
+
+	from := `
+		FROM posts p ` + join + `
+		WHERE ` + strings.Join(conds, " AND ")
+
+	var total int
+	if err := s.db.QueryRowContext(ctx, `SELECT COUNT(*)`+from, args...).Scan(&total); err != nil {
+		return nil, 0, fmt.Errorf("count search results: %w", err)
+	}
+	if total <= q.Offset {
+		return []*PostSearchResult{}, total, nil
+	}
+
+	query := `
+		SELECT p.id, p.anon_id, p.text, p.created_at, p.likes, p.dislikes, p.deleted,
+			` + score + ` AS score, ` + headline + ` AS headline` + from + `
+		ORDER BY score DESC, p.created_at DESC, p.id DESC
+		LIMIT ` + arg(q.Limit) + ` OFFSET ` + arg(q.Offset)
+
+	rows, err := s.db.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, 0, fmt.Errorf("search posts: %w", err)
+	}
+	defer rows.Close()
+
+	results := []*PostSearchResult{}
+	for rows.Next() {
+		p := &Post{}
+		r := &PostSearchResult{Post: p}
+		if err := rows.Scan(&p.ID, &p.AnonID, &p.Text, &p.CreatedAt, &p.Likes, &p.Dislikes, &p.Deleted, &r.RelevanceScore, &r.Highlights); err != nil {
+			return nil, 0, fmt.Errorf("scan search result: %w", err)
+		}
+		r.MatchedTerms = matchedTerms(r.Highlights, q.Hashtags)
+		results = append(results, r)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, 0, fmt.Errorf("iterate search results: %w", err)
+	}
+	return results, total, nil
+}
@@ -0,0 +1,109 @@
+package store
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+)
+
+func (s *PgStore) GetSessionByID(ctx context.Context, sessionID string) (*SessionInfo, error) {
+	query := `
+		SELECT id, anon_id, issued_at, expires_at, token, created_at, COALESCE(last_activity_at, issued_at),
+			COALESCE(refresh_token_hash, ''), COALESCE(refresh_expires_at, expires_at),
+			COALESCE(device_public_id, ''), COALESCE(region, ''), COALESCE(user_agent, '')
+		FROM sessions
+		WHERE id = $1
+	`
+	sess := &SessionInfo{}
+	err := s.db.QueryRowContext(ctx, query, sessionID).Scan(
+		&sess.ID,
+		&sess.AnonID,
+		&sess.IssuedAt,
+		&sess.ExpiresAt,
+		&sess.Token,
+		&sess.CreatedAt,
+		&sess.LastActivityAt,
+		&sess.RefreshTokenHash,
+		&sess.RefreshExpiresAt,
+		&sess.DevicePublicID,
+		&sess.Region,
+		&sess.UserAgent,
+	)
+	if err == sql.ErrNoRows {
+		return nil, ErrSessionNotFound
+	}
+	if err != nil {
+		return nil, fmt.Errorf("get session by id: %w", err)
+	}
+	return sess, nil
+}
+
+// RotateRefreshToken swaps the session's access token and refresh token hash
+// if presentedHash is the current one. The token it replaced is accepted for
+// RefreshGrace and gets the session back unchanged. Presenting any other
+// token of a live family means an old refresh token was replayed: the whole
+// session is revoked and ErrRefreshTokenReused is returned.
+func (s *PgStore) RotateRefreshToken(ctx context.Context, sessionID, presentedHash string, next SessionRotation) (*SessionInfo, error) {
+	query := `
+		UPDATE sessions
+		SET token = $1, refresh_token_hash = $2, issued_at = $3, expires_at = $4,
+			refresh_expires_at = $5, last_activity_at = $3, user_agent = COALESCE(NULLIF($8, ''), user_agent),
+			prev_refresh_token_hash = $7, refresh_rotated_at = $3, refresh_seed = $9
+		WHERE id = $6 AND refresh_token_hash = $7 AND refresh_expires_at > $3
+		RETURNING ` + rotatedSessionColumns
+	sess := &SessionInfo{}
+	err := scanRotatedSession(s.db.QueryRowContext(ctx, query, next.Token, next.RefreshTokenHash, next.IssuedAt, next.ExpiresAt, next.RefreshExpiresAt, sessionID, presentedHash, next.UserAgent, next.RefreshSeed), sess)
+	if err == nil {
+		return sess, nil
+	}
+	if err != sql.ErrNoRows {
+		return nil, fmt.Errorf("rotate refresh token: %w", err)
+	}
+
+	// Not rotated: either the family is gone/expired, the token presented
+	// was replaced within RefreshGrace, or it is not the current one of a
+	// live family.
+	err = scanRotatedSession(s.db.QueryRowContext(ctx, `SELECT `+rotatedSessionColumns+` FROM sessions WHERE id = $1`, sessionID), sess)
+	if err == sql.ErrNoRows || (err == nil && (sess.RefreshTokenHash == "" || sess.RefreshTokenHash == presentedHash)) {
+		return nil, ErrSessionNotFound
+	}
+	if err != nil {
+		return nil, fmt.Errorf("load refresh token family: %w", err)
+	}
+	if sess.PrevRefreshTokenHash == presentedHash && next.IssuedAt.Before(sess.RefreshRotatedAt.Add(RefreshGrace)) {
+		return sess, nil
+	}
+	anonID := sess.AnonID
+
+	if _, err := s.db.ExecContext(ctx, `DELETE FROM sessions WHERE id = $1`, sessionID); err != nil {
+		return nil, fmt.Errorf("revoke refresh token family: %w", err)
+	}
+	_ = s.ReconcileUserActiveStatus(ctx, anonID)
+	return nil, ErrRefreshTokenReused
+}
+
+const rotatedSessionColumns = `id, anon_id, issued_at, expires_at, token, created_at, COALESCE(last_activity_at, issued_at),
+	COALESCE(refresh_token_hash, ''), COALESCE(refresh_expires_at, expires_at),
+	COALESCE(prev_refresh_token_hash, ''), COALESCE(refresh_rotated_at, issued_at), COALESCE(refresh_seed, ''),
+	COALESCE(device_public_id, ''), COALESCE(region, ''), COALESCE(user_agent, '')`
+
+// scanRotatedSession scans rotatedSessionColumns into sess.
+func scanRotatedSession(row *sql.Row, sess *SessionInfo) error {
+	return row.Scan(
+		&sess.ID,
+		&sess.AnonID,
+		&sess.IssuedAt,
+		&sess.ExpiresAt,
+		&sess.Token,
+		&sess.CreatedAt,
+		&sess.LastActivityAt,
+		&sess.RefreshTokenHash,
+		&sess.RefreshExpiresAt,
+		&sess.PrevRefreshTokenHash,
+		&sess.RefreshRotatedAt,
+		&sess.RefreshSeed,
+		&sess.DevicePublicID,
+		&sess.Region,
+		&sess.UserAgent,
+	)
+}
@@ -0,0 +1,87 @@
+package store
+
+import (
+	"context"
+	"fmt"
+	"time"
+)
+
+// GetTrendingTags returns the limit tags carried by the most non-deleted
+// posts created at or after since, most used first.
+func (s *PgStore) GetTrendingTags(ctx context.Context, since time.Time, limit int) ([]TagCount, error) {
+	if limit <= 0 {
+		limit = 20
+	}
+	query := `
+		SELECT t.tag, COUNT(*) AS posts
+		FROM post_tags t
+		JOIN posts p ON p.id = t.post_id
+		WHERE t.created_at >= $1 AND p.deleted = false
+		GROUP BY t.tag
+		ORDER BY posts DESC, t.tag
+		LIMIT $2
+	`
+	rows, err := s.db.QueryContext(ctx, query, since, limit)
+	if err != nil {
+		return nil, fmt.Errorf("query trending tags: %w", err)
+	}
+	defer rows.Close()
+
+	out := []TagCount{}
+	for rows.Next() {
+		var tc TagCount
+		if err := rows.Scan(&tc.Tag, &tc.Posts); err != nil {
+			return nil, fmt.Errorf("scan trending tags: %w", err)
+		}
+		out = append(out, tc)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("iterate trending tags: %w", err)
+	}
+	return out, nil
+}
+
+// FollowTag makes posts carrying tag part of anonID's following feed.
+// Following a tag again is not an error.
+func (s *PgStore) FollowTag(ctx context.Context, anonID, tag string, now time.Time) error {
+	query := `
+		INSERT INTO tag_follows (anon_id, tag, created_at)
+		VALUES ($1, $2, $3)
+		ON CONFLICT (anon_id, tag) DO NOTHING
+	`
+	if _, err := s.db.ExecContext(ctx, query, anonID, tag, now); err != nil {
+		return fmt.Errorf("follow tag: %w", err)
+	}
+	return nil
+}
+
+// UnfollowTag undoes FollowTag. Unfollowing a tag not followed is not an
+// error.
+func (s *PgStore) UnfollowTag(ctx context.Context, anonID, tag string) error {
+	if _, err := s.db.ExecContext(ctx, `DELETE FROM tag_follows WHERE anon_id = $1 AND tag = $2`, anonID, tag); err != nil {
+		return fmt.Errorf("unfollow tag: %w", err)
+	}
+	return nil
+}
+
+// GetFollowedTags returns the tags anonID follows, alphabetically.
+func (s *PgStore) GetFollowedTags(ctx context.Context, anonID string) ([]string, error) {
+	rows, err := s.db.QueryContext(ctx, `SELECT tag FROM tag_follows WHERE anon_id = $1 ORDER BY tag`, anonID)
+	if err != nil {
+		return nil, fmt.Errorf("query followed tags: %w", err)
+	}
+	defer rows.Close()
+
+	out := []string{}
+	for rows.Next() {
+		var tag string
+		if err := rows.Scan(&tag); err != nil {
+			return nil, fmt.Errorf("scan followed tags: %w", err)
+		}
+		out = append(out, tag)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("iterate followed tags: %w", err)
+	}
+	return out, nil
+}
@@ -0,0 +1,95 @@
+package store_test
+
+import (
+	"crypto/rand"
+	"database/sql"
+	"encoding/hex"
+	"net/url"
+	"os"
+	"strconv"
+	"strings"
+	"testing"
+
+	"anon-backend/internal/store"
+	"anon-backend/internal/store/storetest"
+
+	_ "github.com/lib/pq"
+)
+
+// TestPgStore runs the conformance suite against Postgres. DATABASE_URL
+// must point at a server where the user may create databases; the suite
+// works in throwaway databases and leaves the one named in the URL alone.
+func TestPgStore(t *testing.T) {
+	dsn := os.Getenv("DATABASE_URL")
+	if dsn == "" {
+		t.Skip("DATABASE_URL not set")
+	}
+
+	admin, err := sql.Open("postgres", dsn)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { admin.Close() })
+
+	// Migrate a template once, then clone it for each subtest. Tables
+	// cannot simply be truncated between tests: the audit log refuses it.
+	template := "anon_storetest_" + randomSuffix(t) + "_tmpl"
+	createDatabase(t, admin, template, "")
+	db := openDatabase(t, dsn, template)
+	if err := store.RunMigrations(db); err != nil {
+		t.Fatal(err)
+	}
+	db.Close()
+
+	n := 0
+	storetest.Run(t, func(t *testing.T) store.Store {
+		n++
+		name := strings.TrimSuffix(template, "_tmpl") + "_" + strconv.Itoa(n)
+		createDatabase(t, admin, name, template)
+		return store.NewPgStore(openDatabase(t, dsn, name))
+	})
+}
+
+// createDatabase creates name, optionally from template, and drops it
+// when t finishes.
+func createDatabase(t *testing.T, admin *sql.DB, name, template string) {
+	t.Helper()
+	query := `CREATE DATABASE ` + name
+	if template != "" {
+		query += ` TEMPLATE ` + template
+	}
+	if _, err := admin.Exec(query); err != nil {
+		t.Fatalf("create database %s: %v", name, err)
+	}
+	t.Cleanup(func() {
+		if _, err := admin.Exec(`DROP DATABASE IF EXISTS ` + name); err != nil {
+			t.Errorf("drop database %s: %v", name, err)
+		}
+	})
+}
+
+// openDatabase connects to database name on the server dsn points at. The
+// connection is closed before the database is dropped.
+func openDatabase(t *testing.T, dsn, name string) *sql.DB {
+	t.Helper()
+	if u, err := url.Parse(dsn); err == nil && (u.Scheme == "postgres" || u.Scheme == "postgresql") {
+		u.Path = "/" + name
+		dsn = u.String()
+	} else {
+		dsn += " dbname=" + name
+	}
+	db, err := sql.Open("postgres", dsn)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { db.Close() })
+	return db
+}
+
+func randomSuffix(t *testing.T) string {
+	b := make([]byte, 4)
+	if _, err := rand.Read(b); err != nil {
+		t.Fatal(err)
+	}
+	return hex.EncodeToString(b)
+}
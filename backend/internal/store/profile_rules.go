@@ -2,12 +2,13 @@ package store
 
 import (
 	"errors"
+	"fmt"
 	"regexp"
 	"strings"
 )
 
 var (
-	ErrProfileNotFound   = errors.New("profile not found")
+	ErrProfileNotFound   = fmt.Errorf("profile %w", ErrNotFound)
 	ErrUsernameTaken     = errors.New("username already taken")
 	ErrInvalidUserUpdate = errors.New("invalid profile update")
 	ErrAlreadyReported   = errors.New("already reported")
@@ -0,0 +1,126 @@
+package store
+
+import (
+	"errors"
+	"fmt"
+	"time"
+
+	"anon-backend/internal/config"
+)
+
+// ErrQuotaExceeded is matched by every *QuotaExceededError.
+var ErrQuotaExceeded = errors.New("quota exceeded")
+
+// QuotaExceededError is returned when an item was refused because one of
+// its quota windows is used up. Nothing was written.
+type QuotaExceededError struct {
+	Resource string
+	Result   QuotaResult
+}
+
+func (e *QuotaExceededError) Error() string {
+	if u, ok := e.Result.Exhausted(); ok {
+		return fmt.Sprintf("%s quota exceeded (%d per %s)", e.Resource, u.Limit, u.Window)
+	}
+	return e.Resource + " quota exceeded"
+}
+
+func (e *QuotaExceededError) Unwrap() error { return ErrQuotaExceeded }
+
+// QuotaUsage is the state of one window of a quota.
+type QuotaUsage struct {
+	Window    string
+	Limit     int
+	Used      int
+	Remaining int
+	ResetAt   time.Time
+}
+
+// QuotaResult is the outcome of TakeQuota. Usage counts the new item when
+// Allowed.
+type QuotaResult struct {
+	Allowed bool
+	Usage   []QuotaUsage
+}
+
+// Exhausted returns the window that blocks the next item the longest, or
+// false if every window has room.
+func (r QuotaResult) Exhausted() (QuotaUsage, bool) {
+	var out QuotaUsage
+	found := false
+	for _, u := range r.Usage {
+		if u.Remaining > 0 {
+			continue
+		}
+		if !found || u.ResetAt.After(out.ResetAt) {
+			out = u
+			found = true
+		}
+	}
+	return out, found
+}
+
+// QuotaWindowStart returns the start of the config.Quota* window containing
+// now. Windows are calendar periods in UTC, so every instance agrees on when
+// a counter resets; weeks start on Monday and unknown windows count as days.
+func QuotaWindowStart(window string, now time.Time) time.Time {
+	now = now.UTC()
+	switch window {
+	case config.QuotaHour:
+		return now.Truncate(time.Hour)
+	case config.QuotaWeek:
+		day := time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, time.UTC)
+		return day.AddDate(0, 0, -((int(day.Weekday()) + 6) % 7))
+	default:
+		return time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, time.UTC)
+	}
+}
+
+func quotaWindowEnd(window string, start time.Time) time.Time {
+	switch window {
+	case config.QuotaHour:
+		return start.Add(time.Hour)
+	case config.QuotaWeek:
+		return start.AddDate(0, 0, 7)
+	default:
+		return start.AddDate(0, 0, 1)
+	}
+}
+
+// quotaUsage builds the usage of each limit from its current count (used is
+// parallel to limits).
+func quotaUsage(limits []config.QuotaLimit, used []int, now time.Time) []QuotaUsage {
+	out := make([]QuotaUsage, len(limits))
+	for i, l := range limits {
+		remaining := l.Max - used[i]
+		if remaining < 0 {
+			remaining = 0
+		}
+		out[i] = QuotaUsage{
+			Window:    l.Window,
+			Limit:     l.Max,
+			Used:      used[i],
+			Remaining: remaining,
+			ResetAt:   quotaWindowEnd(l.Window, QuotaWindowStart(l.Window, now)),
+		}
+	}
+	return out
+}
+
+// takeQuota allows one more item if every window has room, and then counts
+// it in used. Both stores call it so they decide identically.
+func takeQuota(limits []config.QuotaLimit, used []int, now time.Time) QuotaResult {
+	res := QuotaResult{Allowed: true}
+	for i, l := range limits {
+		if used[i] >= l.Max {
+			res.Allowed = false
+		}
+	}
+	if res.Allowed {
+		for i := range used {
+			used[i]++
+		}
+	}
+	res.Usage = quotaUsage(limits, used, now)
+	return res
+}
@@ -0,0 +1,188 @@
+package store
+
+import (
+	"context"
+	"errors"
+	"math"
+	"time"
+)
+
+// ErrInvalidTrendingQuery is returned for a TrendingQuery whose Algo or
+// Window is not one of the constants below.
+var ErrInvalidTrendingQuery = errors.New("invalid trending query")
+
+// Trending algorithms, each a column of post_scores.
+const (
+	TrendingHot    = "hot"    // engagement, with newer posts winning over time
+	TrendingRising = "rising" // engagement per hour of age, decaying fast
+	TrendingTop    = "top"    // engagement alone
+)
+
+// Trending windows restrict a ranking to posts created that recently.
+const (
+	TrendingWindowDay  = "day"
+	TrendingWindowWeek = "week"
+	TrendingWindowAll  = "all" // everything the ranking job scored
+)
+
+var trendingWindows = map[string]time.Duration{
+	TrendingWindowDay:  24 * time.Hour,
+	TrendingWindowWeek: 7 * 24 * time.Hour,
+	TrendingWindowAll:  0,
+}
+
+// TrendingQuery selects a page of ranked posts.
+type TrendingQuery struct {
+	Algo   string // Trending*; empty means TrendingHot
+	Window string // TrendingWindow*; empty means TrendingWindowAll
+	Limit  int
+	Offset int
+}
+
+// normalize fills in defaults, clamps the page and rejects unknown values.
+// It returns the earliest created_at the window admits.
+func (q *TrendingQuery) normalize(now time.Time) (time.Time, error) {
+	if q.Algo == "" {
+		q.Algo = TrendingHot
+	}
+	if q.Window == "" {
+		q.Window = TrendingWindowAll
+	}
+	switch q.Algo {
+	case TrendingHot, TrendingRising, TrendingTop:
+	default:
+		return time.Time{}, ErrInvalidTrendingQuery
+	}
+	window, ok := trendingWindows[q.Window]
+	if !ok {
+		return time.Time{}, ErrInvalidTrendingQuery
+	}
+
+	if q.Limit <= 0 {
+		q.Limit = 20
+	}
+	if q.Limit > 50 {
+		q.Limit = 50
+	}
+	if q.Offset < 0 {
+		q.Offset = 0
+	}
+
+	if window == 0 {
+		return time.Time{}, nil
+	}
+	return now.Add(-window), nil
+}
+
+// RankingPolicy weighs engagement and sets how fast it decays. Every store
+// ranks with the same policy through ScorePost, so results agree between
+// backends.
+type RankingPolicy struct {
+	Like    float64
+	Dislike float64 // subtracted
+	Comment float64
+	Report  float64 // subtracted
+	// HotTimescale is how much newer a post must be to match ten times
+	// the engagement in the hot ranking.
+	HotTimescale time.Duration
+	// RisingGravity is the exponent of the age penalty in the rising
+	// ranking; higher values favour newer posts.
+	RisingGravity float64
+}
+
+// DefaultRankingPolicy is used when nothing is configured.
+func DefaultRankingPolicy() RankingPolicy {
+	return RankingPolicy{
+		Like:          1,
+		Dislike:       1,
+		Comment:       2,
+		Report:        5,
+		HotTimescale:  45000 * time.Second,
+		RisingGravity: 1.8,
+	}
+}
+
+// PostSignals are the engagement counts a post is ranked by.
+type PostSignals struct {
+	PostID    string
+	CreatedAt time.Time
+	Likes     int
+	Dislikes  int
+	Comments  int // non-deleted comments
+	Reports   int // moderation reports
+}
+
+// PostScore is the ranking of one post as of ComputedAt.
+type PostScore struct {
+	PostID     string
+	Comments   int
+	Reports    int
+	Hot        float64
+	Rising     float64
+	Top        float64
+	ComputedAt time.Time
+}
+
+// ScorePost ranks one post under policy at now.
+func ScorePost(sig PostSignals, policy RankingPolicy, now time.Time) PostScore {
+	net := policy.Like*float64(sig.Likes) -
+		policy.Dislike*float64(sig.Dislikes) +
+		policy.Comment*float64(sig.Comments) -
+		policy.Report*float64(sig.Reports)
+
+	// Reddit's hot: the order of magnitude of the net engagement plus a
+	// bonus that grows with the creation time, so older posts sink.
+	sign := 0.0
+	if net > 0 {
+		sign = 1
+	} else if net < 0 {
+		sign = -1
+	}
+	timescale := policy.HotTimescale.Seconds()
+	if timescale <= 0 {
+		timescale = DefaultRankingPolicy().HotTimescale.Seconds()
+	}
+	hot := sign*math.Log10(math.Max(math.Abs(net), 1)) + float64(sig.CreatedAt.Unix())/timescale
+
+	// Hacker News' gravity: engagement divided by a power of the age.
+	ageHours := math.Max(now.Sub(sig.CreatedAt).Hours(), 0)
+	rising := net / math.Pow(ageHours+2, policy.RisingGravity)
+
+	return PostScore{
+		PostID:     sig.PostID,
+		Comments:   sig.Comments,
+		Reports:    sig.Reports,
+		Hot:        round7(hot),
+		Rising:     round7(rising),
+		Top:        round7(net),
+		ComputedAt: now,
+	}
+}
+
+// round7 keeps scores stable through a round trip to the database.
+func round7(f float64) float64 {
+	return math.Round(f*1e7) / 1e7
+}
+
+// RefreshPostScores rescores every post created within horizon of now (0
+// means all posts) and replaces the stored scores with the result. It
+// returns the number of posts scored.
+func RefreshPostScores(ctx context.Context, st Store, policy RankingPolicy, horizon time.Duration, now time.Time) (int, error) {
+	var since time.Time
+	if horizon > 0 {
+		since = now.Add(-horizon)
+	}
+	signals, err := st.GetPostSignals(ctx, since)
+	if err != nil {
+		return 0, err
+	}
+
+	scores := make([]PostScore, len(signals))
+	for i, sig := range signals {
+		scores[i] = ScorePost(sig, policy, now)
+	}
+	if err := st.ReplacePostScores(ctx, scores); err != nil {
+		return 0, err
+	}
+	return len(scores), nil
+}
@@ -0,0 +1,59 @@
+package store
+
+import (
+	"math"
+	"time"
+)
+
+// RateLimitBucket is the persisted state of one token bucket: a policy
+// (route group) and key (anon id or IP) pair. Limited counts rejected
+// requests so the abuse dashboard can see who hits the limits.
+type RateLimitBucket struct {
+	Policy        string     `json:"policy"`
+	Key           string     `json:"key"`
+	Tokens        float64    `json:"tokens"`
+	UpdatedAt     time.Time  `json:"updated_at"`
+	Limited       int64      `json:"limited"`
+	LastLimitedAt *time.Time `json:"last_limited_at,omitempty"`
+}
+
+// RateLimitResult is the outcome of taking one token.
+type RateLimitResult struct {
+	Allowed   bool
+	Remaining int
+	// ResetAfter is how long until the bucket is full again; RetryAfter,
+	// set when not allowed, how long until the next token.
+	ResetAfter time.Duration
+	RetryAfter time.Duration
+}
+
+// takeRateLimitToken refills b for the time elapsed since its last update at
+// capacity tokens per window, then takes a token if one is available. Both
+// stores call it so they count identically.
+func takeRateLimitToken(b *RateLimitBucket, capacity int, window time.Duration, now time.Time) RateLimitResult {
+	rate := float64(capacity) / window.Seconds() // tokens per second
+	if b.UpdatedAt.IsZero() {
+		b.Tokens = float64(capacity)
+	} else if elapsed := now.Sub(b.UpdatedAt).Seconds(); elapsed > 0 {
+		b.Tokens = math.Min(float64(capacity), b.Tokens+elapsed*rate)
+	}
+	b.UpdatedAt = now
+
+	res := RateLimitResult{}
+	if b.Tokens >= 1 {
+		b.Tokens--
+		res.Allowed = true
+	} else {
+		b.Limited++
+		t := now
+		b.LastLimitedAt = &t
+		res.RetryAfter = secondsDuration((1 - b.Tokens) / rate)
+	}
+	res.Remaining = int(b.Tokens)
+	res.ResetAfter = secondsDuration((float64(capacity) - b.Tokens) / rate)
+	return res
+}
+
+func secondsDuration(s float64) time.Duration {
+	return time.Duration(math.Ceil(s)) * time.Second
+}
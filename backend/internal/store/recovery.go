@@ -0,0 +1,31 @@
+package store
+
+import (
+	"errors"
+	"fmt"
+	"time"
+)
+
+var (
+	ErrRecoveryKeyInvalid = errors.New("recovery key invalid")
+	ErrDeviceLinkNotFound = fmt.Errorf("device link %w", ErrNotFound)
+	// ErrDeviceLinkPending is returned when a link code is redeemed before an
+	// existing device has approved it.
+	ErrDeviceLinkPending = errors.New("device link not approved")
+	// ErrIdentityBanned is returned when a device tries to join an identity
+	// with an active ban; the recovery key or link is left unused.
+	ErrIdentityBanned = errors.New("identity banned")
+)
+
+// DeviceLink is a request from a new device to join an existing identity.
+// The new device shows Code; a signed-in device of the identity approves it,
+// which fills AnonID; the new device then bootstraps with the code.
+type DeviceLink struct {
+	Code           string
+	DevicePublicID string
+	AnonID         string // empty until approved
+	CreatedAt      time.Time
+	ExpiresAt      time.Time
+	ApprovedAt     *time.Time
+	ConsumedAt     *time.Time
+}
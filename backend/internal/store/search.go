@@ -0,0 +1,252 @@
+package store
+
+import (
+	"fmt"
+	"html"
+	"strings"
+	"unicode"
+)
+
+// SearchQuery selects a page of posts matching a user's search.
+//
+// Text is matched against post text the way Postgres' english text search
+// does it: words are stemmed and stop words ignored, every term must
+// match, "quoted words" must appear together in that order and a word
+// ending in * matches as a prefix. A Text naming a post's author by
+// username or anon id matches all their posts, ahead of text matches.
+// When nothing matches, the words are matched again with trigram
+// similarity, so a misspelled query still finds something.
+type SearchQuery struct {
+	Text     string   // the query less its hashtags
+	Hashtags []string // posts must carry all of these
+	Limit    int
+	Offset   int
+}
+
+// Scores tell which way a result matched; results are ordered by score,
+// then newest first. Text matches add their rank, normalized into [0, 1),
+// to searchScoreText. Trigram matches score their average word
+// similarity, between searchFuzzyThreshold and 1.
+const (
+	searchScoreAuthor    = 2.0 // Text names the author
+	searchScoreText      = 1.0
+	searchFuzzyThreshold = 0.6 // pg_trgm's default word_similarity_threshold, used by <%
+)
+
+// Highlights are the post text, HTML-escaped as by escapeHighlight, with
+// every word that matched the query wrapped in these, so clients can
+// render them as HTML.
+const (
+	highlightStart = "<mark>"
+	highlightStop  = "</mark>"
+)
+
+// escapeHighlight escapes text for Highlights. escapeHighlightSQL does the
+// same in SQL, for ts_headline's output.
+func escapeHighlight(text string) string {
+	return html.EscapeString(text)
+}
+
+// escapeHighlightSQL wraps a text column in the replacements
+// escapeHighlight makes; & goes first so the others are not escaped twice.
+func escapeHighlightSQL(col string) string {
+	for _, r := range [][2]string{{"&", "&amp;"}, {"'", "&#39;"}, {"<", "&lt;"}, {">", "&gt;"}, {`"`, "&#34;"}} {
+		col = "replace(" + col + ", '" + strings.ReplaceAll(r[0], "'", "''") + "', '" + r[1] + "')"
+	}
+	return col
+}
+
+// ParseSearchQuery splits what a user typed into hashtags and the rest.
+// Hashtags inside quotes stay part of the phrase.
+func ParseSearchQuery(raw string) SearchQuery {
+	var q SearchQuery
+	var text []string
+	for i, chunk := range strings.Split(raw, `"`) {
+		if i%2 == 1 {
+			text = append(text, `"`+chunk+`"`)
+			continue
+		}
+		for _, field := range strings.Fields(chunk) {
+			if strings.HasPrefix(field, "#") {
+				q.Hashtags = append(q.Hashtags, ExtractHashtags(field)...)
+				continue
+			}
+			text = append(text, field)
+		}
+	}
+	q.Text = strings.TrimSpace(strings.Join(text, " "))
+	return q
+}
+
+// normalize fills in defaults.
+func (q *SearchQuery) normalize() {
+	q.Text = strings.TrimSpace(q.Text)
+	for i, tag := range q.Hashtags {
+		q.Hashtags[i] = strings.ToLower(tag)
+	}
+	if q.Limit <= 0 {
+		q.Limit = 20
+	}
+	if q.Offset < 0 {
+		q.Offset = 0
+	}
+}
+
+// searchTerm is one word, or one quoted phrase, of a query.
+type searchTerm struct {
+	words   []string // lowercased; stop words are kept so offsets hold
+	offsets []int    // position of each word relative to the first
+	prefix  bool     // the last word matches as a prefix
+}
+
+// parseSearchTerms splits the text of a query into terms.
+func parseSearchTerms(text string) []searchTerm {
+	var terms []searchTerm
+	for i, chunk := range strings.Split(text, `"`) {
+		if i%2 == 1 {
+			if words := searchWords(chunk); len(words) > 0 {
+				offsets := make([]int, len(words))
+				for j := range offsets {
+					offsets[j] = j
+				}
+				terms = append(terms, searchTerm{words: words, offsets: offsets, prefix: strings.HasSuffix(strings.TrimSpace(chunk), "*")})
+			}
+			continue
+		}
+		for _, field := range strings.Fields(chunk) {
+			words := searchWords(field)
+			for j, w := range words {
+				terms = append(terms, searchTerm{
+					words:   []string{w},
+					offsets: []int{0},
+					prefix:  j == len(words)-1 && strings.HasSuffix(field, "*"),
+				})
+			}
+		}
+	}
+	return terms
+}
+
+// searchWords returns the lowercased words of s, splitting wherever the
+// text search parser does.
+func searchWords(s string) []string {
+	return strings.FieldsFunc(strings.ToLower(s), func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsNumber(r)
+	})
+}
+
+// searchable reports whether t has a word that is not a stop word; terms
+// that do not are dropped from the query, as Postgres does.
+func (t searchTerm) searchable() bool {
+	for _, w := range t.words {
+		if !stopWords[w] {
+			return true
+		}
+	}
+	return false
+}
+
+// tsQuery renders terms for to_tsquery('english', ...). Words only hold
+// letters and digits, so they need no quoting; Postgres stems them.
+func tsQuery(terms []searchTerm) string {
+	var parts []string
+	for _, t := range terms {
+		if !t.searchable() {
+			continue
+		}
+		var b strings.Builder
+		last := -1
+		for i, w := range t.words {
+			if stopWords[w] {
+				continue
+			}
+			if last >= 0 {
+				if gap := t.offsets[i] - last; gap == 1 {
+					b.WriteString(" <-> ")
+				} else {
+					fmt.Fprintf(&b, " <%d> ", gap)
+				}
+			}
+			b.WriteString(w)
+			if t.prefix && i == len(t.words)-1 {
+				b.WriteString(":*")
+			}
+			last = t.offsets[i]
+		}
+		parts = append(parts, b.String())
+	}
+	return strings.Join(parts, " & ")
+}
+
+// fuzzyWords returns every word of terms, stop words included, for the
+// trigram fallback.
+func fuzzyWords(terms []searchTerm) []string {
+	var words []string
+	for _, t := range terms {
+		words = append(words, t.words...)
+	}
+	return words
+}
+
+// normalizeSearchToken reduces a username for comparison, dropping case
+// and separators.
+func normalizeSearchToken(s string) string {
+	runes := make([]rune, 0, len(s))
+	for _, r := range strings.ToLower(s) {
+		if (r >= 'a' && r <= 'z') || (r >= '0' && r <= '9') || r == '_' {
+			runes = append(runes, r)
+		}
+	}
+	return string(runes)
+}
+
+// matchedTerms lists the distinct words highlighted in highlights, then
+// hashtags.
+func matchedTerms(highlights string, hashtags []string) []string {
+	terms := []string{}
+	seen := make(map[string]bool)
+	rest := highlights
+	for {
+		i := strings.Index(rest, highlightStart)
+		if i < 0 {
+			break
+		}
+		rest = rest[i+len(highlightStart):]
+		j := strings.Index(rest, highlightStop)
+		if j < 0 {
+			break
+		}
+		word := strings.ToLower(html.UnescapeString(rest[:j]))
+		if !seen[word] {
+			seen[word] = true
+			terms = append(terms, word)
+		}
+		rest = rest[j+len(highlightStop):]
+	}
+	for _, tag := range hashtags {
+		if !seen[tag] {
+			seen[tag] = true
+			terms = append(terms, tag)
+		}
+	}
+	return terms
+}
+
+// stopWords is Postgres' english stop word list.
+var stopWords = func() map[string]bool {
+	m := make(map[string]bool)
+	for _, w := range strings.Fields(`
+		i me my myself we our ours ourselves you your yours yourself
+		yourselves he him his himself she her hers herself it its itself
+		they them their theirs themselves what which who whom this that
+		these those am is are was were be been being have has had having do
+		does did doing a an the and but if or because as until while of at
+		by for with about against between into through during before after
+		above below to from up down in out on off over under again further
+		then once here there when where why how all any both each few more
+		most other some such no nor not only own same so than too very s t
+		can will just don should now`) {
+		m[w] = true
+	}
+	return m
+}()
@@ -1,12 +1,14 @@
 package store
 
 import (
+	"context"
+	"errors"
 	"fmt"
 	"time"
 )
 
 // SeedTestData creates dummy test posts for development/testing
-func SeedTestData() {
+func SeedTestData(ctx context.Context) error {
 	store := DefaultStore()
 
 	testAnonIDs := []string{
@@ -30,8 +32,8 @@ func SeedTestData() {
 		postID := fmt.Sprintf("post_test_%d", i)
 
 		// Check if post already exists
-		_, exists := store.GetPost(postID)
-		if !exists {
+		_, err := store.GetPost(ctx, postID)
+		if errors.Is(err, ErrNotFound) {
 			post := &Post{
 				ID:        postID,
 				AnonID:    anonID,
@@ -42,12 +44,16 @@ func SeedTestData() {
 				Deleted:   false,
 			}
 
-			store.PutPost(post)
+			if err := store.PutPost(ctx, post); err != nil {
+				return err
+			}
+		} else if err != nil {
+			return err
 		}
 
 		// Create a device for this anonID if it doesn't exist
-		existingDevice, _ := store.GetDeviceByAnonID(anonID)
-		if existingDevice == nil {
+		_, err = store.GetDeviceByAnonID(ctx, anonID)
+		if errors.Is(err, ErrNotFound) {
 			device := &Device{
 				DevicePublicID:   fmt.Sprintf("device_%d", i),
 				DeviceSecretHash: "test_hash",
@@ -56,9 +62,14 @@ func SeedTestData() {
 				CreatedAt:        now,
 				UpdatedAt:        now,
 			}
-			store.CreateDevice(device)
+			if err := store.CreateDevice(ctx, device); err != nil {
+				return err
+			}
+		} else if err != nil {
+			return err
 		}
 	}
 
 	fmt.Println("[DEBUG] Test data ready: 4 posts from different users")
+	return nil
 }
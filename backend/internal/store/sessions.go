@@ -0,0 +1,34 @@
+package store
+
+import (
+	"errors"
+	"fmt"
+	"time"
+)
+
+var (
+	ErrSessionNotFound    = fmt.Errorf("session %w", ErrNotFound)
+	ErrRefreshTokenReused = errors.New("refresh token reused")
+)
+
+// NewSessionID returns a fresh session id. Refresh tokens embed it, so
+// handlers need it before the session row is written.
+func NewSessionID() (string, error) {
+	return newUUID()
+}
+
+// RefreshGrace is how long after a rotation the refresh token it replaced
+// is still accepted, so a client whose refresh response was lost can retry.
+// RotateRefreshToken then returns the session unchanged.
+const RefreshGrace = 10 * time.Second
+
+// SessionRotation is the new state written by RotateRefreshToken.
+type SessionRotation struct {
+	Token            string
+	RefreshTokenHash string
+	RefreshSeed      string // derives the new refresh token from the presented one
+	IssuedAt         time.Time
+	ExpiresAt        time.Time
+	RefreshExpiresAt time.Time
+	UserAgent        string // kept as is when empty
+}
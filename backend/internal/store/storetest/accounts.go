@@ -0,0 +1,386 @@
+package storetest
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"anon-backend/internal/store"
+)
+
+func putDevice(t *testing.T, st store.Store, id, anonID, username string, at time.Time) {
+	t.Helper()
+	must(t, st.CreateDevice(ctx, &store.Device{
+		DevicePublicID:   id,
+		DeviceSecretHash: "hash-" + id,
+		AnonID:           anonID,
+		Username:         username,
+		CreatedAt:        at,
+	}))
+}
+
+func testDevices(t *testing.T, st store.Store) {
+	now := baseTime()
+	putDevice(t, st, "dev-2", "anon-a", "ghost_one", now)
+	putDevice(t, st, "dev-1", "anon-a", "ghost_one", now.Add(-time.Hour))
+	putDevice(t, st, "dev-3", "anon-b", "ghost_two", now)
+
+	d, err := st.GetDevice(ctx, "dev-2")
+	must(t, err)
+	if d.AnonID != "anon-a" || d.AuthMethod != store.DeviceAuthHMAC || !d.CreatedAt.Equal(now) || !d.UpdatedAt.Equal(now) {
+		t.Fatalf("GetDevice = %+v", d)
+	}
+	_, err = st.GetDevice(ctx, "missing")
+	wantErr(t, err, store.ErrNotFound)
+
+	// the first device stands for the identity
+	d, err = st.GetDeviceByAnonID(ctx, "anon-a")
+	must(t, err)
+	if d.DevicePublicID != "dev-1" {
+		t.Fatalf("GetDeviceByAnonID = %s, want dev-1", d.DevicePublicID)
+	}
+	_, err = st.GetDeviceByAnonID(ctx, "anon-missing")
+	wantErr(t, err, store.ErrNotFound)
+
+	devices, err := st.GetDevicesByAnonID(ctx, "anon-a")
+	must(t, err)
+	var ids []string
+	for _, d := range devices {
+		ids = append(ids, d.DevicePublicID)
+	}
+	wantIDs(t, ids, []string{"dev-1", "dev-2"})
+
+	// linked devices share a username; other identities cannot take it
+	err = st.CreateDevice(ctx, &store.Device{DevicePublicID: "dev-4", DeviceSecretHash: "hash-dev-4", AnonID: "anon-b", Username: "ghost_one", CreatedAt: now})
+	wantErr(t, err, store.ErrUsernameTaken)
+
+	users, err := st.GetAllUsers(ctx)
+	must(t, err)
+	if len(users) != 2 {
+		t.Fatalf("GetAllUsers returned %d users, want one per identity", len(users))
+	}
+
+	must(t, st.CreateDeviceNonce(ctx, "dev-1", "nonce-1", now.Add(time.Minute)))
+	ok, err := st.ConsumeDeviceNonce(ctx, "dev-1", "nonce-1", now)
+	must(t, err)
+	if !ok {
+		t.Fatal("fresh nonce refused")
+	}
+	ok, err = st.ConsumeDeviceNonce(ctx, "dev-1", "nonce-1", now)
+	must(t, err)
+	if ok {
+		t.Fatal("nonce accepted twice")
+	}
+	must(t, st.CreateDeviceNonce(ctx, "dev-1", "nonce-2", now.Add(-time.Second)))
+	ok, err = st.ConsumeDeviceNonce(ctx, "dev-1", "nonce-2", now)
+	must(t, err)
+	if ok {
+		t.Fatal("expired nonce accepted")
+	}
+}
+
+func testRecovery(t *testing.T, st store.Store) {
+	now := baseTime()
+	putDevice(t, st, "dev-1", "anon-a", "ghost_one", now.Add(-time.Hour))
+	putDevice(t, st, "dev-2", "anon-b", "ghost_two", now.Add(-time.Hour))
+	must(t, st.PutRecoveryKey(ctx, "anon-a", "key-a", now))
+
+	// a device that cannot join leaves the key unused
+	err := st.RedeemRecoveryKey(ctx, "key-a", &store.Device{DevicePublicID: "dev-2", DeviceSecretHash: "hash", CreatedAt: now}, now)
+	if err == nil {
+		t.Fatal("redeemed a recovery key for an existing device")
+	}
+	until := now.Add(time.Hour)
+	must(t, st.CreateUserBan(ctx, "anon-a", "spam", "admin", now, &until, false))
+	err = st.RedeemRecoveryKey(ctx, "key-a", &store.Device{DevicePublicID: "dev-3", DeviceSecretHash: "hash", CreatedAt: now}, now)
+	wantErr(t, err, store.ErrIdentityBanned)
+	if _, err := st.GetDevice(ctx, "dev-3"); !errors.Is(err, store.ErrNotFound) {
+		t.Fatalf("banned identity gained a device: %v", err)
+	}
+
+	later := until.Add(time.Minute)
+	dev := &store.Device{DevicePublicID: "dev-3", DeviceSecretHash: "hash", CreatedAt: later}
+	must(t, st.RedeemRecoveryKey(ctx, "key-a", dev, later))
+	d, err := st.GetDevice(ctx, "dev-3")
+	must(t, err)
+	if d.AnonID != "anon-a" || d.Username != "ghost_one" {
+		t.Fatalf("redeemed device = %+v, want anon-a/ghost_one", d)
+	}
+	err = st.RedeemRecoveryKey(ctx, "key-a", &store.Device{DevicePublicID: "dev-4", DeviceSecretHash: "hash", CreatedAt: later}, later)
+	wantErr(t, err, store.ErrRecoveryKeyInvalid)
+
+	// links follow the same rules
+	must(t, st.CreateDeviceLink(ctx, &store.DeviceLink{Code: "LINK", DevicePublicID: "dev-4", CreatedAt: now, ExpiresAt: now.Add(2 * time.Hour)}))
+	err = st.ConsumeDeviceLink(ctx, "LINK", &store.Device{DevicePublicID: "dev-4", DeviceSecretHash: "hash", CreatedAt: now}, now)
+	wantErr(t, err, store.ErrDeviceLinkPending)
+	_, err = st.ApproveDeviceLink(ctx, "LINK", "anon-b", now)
+	must(t, err)
+	must(t, st.CreateUserBan(ctx, "anon-b", "spam", "admin", now, &until, false))
+	err = st.ConsumeDeviceLink(ctx, "LINK", &store.Device{DevicePublicID: "dev-4", DeviceSecretHash: "hash", CreatedAt: now}, now)
+	wantErr(t, err, store.ErrIdentityBanned)
+
+	dev = &store.Device{DevicePublicID: "dev-4", DeviceSecretHash: "hash", CreatedAt: later}
+	must(t, st.ConsumeDeviceLink(ctx, "LINK", dev, later))
+	if dev.AnonID != "anon-b" || dev.Username != "ghost_two" {
+		t.Fatalf("linked device = %+v, want anon-b/ghost_two", dev)
+	}
+	err = st.ConsumeDeviceLink(ctx, "LINK", &store.Device{DevicePublicID: "dev-4", DeviceSecretHash: "hash", CreatedAt: later}, later)
+	wantErr(t, err, store.ErrDeviceLinkNotFound)
+}
+
+func testSessions(t *testing.T, st store.Store) {
+	now := baseTime()
+	must(t, st.PutSession(ctx, store.SessionInfo{AnonID: "anon-a", Token: "tok-1", IssuedAt: now.Add(-2 * time.Hour), ExpiresAt: now.Add(time.Hour)}))
+	must(t, st.PutSession(ctx, store.SessionInfo{AnonID: "anon-a", Token: "tok-2", IssuedAt: now.Add(-time.Hour), LastActivityAt: now.Add(-time.Minute), ExpiresAt: now.Add(time.Hour)}))
+	must(t, st.PutSession(ctx, store.SessionInfo{AnonID: "anon-b", Token: "tok-3", IssuedAt: now, ExpiresAt: now.Add(time.Hour)}))
+
+	sess, err := st.GetSessionByToken(ctx, "tok-1")
+	must(t, err)
+	if sess.ID == "" || sess.AnonID != "anon-a" || !sess.CreatedAt.Equal(now.Add(-2*time.Hour)) || !sess.LastActivityAt.Equal(sess.IssuedAt) {
+		t.Fatalf("GetSessionByToken = %+v", sess)
+	}
+	byID, err := st.GetSessionByID(ctx, sess.ID)
+	must(t, err)
+	if byID.Token != "tok-1" {
+		t.Fatalf("GetSessionByID token = %q", byID.Token)
+	}
+
+	_, err = st.GetSessionByToken(ctx, "missing")
+	wantErr(t, err, store.ErrSessionNotFound)
+	wantErr(t, err, store.ErrNotFound)
+	wantErr(t, st.UpdateSessionActivity(ctx, "missing"), store.ErrNotFound)
+
+	tokens := func(anonID string) []string {
+		t.Helper()
+		list, err := st.GetSessionsByAnonID(ctx, anonID)
+		must(t, err)
+		out := make([]string, len(list))
+		for i, s := range list {
+			out[i] = s.Token
+		}
+		return out
+	}
+	// most recently active first
+	wantIDs(t, tokens("anon-a"), []string{"tok-2", "tok-1"})
+
+	all, err := st.GetAllSessions(ctx)
+	must(t, err)
+	if len(all) != 3 || all[0].Token != "tok-3" {
+		t.Fatalf("GetAllSessions = %d sessions, first %q", len(all), all[0].Token)
+	}
+
+	// the least recently active session goes first
+	must(t, st.EnforceSessionLimit(ctx, "anon-a", 1))
+	wantIDs(t, tokens("anon-a"), []string{"tok-2"})
+
+	must(t, st.RevokeSession(ctx, "tok-2"))
+	wantErr(t, st.RevokeSession(ctx, "tok-2"), store.ErrNotFound)
+	wantIDs(t, tokens("anon-a"), nil)
+
+	n, err := st.RevokeAllSessionsForUser(ctx, "anon-b")
+	must(t, err)
+	if n != 1 {
+		t.Fatalf("RevokeAllSessionsForUser = %d, want 1", n)
+	}
+	wantIDs(t, tokens("anon-b"), nil)
+}
+
+func testRefreshRotation(t *testing.T, st store.Store) {
+	now := baseTime()
+	must(t, st.PutSession(ctx, store.SessionInfo{AnonID: "anon-a", Token: "tok-1", IssuedAt: now, ExpiresAt: now.Add(time.Hour), RefreshTokenHash: "rt-1", RefreshExpiresAt: now.Add(24 * time.Hour)}))
+	sess, err := st.GetSessionByToken(ctx, "tok-1")
+	must(t, err)
+	rotate := func(presented, next string, at time.Time) (*store.SessionInfo, error) {
+		return st.RotateRefreshToken(ctx, sess.ID, presented, store.SessionRotation{
+			Token:            "tok-" + next,
+			RefreshTokenHash: "rt-" + next,
+			RefreshSeed:      "seed-" + next,
+			IssuedAt:         at,
+			ExpiresAt:        at.Add(time.Hour),
+			RefreshExpiresAt: at.Add(24 * time.Hour),
+		})
+	}
+
+	got, err := rotate("rt-1", "2", now)
+	must(t, err)
+	if got.Token != "tok-2" || got.RefreshTokenHash != "rt-2" || got.RefreshSeed != "seed-2" {
+		t.Fatalf("RotateRefreshToken = %+v", got)
+	}
+
+	// a retry of the replaced token gets the same successor
+	got, err = rotate("rt-1", "3", now.Add(store.RefreshGrace/2))
+	must(t, err)
+	if got.Token != "tok-2" || got.RefreshTokenHash != "rt-2" || got.RefreshSeed != "seed-2" {
+		t.Fatalf("RotateRefreshToken within grace = %+v, want the first successor", got)
+	}
+
+	later := now.Add(time.Minute)
+	got, err = rotate("rt-2", "3", later)
+	must(t, err)
+	if got.Token != "tok-3" {
+		t.Fatalf("RotateRefreshToken token = %q, want tok-3", got.Token)
+	}
+	// the grace runs from the last rotation only
+	_, err = rotate("rt-1", "4", later)
+	wantErr(t, err, store.ErrRefreshTokenReused)
+	_, err = st.GetSessionByID(ctx, sess.ID)
+	wantErr(t, err, store.ErrSessionNotFound)
+
+	// and the replaced token is refused once the grace is over
+	must(t, st.PutSession(ctx, store.SessionInfo{AnonID: "anon-b", Token: "tok-5", IssuedAt: now, ExpiresAt: now.Add(time.Hour), RefreshTokenHash: "rt-5", RefreshExpiresAt: now.Add(24 * time.Hour)}))
+	sess, err = st.GetSessionByToken(ctx, "tok-5")
+	must(t, err)
+	_, err = rotate("rt-5", "6", now)
+	must(t, err)
+	_, err = rotate("rt-5", "7", now.Add(store.RefreshGrace))
+	wantErr(t, err, store.ErrRefreshTokenReused)
+	_, err = st.GetSessionByID(ctx, sess.ID)
+	wantErr(t, err, store.ErrSessionNotFound)
+}
+
+func testAdmins(t *testing.T, st store.Store) {
+	now := baseTime()
+	admin := &store.Admin{Email: " Ops@Example.com ", PasswordHash: "hash", Role: "superadmin", CreatedAt: now}
+	must(t, st.CreateAdmin(ctx, admin))
+	if admin.ID == "" || admin.Email != "ops@example.com" {
+		t.Fatalf("CreateAdmin left %+v", admin)
+	}
+	wantErr(t, st.CreateAdmin(ctx, &store.Admin{Email: "OPS@example.com", PasswordHash: "hash", Role: "support"}), store.ErrAdminExists)
+	wantErr(t, st.CreateAdmin(ctx, &store.Admin{Email: "other@example.com", PasswordHash: "hash", Role: "admin"}), store.ErrInvalidAdminAccount)
+
+	got, err := st.GetAdminByEmail(ctx, "OPS@EXAMPLE.COM")
+	must(t, err)
+	if got.ID != admin.ID || got.Role != "superadmin" || !got.CreatedAt.Equal(now) {
+		t.Fatalf("GetAdminByEmail = %+v", got)
+	}
+	_, err = st.GetAdminByEmail(ctx, "nobody@example.com")
+	wantErr(t, err, store.ErrNotFound)
+
+	n, err := st.CountAdmins(ctx)
+	must(t, err)
+	if n != 1 {
+		t.Fatalf("CountAdmins = %d", n)
+	}
+
+	must(t, st.SetAdminDisabled(ctx, admin.ID, true))
+	must(t, st.SetAdminRole(ctx, admin.ID, "moderator"))
+	wantErr(t, st.SetAdminRole(ctx, admin.ID, "admin"), store.ErrInvalidAdminAccount)
+	wantErr(t, st.SetAdminDisabled(ctx, "missing", true), store.ErrAdminNotFound)
+	wantErr(t, st.SetAdminRole(ctx, "missing", "support"), store.ErrAdminNotFound)
+	got, err = st.GetAdminByID(ctx, admin.ID)
+	must(t, err)
+	if !got.Disabled || got.Role != "moderator" {
+		t.Fatalf("admin after SetAdminDisabled and SetAdminRole = %+v", got)
+	}
+	must(t, st.SetAdminDisabled(ctx, admin.ID, false))
+	must(t, st.SetAdminRole(ctx, admin.ID, "superadmin"))
+
+	wantErr(t, st.EnableAdminTOTP(ctx, admin.ID, 1, nil), store.ErrAdminTOTPNotPending)
+	must(t, st.SetAdminTOTPPending(ctx, admin.ID, "secret"))
+	must(t, st.EnableAdminTOTP(ctx, admin.ID, 10, []string{"code-hash"}))
+
+	got, err = st.GetAdminByID(ctx, admin.ID)
+	must(t, err)
+	if !got.TOTPEnabled || got.TOTPSecret != "secret" || got.TOTPPendingSecret != "" || got.TOTPLastCounter != 10 {
+		t.Fatalf("admin after EnableAdminTOTP = %+v", got)
+	}
+
+	for _, step := range []struct {
+		counter int64
+		want    bool
+	}{{10, false}, {11, true}, {11, false}, {9, false}} {
+		ok, err := st.UseAdminTOTPCounter(ctx, admin.ID, step.counter)
+		must(t, err)
+		if ok != step.want {
+			t.Fatalf("UseAdminTOTPCounter(%d) = %v, want %v", step.counter, ok, step.want)
+		}
+	}
+
+	for _, want := range []bool{true, false} {
+		ok, err := st.UseAdminRecoveryCode(ctx, admin.ID, "code-hash", now)
+		must(t, err)
+		if ok != want {
+			t.Fatalf("UseAdminRecoveryCode = %v, want %v", ok, want)
+		}
+	}
+
+	fail := func(at time.Time) bool {
+		t.Helper()
+		locked, err := st.RecordAdminMFAFailure(ctx, admin.ID, at, 3, 15*time.Minute)
+		must(t, err)
+		return locked
+	}
+	// a success, or a long enough pause, starts the count again
+	fail(now)
+	fail(now)
+	must(t, st.ClearAdminMFAFailures(ctx, admin.ID))
+	fail(now)
+	fail(now)
+	if fail(now.Add(time.Hour)) {
+		t.Fatal("locked out by failures an hour apart")
+	}
+	fail(now.Add(time.Hour))
+	at := now.Add(time.Hour + time.Minute)
+	if !fail(at) {
+		t.Fatal("third failure in a row did not lock the admin out")
+	}
+	got, err = st.GetAdminByID(ctx, admin.ID)
+	must(t, err)
+	if !got.MFALocked(at) || !got.MFALocked(at.Add(14*time.Minute)) || got.MFALocked(at.Add(15*time.Minute)) {
+		t.Fatalf("MFALockedUntil = %v, want %v", got.MFALockedUntil, at.Add(15*time.Minute))
+	}
+	_, err = st.RecordAdminMFAFailure(ctx, "missing", now, 3, time.Minute)
+	wantErr(t, err, store.ErrAdminNotFound)
+}
+
+func testProfiles(t *testing.T, st store.Store) {
+	now := baseTime()
+	wantErr(t, st.EnsureProfileForAnon(ctx, "anon-a", "EU", now), store.ErrNotFound)
+
+	putDevice(t, st, "dev-1", "anon-a", "ghost_one", now)
+	must(t, st.EnsureProfileForAnon(ctx, "anon-a", "EU", now))
+	// a second call keeps what is already set
+	must(t, st.EnsureProfileForAnon(ctx, "anon-a", "US", now))
+
+	putPost(t, st, "p1", "anon-a", now)
+	putPost(t, st, "p2", "anon-a", now)
+	must(t, st.DeletePostByUser(ctx, "p2", "anon-a"))
+	must(t, st.ReactToPost(ctx, "p1", "anon-b", "like"))
+	must(t, st.AddComment(ctx, &store.PostComment{ID: "c1", PostID: "p1", AnonID: "anon-a", Text: "hi", CreatedAt: now}))
+	must(t, st.AddCommentReply(ctx, &store.CommentReply{ID: "r1", CommentID: "c1", AnonID: "anon-a", Text: "hi", CreatedAt: now}))
+
+	p, err := st.GetProfileByAnonID(ctx, "anon-a")
+	must(t, err)
+	if p.Username != "ghost_one" || p.UsernameSuffix != "one" || p.Region != "EU" || p.StatusLabel != "Clean" {
+		t.Fatalf("GetProfileByAnonID = %+v", p)
+	}
+	if p.PostsCount != 1 || p.CommentsCount != 2 || p.ReactionsCount != 1 {
+		t.Fatalf("posts/comments/reactions = %d/%d/%d, want 1/2/1", p.PostsCount, p.CommentsCount, p.ReactionsCount)
+	}
+
+	_, err = st.GetProfileByAnonID(ctx, "anon-missing")
+	wantErr(t, err, store.ErrNotFound)
+
+	bio := "hello"
+	p, err = st.UpdateProfile(ctx, "anon-a", store.ProfileUpdateInput{Bio: &bio}, now)
+	must(t, err)
+	if p.Bio != "hello" {
+		t.Fatalf("bio = %q", p.Bio)
+	}
+
+	for _, tc := range []struct {
+		name, exclude string
+		want          bool
+	}{
+		{"ghost_one", "", false},
+		{"GHOST_ONE", "", false},
+		{"ghost_one", "anon-a", true},
+		{"ghost_free", "", true},
+	} {
+		ok, err := st.IsUsernameAvailable(ctx, tc.name, tc.exclude)
+		must(t, err)
+		if ok != tc.want {
+			t.Fatalf("IsUsernameAvailable(%q, %q) = %v", tc.name, tc.exclude, ok)
+		}
+	}
+}
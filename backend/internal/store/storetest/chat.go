@@ -0,0 +1,107 @@
+package storetest
+
+import (
+	"testing"
+	"time"
+
+	"anon-backend/internal/store"
+)
+
+func testChat(t *testing.T, st store.Store) {
+	now := baseTime()
+	room := "anon-a:anon-b"
+	for i, m := range []*store.ChatMessage{
+		{ID: "m1", FromAnon: "anon-a", ToAnon: "anon-b", Text: "one", ClientID: "cl-1"},
+		{ID: "m2", FromAnon: "anon-b", ToAnon: "anon-a", Text: "two"},
+		{ID: "m3", FromAnon: "anon-a", ToAnon: "anon-b", Text: "three", ClientID: "cl-2"},
+	} {
+		m.RoomID = room
+		m.CreatedAt = now.Add(time.Duration(i) * time.Second)
+		must(t, st.PutChatMessage(ctx, m))
+	}
+
+	dup := &store.ChatMessage{ID: "m4", RoomID: room, FromAnon: "anon-a", ToAnon: "anon-b", Text: "again", ClientID: "cl-1", CreatedAt: now}
+	wantErr(t, st.PutChatMessage(ctx, dup), store.ErrDuplicateChatMessage)
+
+	m, err := st.GetChatMessageByClientID(ctx, "anon-a", "cl-1")
+	must(t, err)
+	if m.ID != "m1" || m.Kind != store.ChatKindText || m.Text != "one" {
+		t.Fatalf("GetChatMessageByClientID = %+v", m)
+	}
+	// client ids are scoped to the sender
+	_, err = st.GetChatMessageByClientID(ctx, "anon-b", "cl-1")
+	wantErr(t, err, store.ErrNotFound)
+
+	msgIDs := func(msgs []*store.ChatMessage) []string {
+		ids := make([]string, len(msgs))
+		for i, m := range msgs {
+			ids[i] = m.ID
+		}
+		return ids
+	}
+
+	history, err := st.GetChatHistory(ctx, room, nil, 2)
+	must(t, err)
+	wantIDs(t, msgIDs(history), []string{"m3", "m2"})
+	last := history[len(history)-1]
+	history, err = st.GetChatHistory(ctx, room, &store.Cursor{CreatedAt: last.CreatedAt, ID: last.ID}, 2)
+	must(t, err)
+	wantIDs(t, msgIDs(history), []string{"m1"})
+
+	undelivered, err := st.GetUndeliveredChatMessages(ctx, "", "anon-b")
+	must(t, err)
+	wantIDs(t, msgIDs(undelivered), []string{"m1", "m3"})
+
+	// a second mark changes nothing, so only one caller reports delivery
+	updated, err := st.MarkChatMessagesDelivered(ctx, []string{"m1"}, now)
+	must(t, err)
+	wantIDs(t, updated, []string{"m1"})
+	updated, err = st.MarkChatMessagesDelivered(ctx, []string{"m1"}, now)
+	must(t, err)
+	wantIDs(t, updated, nil)
+	undelivered, err = st.GetUndeliveredChatMessages(ctx, room, "anon-b")
+	must(t, err)
+	wantIDs(t, msgIDs(undelivered), []string{"m3"})
+
+	// only the recipient can mark a message read, and only once
+	updated, err = st.MarkChatMessagesRead(ctx, room, "anon-a", []string{"m3"}, now)
+	must(t, err)
+	wantIDs(t, updated, nil)
+	updated, err = st.MarkChatMessagesRead(ctx, room, "anon-b", []string{"m3"}, now)
+	must(t, err)
+	wantIDs(t, updated, []string{"m3"})
+	updated, err = st.MarkChatMessagesRead(ctx, room, "anon-b", []string{"m3"}, now)
+	must(t, err)
+	wantIDs(t, updated, nil)
+
+	undelivered, err = st.GetUndeliveredChatMessages(ctx, room, "anon-b")
+	must(t, err)
+	wantIDs(t, msgIDs(undelivered), nil)
+}
+
+func testWSTickets(t *testing.T, st store.Store) {
+	now := baseTime()
+	redeem := func(id string, expiresAt, at time.Time, want bool) {
+		t.Helper()
+		ok, err := st.RedeemWSTicket(ctx, id, expiresAt, at)
+		must(t, err)
+		if ok != want {
+			t.Fatalf("RedeemWSTicket(%s) = %v, want %v", id, ok, want)
+		}
+	}
+	redeem("wst_1", now.Add(30*time.Second), now, true)
+	redeem("wst_1", now.Add(30*time.Second), now, false)
+	redeem("wst_2", now.Add(-time.Second), now, false)
+
+	// a ticket stays used until it has expired and can no longer verify
+	n, err := st.CleanupWSTickets(ctx, now)
+	must(t, err)
+	if n != 0 {
+		t.Fatalf("CleanupWSTickets removed %d live tickets", n)
+	}
+	n, err = st.CleanupWSTickets(ctx, now.Add(time.Minute))
+	must(t, err)
+	if n != 1 {
+		t.Fatalf("CleanupWSTickets removed %d tickets, want 1", n)
+	}
+}
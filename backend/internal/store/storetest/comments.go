@@ -0,0 +1,174 @@
+package storetest
+
+import (
+	"reflect"
+	"testing"
+	"time"
+
+	"anon-backend/internal/store"
+)
+
+func testComments(t *testing.T, st store.Store) {
+	now := baseTime()
+	putPost(t, st, "p1", "anon-a", now)
+	putPost(t, st, "gone", "anon-a", now)
+	must(t, st.DeletePostByUser(ctx, "gone", "anon-a"))
+
+	must(t, st.AddComment(ctx, &store.PostComment{ID: "c1", PostID: "p1", AnonID: "anon-b", Text: "first", CreatedAt: now.Add(time.Second)}))
+	must(t, st.AddComment(ctx, &store.PostComment{ID: "c2", PostID: "p1", AnonID: "anon-a", Text: "second", CreatedAt: now.Add(2 * time.Second)}))
+	wantErr(t, st.AddComment(ctx, &store.PostComment{ID: "c3", PostID: "missing", AnonID: "anon-b", Text: "x", CreatedAt: now}), store.ErrNotFound)
+	wantErr(t, st.AddComment(ctx, &store.PostComment{ID: "c4", PostID: "gone", AnonID: "anon-b", Text: "x", CreatedAt: now}), store.ErrNotFound)
+
+	commentIDs := func() []string {
+		t.Helper()
+		comments, err := st.GetComments(ctx, "p1")
+		must(t, err)
+		ids := make([]string, len(comments))
+		for i, c := range comments {
+			ids[i] = c.ID
+		}
+		return ids
+	}
+	count := func(want int) {
+		t.Helper()
+		n, err := st.GetCommentsCount(ctx, "p1")
+		must(t, err)
+		if n != want {
+			t.Fatalf("comments count = %d, want %d", n, want)
+		}
+	}
+
+	wantIDs(t, commentIDs(), []string{"c1", "c2"})
+	count(2)
+
+	must(t, st.ReactToComment(ctx, "c1", "anon-a", "like"))
+	r, err := st.GetCommentReaction(ctx, "c1", "anon-a")
+	must(t, err)
+	if r != "like" {
+		t.Fatalf("comment reaction = %q", r)
+	}
+	c, err := st.GetComment(ctx, "c1")
+	must(t, err)
+	if c.Likes != 1 || c.PostID != "p1" || c.Text != "first" {
+		t.Fatalf("GetComment = %+v", c)
+	}
+
+	wantErr(t, st.DeleteCommentByUser(ctx, "missing", "anon-b"), store.ErrNotFound)
+	wantErr(t, st.DeleteCommentByUser(ctx, "c1", "anon-a"), store.ErrForbidden)
+	must(t, st.DeleteCommentByUser(ctx, "c1", "anon-b"))
+
+	wantIDs(t, commentIDs(), []string{"c2"})
+	count(1)
+	c, err = st.GetComment(ctx, "c1")
+	must(t, err)
+	if !c.Deleted {
+		t.Fatal("comment not marked deleted")
+	}
+	wantErr(t, st.ReactToComment(ctx, "c1", "anon-a", "like"), store.ErrNotFound)
+
+	_, err = st.GetComment(ctx, "missing")
+	wantErr(t, err, store.ErrNotFound)
+}
+
+func testReplies(t *testing.T, st store.Store) {
+	now := baseTime()
+	putPost(t, st, "p1", "anon-a", now)
+	must(t, st.AddComment(ctx, &store.PostComment{ID: "c1", PostID: "p1", AnonID: "anon-a", Text: "comment", CreatedAt: now}))
+	must(t, st.AddComment(ctx, &store.PostComment{ID: "gone", PostID: "p1", AnonID: "anon-a", Text: "comment", CreatedAt: now}))
+	must(t, st.DeleteCommentByUser(ctx, "gone", "anon-a"))
+
+	must(t, st.AddCommentReply(ctx, &store.CommentReply{ID: "r1", CommentID: "c1", AnonID: "anon-b", Text: "first", CreatedAt: now.Add(time.Second)}))
+	must(t, st.AddCommentReply(ctx, &store.CommentReply{ID: "r2", CommentID: "c1", AnonID: "anon-c", Text: "second", CreatedAt: now.Add(2 * time.Second)}))
+	wantErr(t, st.AddCommentReply(ctx, &store.CommentReply{ID: "r3", CommentID: "missing", AnonID: "anon-b", Text: "x", CreatedAt: now}), store.ErrNotFound)
+	wantErr(t, st.AddCommentReply(ctx, &store.CommentReply{ID: "r4", CommentID: "gone", AnonID: "anon-b", Text: "x", CreatedAt: now}), store.ErrNotFound)
+
+	replyIDs := func() []string {
+		t.Helper()
+		replies, err := st.GetCommentReplies(ctx, "c1")
+		must(t, err)
+		ids := make([]string, len(replies))
+		for i, r := range replies {
+			ids[i] = r.ID
+		}
+		return ids
+	}
+
+	wantIDs(t, replyIDs(), []string{"r1", "r2"})
+	n, err := st.GetCommentRepliesCount(ctx, "c1")
+	must(t, err)
+	if n != 2 {
+		t.Fatalf("replies count = %d, want 2", n)
+	}
+
+	must(t, st.ReactToReply(ctx, "r1", "anon-a", "dislike"))
+	r, err := st.GetReplyReaction(ctx, "r1", "anon-a")
+	must(t, err)
+	if r != "dislike" {
+		t.Fatalf("reply reaction = %q", r)
+	}
+
+	wantErr(t, st.DeleteCommentReplyByUser(ctx, "missing", "anon-b"), store.ErrNotFound)
+	wantErr(t, st.DeleteCommentReplyByUser(ctx, "r1", "anon-c"), store.ErrForbidden)
+	must(t, st.DeleteCommentReplyByUser(ctx, "r1", "anon-b"))
+
+	wantIDs(t, replyIDs(), []string{"r2"})
+	n, err = st.GetCommentRepliesCount(ctx, "c1")
+	must(t, err)
+	if n != 1 {
+		t.Fatalf("replies count = %d, want 1", n)
+	}
+
+	reply, err := st.GetReply(ctx, "r1")
+	must(t, err)
+	if !reply.Deleted || reply.CommentID != "c1" {
+		t.Fatalf("GetReply = %+v", reply)
+	}
+	_, err = st.GetReply(ctx, "missing")
+	wantErr(t, err, store.ErrNotFound)
+}
+
+func testBatchLookups(t *testing.T, st store.Store) {
+	now := baseTime()
+	putDevice(t, st, "dev-2", "anon-a", "ghost_late", now)
+	putDevice(t, st, "dev-1", "anon-a", "ghost_one", now.Add(-time.Hour))
+	putDevice(t, st, "dev-3", "anon-b", "ghost_two", now)
+	putPost(t, st, "p1", "anon-a", now)
+	putPost(t, st, "p2", "anon-a", now)
+	must(t, st.AddComment(ctx, &store.PostComment{ID: "c1", PostID: "p1", AnonID: "anon-b", Text: "one", CreatedAt: now}))
+	must(t, st.AddComment(ctx, &store.PostComment{ID: "c2", PostID: "p1", AnonID: "anon-b", Text: "two", CreatedAt: now}))
+	for _, id := range []string{"r1", "r2", "r3"} {
+		must(t, st.AddCommentReply(ctx, &store.CommentReply{ID: id, CommentID: "c1", AnonID: "anon-a", Text: id, CreatedAt: now}))
+	}
+	must(t, st.DeleteCommentReplyByUser(ctx, "r3", "anon-a"))
+
+	must(t, st.ReactToPost(ctx, "p1", "anon-b", "like"))
+	must(t, st.ReactToPost(ctx, "p2", "anon-a", "dislike"))
+	must(t, st.ReactToComment(ctx, "c2", "anon-b", "dislike"))
+	must(t, st.ReactToReply(ctx, "r2", "anon-b", "like"))
+
+	check := func(name string, got, want interface{}, err error) {
+		t.Helper()
+		must(t, err)
+		if !reflect.DeepEqual(got, want) {
+			t.Fatalf("%s = %v, want %v", name, got, want)
+		}
+	}
+
+	posts, err := st.GetPostReactionsFor(ctx, "anon-b", []string{"p1", "p2", "missing"})
+	check("GetPostReactionsFor", posts, map[string]string{"p1": "like"}, err)
+	comments, err := st.GetCommentReactionsFor(ctx, "anon-b", []string{"c1", "c2"})
+	check("GetCommentReactionsFor", comments, map[string]string{"c2": "dislike"}, err)
+	replies, err := st.GetReplyReactionsFor(ctx, "anon-b", []string{"r1", "r2"})
+	check("GetReplyReactionsFor", replies, map[string]string{"r2": "like"}, err)
+
+	// deleted replies are not counted
+	counts, err := st.GetReplyCounts(ctx, []string{"c1", "c2"})
+	check("GetReplyCounts", counts, map[string]int{"c1": 2}, err)
+
+	// the first device names the identity, as in GetDeviceByAnonID
+	names, err := st.GetUsernames(ctx, []string{"anon-a", "anon-b", "anon-a", "anon-missing"})
+	check("GetUsernames", names, map[string]string{"anon-a": "ghost_one", "anon-b": "ghost_two"}, err)
+
+	names, err = st.GetUsernames(ctx, nil)
+	check("GetUsernames(nil)", names, map[string]string{}, err)
+}
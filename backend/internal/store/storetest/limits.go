@@ -0,0 +1,169 @@
+package storetest
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"anon-backend/internal/config"
+	"anon-backend/internal/store"
+)
+
+func testRateLimit(t *testing.T, st store.Store) {
+	now := baseTime()
+	take := func(key string, at time.Time) store.RateLimitResult {
+		t.Helper()
+		res, err := st.TakeRateLimitToken(ctx, "posts", key, 2, time.Minute, at)
+		must(t, err)
+		return res
+	}
+
+	if res := take("anon-a", now); !res.Allowed || res.Remaining != 1 {
+		t.Fatalf("first take = %+v", res)
+	}
+	if res := take("anon-a", now); !res.Allowed || res.Remaining != 0 {
+		t.Fatalf("second take = %+v", res)
+	}
+	res := take("anon-a", now)
+	if res.Allowed || res.RetryAfter != 30*time.Second {
+		t.Fatalf("third take = %+v", res)
+	}
+	// buckets are per key
+	if res := take("anon-b", now); !res.Allowed {
+		t.Fatalf("other key = %+v", res)
+	}
+	// one token comes back every 30s
+	if res := take("anon-a", now.Add(30*time.Second)); !res.Allowed {
+		t.Fatalf("take after refill = %+v", res)
+	}
+
+	limited, err := st.ListRateLimitedBuckets(ctx, now.Add(-time.Minute), 10)
+	must(t, err)
+	if len(limited) != 1 || limited[0].Key != "anon-a" || limited[0].Limited != 1 {
+		t.Fatalf("ListRateLimitedBuckets = %+v", limited)
+	}
+
+	n, err := st.CleanupRateLimitBuckets(ctx, now.Add(time.Second))
+	must(t, err)
+	if n != 1 {
+		t.Fatalf("CleanupRateLimitBuckets removed %d buckets, want 1", n)
+	}
+}
+
+func testQuota(t *testing.T, st store.Store) {
+	now := time.Date(2026, 3, 4, 10, 30, 0, 0, time.UTC)
+	limits := []config.QuotaLimit{{Window: config.QuotaHour, Max: 2}, {Window: config.QuotaDay, Max: 3}}
+	take := func(anonID string, at time.Time) store.QuotaResult {
+		t.Helper()
+		res, err := st.TakeQuota(ctx, anonID, "comments", limits, at)
+		must(t, err)
+		return res
+	}
+
+	take("anon-a", now)
+	if res := take("anon-a", now); !res.Allowed || res.Usage[0].Remaining != 0 || res.Usage[1].Remaining != 1 {
+		t.Fatalf("second take = %+v", res)
+	}
+	res := take("anon-a", now)
+	if res.Allowed {
+		t.Fatalf("third take in the hour allowed: %+v", res)
+	}
+	if u, ok := res.Exhausted(); !ok || u.Window != config.QuotaHour || !u.ResetAt.Equal(now.Truncate(time.Hour).Add(time.Hour)) {
+		t.Fatalf("Exhausted = %+v, %v", u, ok)
+	}
+	// a refused item is not counted
+	usage, err := st.GetQuotaUsage(ctx, "anon-a", "comments", limits, now)
+	must(t, err)
+	if usage[0].Used != 2 || usage[1].Used != 2 {
+		t.Fatalf("usage = %+v", usage)
+	}
+	// quotas are per anon and per resource
+	if res := take("anon-b", now); !res.Allowed {
+		t.Fatalf("other anon = %+v", res)
+	}
+	if res, err := st.TakeQuota(ctx, "anon-a", "replies", limits, now); err != nil || !res.Allowed {
+		t.Fatalf("other resource = %+v, %v", res, err)
+	}
+
+	later := now.Add(time.Hour)
+	if res := take("anon-a", later); !res.Allowed {
+		t.Fatalf("take in the next hour = %+v", res)
+	}
+	if res := take("anon-a", later); res.Allowed {
+		t.Fatalf("fourth take in the day allowed: %+v", res)
+	}
+
+	n, err := st.CleanupQuotaUsage(ctx, later)
+	must(t, err)
+	// the three hourly counters of the first hour have expired
+	if n != 3 {
+		t.Fatalf("CleanupQuotaUsage removed %d counters, want 3", n)
+	}
+}
+
+func testCreatePostWithQuota(t *testing.T, st store.Store) {
+	now := baseTime()
+	limits := []config.QuotaLimit{{Window: config.QuotaDay, Max: 1}}
+
+	res, err := st.CreatePostWithQuota(ctx, &store.Post{ID: "p1", AnonID: "anon-a", Text: "one", CreatedAt: now}, limits, now)
+	must(t, err)
+	if !res.Allowed {
+		t.Fatalf("first post refused: %+v", res)
+	}
+
+	_, err = st.CreatePostWithQuota(ctx, &store.Post{ID: "p2", AnonID: "anon-a", Text: "two", CreatedAt: now}, limits, now)
+	var qe *store.QuotaExceededError
+	if !errors.As(err, &qe) || qe.Resource != config.QuotaPosts || qe.Result.Allowed {
+		t.Fatalf("second post error = %v", err)
+	}
+	wantErr(t, err, store.ErrQuotaExceeded)
+
+	// the refused post was not stored
+	_, err = st.GetPost(ctx, "p2")
+	wantErr(t, err, store.ErrNotFound)
+	feed, err := st.GetFeed(ctx, 10)
+	must(t, err)
+	wantIDs(t, postIDs(feed), []string{"p1"})
+}
+
+func testAddCommentWithQuota(t *testing.T, st store.Store) {
+	now := baseTime()
+	limits := []config.QuotaLimit{{Window: config.QuotaDay, Max: 1}}
+	putPost(t, st, "p1", "anon-a", now)
+
+	// a comment on a missing post does not use up the quota
+	_, err := st.AddCommentWithQuota(ctx, &store.PostComment{ID: "c0", PostID: "missing", AnonID: "anon-b", Text: "lost", CreatedAt: now}, limits, now)
+	wantErr(t, err, store.ErrNotFound)
+	res, err := st.AddCommentWithQuota(ctx, &store.PostComment{ID: "c1", PostID: "p1", AnonID: "anon-b", Text: "one", CreatedAt: now}, limits, now)
+	must(t, err)
+	if !res.Allowed {
+		t.Fatalf("first comment refused: %+v", res)
+	}
+	_, err = st.AddCommentWithQuota(ctx, &store.PostComment{ID: "c2", PostID: "p1", AnonID: "anon-b", Text: "two", CreatedAt: now}, limits, now)
+	var qe *store.QuotaExceededError
+	if !errors.As(err, &qe) || qe.Resource != config.QuotaComments {
+		t.Fatalf("second comment error = %v", err)
+	}
+	comments, err := st.GetComments(ctx, "p1")
+	must(t, err)
+	if len(comments) != 1 || comments[0].ID != "c1" {
+		t.Fatalf("GetComments = %d comments, want only c1", len(comments))
+	}
+
+	_, err = st.AddCommentReplyWithQuota(ctx, &store.CommentReply{ID: "r0", CommentID: "missing", AnonID: "anon-b", Text: "lost", CreatedAt: now}, limits, now)
+	wantErr(t, err, store.ErrNotFound)
+	res, err = st.AddCommentReplyWithQuota(ctx, &store.CommentReply{ID: "r1", CommentID: "c1", AnonID: "anon-b", Text: "one", CreatedAt: now}, limits, now)
+	must(t, err)
+	if !res.Allowed {
+		t.Fatalf("first reply refused: %+v", res)
+	}
+	_, err = st.AddCommentReplyWithQuota(ctx, &store.CommentReply{ID: "r2", CommentID: "c1", AnonID: "anon-b", Text: "two", CreatedAt: now}, limits, now)
+	if !errors.As(err, &qe) || qe.Resource != config.QuotaReplies {
+		t.Fatalf("second reply error = %v", err)
+	}
+	replies, err := st.GetCommentReplies(ctx, "c1")
+	must(t, err)
+	if len(replies) != 1 || replies[0].ID != "r1" {
+		t.Fatalf("GetCommentReplies = %d replies, want only r1", len(replies))
+	}
+}
@@ -0,0 +1,91 @@
+package storetest
+
+import (
+	"encoding/json"
+	"testing"
+	"time"
+
+	"anon-backend/internal/store"
+)
+
+func testAudit(t *testing.T, st store.Store) {
+	now := baseTime()
+	for i, action := range []string{"user_banned", "session_revoked", "post_deleted"} {
+		must(t, st.LogAuditEvent(ctx, store.AuditLog{
+			Action:    action,
+			AnonID:    "anon-a",
+			ActorID:   "admin-1",
+			Details:   action + " by test",
+			Timestamp: now.Add(time.Duration(i) * time.Second),
+		}))
+	}
+
+	logs, err := st.GetAuditLogs(ctx)
+	must(t, err)
+	var actions []string
+	for _, l := range logs {
+		actions = append(actions, l.Action)
+	}
+	wantIDs(t, actions, []string{"post_deleted", "session_revoked", "user_banned"})
+
+	// an entry that cannot be hashed is not written
+	err = st.LogAuditEvent(ctx, store.AuditLog{Action: "broken", Before: json.RawMessage("{"), Timestamp: now})
+	if err == nil {
+		t.Fatal("LogAuditEvent accepted a Before that is not JSON")
+	}
+	logs2, err := st.GetAuditLogs(ctx)
+	must(t, err)
+	if len(logs2) != len(logs) {
+		t.Fatalf("GetAuditLogs = %d entries after a failed write, want %d", len(logs2), len(logs))
+	}
+
+	after, err := st.ListAuditLogsAfter(ctx, logs[2].Seq, 10)
+	must(t, err)
+	if len(after) != 2 || after[0].Seq != logs[1].Seq || after[1].PrevHash != after[0].Hash {
+		t.Fatalf("ListAuditLogsAfter = %+v", after)
+	}
+
+	v, err := store.VerifyAuditLogs(ctx, st)
+	must(t, err)
+	if !v.OK || v.Checked != 3 || v.HeadHash != logs[0].Hash {
+		t.Fatalf("VerifyAuditLogs = %+v", v)
+	}
+}
+
+func testReports(t *testing.T, st store.Store) {
+	now := baseTime()
+	putPost(t, st, "p1", "anon-a", now)
+
+	// moderation reports are idempotent per reporter
+	must(t, st.ReportPost(ctx, "p1", "anon-a", "anon-b", "spam", now))
+	must(t, st.ReportPost(ctx, "p1", "anon-a", "anon-b", "spam", now))
+	must(t, st.ReportPost(ctx, "p1", "anon-a", "anon-c", "abuse", now.Add(time.Second)))
+	n, err := st.GetPostReportCount(ctx, "p1")
+	must(t, err)
+	if n != 2 {
+		t.Fatalf("GetPostReportCount = %d, want 2", n)
+	}
+
+	top, err := st.GetTopReportedPostByAnon(ctx, "anon-a", 3)
+	must(t, err)
+	if top != nil {
+		t.Fatalf("post below threshold reported: %+v", top)
+	}
+	top, err = st.GetTopReportedPostByAnon(ctx, "anon-a", 2)
+	must(t, err)
+	if top == nil || top.PostID != "p1" || top.ReportCount != 2 || top.Reason != "abuse" {
+		t.Fatalf("GetTopReportedPostByAnon = %+v", top)
+	}
+
+	// user-facing reports refuse repeats, independently of the above
+	must(t, st.ReportPostV2(ctx, "anon-b", "anon-a", "p1", "spam", now))
+	wantErr(t, st.ReportPostV2(ctx, "anon-b", "anon-a", "p1", "spam", now), store.ErrAlreadyReported)
+	must(t, st.ReportProfile(ctx, "anon-c", "anon-a", "impersonation", now))
+	wantErr(t, st.ReportProfile(ctx, "anon-c", "anon-a", "impersonation", now), store.ErrAlreadyReported)
+
+	n, err = st.GetUserReportCount(ctx, "anon-a")
+	must(t, err)
+	if n != 2 {
+		t.Fatalf("GetUserReportCount = %d, want 2", n)
+	}
+}
@@ -0,0 +1,456 @@
+package storetest
+
+import (
+	"fmt"
+	"testing"
+	"time"
+
+	"anon-backend/internal/store"
+)
+
+func putPost(t *testing.T, st store.Store, id, anonID string, at time.Time) {
+	t.Helper()
+	must(t, st.PutPost(ctx, &store.Post{ID: id, AnonID: anonID, Text: "post " + id, CreatedAt: at}))
+}
+
+func postIDs(posts []*store.Post) []string {
+	ids := make([]string, len(posts))
+	for i, p := range posts {
+		ids[i] = p.ID
+	}
+	return ids
+}
+
+func testFeed(t *testing.T, st store.Store) {
+	now := baseTime()
+	// stored out of order on purpose; the feed is ordered by created_at
+	putPost(t, st, "p2", "anon-a", now.Add(-time.Minute))
+	putPost(t, st, "p1", "anon-a", now.Add(-2*time.Minute))
+	putPost(t, st, "p3", "anon-b", now)
+	putPost(t, st, "p4", "anon-b", now.Add(-3*time.Minute))
+	must(t, st.DeletePostByUser(ctx, "p4", "anon-b"))
+
+	feed, err := st.GetFeed(ctx, 10)
+	must(t, err)
+	wantIDs(t, postIDs(feed), []string{"p3", "p2", "p1"})
+
+	feed, err = st.GetFeed(ctx, 2)
+	must(t, err)
+	wantIDs(t, postIDs(feed), []string{"p3", "p2"})
+
+	feed, err = st.GetFeed(ctx, 0)
+	must(t, err)
+	wantIDs(t, postIDs(feed), []string{"p3", "p2", "p1"})
+}
+
+func testFeedPage(t *testing.T, st store.Store) {
+	now := baseTime()
+	// p3 and p4 share a timestamp; the id breaks the tie
+	putPost(t, st, "p1", "anon-a", now.Add(-2*time.Minute))
+	putPost(t, st, "p2", "anon-b", now.Add(-time.Minute))
+	putPost(t, st, "p3", "anon-c", now)
+	putPost(t, st, "p4", "anon-a", now)
+	putPost(t, st, "p5", "anon-b", now.Add(-3*time.Minute))
+	must(t, st.DeletePostByUser(ctx, "p5", "anon-b"))
+
+	page := func(q store.FeedQuery) []*store.Post {
+		t.Helper()
+		posts, err := st.GetFeedPage(ctx, q)
+		must(t, err)
+		return posts
+	}
+
+	// walking the pages visits every post once
+	var seen []string
+	q := store.FeedQuery{Limit: 2}
+	for {
+		posts := page(q)
+		seen = append(seen, postIDs(posts)...)
+		if len(posts) < q.Limit {
+			break
+		}
+		last := posts[len(posts)-1]
+		q.Before = &store.Cursor{CreatedAt: last.CreatedAt, ID: last.ID}
+	}
+	wantIDs(t, seen, []string{"p4", "p3", "p2", "p1"})
+
+	// the trust runs either way; pending requests do not count
+	must(t, st.PutTrust(ctx, &store.TrustRequest{ID: "tr-1", Code: "C1", FromAnon: "anon-a", ToAnon: "anon-b", Status: store.TrustAccepted, CreatedAt: now, UpdatedAt: now}))
+	must(t, st.PutTrust(ctx, &store.TrustRequest{ID: "tr-2", Code: "C2", FromAnon: "anon-c", ToAnon: "anon-b", Status: store.TrustPending, CreatedAt: now, UpdatedAt: now}))
+	wantIDs(t, postIDs(page(store.FeedQuery{Scope: store.FeedScopeTrusted, Viewer: "anon-b"})), []string{"p4", "p1"})
+	wantIDs(t, postIDs(page(store.FeedQuery{Scope: store.FeedScopeTrusted, Viewer: "anon-a"})), []string{"p2"})
+	wantIDs(t, postIDs(page(store.FeedQuery{Scope: store.FeedScopeTrusted, Viewer: "anon-c"})), nil)
+
+	// only an anon's latest ping places it
+	must(t, st.PutGeo(ctx, &store.GeoPing{AnonID: "anon-a", Lat: 10, Lng: 10, Timestamp: now.Add(-time.Minute)}))
+	must(t, st.PutGeo(ctx, &store.GeoPing{AnonID: "anon-a", Lat: 0, Lng: 0.01, Timestamp: now}))
+	must(t, st.PutGeo(ctx, &store.GeoPing{AnonID: "anon-b", Lat: 0, Lng: 0.02, Timestamp: now.Add(-time.Hour)}))
+	must(t, st.PutGeo(ctx, &store.GeoPing{AnonID: "anon-c", Lat: 10, Lng: 10, Timestamp: now}))
+	nearby := store.FeedQuery{Scope: store.FeedScopeNearby, Lat: 0, Lng: 0, RadiusKm: 5}
+	wantIDs(t, postIDs(page(nearby)), []string{"p4", "p1"})
+	nearby.Before = &store.Cursor{CreatedAt: now, ID: "p4"}
+	wantIDs(t, postIDs(page(nearby)), []string{"p1"})
+
+	_, err := st.GetFeedPage(ctx, store.FeedQuery{Scope: "friends"})
+	wantErr(t, err, store.ErrInvalidFeedScope)
+}
+
+func testGetPost(t *testing.T, st store.Store) {
+	now := baseTime()
+	putPost(t, st, "p1", "anon-a", now)
+
+	p, err := st.GetPost(ctx, "p1")
+	must(t, err)
+	if p.AnonID != "anon-a" || p.Text != "post p1" || !p.CreatedAt.Equal(now) || p.Deleted {
+		t.Fatalf("GetPost = %+v", p)
+	}
+
+	_, err = st.GetPost(ctx, "missing")
+	wantErr(t, err, store.ErrNotFound)
+	wantErr(t, err, store.ErrPostNotFound)
+}
+
+func testDeletePostByUser(t *testing.T, st store.Store) {
+	putPost(t, st, "p1", "anon-a", baseTime())
+
+	wantErr(t, st.DeletePostByUser(ctx, "missing", "anon-a"), store.ErrNotFound)
+	wantErr(t, st.DeletePostByUser(ctx, "p1", "anon-b"), store.ErrForbidden)
+
+	p, err := st.GetPost(ctx, "p1")
+	must(t, err)
+	if p.Deleted {
+		t.Fatal("post deleted by another anon")
+	}
+
+	must(t, st.DeletePostByUser(ctx, "p1", "anon-a"))
+	// deleting again is not an error
+	must(t, st.DeletePostByUser(ctx, "p1", "anon-a"))
+
+	p, err = st.GetPost(ctx, "p1")
+	must(t, err)
+	if !p.Deleted {
+		t.Fatal("post not marked deleted")
+	}
+}
+
+func testPostReactions(t *testing.T, st store.Store) {
+	putPost(t, st, "p1", "anon-a", baseTime())
+
+	counts := func(wantLikes, wantDislikes int) {
+		t.Helper()
+		p, err := st.GetPost(ctx, "p1")
+		must(t, err)
+		if p.Likes != wantLikes || p.Dislikes != wantDislikes {
+			t.Fatalf("likes/dislikes = %d/%d, want %d/%d", p.Likes, p.Dislikes, wantLikes, wantDislikes)
+		}
+	}
+	reaction := func(want string) {
+		t.Helper()
+		got, err := st.GetPostReaction(ctx, "p1", "anon-b")
+		must(t, err)
+		if got != want {
+			t.Fatalf("reaction = %q, want %q", got, want)
+		}
+	}
+
+	reaction("")
+	must(t, st.ReactToPost(ctx, "p1", "anon-b", "like"))
+	reaction("like")
+	counts(1, 0)
+
+	// the same reaction again toggles it off
+	must(t, st.ReactToPost(ctx, "p1", "anon-b", "like"))
+	reaction("")
+	counts(0, 0)
+
+	// a different reaction replaces the previous one
+	must(t, st.ReactToPost(ctx, "p1", "anon-b", "like"))
+	must(t, st.ReactToPost(ctx, "p1", "anon-b", "dislike"))
+	reaction("dislike")
+	counts(0, 1)
+
+	must(t, st.ReactToPost(ctx, "p1", "anon-c", "dislike"))
+	counts(0, 2)
+
+	wantErr(t, st.ReactToPost(ctx, "missing", "anon-b", "like"), store.ErrNotFound)
+	must(t, st.DeletePostByUser(ctx, "p1", "anon-a"))
+	wantErr(t, st.ReactToPost(ctx, "p1", "anon-b", "like"), store.ErrNotFound)
+}
+
+func testPostsByAnonID(t *testing.T, st store.Store) {
+	now := baseTime()
+	putPost(t, st, "a1", "anon-a", now.Add(-3*time.Minute))
+	putPost(t, st, "a2", "anon-a", now.Add(-2*time.Minute))
+	putPost(t, st, "b1", "anon-b", now.Add(-time.Minute))
+	putPost(t, st, "a3", "anon-a", now)
+	must(t, st.DeletePostByUser(ctx, "a2", "anon-a"))
+
+	posts, err := st.GetPostsByAnonID(ctx, "anon-a", 10)
+	must(t, err)
+	wantIDs(t, postIDs(posts), []string{"a3", "a1"})
+
+	posts, err = st.GetPostsByAnonID(ctx, "anon-a", 1)
+	must(t, err)
+	wantIDs(t, postIDs(posts), []string{"a3"})
+
+	posts, err = st.GetPostsByAnonID(ctx, "anon-c", 10)
+	must(t, err)
+	wantIDs(t, postIDs(posts), nil)
+}
+
+func testTrending(t *testing.T, st store.Store) {
+	now := baseTime()
+	putPost(t, st, "old", "anon-a", now.Add(-72*time.Hour))
+	putPost(t, st, "liked", "anon-a", now.Add(-time.Hour))
+	putPost(t, st, "plain", "anon-a", now.Add(-time.Minute))
+	putPost(t, st, "disliked", "anon-a", now)
+	putPost(t, st, "deleted", "anon-a", now)
+	for _, anon := range []string{"anon-b", "anon-c", "anon-d", "anon-e", "anon-f"} {
+		must(t, st.ReactToPost(ctx, "old", anon, "like"))
+	}
+	for _, anon := range []string{"anon-b", "anon-c", "anon-d"} {
+		must(t, st.ReactToPost(ctx, "liked", anon, "like"))
+	}
+	must(t, st.ReactToPost(ctx, "disliked", "anon-b", "dislike"))
+	must(t, st.AddComment(ctx, &store.PostComment{ID: "c1", PostID: "plain", AnonID: "anon-b", Text: "hi", CreatedAt: now}))
+	must(t, st.AddComment(ctx, &store.PostComment{ID: "c2", PostID: "plain", AnonID: "anon-b", Text: "bye", CreatedAt: now}))
+	must(t, st.DeleteCommentByUser(ctx, "c2", "anon-b"))
+	must(t, st.DeletePostByUser(ctx, "deleted", "anon-a"))
+
+	trending := func(q store.TrendingQuery) []store.PostWithStats {
+		t.Helper()
+		posts, err := st.GetTrendingPosts(ctx, q)
+		must(t, err)
+		return posts
+	}
+	ids := func(posts []store.PostWithStats) []string {
+		out := make([]string, len(posts))
+		for i, p := range posts {
+			out[i] = p.ID
+		}
+		return out
+	}
+
+	// nothing is ranked until the job has run
+	wantIDs(t, ids(trending(store.TrendingQuery{})), nil)
+
+	n, err := store.RefreshPostScores(ctx, st, store.DefaultRankingPolicy(), 0, now)
+	must(t, err)
+	if n != 4 {
+		t.Fatalf("RefreshPostScores scored %d posts, want 4", n)
+	}
+
+	posts := trending(store.TrendingQuery{})
+	wantIDs(t, ids(posts), []string{"liked", "plain", "disliked", "old"})
+	if p := posts[0]; p.LikeCount != 3 || p.DislikeCount != 0 || p.CommentCount != 0 {
+		t.Fatalf("liked stats = %d/%d/%d", p.LikeCount, p.DislikeCount, p.CommentCount)
+	}
+	// a comment weighs two likes
+	if p := posts[1]; p.CommentCount != 1 || p.TopScore != 2 {
+		t.Fatalf("plain stats = %d comments, top %v", p.CommentCount, p.TopScore)
+	}
+
+	wantIDs(t, ids(trending(store.TrendingQuery{Algo: store.TrendingTop, Window: store.TrendingWindowWeek})), []string{"old", "liked", "plain", "disliked"})
+	wantIDs(t, ids(trending(store.TrendingQuery{Algo: store.TrendingTop, Window: store.TrendingWindowDay})), []string{"liked", "plain", "disliked"})
+	wantIDs(t, ids(trending(store.TrendingQuery{Algo: store.TrendingRising, Window: store.TrendingWindowDay})), []string{"plain", "liked", "disliked"})
+	wantIDs(t, ids(trending(store.TrendingQuery{Limit: 1, Offset: 1})), []string{"plain"})
+
+	// reports count against a post
+	for _, anon := range []string{"anon-b", "anon-c"} {
+		must(t, st.ReportPost(ctx, "liked", "anon-a", anon, "spam", now))
+	}
+	_, err = store.RefreshPostScores(ctx, st, store.DefaultRankingPolicy(), 0, now)
+	must(t, err)
+	wantIDs(t, ids(trending(store.TrendingQuery{Algo: store.TrendingTop, Window: store.TrendingWindowDay})), []string{"plain", "disliked", "liked"})
+
+	// deleted posts drop out before the next run
+	must(t, st.DeletePostByUser(ctx, "plain", "anon-a"))
+	wantIDs(t, ids(trending(store.TrendingQuery{Window: store.TrendingWindowDay})), []string{"disliked", "liked"})
+
+	// the horizon bounds what gets scored
+	n, err = store.RefreshPostScores(ctx, st, store.DefaultRankingPolicy(), 24*time.Hour, now)
+	must(t, err)
+	if n != 2 {
+		t.Fatalf("RefreshPostScores with a day horizon scored %d posts, want 2", n)
+	}
+	wantIDs(t, ids(trending(store.TrendingQuery{Algo: store.TrendingTop})), []string{"disliked", "liked"})
+
+	_, err = st.GetTrendingPosts(ctx, store.TrendingQuery{Algo: "best"})
+	wantErr(t, err, store.ErrInvalidTrendingQuery)
+	_, err = st.GetTrendingPosts(ctx, store.TrendingQuery{Window: "year"})
+	wantErr(t, err, store.ErrInvalidTrendingQuery)
+}
+
+func testTags(t *testing.T, st store.Store) {
+	now := baseTime()
+	put := func(id, anonID, text string, at time.Time) {
+		t.Helper()
+		must(t, st.PutPost(ctx, &store.Post{ID: id, AnonID: anonID, Text: text, CreatedAt: at}))
+	}
+	put("p1", "anon-a", "off to #Travel #food", now.Add(-48*time.Hour))
+	put("p2", "anon-b", "#travel again, #TRAVEL", now.Add(-2*time.Hour))
+	put("p3", "anon-c", "#food #café", now.Add(-time.Minute))
+	put("p4", "anon-a", "no tags here", now)
+	put("p5", "anon-b", "#travel #gone", now)
+	must(t, st.DeletePostByUser(ctx, "p5", "anon-b"))
+
+	wantIDs(t, store.ExtractHashtags("#Go, #go and #Rust_lang!"), []string{"go", "rust_lang"})
+
+	page := func(q store.FeedQuery) []string {
+		t.Helper()
+		posts, err := st.GetFeedPage(ctx, q)
+		must(t, err)
+		return postIDs(posts)
+	}
+	wantIDs(t, page(store.FeedQuery{Tag: "travel"}), []string{"p2", "p1"})
+	wantIDs(t, page(store.FeedQuery{Tag: "travel", Limit: 1, Before: &store.Cursor{CreatedAt: now.Add(-2 * time.Hour), ID: "p2"}}), []string{"p1"})
+	wantIDs(t, page(store.FeedQuery{Tag: "café"}), []string{"p3"})
+	wantIDs(t, page(store.FeedQuery{Tag: "gone"}), nil)
+
+	// trending renders counts as "tag:posts" for comparison
+	trending := func(since time.Time) []string {
+		t.Helper()
+		tags, err := st.GetTrendingTags(ctx, since, 10)
+		must(t, err)
+		out := make([]string, len(tags))
+		for i, tc := range tags {
+			out[i] = fmt.Sprintf("%s:%d", tc.Tag, tc.Posts)
+		}
+		return out
+	}
+	// ties are broken alphabetically; deleted posts do not count
+	wantIDs(t, trending(now.Add(-72*time.Hour)), []string{"food:2", "travel:2", "café:1"})
+	wantIDs(t, trending(now.Add(-24*time.Hour)), []string{"café:1", "food:1", "travel:1"})
+	wantIDs(t, trending(now.Add(-time.Hour)), []string{"café:1", "food:1"})
+
+	// following is idempotent both ways
+	must(t, st.FollowTag(ctx, "anon-d", "travel", now))
+	must(t, st.FollowTag(ctx, "anon-d", "travel", now))
+	must(t, st.FollowTag(ctx, "anon-d", "café", now))
+	tags, err := st.GetFollowedTags(ctx, "anon-d")
+	must(t, err)
+	wantIDs(t, tags, []string{"café", "travel"})
+
+	// a post carrying two followed tags shows up once
+	put("p6", "anon-c", "#travel #café", now.Add(time.Minute))
+	following := store.FeedQuery{Scope: store.FeedScopeFollowing, Viewer: "anon-d"}
+	wantIDs(t, page(following), []string{"p6", "p3", "p2", "p1"})
+	wantIDs(t, page(store.FeedQuery{Scope: store.FeedScopeFollowing, Viewer: "anon-e"}), nil)
+
+	must(t, st.UnfollowTag(ctx, "anon-d", "travel"))
+	must(t, st.UnfollowTag(ctx, "anon-d", "travel"))
+	wantIDs(t, page(following), []string{"p6", "p3"})
+	tags, err = st.GetFollowedTags(ctx, "anon-d")
+	must(t, err)
+	wantIDs(t, tags, []string{"café"})
+
+	for in, want := range map[string]string{"#Travel": "travel", " café ": "café", "rust_lang": "rust_lang"} {
+		got, err := store.NormalizeTag(in)
+		must(t, err)
+		if got != want {
+			t.Fatalf("NormalizeTag(%q) = %q, want %q", in, got, want)
+		}
+	}
+	for _, in := range []string{"", "#", "two words", "semi;colon"} {
+		_, err := store.NormalizeTag(in)
+		wantErr(t, err, store.ErrInvalidTag)
+	}
+}
+
+func testSearch(t *testing.T, st store.Store) {
+	now := baseTime()
+	put := func(id, anonID, text string, at time.Time) {
+		t.Helper()
+		must(t, st.PutPost(ctx, &store.Post{ID: id, AnonID: anonID, Text: text, CreatedAt: at}))
+	}
+	put("p1", "anon-a", "My red car is fast", now.Add(-5*time.Minute))
+	put("p2", "anon-b", "A red and very shiny car", now.Add(-4*time.Minute))
+	put("p3", "anon-c", "Cats running through the garden #pets", now.Add(-3*time.Minute))
+	put("p4", "anon-a", "Traveling soon #travel #pets", now.Add(-2*time.Minute))
+	put("p5", "anon-b", "red car, deleted", now.Add(-time.Minute))
+	put("p6", "anon-d", `Bikes <b>&</b> "more" amp`, now.Add(-time.Minute))
+	must(t, st.DeletePostByUser(ctx, "p5", "anon-b"))
+	putDevice(t, st, "dev-c", "anon-c", "Night Owl", now)
+
+	search := func(raw string, limit, offset int) ([]*store.PostSearchResult, int) {
+		t.Helper()
+		q := store.ParseSearchQuery(raw)
+		q.Limit, q.Offset = limit, offset
+		results, total, err := st.SearchPosts(ctx, q)
+		must(t, err)
+		return results, total
+	}
+	ids := func(results []*store.PostSearchResult) []string {
+		out := make([]string, len(results))
+		for i, r := range results {
+			out[i] = r.Post.ID
+		}
+		return out
+	}
+
+	// every word must match; closer together ranks higher
+	results, total := search("red car", 0, 0)
+	wantIDs(t, ids(results), []string{"p1", "p2"})
+	if total != 2 || results[0].RelevanceScore <= results[1].RelevanceScore || results[1].RelevanceScore < 1 {
+		t.Fatalf("red car: total %d, scores %v, %v", total, results[0].RelevanceScore, results[1].RelevanceScore)
+	}
+	if r := results[0]; r.Highlights != "My <mark>red</mark> <mark>car</mark> is fast" {
+		t.Fatalf("red car highlights = %q", r.Highlights)
+	}
+	wantIDs(t, results[0].MatchedTerms, []string{"red", "car"})
+	results, total = search("red car", 1, 1)
+	wantIDs(t, ids(results), []string{"p2"})
+	if total != 2 {
+		t.Fatalf("red car page 2: total %d, want 2", total)
+	}
+
+	results, _ = search(`"red car"`, 0, 0)
+	wantIDs(t, ids(results), []string{"p1"})
+
+	// words are stemmed on both sides
+	results, _ = search("cat run", 0, 0)
+	wantIDs(t, ids(results), []string{"p3"})
+	if r := results[0]; r.Highlights != "<mark>Cats</mark> <mark>running</mark> through the garden #pets" {
+		t.Fatalf("cat run highlights = %q", r.Highlights)
+	}
+
+	// highlights are safe to render as HTML
+	results, _ = search("bike", 0, 0)
+	wantIDs(t, ids(results), []string{"p6"})
+	if r := results[0]; r.Highlights != "<mark>Bikes</mark> &lt;b&gt;&amp;&lt;/b&gt; &#34;more&#34; amp" {
+		t.Fatalf("bike highlights = %q", r.Highlights)
+	}
+	// and words that are also entity names are not marked inside entities
+	results, _ = search("amp", 0, 0)
+	wantIDs(t, ids(results), []string{"p6"})
+	if r := results[0]; r.Highlights != "Bikes &lt;b&gt;&amp;&lt;/b&gt; &#34;more&#34; <mark>amp</mark>" {
+		t.Fatalf("amp highlights = %q", r.Highlights)
+	}
+
+	results, _ = search("trav*", 0, 0)
+	wantIDs(t, ids(results), []string{"p4"})
+
+	// hashtags alone list the posts carrying them, newest first
+	results, _ = search("#pets", 0, 0)
+	wantIDs(t, ids(results), []string{"p4", "p3"})
+	wantIDs(t, results[0].MatchedTerms, []string{"pets"})
+	results, total = search("garden #travel", 0, 0)
+	if len(results) != 0 || total != 0 {
+		t.Fatalf("garden #travel = %v, total %d", ids(results), total)
+	}
+
+	// the author's username or anon id finds their posts
+	results, _ = search("nightowl", 0, 0)
+	wantIDs(t, ids(results), []string{"p3"})
+	results, _ = search("ANON-A", 0, 0)
+	wantIDs(t, ids(results), []string{"p4", "p1"})
+
+	// a typo still finds something, ranked below any text match
+	results, _ = search("gardn", 0, 0)
+	wantIDs(t, ids(results), []string{"p3"})
+	if r := results[0]; r.RelevanceScore >= 1 || r.Highlights != r.Post.Text {
+		t.Fatalf("gardn: score %v, highlights %q", r.RelevanceScore, r.Highlights)
+	}
+	results, _ = search("zebra", 0, 0)
+	wantIDs(t, ids(results), nil)
+}
@@ -0,0 +1,116 @@
+package storetest
+
+import (
+	"testing"
+	"time"
+
+	"anon-backend/internal/store"
+)
+
+func testLinkCards(t *testing.T, st store.Store) {
+	now := baseTime()
+	for _, c := range []*store.LinkCard{
+		{Code: "OLD", OwnerAnon: "anon-a", Status: store.CardActive, CreatedAt: now.Add(-time.Hour), ExpiresAt: now.Add(time.Hour)},
+		{Code: "NEW", OwnerAnon: "anon-a", Status: store.CardActive, CreatedAt: now, ExpiresAt: now.Add(time.Hour)},
+		{Code: "OTHER", OwnerAnon: "anon-b", Status: store.CardActive, CreatedAt: now, ExpiresAt: now.Add(time.Hour)},
+	} {
+		must(t, st.PutCard(ctx, c))
+	}
+
+	card, err := st.GetCard(ctx, "OLD")
+	must(t, err)
+	if card.OwnerAnon != "anon-a" || card.Status != store.CardActive || !card.ExpiresAt.Equal(now.Add(time.Hour)) {
+		t.Fatalf("GetCard = %+v", card)
+	}
+
+	card.Status = store.CardUsed
+	card.UsedBy = "anon-b"
+	must(t, st.PutCard(ctx, card))
+	card, err = st.GetCard(ctx, "OLD")
+	must(t, err)
+	if card.Status != store.CardUsed || card.UsedBy != "anon-b" {
+		t.Fatalf("GetCard after update = %+v", card)
+	}
+
+	_, err = st.GetCard(ctx, "MISSING")
+	wantErr(t, err, store.ErrNotFound)
+
+	cards, err := st.CardsByOwner(ctx, "anon-a")
+	must(t, err)
+	var codes []string
+	for _, c := range cards {
+		codes = append(codes, c.Code)
+	}
+	wantIDs(t, codes, []string{"NEW", "OLD"})
+}
+
+func testTrust(t *testing.T, st store.Store) {
+	now := baseTime()
+	older := &store.TrustRequest{ID: "tr-1", Code: "C1", FromAnon: "anon-a", ToAnon: "anon-b", Status: store.TrustPending, CreatedAt: now.Add(-time.Minute), UpdatedAt: now.Add(-time.Minute)}
+	newer := &store.TrustRequest{ID: "tr-2", Code: "C2", FromAnon: "anon-c", ToAnon: "anon-a", Status: store.TrustPending, CreatedAt: now, UpdatedAt: now}
+	must(t, st.PutTrust(ctx, older))
+	must(t, st.PutTrust(ctx, newer))
+
+	list, err := st.TrustForAnon(ctx, "anon-a")
+	must(t, err)
+	var ids []string
+	for _, tr := range list {
+		ids = append(ids, tr.ID)
+	}
+	wantIDs(t, ids, []string{"tr-2", "tr-1"})
+
+	ok, err := st.TrustAccepted(ctx, "anon-a", "anon-b")
+	must(t, err)
+	if ok {
+		t.Fatal("pending request counted as accepted")
+	}
+
+	accepted := *older
+	accepted.Status = store.TrustAccepted
+	accepted.UpdatedAt = now
+	must(t, st.PutTrust(ctx, &accepted))
+
+	got, err := st.GetTrust(ctx, "tr-1")
+	must(t, err)
+	if got.Status != store.TrustAccepted || !got.UpdatedAt.Equal(now) {
+		t.Fatalf("GetTrust = %+v", got)
+	}
+	for _, pair := range [][2]string{{"anon-a", "anon-b"}, {"anon-b", "anon-a"}} {
+		ok, err := st.TrustAccepted(ctx, pair[0], pair[1])
+		must(t, err)
+		if !ok {
+			t.Fatalf("TrustAccepted(%s, %s) = false", pair[0], pair[1])
+		}
+	}
+	ok, err = st.TrustAccepted(ctx, "anon-a", "anon-c")
+	must(t, err)
+	if ok {
+		t.Fatal("TrustAccepted(anon-a, anon-c) = true")
+	}
+
+	_, err = st.GetTrust(ctx, "tr-missing")
+	wantErr(t, err, store.ErrNotFound)
+}
+
+func testGeo(t *testing.T, st store.Store) {
+	now := baseTime()
+	for _, p := range []*store.GeoPing{
+		{AnonID: "anon-a", Lat: 0, Lng: 0, Timestamp: now.Add(-2 * time.Minute)},
+		{AnonID: "anon-a", Lat: 0, Lng: 0.01, Timestamp: now.Add(-time.Minute)},
+		{AnonID: "anon-b", Lat: 0, Lng: 0.02, Timestamp: now},
+		{AnonID: "anon-c", Lat: 10, Lng: 10, Timestamp: now},
+	} {
+		must(t, st.PutGeo(ctx, p))
+	}
+
+	pings, err := st.GetNearby(ctx, 0, 0, 5)
+	must(t, err)
+	var anons []string
+	for _, p := range pings {
+		anons = append(anons, p.AnonID)
+	}
+	wantIDs(t, anons, []string{"anon-b", "anon-a"})
+	if pings[1].Lng != 0.01 || !pings[1].Timestamp.Equal(now.Add(-time.Minute)) {
+		t.Fatalf("nearby ping for anon-a is not its latest: %+v", pings[1])
+	}
+}
@@ -0,0 +1,92 @@
+// Package storetest holds the behavioural tests every store.Store must
+// pass. The in-memory and Postgres stores run the same suite, so a change
+// to one that the other does not follow shows up as a failing test rather
+// than as a difference between development and production.
+package storetest
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"anon-backend/internal/store"
+)
+
+// Run runs the suite against the stores newStore returns. Every subtest
+// gets a fresh, empty store.
+func Run(t *testing.T, newStore func(t *testing.T) store.Store) {
+	tests := []struct {
+		name string
+		fn   func(t *testing.T, st store.Store)
+	}{
+		{"LinkCards", testLinkCards},
+		{"Trust", testTrust},
+		{"Geo", testGeo},
+		{"Feed", testFeed},
+		{"FeedPage", testFeedPage},
+		{"GetPost", testGetPost},
+		{"DeletePostByUser", testDeletePostByUser},
+		{"PostReactions", testPostReactions},
+		{"PostsByAnonID", testPostsByAnonID},
+		{"Trending", testTrending},
+		{"Tags", testTags},
+		{"Search", testSearch},
+		{"Comments", testComments},
+		{"Replies", testReplies},
+		{"BatchLookups", testBatchLookups},
+		{"Chat", testChat},
+		{"WSTickets", testWSTickets},
+		{"Devices", testDevices},
+		{"Recovery", testRecovery},
+		{"Sessions", testSessions},
+		{"RefreshRotation", testRefreshRotation},
+		{"Admins", testAdmins},
+		{"Profiles", testProfiles},
+		{"RateLimit", testRateLimit},
+		{"Quota", testQuota},
+		{"CreatePostWithQuota", testCreatePostWithQuota},
+		{"AddCommentWithQuota", testAddCommentWithQuota},
+		{"Audit", testAudit},
+		{"Reports", testReports},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			tc.fn(t, newStore(t))
+		})
+	}
+}
+
+var ctx = context.Background()
+
+// baseTime is truncated to whole seconds so it survives a round trip
+// through any backend's timestamp precision.
+func baseTime() time.Time {
+	return time.Now().UTC().Truncate(time.Second)
+}
+
+func must(t *testing.T, err error) {
+	t.Helper()
+	if err != nil {
+		t.Fatal(err)
+	}
+}
+
+func wantErr(t *testing.T, err, target error) {
+	t.Helper()
+	if !errors.Is(err, target) {
+		t.Fatalf("got error %v, want %v", err, target)
+	}
+}
+
+func wantIDs(t *testing.T, got, want []string) {
+	t.Helper()
+	if len(got) != len(want) {
+		t.Fatalf("got %v, want %v", got, want)
+	}
+	for i := range got {
+		if got[i] != want[i] {
+			t.Fatalf("got %v, want %v", got, want)
+		}
+	}
+}
@@ -0,0 +1,18 @@
+package types
+
+type ChatMessageDTO struct {
+	ID          string `json:"id"`
+	From        string `json:"from"`
+	To          string `json:"to"`
+	Kind        string `json:"kind"` // "text" or "ciphertext"
+	Text        string `json:"text"`
+	Ciphertext  string `json:"ciphertext,omitempty"`   // base64, opaque to the server
+	SentAt      string `json:"sent_at"`                // ISO 8601
+	DeliveredAt string `json:"delivered_at,omitempty"` // ISO 8601
+	ReadAt      string `json:"read_at,omitempty"`      // ISO 8601
+}
+
+type ChatHistoryResponse struct {
+	Messages   []ChatMessageDTO `json:"messages"`
+	NextCursor string           `json:"next_cursor,omitempty"`
+}
@@ -0,0 +1,35 @@
+package types
+
+type SignedPrekeyDTO struct {
+	KeyID     int    `json:"key_id"`
+	PublicKey string `json:"public_key"` // base64
+	Signature string `json:"signature"`  // base64, by the identity key
+}
+
+type OneTimePrekeyDTO struct {
+	KeyID     int    `json:"key_id"`
+	PublicKey string `json:"public_key"` // base64
+}
+
+type KeysUploadRequest struct {
+	DevicePublicID string             `json:"device_public_id"`
+	IdentityKey    string             `json:"identity_key"` // base64
+	SignedPrekey   SignedPrekeyDTO    `json:"signed_prekey"`
+	OneTimePrekeys []OneTimePrekeyDTO `json:"one_time_prekeys,omitempty"`
+}
+
+type KeysUploadResponse struct {
+	OneTimePrekeysRemaining int `json:"one_time_prekeys_remaining"`
+}
+
+type DeviceKeyBundleDTO struct {
+	DevicePublicID string            `json:"device_public_id"`
+	IdentityKey    string            `json:"identity_key"`
+	SignedPrekey   SignedPrekeyDTO   `json:"signed_prekey"`
+	OneTimePrekey  *OneTimePrekeyDTO `json:"one_time_prekey,omitempty"`
+}
+
+type KeysBundleResponse struct {
+	AnonID  string               `json:"anon_id"`
+	Devices []DeviceKeyBundleDTO `json:"devices"`
+}
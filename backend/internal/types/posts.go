@@ -17,12 +17,35 @@ type PostDTO struct {
 }
 
 type PostCreateResponse struct {
-	Post           PostDTO `json:"post"`
-	PostsRemaining int     `json:"posts_remaining"`
+	Post           PostDTO  `json:"post"`
+	PostsRemaining int      `json:"posts_remaining"` // -1 when unlimited
+	Quota          QuotaDTO `json:"quota"`
+}
+
+type QuotaWindowDTO struct {
+	Window    string `json:"window"` // "hour", "day" or "week"
+	Limit     int    `json:"limit"`
+	Used      int    `json:"used"`
+	Remaining int    `json:"remaining"`
+	ResetAt   string `json:"reset_at"` // ISO 8601
+}
+
+type QuotaDTO struct {
+	Unlimited bool             `json:"unlimited"`
+	Remaining int              `json:"remaining"`          // -1 when unlimited
+	ResetAt   string           `json:"reset_at,omitempty"` // ISO 8601
+	Windows   []QuotaWindowDTO `json:"windows"`
+}
+
+type PostRemainingResponse struct {
+	Remaining int                 `json:"remaining"` // posts; same as quotas["posts"].remaining
+	Tier      string              `json:"tier"`
+	Quotas    map[string]QuotaDTO `json:"quotas"`
 }
 
 type PostFeedResponse struct {
-	Posts []PostDTO `json:"posts"`
+	Posts      []PostDTO `json:"posts"`
+	NextCursor string    `json:"next_cursor,omitempty"`
 }
 
 type TrendingPostDTO struct {
@@ -31,6 +54,8 @@ type TrendingPostDTO struct {
 	DislikeCount int     `json:"dislike_count"`
 	CommentCount int     `json:"comment_count"`
 	HotScore     float64 `json:"hot_score"`
+	RisingScore  float64 `json:"rising_score"`
+	TopScore     float64 `json:"top_score"`
 }
 
 type TrendingPostsResponse struct {
@@ -117,7 +142,7 @@ type SearchResult struct {
 	Post           PostDTO  `json:"post"`
 	RelevanceScore float64  `json:"relevance_score"`
 	MatchedTerms   []string `json:"matched_terms,omitempty"`
-	Highlights     string   `json:"highlights,omitempty"`
+	Highlights     string   `json:"highlights,omitempty"` // HTML-escaped post text with matches in <mark></mark>
 }
 
 type SearchResponse struct {
@@ -0,0 +1,24 @@
+package types
+
+type RecoveryKeyResponse struct {
+	RecoveryKey string `json:"recovery_key"`
+	CreatedAt   string `json:"created_at"`
+}
+
+type DeviceLinkStartRequest struct {
+	DevicePublicID string `json:"device_public_id"`
+}
+
+type DeviceLinkStartResponse struct {
+	LinkCode     string `json:"link_code"`
+	ExpiresInSec int    `json:"expires_in_sec"`
+}
+
+type DeviceLinkApproveRequest struct {
+	LinkCode string `json:"link_code"`
+}
+
+type DeviceLinkStatusResponse struct {
+	Status    string `json:"status"` // pending, approved, consumed or expired
+	ExpiresAt string `json:"expires_at"`
+}
@@ -16,13 +16,26 @@ type BootstrapRequest struct {
 	Proof            string `json:"proof"`
 	Region           string `json:"region,omitempty"`
 	DeviceSecretHash string `json:"device_secret_hash,omitempty"`
+	// DevicePublicKey registers a new device for Ed25519 auth; Proof is then
+	// the base64 signature of "device_public_id|nonce|ts".
+	DevicePublicKey string `json:"device_public_key,omitempty"`
+	// RecoveryKey or LinkCode make a new device join an existing identity
+	// instead of creating one.
+	RecoveryKey string `json:"recovery_key,omitempty"`
+	LinkCode    string `json:"link_code,omitempty"`
 }
 
 type BootstrapResponse struct {
-	Token     string `json:"token"`
-	AnonID    string `json:"anon_id"`
-	Username  string `json:"username"`
-	ExpiresAt string `json:"expires_at"`
+	Token            string `json:"token"`
+	AnonID           string `json:"anon_id"`
+	Username         string `json:"username"`
+	ExpiresAt        string `json:"expires_at"`
+	RefreshToken     string `json:"refresh_token"`
+	RefreshExpiresAt string `json:"refresh_expires_at"`
+}
+
+type RefreshRequest struct {
+	RefreshToken string `json:"refresh_token"`
 }
 
 type MeResponse struct {
@@ -31,3 +44,25 @@ type MeResponse struct {
 	Region   string `json:"region,omitempty"`
 	ExpISO   string `json:"expires_at"`
 }
+
+type SessionDeviceDTO struct {
+	ID             string `json:"id"`
+	DevicePublicID string `json:"device_public_id,omitempty"`
+	Region         string `json:"region,omitempty"`
+	UserAgent      string `json:"user_agent,omitempty"`
+	CreatedAt      string `json:"created_at"`
+	LastActivityAt string `json:"last_activity_at"`
+	ExpiresAt      string `json:"expires_at"`
+	Current        bool   `json:"current"`
+}
+
+type SessionDevicesResponse struct {
+	Sessions []SessionDeviceDTO `json:"sessions"`
+}
+
+// SessionRevokeRequest names one session to sign out, or asks to sign out
+// every session but the caller's own.
+type SessionRevokeRequest struct {
+	SessionID string `json:"session_id,omitempty"`
+	Others    bool   `json:"others,omitempty"`
+}
@@ -0,0 +1,20 @@
+package types
+
+type TagCountDTO struct {
+	Tag   string `json:"tag"`
+	Posts int    `json:"posts"` // posts carrying the tag within the window
+}
+
+type TrendingTagsResponse struct {
+	Window string        `json:"window"` // "hour", "day" or "week"
+	Tags   []TagCountDTO `json:"tags"`
+}
+
+type FollowedTagsResponse struct {
+	Tags []string `json:"tags"`
+}
+
+type TagFollowResponse struct {
+	Tag       string `json:"tag"`
+	Following bool   `json:"following"`
+}
@@ -0,0 +1,81 @@
+package ws
+
+import (
+	"encoding/json"
+	"strings"
+	"sync"
+)
+
+// Envelope is one frame on its way to the sockets of To that carry the
+// conversation with Peer. It is what hubs exchange through a Broker.
+type Envelope struct {
+	Origin string          `json:"origin"` // id of the publishing hub
+	To     string          `json:"to"`
+	Peer   string          `json:"peer"`
+	Data   json.RawMessage `json:"data"` // frames are JSON already
+
+	// Receipt, when set, is the id of a stored chat message; every hub that
+	// hands the envelope to at least one socket reports it via OnDelivered.
+	Receipt string `json:"receipt,omitempty"`
+
+	// Close, when non-zero, carries no frame: every socket of To (only
+	// those opened from Session, if set) is closed with this code and
+	// Reason, whatever its peer.
+	Close   int    `json:"close,omitempty"`
+	Reason  string `json:"reason,omitempty"`
+	Session string `json:"session,omitempty"`
+}
+
+// MaxEnvelopeSize bounds an encoded Envelope so that every Broker can carry
+// it: Postgres' NOTIFY payload limit (8000 bytes by default) is the
+// tightest.
+const MaxEnvelopeSize = 7999
+
+// EnvelopeFits reports whether env, once a hub has stamped its origin on
+// it, encodes within MaxEnvelopeSize. Frames that do not fit would reach
+// local sockets only, so callers refuse them up front.
+func EnvelopeFits(env Envelope) bool {
+	env.Origin = strings.Repeat("0", 2*hubIDBytes)
+	payload, err := json.Marshal(env)
+	return err == nil && len(payload) <= MaxEnvelopeSize
+}
+
+// Broker fans envelopes out to every hub, including ones in other processes,
+// so that a peer connected to another API instance still gets its frames.
+// Handlers run on the broker's delivery path and should return quickly.
+type Broker interface {
+	Publish(env Envelope) error
+	Subscribe(handler func(Envelope))
+	Close() error
+}
+
+// MemBroker is an in-process Broker. It is enough for a single instance and
+// lets several hubs in one process (e.g. tests) behave like separate nodes.
+type MemBroker struct {
+	mu       sync.RWMutex
+	handlers []func(Envelope)
+}
+
+func NewMemBroker() *MemBroker {
+	return &MemBroker{}
+}
+
+func (b *MemBroker) Publish(env Envelope) error {
+	b.mu.RLock()
+	handlers := make([]func(Envelope), len(b.handlers))
+	copy(handlers, b.handlers)
+	b.mu.RUnlock()
+
+	for _, h := range handlers {
+		h(env)
+	}
+	return nil
+}
+
+func (b *MemBroker) Subscribe(handler func(Envelope)) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.handlers = append(b.handlers, handler)
+}
+
+func (b *MemBroker) Close() error { return nil }
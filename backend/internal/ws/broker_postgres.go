@@ -0,0 +1,125 @@
+package ws
+
+import (
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"sync"
+	"time"
+
+	"github.com/lib/pq"
+)
+
+// pgBrokerChannel is the LISTEN/NOTIFY channel shared by all API instances.
+const pgBrokerChannel = "ws_hub"
+
+var ErrPayloadTooLarge = errors.New("broker payload too large")
+
+// PgBroker is a Broker on top of Postgres LISTEN/NOTIFY, so several API
+// instances can share chat traffic without extra infrastructure.
+type PgBroker struct {
+	db       *sql.DB
+	listener *pq.Listener
+
+	mu       sync.RWMutex
+	handlers []func(Envelope)
+
+	done      chan struct{}
+	closeOnce sync.Once
+}
+
+// NewPgBroker publishes through db and listens on a dedicated connection
+// opened from dsn.
+func NewPgBroker(db *sql.DB, dsn string) (*PgBroker, error) {
+	listener := pq.NewListener(dsn, 10*time.Second, time.Minute, func(ev pq.ListenerEventType, err error) {
+		if err != nil {
+			log.Printf("ws broker: listener event %d: %v", ev, err)
+		}
+	})
+	if err := listener.Listen(pgBrokerChannel); err != nil {
+		_ = listener.Close()
+		return nil, fmt.Errorf("listen %s: %w", pgBrokerChannel, err)
+	}
+
+	b := &PgBroker{
+		db:       db,
+		listener: listener,
+		done:     make(chan struct{}),
+	}
+	go b.run()
+	return b, nil
+}
+
+func (b *PgBroker) Publish(env Envelope) error {
+	payload, err := json.Marshal(env)
+	if err != nil {
+		return fmt.Errorf("encode envelope: %w", err)
+	}
+	if len(payload) > MaxEnvelopeSize {
+		return ErrPayloadTooLarge
+	}
+	if _, err := b.db.Exec(`SELECT pg_notify($1, $2)`, pgBrokerChannel, string(payload)); err != nil {
+		return fmt.Errorf("notify %s: %w", pgBrokerChannel, err)
+	}
+	return nil
+}
+
+func (b *PgBroker) Subscribe(handler func(Envelope)) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.handlers = append(b.handlers, handler)
+}
+
+func (b *PgBroker) Close() error {
+	var err error
+	b.closeOnce.Do(func() {
+		close(b.done)
+		err = b.listener.Close()
+	})
+	return err
+}
+
+func (b *PgBroker) run() {
+	// keepalive so a silently dropped listener connection gets noticed
+	ticker := time.NewTicker(90 * time.Second)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case n, ok := <-b.listener.Notify:
+			if !ok {
+				return
+			}
+			if n == nil {
+				// reconnected; anything sent meanwhile is lost, clients
+				// catch up through the undelivered replay on reconnect
+				continue
+			}
+			var env Envelope
+			if err := json.Unmarshal([]byte(n.Extra), &env); err != nil {
+				log.Printf("ws broker: bad envelope: %v", err)
+				continue
+			}
+			b.dispatch(env)
+
+		case <-ticker.C:
+			go func() { _ = b.listener.Ping() }()
+
+		case <-b.done:
+			return
+		}
+	}
+}
+
+func (b *PgBroker) dispatch(env Envelope) {
+	b.mu.RLock()
+	handlers := make([]func(Envelope), len(b.handlers))
+	copy(handlers, b.handlers)
+	b.mu.RUnlock()
+
+	for _, h := range handlers {
+		h(env)
+	}
+}
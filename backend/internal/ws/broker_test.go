@@ -0,0 +1,112 @@
+package ws
+
+import (
+	"encoding/json"
+	"errors"
+	"strings"
+	"sync"
+	"testing"
+)
+
+func TestMemBrokerFanOut(t *testing.T) {
+	broker := NewMemBroker()
+	hubA := NewHubWithBroker(broker)
+	hubB := NewHubWithBroker(broker)
+	hubC := NewHubWithBroker(broker)
+
+	onA := NewConn(nil, "anon-b", "", "", PolicySpill)
+	onB := NewConn(nil, "anon-b", "", "", PolicySpill)
+	pair := NewConn(nil, "anon-b", "anon-x", "", PolicySpill) // bound to another peer
+	other := NewConn(nil, "anon-c", "", "", PolicySpill)
+	hubA.Register(onA)
+	hubB.Register(onB)
+	hubB.Register(pair)
+	hubC.Register(other)
+
+	if sent := hubA.SendTo("anon-b", "anon-a", []byte(`{"type":"msg"}`)); sent != 1 {
+		t.Fatalf("SendTo accepted by %d local sockets, want 1", sent)
+	}
+	// the publishing hub skips its own envelope when the broker echoes it
+	for name, c := range map[string]*Conn{"hub A": onA, "hub B": onB} {
+		if got := c.Stats().Queued; got != 1 {
+			t.Fatalf("socket on %s queued %d frames, want 1", name, got)
+		}
+	}
+	if pair.Stats().Queued != 0 || other.Stats().Queued != 0 {
+		t.Fatal("frame reached a socket of another conversation or anon")
+	}
+
+	if closed := hubC.Disconnect("anon-b", CloseUserBanned, "banned"); closed != 0 {
+		t.Fatalf("Disconnect closed %d sockets on a hub without any, want 0", closed)
+	}
+	for _, c := range []*Conn{onA, onB, pair} {
+		if c.closeCode != CloseUserBanned {
+			t.Fatalf("socket close code = %d, want %d", c.closeCode, CloseUserBanned)
+		}
+	}
+}
+
+// Every hub that reaches the recipient runs OnDelivered once; the receipt
+// hook claims the message in shared state, as the store does, so the
+// sender hears of it once.
+func TestSingleReceiptAcrossHubs(t *testing.T) {
+	broker := NewMemBroker()
+	hubs := []*Hub{NewHubWithBroker(broker), NewHubWithBroker(broker), NewHubWithBroker(broker)}
+
+	var mu sync.Mutex
+	claimed := map[string]bool{}
+	runs := 0
+	for _, h := range hubs {
+		h := h
+		h.OnDelivered(func(env Envelope) {
+			mu.Lock()
+			runs++
+			first := !claimed[env.Receipt]
+			claimed[env.Receipt] = true
+			mu.Unlock()
+			if first {
+				h.SendTo(env.Peer, env.To, []byte(`{"type":"delivered"}`))
+			}
+		})
+	}
+
+	sender := NewConn(nil, "anon-a", "", "", PolicySpill)
+	hubs[0].Register(sender)
+	hubs[1].Register(NewConn(nil, "anon-b", "", "", PolicySpill))
+	hubs[2].Register(NewConn(nil, "anon-b", "", "", PolicySpill))
+
+	hubs[0].Deliver(Envelope{To: "anon-b", Peer: "anon-a", Data: []byte(`{"type":"msg"}`), Receipt: "m1"})
+
+	if runs != 2 {
+		t.Fatalf("OnDelivered ran %d times, want once per hub holding the recipient", runs)
+	}
+	if got := sender.Stats().Queued; got != 1 {
+		t.Fatalf("sender got %d receipts, want 1", got)
+	}
+}
+
+func TestEnvelopeSizeLimit(t *testing.T) {
+	// size the frame so the envelope lands exactly on the limit
+	env := Envelope{To: "anon-b", Peer: "anon-a", Receipt: "m1"}
+	env.Data = json.RawMessage(`""`)
+	env.Origin = strings.Repeat("0", 2*hubIDBytes)
+	base, err := json.Marshal(env)
+	if err != nil {
+		t.Fatal(err)
+	}
+	env.Origin = ""
+	fill := strings.Repeat("x", MaxEnvelopeSize-len(base))
+	env.Data = json.RawMessage(`"` + fill + `"`)
+	if !EnvelopeFits(env) {
+		t.Fatal("envelope of MaxEnvelopeSize bytes does not fit")
+	}
+
+	env.Data = json.RawMessage(`"` + fill + `x"`)
+	if EnvelopeFits(env) {
+		t.Fatal("envelope over MaxEnvelopeSize fits")
+	}
+	env.Origin = strings.Repeat("0", 2*hubIDBytes) // as Deliver stamps it
+	if err := (&PgBroker{}).Publish(env); !errors.Is(err, ErrPayloadTooLarge) {
+		t.Fatalf("PgBroker.Publish of an oversized envelope = %v, want ErrPayloadTooLarge", err)
+	}
+}
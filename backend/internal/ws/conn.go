@@ -2,52 +2,274 @@ package ws
 
 import (
 	"sync"
+	"sync/atomic"
 	"time"
 
 	"github.com/gorilla/websocket"
 )
 
+// OverflowPolicy decides what a Conn does when its send buffer is full.
+type OverflowPolicy string
+
+const (
+	// PolicyDropOldest evicts the oldest queued frame to make room.
+	PolicyDropOldest OverflowPolicy = "drop_oldest"
+	// PolicyDisconnect closes the socket with CloseSlowConsumer so the
+	// client reconnects and resyncs from history.
+	PolicyDisconnect OverflowPolicy = "disconnect"
+	// PolicySpill parks the frame on the socket, behind the buffer, and
+	// feeds parked frames to the buffer as it drains. A socket with more
+	// than spillSize frames parked is closed as by PolicyDisconnect.
+	PolicySpill OverflowPolicy = "spill"
+)
+
+// ParseOverflowPolicy returns the policy named s, or def when s is unknown.
+func ParseOverflowPolicy(s string, def OverflowPolicy) OverflowPolicy {
+	switch OverflowPolicy(s) {
+	case PolicyDropOldest, PolicyDisconnect, PolicySpill:
+		return OverflowPolicy(s)
+	}
+	return def
+}
+
+// CloseSlowConsumer is the websocket close code sent when a client cannot
+// keep up; clients should reconnect and fetch history.
+const CloseSlowConsumer = 4008
+
+// Close codes sent when moderation ends a socket. Clients should not
+// reconnect without a fresh session.
+const (
+	CloseSessionRevoked = 4001
+	CloseUserBanned     = 4003
+)
+
+const (
+	sendBufferSize = 32
+	spillSize      = 512
+)
+
+// Stats counts what happened to frames offered to one or more Conns.
+type Stats struct {
+	Queued          uint64 `json:"queued"`
+	Dropped         uint64 `json:"dropped"`
+	Spilled         uint64 `json:"spilled"`
+	SlowDisconnects uint64 `json:"slow_disconnects"`
+}
+
+const (
+	statQueued = iota
+	statDropped
+	statSpilled
+	statSlowDisconnects
+	numStats
+)
+
+type counters [numStats]atomic.Uint64
+
+func (k *counters) snapshot() Stats {
+	return Stats{
+		Queued:          k[statQueued].Load(),
+		Dropped:         k[statDropped].Load(),
+		Spilled:         k[statSpilled].Load(),
+		SlowDisconnects: k[statSlowDisconnects].Load(),
+	}
+}
+
 // Conn represents one websocket connection for one anon identity.
+// A Conn with an empty peer is multiplexed and carries every conversation
+// of its anon; otherwise it is bound to the single anon/peer pair.
 type Conn struct {
-	ws   *websocket.Conn
-	anon string
-	peer string
+	ws      *websocket.Conn
+	anon    string
+	peer    string
+	session string
+	policy  OverflowPolicy
 
-	send chan []byte
+	mu           sync.Mutex
+	send         chan []byte
+	spill        [][]byte // frames parked by PolicySpill, oldest first
+	closed       bool
+	closeCode    int
+	closeReason  string
+	offerRefused bool
+	onDrain      func(*Conn)
 
-	closeOnce sync.Once
+	stats counters
+	hub   *counters // totals of the hub c is registered with, if any
 }
 
-func NewConn(wsConn *websocket.Conn, anon, peer string) *Conn {
+func NewConn(wsConn *websocket.Conn, anon, peer, session string, policy OverflowPolicy) *Conn {
 	return &Conn{
-		ws:   wsConn,
-		anon: anon,
-		peer: peer,
-		// small buffer to avoid blocking hub; drop if overloaded
-		send: make(chan []byte, 32),
+		ws:      wsConn,
+		anon:    anon,
+		peer:    peer,
+		session: session,
+		policy:  ParseOverflowPolicy(string(policy), PolicySpill),
+		// small buffer to avoid blocking hub; policy decides on overflow
+		send:      make(chan []byte, sendBufferSize),
+		closeCode: websocket.CloseNormalClosure,
 	}
 }
 
 func (c *Conn) Anon() string { return c.anon }
 func (c *Conn) Peer() string { return c.peer }
 
-// Enqueue tries to send without blocking forever.
-// Returns false if message was dropped.
+// Session is the id of the session the socket was opened from.
+func (c *Conn) Session() string { return c.session }
+
+func (c *Conn) Policy() OverflowPolicy { return c.policy }
+
+// Multiplexed reports whether c serves all of its anon's conversations.
+func (c *Conn) Multiplexed() bool { return c.peer == "" }
+
+// Accepts reports whether frames about the conversation with peer belong on c.
+func (c *Conn) Accepts(peer string) bool { return c.peer == "" || c.peer == peer }
+
+// Stats returns this connection's frame counters.
+func (c *Conn) Stats() Stats { return c.stats.snapshot() }
+
+// OnDrain sets the handler run once the buffer has drained after Offer
+// refused a frame, typically to resume replaying undelivered messages.
+func (c *Conn) OnDrain(fn func(*Conn)) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.onDrain = fn
+}
+
+func (c *Conn) inc(stat int) {
+	c.stats[stat].Add(1)
+	if c.hub != nil {
+		c.hub[stat].Add(1)
+	}
+}
+
+// Enqueue tries to send without blocking.
+// Returns false if the frame did not make it into the buffer; what happens
+// then depends on the connection's OverflowPolicy.
 func (c *Conn) Enqueue(msg []byte) bool {
-	select {
-	case c.send <- msg:
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if c.closed {
+		return false
+	}
+
+	if len(c.spill) == 0 {
+		select {
+		case c.send <- msg:
+			c.inc(statQueued)
+			return true
+		default:
+		}
+	}
+
+	switch c.policy {
+	case PolicyDropOldest:
+		select {
+		case <-c.send:
+			c.inc(statDropped)
+		default:
+		}
+		select {
+		case c.send <- msg:
+			c.inc(statQueued)
+			return true
+		default:
+			c.inc(statDropped)
+			return false
+		}
+
+	case PolicyDisconnect:
+		c.inc(statDropped)
+		c.inc(statSlowDisconnects)
+		c.closeLocked(CloseSlowConsumer, "slow consumer: reconnect and resync")
+		return false
+
+	default: // PolicySpill
+		if len(c.spill) >= spillSize {
+			c.inc(statDropped)
+			c.inc(statSlowDisconnects)
+			c.closeLocked(CloseSlowConsumer, "slow consumer: reconnect and resync")
+			return false
+		}
+		c.inc(statSpilled)
+		c.spill = append(c.spill, msg)
 		return true
-	default:
-		// buffer full => drop (dev/simple)
+	}
+}
+
+// Offer queues msg only if the buffer has room, whatever the overflow
+// policy. A refused frame makes the OnDrain handler run once the buffer
+// has drained, so a replay can stop and pick up where it left off instead
+// of overflowing.
+func (c *Conn) Offer(msg []byte) bool {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if c.closed {
 		return false
 	}
+	if len(c.spill) == 0 {
+		select {
+		case c.send <- msg:
+			c.inc(statQueued)
+			return true
+		default:
+		}
+	}
+	c.offerRefused = true
+	return false
 }
 
+// CloseWithReason stops accepting frames and lets WritePump flush what is
+// queued, then send a close frame carrying code and reason.
+func (c *Conn) CloseWithReason(code int, reason string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.closeLocked(code, reason)
+}
+
+func (c *Conn) closeLocked(code int, reason string) {
+	if c.closed {
+		return
+	}
+	c.closed = true
+	c.closeCode = code
+	c.closeReason = reason
+	c.spill = nil
+	close(c.send)
+}
+
+// Close shuts the connection down immediately.
 func (c *Conn) Close() {
-	c.closeOnce.Do(func() {
-		_ = c.ws.Close()
-		close(c.send)
-	})
+	c.mu.Lock()
+	c.closeLocked(websocket.CloseNormalClosure, "")
+	c.mu.Unlock()
+	_ = c.ws.Close()
+}
+
+// drained moves parked frames into the room a write made and runs the
+// OnDrain handler once the buffer is empty again.
+func (c *Conn) drained() {
+	c.mu.Lock()
+	// only senders holding c.mu fill the buffer, so room seen here stays
+	for len(c.spill) > 0 && !c.closed && len(c.send) < cap(c.send) {
+		c.send <- c.spill[0]
+		c.spill[0] = nil
+		c.spill = c.spill[1:]
+		c.inc(statQueued)
+	}
+	if !c.offerRefused || c.closed || len(c.spill) > 0 || len(c.send) > 0 {
+		c.mu.Unlock()
+		return
+	}
+	c.offerRefused = false
+	fn := c.onDrain
+	c.mu.Unlock()
+
+	if fn != nil {
+		go fn(c)
+	}
 }
 
 // WritePump owns all writes to the websocket.
@@ -55,20 +277,26 @@ func (c *Conn) WritePump() {
 	// ping to keep connection alive
 	ticker := time.NewTicker(30 * time.Second)
 	defer ticker.Stop()
-	defer c.Close()
+	defer func() { _ = c.ws.Close() }()
 
 	for {
 		select {
 		case msg, ok := <-c.send:
 			if !ok {
-				// hub closed channel
-				_ = c.ws.WriteMessage(websocket.CloseMessage, []byte{})
+				// closed: tell the client why so it can reconnect and resync
+				c.mu.Lock()
+				code, reason := c.closeCode, c.closeReason
+				c.mu.Unlock()
+				_ = c.ws.WriteControl(websocket.CloseMessage,
+					websocket.FormatCloseMessage(code, reason),
+					time.Now().Add(time.Second))
 				return
 			}
 			_ = c.ws.SetWriteDeadline(time.Now().Add(10 * time.Second))
 			if err := c.ws.WriteMessage(websocket.TextMessage, msg); err != nil {
 				return
 			}
+			c.drained()
 
 		case <-ticker.C:
 			_ = c.ws.SetWriteDeadline(time.Now().Add(10 * time.Second))
@@ -0,0 +1,96 @@
+package ws
+
+import (
+	"fmt"
+	"testing"
+)
+
+// readFrame takes the next frame off c's buffer, as WritePump would.
+func readFrame(t *testing.T, c *Conn) string {
+	t.Helper()
+	select {
+	case msg := <-c.send:
+		c.drained()
+		return string(msg)
+	default:
+		t.Fatal("no frame queued")
+		return ""
+	}
+}
+
+// A frame that reaches one socket of the recipient counts as delivered, so
+// a sibling socket that had to spill it still gets it once it catches up.
+func TestSpilledSocketGetsDeliveredFrames(t *testing.T) {
+	hub := NewHub()
+	delivered := 0
+	hub.OnDelivered(func(Envelope) { delivered++ })
+
+	fast := NewConn(nil, "anon-b", "", "", PolicySpill)
+	slow := NewConn(nil, "anon-b", "", "", PolicySpill)
+	hub.Register(fast)
+	hub.Register(slow)
+	for i := 0; i < sendBufferSize; i++ {
+		if !slow.Enqueue([]byte(fmt.Sprint("old-", i))) {
+			t.Fatal("filling the buffer refused a frame")
+		}
+	}
+
+	hub.Deliver(Envelope{To: "anon-b", Data: []byte("new"), Receipt: "m1"})
+	if delivered != 1 {
+		t.Fatalf("delivered hook ran %d times, want 1", delivered)
+	}
+	if got := slow.Stats().Spilled; got != 1 {
+		t.Fatalf("slow socket spilled %d frames, want 1", got)
+	}
+	if got := readFrame(t, fast); got != "new" {
+		t.Fatalf("fast socket got %q", got)
+	}
+
+	for i := 0; i < sendBufferSize; i++ {
+		if got, want := readFrame(t, slow), fmt.Sprint("old-", i); got != want {
+			t.Fatalf("slow socket frame %d = %q, want %q", i, got, want)
+		}
+	}
+	if got := readFrame(t, slow); got != "new" {
+		t.Fatalf("slow socket got %q after draining, want the spilled frame", got)
+	}
+}
+
+func TestSpillOverflowDisconnects(t *testing.T) {
+	c := NewConn(nil, "anon-a", "", "", PolicySpill)
+	for i := 0; i < sendBufferSize+spillSize; i++ {
+		if !c.Enqueue([]byte("x")) {
+			t.Fatalf("frame %d refused before the spill was full", i)
+		}
+	}
+	if c.Enqueue([]byte("x")) {
+		t.Fatal("accepted a frame past the spill")
+	}
+	if s := c.Stats(); s.SlowDisconnects != 1 || c.closeCode != CloseSlowConsumer {
+		t.Fatalf("stats %+v, close code %d after overflowing the spill", s, c.closeCode)
+	}
+}
+
+// Offer never overflows: it stops at a full buffer and has the drain
+// handler pick up once the socket caught up.
+func TestOfferWaitsForDrain(t *testing.T) {
+	c := NewConn(nil, "anon-a", "", "", PolicyDisconnect)
+	resumed := make(chan struct{}, 1)
+	c.OnDrain(func(*Conn) { resumed <- struct{}{} })
+
+	for i := 0; i < sendBufferSize; i++ {
+		if !c.Offer([]byte("x")) {
+			t.Fatalf("frame %d refused with room in the buffer", i)
+		}
+	}
+	if c.Offer([]byte("x")) {
+		t.Fatal("offer accepted past a full buffer")
+	}
+	if c.Stats().SlowDisconnects != 0 {
+		t.Fatal("offer triggered the overflow policy")
+	}
+	for i := 0; i < sendBufferSize; i++ {
+		readFrame(t, c)
+	}
+	<-resumed
+}
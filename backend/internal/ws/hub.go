@@ -1,18 +1,60 @@
 package ws
 
-import "sync"
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"log"
+	"sync"
+)
 
 // Hub routes messages by anon id.
 // Minimal, explicit, safe: mutex + map[anon]set(conns).
+// With a Broker, frames also reach sockets held by other hubs.
 type Hub struct {
 	mu    sync.RWMutex
 	conns map[string]map[*Conn]struct{}
+
+	id          string
+	broker      Broker
+	onDelivered func(Envelope)
+
+	stats counters // totals across every Conn ever registered here
 }
 
+// hubIDBytes is the size of a hub's random id, sent hex encoded as every
+// envelope's Origin.
+const hubIDBytes = 8
+
+// NewHub returns a hub that only knows its own sockets.
 func NewHub() *Hub {
-	return &Hub{
-		conns: make(map[string]map[*Conn]struct{}),
+	return NewHubWithBroker(nil)
+}
+
+// NewHubWithBroker returns a hub that publishes every frame through b and
+// delivers frames published by other hubs. A nil b behaves like NewHub.
+func NewHubWithBroker(b Broker) *Hub {
+	idBytes := make([]byte, hubIDBytes)
+	_, _ = rand.Read(idBytes)
+
+	h := &Hub{
+		conns:  make(map[string]map[*Conn]struct{}),
+		id:     hex.EncodeToString(idBytes),
+		broker: b,
+	}
+	if b != nil {
+		b.Subscribe(h.receive)
 	}
+	return h
+}
+
+// OnDelivered sets the hook run when an envelope with a Receipt reaches at
+// least one socket on this hub. A recipient with sockets on several hubs
+// makes each of them run it, so fn must be idempotent. Set it before
+// serving traffic.
+func (h *Hub) OnDelivered(fn func(Envelope)) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	h.onDelivered = fn
 }
 
 func (h *Hub) Register(c *Conn) {
@@ -25,6 +67,15 @@ func (h *Hub) Register(c *Conn) {
 		h.conns[c.Anon()] = set
 	}
 	set[c] = struct{}{}
+
+	c.mu.Lock()
+	c.hub = &h.stats
+	c.mu.Unlock()
+}
+
+// Stats returns frame counters summed over all connections of this hub.
+func (h *Hub) Stats() Stats {
+	return h.stats.snapshot()
 }
 
 func (h *Hub) Unregister(c *Conn) {
@@ -42,19 +93,83 @@ func (h *Hub) Unregister(c *Conn) {
 	c.Close()
 }
 
-// SendTo delivers to ALL active sockets registered under anon.
+// SendTo delivers to the active sockets registered under anon that carry the
+// conversation with peer: multiplexed sockets and sockets bound to peer.
 // If a client is slow, messages may drop (by design for dev).
-func (h *Hub) SendTo(anon string, msg []byte) {
+// Returns how many local sockets accepted the message.
+func (h *Hub) SendTo(anon, peer string, msg []byte) int {
+	return h.Deliver(Envelope{To: anon, Peer: peer, Data: msg})
+}
+
+// Deliver hands env to local sockets and publishes it for other hubs.
+// Returns how many local sockets accepted it.
+func (h *Hub) Deliver(env Envelope) int {
+	env.Origin = h.id
+	sent := h.deliverLocal(env)
+
+	if h.broker != nil {
+		if err := h.broker.Publish(env); err != nil {
+			log.Printf("ws hub: publish to %s failed: %v", env.To, err)
+		}
+	}
+	return sent
+}
+
+// Disconnect closes every socket of anon, on this hub and on the hubs
+// reached through the broker, with code and reason. Returns how many local
+// sockets were closed.
+func (h *Hub) Disconnect(anon string, code int, reason string) int {
+	return h.Deliver(Envelope{To: anon, Close: code, Reason: reason})
+}
+
+// DisconnectSession is Disconnect limited to the sockets opened from one
+// session of anon.
+func (h *Hub) DisconnectSession(anon, sessionID string, code int, reason string) int {
+	return h.Deliver(Envelope{To: anon, Session: sessionID, Close: code, Reason: reason})
+}
+
+// receive handles envelopes from the broker; our own come back too.
+func (h *Hub) receive(env Envelope) {
+	if env.Origin == h.id {
+		return
+	}
+	h.deliverLocal(env)
+}
+
+func (h *Hub) deliverLocal(env Envelope) int {
 	h.mu.RLock()
-	set := h.conns[anon]
+	set := h.conns[env.To]
 	// copy to avoid holding lock during sends
 	list := make([]*Conn, 0, len(set))
 	for c := range set {
-		list = append(list, c)
+		switch {
+		case env.Close != 0:
+			if env.Session == "" || c.Session() == env.Session {
+				list = append(list, c)
+			}
+		case c.Accepts(env.Peer):
+			list = append(list, c)
+		}
 	}
+	onDelivered := h.onDelivered
 	h.mu.RUnlock()
 
+	if env.Close != 0 {
+		for _, c := range list {
+			c.CloseWithReason(env.Close, env.Reason)
+		}
+		return len(list)
+	}
+
+	sent := 0
 	for _, c := range list {
-		c.Enqueue(msg)
+		if c.Enqueue(env.Data) {
+			sent++
+		}
+	}
+
+	if sent > 0 && env.Receipt != "" && onDelivered != nil {
+		onDelivered(env)
 	}
+	return sent
 }
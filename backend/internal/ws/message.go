@@ -1,19 +1,53 @@
 package ws
 
-import "time"
+// Frame types shared by both directions of the chat socket.
+const (
+	TypeMsg        = "msg"        // chat message
+	TypeCiphertext = "ciphertext" // E2E chat message, relayed and stored opaquely
+	TypeAck        = "ack"        // server → sender: message stored, carries server id
+	TypeDelivered  = "delivered"  // server → sender: message reached a peer socket
+	TypeRead       = "read"       // client → server, then server → sender: message seen
+	TypeTyping     = "typing"     // ephemeral, relayed to the peer, never stored
+	TypeError      = "error"      // server → client: frame rejected
+)
+
+// Error codes carried by "error" frames.
+const (
+	ErrCodeNotTrusted   = "not_trusted"
+	ErrCodeBadRecipient = "bad_recipient"
+	ErrCodeTooLarge     = "too_large" // the encoded message exceeds ws.MaxEnvelopeSize
+)
+
+// Typing states.
+const (
+	TypingStart = "start"
+	TypingStop  = "stop"
+)
 
 // Client → Server
 type ClientMessage struct {
-	Type     string    `json:"type"`      // "msg"
-	ClientID string    `json:"client_id"` // for de-dupe
-	Text     string    `json:"text"`
-	SentAt   time.Time `json:"sent_at"`
+	Type       string   `json:"type"`                // "msg" | "ciphertext" | "read" | "typing"
+	To         string   `json:"to,omitempty"`        // peer anon id; required on multiplexed sockets
+	ClientID   string   `json:"client_id,omitempty"` // for de-dupe of "msg"/"ciphertext" retries
+	Text       string   `json:"text,omitempty"`
+	Ciphertext string   `json:"ciphertext,omitempty"` // base64 E2E payload of a "ciphertext" frame
+	IDs        []string `json:"ids,omitempty"`        // "read": server message ids seen
+	State      string   `json:"state,omitempty"`      // "typing": "start" | "stop"
+	SentAt     string   `json:"sent_at,omitempty"`
 }
 
 // Server → Client
 type ServerMessage struct {
-	Type   string    `json:"type"` // "msg"
-	From   string    `json:"from"` // anon id
-	Text   string    `json:"text"`
-	SentAt time.Time `json:"sent_at"`
+	Type       string   `json:"type"`
+	ID         string   `json:"id,omitempty"`        // server message id ("msg", "ciphertext", "ack")
+	ClientID   string   `json:"client_id,omitempty"` // echoed to the sender ("msg", "ciphertext", "ack")
+	From       string   `json:"from,omitempty"`      // anon id
+	To         string   `json:"to,omitempty"`        // anon id
+	Text       string   `json:"text,omitempty"`
+	Ciphertext string   `json:"ciphertext,omitempty"` // base64 E2E payload of a "ciphertext" frame
+	IDs        []string `json:"ids,omitempty"`        // "delivered", "read"
+	State      string   `json:"state,omitempty"`      // "typing"
+	SentAt     string   `json:"sent_at,omitempty"`
+	At         string   `json:"at,omitempty"` // when delivered/read happened
+	Error      string   `json:"error,omitempty"`
 }
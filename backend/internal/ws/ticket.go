@@ -0,0 +1,42 @@
+package ws
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"time"
+)
+
+// Ticket authorizes one websocket upgrade. An empty PeerAnon asks for a
+// multiplexed socket covering all of MyAnon's trusted peers. Tickets travel
+// as signed tokens (see security.SignWSTicket); this is their decoded form.
+// The store records redeemed ids (store.RedeemWSTicket) so that a ticket
+// opens one socket across every API instance.
+type Ticket struct {
+	ID        string
+	MyAnon    string
+	PeerAnon  string
+	SessionID string // session that asked for the ticket; revoking it closes the socket
+	Expires   time.Time
+}
+
+// RandomToken returns a fresh ticket id.
+func RandomToken() string {
+	b := make([]byte, 16)
+	_, _ = rand.Read(b)
+	return "wst_" + hex.EncodeToString(b)
+}
+
+func (t *Ticket) Multiplexed() bool { return t.PeerAnon == "" }
+
+func (t *Ticket) RoomID() string {
+	return RoomID(t.MyAnon, t.PeerAnon)
+}
+
+// RoomID is the stable id for the conversation between a and b,
+// independent of who is asking.
+func RoomID(a, b string) string {
+	if a < b {
+		return a + ":" + b
+	}
+	return b + ":" + a
+}
@@ -23,8 +23,6 @@ import {
     banAdminUser,
     clearAdminToken,
     deleteAdminPost,
-    deleteAuditLogs,
-    clearAuditLogs,
     fetchAdminAbuse,
     fetchAdminAudit,
     fetchAdminHealth,
@@ -35,12 +33,14 @@ import {
     fetchAdminUsers,
     getAdminToken,
     revokeSession,
+    verifyAdminAudit,
     type AbuseReport,
     type AdminPost,
     type AdminSession,
     type AdminStats,
     type AdminUser,
     type AuditLog,
+    type AuditVerification,
     type TrustLink,
 } from "../services/adminApi";
 
@@ -104,10 +104,9 @@ export default function Admin() {
     // Session selection state
     const [selectedSessions, setSelectedSessions] = useState<Set<string>>(new Set());
 
-    // Audit log selection state
-    const [selectedAuditLogs, setSelectedAuditLogs] = useState<Set<string>>(new Set());
-    const [deleteAuditModalOpen, setDeleteAuditModalOpen] = useState(false);
-    const [clearAuditModalOpen, setClearAuditModalOpen] = useState(false);
+    // Audit chain verification state
+    const [auditVerification, setAuditVerification] = useState<AuditVerification | null>(null);
+    const [isVerifyingAudit, setIsVerifyingAudit] = useState(false);
 
     // Post detail modal state
     const [postDetailModalOpen, setPostDetailModalOpen] = useState(false);
@@ -333,82 +332,26 @@ export default function Admin() {
     };
 
     // Audit log handlers
-    const handleSelectAuditLog = (id: string) => {
-        if (!id || !id.trim()) return;
-        setSelectedAuditLogs((prev) => {
-            const next = new Set(prev);
-            if (next.has(id)) {
-                next.delete(id);
-            } else {
-                next.add(id);
-            }
-            return next;
-        });
-    };
-
-    const handleSelectAllAuditLogs = (isSelected: boolean, filteredLogs: AuditLog[]) => {
-        const selectableIDs = filteredLogs
-            .map((log) => (log.id || "").trim())
-            .filter((id) => id.length > 0);
-
-        if (isSelected) {
-            // Add all filtered log IDs to selection
-            setSelectedAuditLogs((prev) => {
-                const next = new Set(prev);
-                selectableIDs.forEach((id) => next.add(id));
-                return next;
-            });
-        } else {
-            // Remove all filtered log IDs from selection
-            setSelectedAuditLogs((prev) => {
-                const next = new Set(prev);
-                selectableIDs.forEach((id) => next.delete(id));
-                return next;
-            });
-        }
-    };
-
-    const handleDeleteAuditLogsClick = () => {
-        if (selectedAuditLogs.size === 0) return;
-        setDeleteAuditModalOpen(true);
-    };
-
-    const handleConfirmDeleteAuditLogs = async () => {
-        if (selectedAuditLogs.size === 0) return;
-
+    const handleVerifyAudit = async () => {
+        setIsVerifyingAudit(true);
         try {
-            await deleteAuditLogs(Array.from(selectedAuditLogs));
-            setAuditLogs((prev) => prev.filter((log) => !selectedAuditLogs.has(log.id)));
-            setSelectedAuditLogs(new Set());
-            setDeleteAuditModalOpen(false);
+            setAuditVerification(await verifyAdminAudit());
         } catch (err) {
-            const msg = err instanceof Error ? err.message : "Failed to delete audit logs";
+            const msg = err instanceof Error ? err.message : "Failed to verify audit log";
             setError(msg);
+        } finally {
+            setIsVerifyingAudit(false);
         }
     };
 
-    const handleClearAuditLogsClick = () => {
-        setClearAuditModalOpen(true);
-    };
-
-    const handleConfirmClearAuditLogs = async () => {
-        try {
-            await clearAuditLogs();
-            setAuditLogs([]);
-            setSelectedAuditLogs(new Set());
-            setClearAuditModalOpen(false);
-        } catch (err) {
-            const msg = err instanceof Error ? err.message : "Failed to clear audit logs";
-            setError(msg);
-        }
-    };
+    const auditActor = (log: AuditLog) => log.actor_email || log.actor_id || log.anon_id || "";
 
     const matchesAuditFilter = (log: AuditLog, rawFilter: string) => {
         const searchTerm = rawFilter.trim().toLowerCase();
         if (!searchTerm) return true;
 
         const action = (log.action || "").toLowerCase();
-        const actor = (log.anon_id || "").toLowerCase();
+        const actor = `${auditActor(log)} ${log.anon_id || ""}`.toLowerCase();
         const details = (log.details || "").toLowerCase();
 
         return (
@@ -432,13 +375,19 @@ export default function Admin() {
 
         if (format === 'csv') {
             // CSV format
-            const headers = ['ID', 'Action', 'Actor', 'Details', 'Timestamp'];
+            const headers = ['Seq', 'ID', 'Action', 'Actor', 'User', 'Target', 'IP', 'Request ID', 'Details', 'Timestamp', 'Hash'];
             const rows = logsToExport.map(log => [
+                String(log.seq ?? ''),
                 log.id || '',
                 log.action || '',
+                auditActor(log),
                 log.anon_id || '',
+                log.target || '',
+                log.ip || '',
+                log.request_id || '',
                 (log.details || '').replace(/"/g, '""'), // Escape quotes and handle null/undefined
-                log.timestamp || ''
+                log.timestamp || '',
+                log.hash || ''
             ]);
             content = [
                 headers.join(','),
@@ -1335,6 +1284,13 @@ export default function Admin() {
                                 <span className="font-mono">{r.rate_status}</span>
                             ),
                         },
+                        {
+                            key: "rate_limit_hits",
+                            label: "Rate Limited (24h)",
+                            render: (r: AbuseReport) => (
+                                <span className="font-mono">{r.rate_limit_hits ?? 0}</span>
+                            ),
+                        },
                         {
                             key: "reported_post",
                             label: "Reported Post",
@@ -1410,7 +1366,6 @@ export default function Admin() {
 
     const renderAudit = () => {
         const filteredAuditLogs = auditLogs.filter((log) => matchesAuditFilter(log, auditFilter));
-        const selectableAuditLogs = filteredAuditLogs.filter((log) => Boolean(log.id && log.id.trim()));
 
         return (
         <div className="space-y-6">
@@ -1448,30 +1403,33 @@ export default function Admin() {
                         <ArrowDownTrayIcon className="h-4 w-4" />
                         Export JSON
                     </button>
-                    {selectedAuditLogs.size > 0 && (
-                        <button
-                            type="button"
-                            onClick={handleDeleteAuditLogsClick}
-                            className="rounded-xl px-4 py-2 text-sm font-mono border border-red-500/30
-                                bg-red-500/10 text-red-800 dark:text-red-300 
-                                hover:bg-red-500/20 transition-colors"
-                        >
-                            Delete Selected ({selectedAuditLogs.size})
-                        </button>
-                    )}
                     <button
                         type="button"
-                        onClick={handleClearAuditLogsClick}
-                        disabled={auditLogs.length === 0}
-                        className="rounded-xl px-4 py-2 text-sm font-mono border border-red-500/30
-                            bg-red-500/10 text-red-800 dark:text-red-300 
-                            hover:bg-red-500/20 disabled:opacity-50 disabled:cursor-not-allowed transition-colors"
+                        onClick={handleVerifyAudit}
+                        disabled={isVerifyingAudit}
+                        className="rounded-xl px-4 py-2 text-sm font-mono border border-emerald-500/30 dark:border-green-500/30
+                            bg-emerald-500/10 dark:bg-green-500/10 text-emerald-800 dark:text-green-300 
+                            hover:bg-emerald-500/20 dark:hover:bg-green-500/20
+                            disabled:opacity-50 disabled:cursor-not-allowed transition-colors"
                     >
-                        Clear All
+                        {isVerifyingAudit ? "Verifying..." : "Verify Chain"}
                     </button>
                 </div>
             </div>
 
+            {auditVerification && (
+                <div
+                    className={`rounded-xl border px-4 py-3 text-sm font-mono ${auditVerification.ok
+                        ? "border-emerald-500/30 bg-emerald-500/10 text-emerald-800 dark:text-green-300"
+                        : "border-red-500/30 bg-red-500/10 text-red-800 dark:text-red-300"}`}
+                >
+                    {auditVerification.ok
+                        ? `Chain intact: ${auditVerification.checked} entries verified` +
+                          (auditVerification.head_hash ? ` (head ${auditVerification.head_hash.slice(0, 12)}…)` : "")
+                        : `Chain broken at seq ${auditVerification.broken_at}: ${auditVerification.reason}`}
+                </div>
+            )}
+
             {/* Search Bar */}
             <div className="relative">
                 <div className="relative">
@@ -1506,12 +1464,8 @@ export default function Admin() {
                 )}
             </div>
 
-            <Panel description="Tracks admin moderation actions for accountability">
+            <Panel description="Append-only record of admin actions; entries expire by retention policy only">
                 <DataTable
-                    selectable
-                    selectedItems={selectedAuditLogs}
-                    onSelectItem={handleSelectAuditLog}
-                    onSelectAll={(isSelected) => handleSelectAllAuditLogs(isSelected, selectableAuditLogs)}
                     columns={[
                         {
                             key: "action",
@@ -1521,10 +1475,20 @@ export default function Admin() {
                             ),
                         },
                         {
-                            key: "anon_id",
+                            key: "actor",
                             label: "Actor",
                             render: (log: AuditLog) => (
-                                <span className="font-mono">{log.anon_id}</span>
+                                <div className="font-mono">
+                                    <div>{auditActor(log)}</div>
+                                    {log.ip && <div className="text-xs opacity-70">{log.ip}</div>}
+                                </div>
+                            ),
+                        },
+                        {
+                            key: "target",
+                            label: "Target",
+                            render: (log: AuditLog) => (
+                                <span className="font-mono text-xs">{log.target || log.anon_id}</span>
                             ),
                         },
                         {
@@ -1850,30 +1814,6 @@ export default function Admin() {
                 danger={true}
             />
 
-            {/* Delete Audit Logs Confirmation Modal */}
-            <ConfirmationModal
-                open={deleteAuditModalOpen}
-                onClose={() => setDeleteAuditModalOpen(false)}
-                onConfirm={handleConfirmDeleteAuditLogs}
-                title="Delete Audit Logs"
-                message={`Are you sure you want to delete ${selectedAuditLogs.size} audit log${selectedAuditLogs.size !== 1 ? 's' : ''}? This action cannot be undone.`}
-                confirmText={`Delete ${selectedAuditLogs.size} Log${selectedAuditLogs.size !== 1 ? 's' : ''}`}
-                cancelText="Cancel"
-                danger={true}
-            />
-
-            {/* Clear All Audit Logs Confirmation Modal */}
-            <ConfirmationModal
-                open={clearAuditModalOpen}
-                onClose={() => setClearAuditModalOpen(false)}
-                onConfirm={handleConfirmClearAuditLogs}
-                title="Clear All Audit Logs"
-                message="Are you sure you want to clear ALL audit logs? This will permanently delete all audit trail records and cannot be undone."
-                confirmText="Clear All Logs"
-                cancelText="Cancel"
-                danger={true}
-            />
-
             {/* Post Detail Modal */}
             <PostDetailModal
                 open={postDetailModalOpen}
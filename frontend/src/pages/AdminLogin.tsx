@@ -2,7 +2,7 @@ import { useEffect, useState } from "react";
 import type { FormEvent } from "react";
 import { useNavigate } from "react-router-dom";
 import AdminShell from "../components/AdminShell";
-import { getAdminToken, loginAdmin, setAdminToken } from "../services/adminApi";
+import { getAdminToken, loginAdmin, setAdminToken, verifyAdminTotp } from "../services/adminApi";
 
 const card =
     "rounded-2xl border border-emerald-500/20 dark:border-green-500/20 bg-white/80 dark:bg-black/55 backdrop-blur p-4 sm:p-5";
@@ -11,6 +11,8 @@ export default function AdminLogin() {
     const navigate = useNavigate();
     const [email, setEmail] = useState("");
     const [password, setPassword] = useState("");
+    const [mfaToken, setMfaToken] = useState<string | null>(null);
+    const [code, setCode] = useState("");
     const [isLoading, setIsLoading] = useState(false);
     const [error, setError] = useState<string | null>(null);
 
@@ -25,7 +27,14 @@ export default function AdminLogin() {
         setError(null);
         setIsLoading(true);
         try {
-            const response = await loginAdmin(email.trim(), password);
+            const response = mfaToken
+                ? await verifyAdminTotp(mfaToken, code)
+                : await loginAdmin(email.trim(), password);
+            if (response.mfa_required && response.mfa_token) {
+                setMfaToken(response.mfa_token);
+                return;
+            }
+            if (!response.token) throw new Error("Login failed");
             setAdminToken(response.token);
             navigate("/admin/panel", { replace: true });
         } catch (err) {
@@ -48,31 +57,50 @@ export default function AdminLogin() {
 
                 <div className={card}>
                     <form onSubmit={handleSubmit} className="space-y-3.5">
-                        <label className="block">
-                            <span className="text-xs font-mono text-slate-600 dark:text-green-300/70">Email</span>
-                            <input
-                                type="email"
-                                value={email}
-                                onChange={(e) => setEmail(e.target.value)}
-                                placeholder="papa@gmail.com"
-                                className="mt-1.5 w-full rounded-xl bg-white/80 dark:bg-white/10 border border-emerald-500/25 px-3 py-2 text-sm text-slate-900 dark:text-green-100 placeholder:text-slate-400 dark:placeholder:text-green-300/45 outline-none focus:ring-2 focus:ring-emerald-500/40 dark:focus:ring-emerald-500/30"
-                                autoComplete="username"
-                                required
-                            />
-                        </label>
+                        {mfaToken ? (
+                            <label className="block">
+                                <span className="text-xs font-mono text-slate-600 dark:text-green-300/70">Authenticator code or recovery code</span>
+                                <input
+                                    type="text"
+                                    value={code}
+                                    onChange={(e) => setCode(e.target.value)}
+                                    placeholder="123456"
+                                    className="mt-1.5 w-full rounded-xl bg-white/80 dark:bg-white/10 border border-emerald-500/25 px-3 py-2 text-sm text-slate-900 dark:text-green-100 placeholder:text-slate-400 dark:placeholder:text-green-300/45 outline-none focus:ring-2 focus:ring-emerald-500/40 dark:focus:ring-emerald-500/30"
+                                    autoComplete="one-time-code"
+                                    inputMode="text"
+                                    autoFocus
+                                    required
+                                />
+                            </label>
+                        ) : (
+                            <>
+                                <label className="block">
+                                    <span className="text-xs font-mono text-slate-600 dark:text-green-300/70">Email</span>
+                                    <input
+                                        type="email"
+                                        value={email}
+                                        onChange={(e) => setEmail(e.target.value)}
+                                        placeholder="papa@gmail.com"
+                                        className="mt-1.5 w-full rounded-xl bg-white/80 dark:bg-white/10 border border-emerald-500/25 px-3 py-2 text-sm text-slate-900 dark:text-green-100 placeholder:text-slate-400 dark:placeholder:text-green-300/45 outline-none focus:ring-2 focus:ring-emerald-500/40 dark:focus:ring-emerald-500/30"
+                                        autoComplete="username"
+                                        required
+                                    />
+                                </label>
 
-                        <label className="block">
-                            <span className="text-xs font-mono text-slate-600 dark:text-green-300/70">Password</span>
-                            <input
-                                type="password"
-                                value={password}
-                                onChange={(e) => setPassword(e.target.value)}
-                                placeholder="papa@"
-                                className="mt-1.5 w-full rounded-xl bg-white/80 dark:bg-white/10 border border-emerald-500/25 px-3 py-2 text-sm text-slate-900 dark:text-green-100 placeholder:text-slate-400 dark:placeholder:text-green-300/45 outline-none focus:ring-2 focus:ring-emerald-500/40 dark:focus:ring-emerald-500/30"
-                                autoComplete="current-password"
-                                required
-                            />
-                        </label>
+                                <label className="block">
+                                    <span className="text-xs font-mono text-slate-600 dark:text-green-300/70">Password</span>
+                                    <input
+                                        type="password"
+                                        value={password}
+                                        onChange={(e) => setPassword(e.target.value)}
+                                        placeholder="papa@"
+                                        className="mt-1.5 w-full rounded-xl bg-white/80 dark:bg-white/10 border border-emerald-500/25 px-3 py-2 text-sm text-slate-900 dark:text-green-100 placeholder:text-slate-400 dark:placeholder:text-green-300/45 outline-none focus:ring-2 focus:ring-emerald-500/40 dark:focus:ring-emerald-500/30"
+                                        autoComplete="current-password"
+                                        required
+                                    />
+                                </label>
+                            </>
+                        )}
 
                         {error && (
                             <div className="text-sm text-red-400 font-mono">{error}</div>
@@ -83,7 +111,7 @@ export default function AdminLogin() {
                             disabled={isLoading}
                             className="w-full rounded-xl px-4 py-2 text-sm font-mono border border-emerald-500/40 bg-emerald-500/12 text-emerald-800 dark:text-emerald-200 hover:bg-emerald-500/20 disabled:opacity-60 disabled:cursor-not-allowed"
                         >
-                            {isLoading ? "Signing in..." : "Sign in"}
+                            {isLoading ? "Signing in..." : mfaToken ? "Verify" : "Sign in"}
                         </button>
                     </form>
                 </div>
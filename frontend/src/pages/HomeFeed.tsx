@@ -4,7 +4,7 @@ import TrustRequestModal from "../components/TrustRequestModal";
 import { useTrust } from "../contexts/TrustContext";
 import { useDialog } from "../contexts/DialogContext";
 import { ensureThreadForPeer } from "../services/thread";
-import { createPost, fetchFeed, deletePost, likePost, dislikePost, getRemainingPosts, createComment, getComments, deleteComment, likeComment, dislikeComment, createCommentReply, getCommentReplies, deleteCommentReply, likeCommentReply, dislikeCommentReply, searchPosts, reportPost } from "../services/postsApi";
+import { createPost, fetchFeed, deletePost, likePost, dislikePost, getRemainingPosts, postsLeft, createComment, getComments, deleteComment, likeComment, dislikeComment, createCommentReply, getCommentReplies, deleteCommentReply, likeCommentReply, dislikeCommentReply, searchPosts, reportPost } from "../services/postsApi";
 import type { ApiPost, ApiComment, ApiCommentReply, ApiSearchResult } from "../services/postsApi";
 import { getMyAnonId } from "../services/session";
 
@@ -54,7 +54,7 @@ export default function HomeFeed() {
     const [postText, setPostText] = useState("");
     const [isSubmitting, setIsSubmitting] = useState(false);
     const [isLoading, setIsLoading] = useState(true);
-    const [postsLeftToday, setPostsLeftToday] = useState(3);
+    const [postsLeftToday, setPostsLeftToday] = useState<number | null>(3);
     const [myAnonId, setMyAnonId] = useState<string | null>(null);
 
     // Search state
@@ -115,7 +115,7 @@ export default function HomeFeed() {
                     getRemainingPosts()
                 ]);
                 setPosts(feedResult.posts);
-                setPostsLeftToday(remainingResult.remaining);
+                setPostsLeftToday(postsLeft(remainingResult.remaining));
 
                 // Load comments for all posts to show accurate comment counts
                 const commentsMap: Record<string, ApiComment[]> = {};
@@ -151,8 +151,8 @@ export default function HomeFeed() {
             return;
         }
 
-        if (postsLeftToday <= 0) {
-            await showAlert({ title: "Posting Limit", message: "Daily post limit reached." });
+        if (postsLeftToday !== null && postsLeftToday <= 0) {
+            await showAlert({ title: "Posting Limit", message: "Post limit reached." });
             return;
         }
 
@@ -164,7 +164,7 @@ export default function HomeFeed() {
             // Initialize empty comments for the new post
             setComments({ [response.post.id]: [], ...comments });
             setPostText("");
-            setPostsLeftToday(response.posts_remaining);
+            setPostsLeftToday(postsLeft(response.posts_remaining));
         } catch (err) {
             console.error("Post creation failed:", err);
             const errorMessage = err instanceof Error ? err.message : "Error creating post";
@@ -880,7 +880,7 @@ export default function HomeFeed() {
                 </button>
             ) : (
                 <div className="rounded-full border border-emerald-600/30 dark:border-green-500/30 bg-white/60 dark:bg-black/20 px-3 py-1 text-sm font-mono text-emerald-800 dark:text-green-300">
-                    {postsLeftToday} posts left
+                    {postsLeftToday !== null ? `${postsLeftToday} posts left` : "Unlimited posts"}
                 </div>
             )}
             </div>
@@ -931,11 +931,11 @@ export default function HomeFeed() {
 
             <div className="flex flex-col sm:flex-row sm:items-center sm:justify-between gap-2">
             <span className="text-xs font-mono text-slate-600 dark:text-green-300/70">
-                {postText.length}/280 • {postsLeftToday} posts left today
+                {postText.length}/280{postsLeftToday !== null ? ` • ${postsLeftToday} posts left` : ""}
             </span>
 
             <button
-                disabled={isSubmitting || postsLeftToday <= 0 || !postText.trim()}
+                disabled={isSubmitting || (postsLeftToday !== null && postsLeftToday <= 0) || !postText.trim()}
                 type="button"
                 onClick={handlePostSubmit}
                 className="w-full sm:w-auto rounded-xl px-4 py-2 text-sm font-mono border border-emerald-500/30 dark:border-green-500/30
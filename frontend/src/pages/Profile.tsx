@@ -29,6 +29,7 @@ import {
   likeComment,
   likeCommentReply,
   likePost,
+  postsLeft,
   type ApiComment,
   type ApiCommentReply,
 } from "../services/postsApi";
@@ -177,7 +178,7 @@ export default function Profile() {
           setIsRegionPublic(!!p.is_region_public);
           const remainingRes = await getRemainingPosts();
           if (!active) return;
-          setPostsLeftToday(remainingRes.remaining);
+          setPostsLeftToday(postsLeft(remainingRes.remaining));
           const postRes = await getProfilePosts(p.anon_id);
           if (!active) return;
           setPosts(postRes.posts || []);
@@ -368,7 +369,7 @@ export default function Profile() {
     const text = newPostText.trim();
     if (!text) return;
     if (postsLeftToday !== null && postsLeftToday <= 0) {
-      window.alert("Daily post limit reached.");
+      window.alert("Post limit reached.");
       return;
     }
     setPosting(true);
@@ -376,7 +377,7 @@ export default function Profile() {
       const res = await createPost(text);
       setPosts((prev) => [res.post, ...prev]);
       setNewPostText("");
-      setPostsLeftToday(res.posts_remaining);
+      setPostsLeftToday(postsLeft(res.posts_remaining));
       setMyProfile((prev) => (prev ? { ...prev, posts_count: prev.posts_count + 1 } : prev));
     } catch {
       window.alert("Failed to create post.");
@@ -843,7 +844,7 @@ export default function Profile() {
             <div className="mt-2 flex items-center justify-between gap-3">
               <span className="text-xs font-mono text-slate-500 dark:text-green-300/60">
                 {newPostText.trim().length}/500
-                {postsLeftToday !== null ? ` • ${postsLeftToday} posts left today` : ""}
+                {postsLeftToday !== null ? ` • ${postsLeftToday} posts left` : ""}
               </span>
               <button
                 onClick={onCreatePost}
@@ -55,6 +55,16 @@ export type AbuseReport = {
         last_reported_at: string;
         reason?: string;
     } | null;
+    rate_limit_hits: number;
+};
+
+export type RateLimitBucket = {
+    policy: string;
+    key: string;
+    tokens: number;
+    updated_at: string;
+    limited: number;
+    last_limited_at?: string;
 };
 
 export type AdminPostDetail = {
@@ -68,14 +78,43 @@ export type AdminPostDetail = {
 
 export type AuditLog = {
     id: string;
+    seq: number;
     action: string;
     anon_id: string;
+    actor_id?: string;
+    actor_email?: string;
+    actor_role?: AdminRole;
+    ip?: string;
+    request_id?: string;
+    target?: string;
+    before?: unknown;
+    after?: unknown;
     details: string;
     timestamp: string;
+    prev_hash: string;
+    hash: string;
+};
+
+export type AuditVerification = {
+    ok: boolean;
+    checked: number;
+    legacy: number;
+    first_seq?: number;
+    last_seq?: number;
+    head_hash?: string;
+    broken_at?: number;
+    reason?: string;
 };
 
+export type AdminRole = "support" | "moderator" | "superadmin";
+
 export type AdminLoginResponse = {
-    token: string;
+    token?: string;
+    email: string;
+    role?: AdminRole;
+    totp_enabled: boolean;
+    mfa_required?: boolean;
+    mfa_token?: string;
 };
 
 export function getAdminToken(): string | null {
@@ -97,6 +136,19 @@ export async function loginAdmin(email: string, password: string) {
     }, { auth: false });
 }
 
+// verifyAdminTotp completes a login that returned mfa_required, using
+// either the current authenticator code or a recovery code.
+export async function verifyAdminTotp(mfaToken: string, code: string) {
+    const trimmed = code.trim();
+    const body = trimmed.includes("-")
+        ? { mfa_token: mfaToken, recovery_code: trimmed }
+        : { mfa_token: mfaToken, code: trimmed };
+    return apiFetch<AdminLoginResponse>("/admin/login/totp", {
+        method: "POST",
+        body: JSON.stringify(body),
+    }, { auth: false });
+}
+
 async function adminFetch<T>(path: string, options: RequestInit = {}): Promise<T> {
     const token = getAdminToken();
     if (!token) throw new Error("Admin session required");
@@ -109,6 +161,17 @@ async function adminFetch<T>(path: string, options: RequestInit = {}): Promise<T
     return apiFetch<T>(`/admin${path}`, { ...options, headers }, { auth: false });
 }
 
+export async function enrollAdminTotp() {
+    return adminFetch<{ secret: string; otpauth_uri: string }>("/totp/enroll", { method: "POST" });
+}
+
+export async function confirmAdminTotp(code: string) {
+    return adminFetch<{ status: string; recovery_codes: string[] }>("/totp/confirm", {
+        method: "POST",
+        body: JSON.stringify({ code: code.trim() }),
+    });
+}
+
 export async function fetchAdminPosts() {
     return adminFetch<{ posts: AdminPost[]; total: number }>("/posts");
 }
@@ -144,7 +207,12 @@ export async function fetchAdminTrust() {
 }
 
 export async function fetchAdminAbuse() {
-    return adminFetch<{ abuse_reports: AbuseReport[]; total_users: number; warning_count: number }>("/abuse");
+    return adminFetch<{
+        abuse_reports: AbuseReport[];
+        rate_limited: RateLimitBucket[];
+        total_users: number;
+        warning_count: number;
+    }>("/abuse");
 }
 
 export async function fetchAdminAudit() {
@@ -173,18 +241,8 @@ export async function fetchUserSessions(anonID: string) {
     return adminFetch<{ sessions: AdminSession[]; total: number }>(`/sessions/user?anon_id=${anonID}`);
 }
 
-export async function deleteAuditLogs(ids: string[]) {
-    return adminFetch<{ status: string; message: string; count: number }>("/audit/delete-batch", {
-        method: "POST",
-        body: JSON.stringify({ ids }),
-    });
-}
-
-export async function clearAuditLogs() {
-    return adminFetch<{ status: string; message: string }>("/audit/clear", {
-        method: "POST",
-        body: JSON.stringify({}),
-    });
+export async function verifyAdminAudit() {
+    return adminFetch<AuditVerification>("/audit/verify");
 }
 
 export async function fetchAdminPostDetail(postId: string) {
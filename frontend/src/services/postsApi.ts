@@ -16,8 +16,34 @@ export type ApiPostResponse = {
     posts: ApiPost[];
 };
 
+export type QuotaWindow = {
+    window: "hour" | "day" | "week";
+    limit: number;
+    used: number;
+    remaining: number;
+    reset_at: string;
+};
+
+export type PostingQuota = {
+    unlimited: boolean;
+    remaining: number; // -1 when unlimited
+    reset_at?: string;
+    windows: QuotaWindow[];
+};
+
+export type PostingQuotasResponse = {
+    remaining: number; // posts; -1 when unlimited
+    tier: string;
+    quotas: Record<"posts" | "comments" | "replies", PostingQuota>;
+};
+
+// postsLeft is null when posting is unlimited.
+export function postsLeft(remaining: number): number | null {
+    return remaining < 0 ? null : remaining;
+}
+
 export async function createPost(text: string) {
-    return apiFetch<{ post: ApiPost; posts_remaining: number }>("/posts/create", {
+    return apiFetch<{ post: ApiPost; posts_remaining: number; quota: PostingQuota }>("/posts/create", {
         method: "POST",
         body: JSON.stringify({ text }),
     });
@@ -28,7 +54,7 @@ export async function fetchFeed() {
 }
 
 export async function getRemainingPosts() {
-    return apiFetch<{ remaining: number }>("/posts/remaining");
+    return apiFetch<PostingQuotasResponse>("/posts/remaining");
 }
 
 export async function deletePost(postId: string) {
@@ -2,14 +2,23 @@ import { apiFetch, getSessionToken, setSessionToken } from "./api";
 import { storage } from "./storage";
 
 type DeviceChallengeResp = { nonce: string; expires_in_sec: number };
-type BootstrapResp = { token: string; anon_id: string; username: string; expires_at: string };
+type BootstrapResp = {
+    token: string;
+    anon_id: string;
+    username: string;
+    expires_at: string;
+    refresh_token?: string;
+    refresh_expires_at?: string;
+};
 type MeResp = { anon_id: string; username: string; expires_at: string };
 
 const DEVICE_PUBLIC_ID_KEY = "ghost_device_public_id";
 const DEVICE_SECRET_KEY = "ghost_device_secret";
+const DEVICE_SIGNING_KEY = "ghost_device_signing_key";
 const USERNAME_KEY = "ghost_username";
 const ANON_ID_KEY = "ghost_anon_id";
 const SESSION_EXPIRY_KEY = "ghost_session_expiry";
+const REFRESH_TOKEN_KEY = "ghost_refresh_token";
 const LEGACY_ANON_ID_KEY = "anon_id";
 const LEGACY_SESSION_EXPIRY_KEY = "session_expiry";
 const SESSION_IDENTITY_EVENT = "ghost-session-identity-updated";
@@ -43,8 +52,16 @@ function getDeviceSecret(): string | null {
     return storage.getJSON<string | null>(DEVICE_SECRET_KEY, null);
 }
 
+// Ed25519 key pair for devices registered with signature auth. The private
+// key never leaves the browser; the server only stores the public half.
+type DeviceSigningKey = { public_key: string; private_key_pkcs8: string };
+
+function getDeviceSigningKey(): DeviceSigningKey | null {
+    return storage.getJSON<DeviceSigningKey | null>(DEVICE_SIGNING_KEY, null);
+}
+
 function hasDeviceKeys(): boolean {
-    return !!getDevicePublicId() && !!getDeviceSecret();
+    return !!getDevicePublicId() && (!!getDeviceSecret() || !!getDeviceSigningKey());
 }
 
 function base64FromBytes(bytes: Uint8Array): string {
@@ -119,19 +136,70 @@ async function computeProof(deviceSecretHash: string, message: string): Promise<
     return base64FromBytes(new Uint8Array(signature));
 }
 
-function ensureDeviceKeys(): { devicePublicId: string; deviceSecret: string } {
-    let devicePublicId = getDevicePublicId();
-    let deviceSecret = getDeviceSecret();
+async function generateDeviceSigningKey(): Promise<DeviceSigningKey | null> {
+    try {
+        const pair = (await crypto.subtle.generateKey({ name: "Ed25519" }, true, [
+            "sign",
+            "verify",
+        ])) as CryptoKeyPair;
+        const publicRaw = await crypto.subtle.exportKey("raw", pair.publicKey);
+        const privatePkcs8 = await crypto.subtle.exportKey("pkcs8", pair.privateKey);
+        return {
+            public_key: base64FromBytes(new Uint8Array(publicRaw)),
+            private_key_pkcs8: base64FromBytes(new Uint8Array(privatePkcs8)),
+        };
+    } catch {
+        // Browser without Ed25519 in WebCrypto: fall back to the HMAC secret.
+        return null;
+    }
+}
 
+async function signWithDeviceKey(signingKey: DeviceSigningKey, message: string): Promise<string> {
+    const keyBytes = bytesFromBase64(signingKey.private_key_pkcs8);
+    const keyBytesSafe = new Uint8Array(keyBytes.length);
+    keyBytesSafe.set(keyBytes);
+    const key = await crypto.subtle.importKey(
+        "pkcs8",
+        keyBytesSafe.buffer,
+        { name: "Ed25519" },
+        false,
+        ["sign"]
+    );
+    const signature = await crypto.subtle.sign("Ed25519", key, new TextEncoder().encode(message));
+    return base64FromBytes(new Uint8Array(signature));
+}
+
+type DeviceKeys =
+    | { devicePublicId: string; signingKey: DeviceSigningKey; deviceSecret?: undefined }
+    | { devicePublicId: string; deviceSecret: string; signingKey?: undefined };
+
+// Devices that already have an HMAC secret keep using it; new devices get an
+// Ed25519 key pair when the browser supports it.
+async function ensureDeviceKeys(): Promise<DeviceKeys> {
+    let devicePublicId = getDevicePublicId();
     if (!devicePublicId) {
         devicePublicId = generateDevicePublicId();
         storage.setJSON(DEVICE_PUBLIC_ID_KEY, devicePublicId);
     }
-    if (!deviceSecret) {
-        deviceSecret = generateDeviceSecret();
-        storage.setJSON(DEVICE_SECRET_KEY, deviceSecret);
+
+    let deviceSecret = getDeviceSecret();
+    if (deviceSecret) {
+        return { devicePublicId, deviceSecret };
+    }
+
+    let signingKey = getDeviceSigningKey();
+    if (!signingKey) {
+        signingKey = await generateDeviceSigningKey();
+        if (signingKey) {
+            storage.setJSON(DEVICE_SIGNING_KEY, signingKey);
+        }
+    }
+    if (signingKey) {
+        return { devicePublicId, signingKey };
     }
 
+    deviceSecret = generateDeviceSecret();
+    storage.setJSON(DEVICE_SECRET_KEY, deviceSecret);
     return { devicePublicId, deviceSecret };
 }
 
@@ -146,14 +214,28 @@ async function requestChallenge(devicePublicId: string): Promise<DeviceChallenge
     );
 }
 
-export async function bootstrapSession(region: string = DEFAULT_REGION): Promise<string> {
-    const { devicePublicId, deviceSecret } = ensureDeviceKeys();
+// Joining an existing identity from a new device: either the account's
+// recovery key, or a link code approved from a signed-in device.
+export type IdentityJoin = { recovery_key: string } | { link_code: string };
+
+export async function bootstrapSession(region: string = DEFAULT_REGION, join?: IdentityJoin): Promise<string> {
+    const keys = await ensureDeviceKeys();
+    const { devicePublicId } = keys;
     const challenge = await requestChallenge(devicePublicId);
 
     const ts = Math.floor(Date.now() / 1000);
-    const deviceSecretHash = await deriveDeviceSecretHash(deviceSecret, devicePublicId);
     const message = `${devicePublicId}|${challenge.nonce}|${ts}`;
-    const proof = await computeProof(deviceSecretHash, message);
+
+    let proof: string;
+    let credentials: { device_secret_hash: string } | { device_public_key: string };
+    if (keys.signingKey) {
+        proof = await signWithDeviceKey(keys.signingKey, message);
+        credentials = { device_public_key: keys.signingKey.public_key };
+    } else {
+        const deviceSecretHash = await deriveDeviceSecretHash(keys.deviceSecret, devicePublicId);
+        proof = await computeProof(deviceSecretHash, message);
+        credentials = { device_secret_hash: deviceSecretHash };
+    }
 
     const res = await apiFetch<BootstrapResp>(
         "/session/bootstrap",
@@ -165,7 +247,8 @@ export async function bootstrapSession(region: string = DEFAULT_REGION): Promise
                 ts,
                 proof,
                 region,
-                device_secret_hash: deviceSecretHash,
+                ...credentials,
+                ...join,
             }),
         },
         { auth: false }
@@ -174,6 +257,9 @@ export async function bootstrapSession(region: string = DEFAULT_REGION): Promise
     setSessionToken(res.token);
     storage.setJSON(ANON_ID_KEY, res.anon_id);
     setStoredUsername(res.username);
+    if (res.refresh_token) {
+        storage.setJSON(REFRESH_TOKEN_KEY, res.refresh_token);
+    }
 
     if (res.expires_at) {
         storage.setJSON(SESSION_EXPIRY_KEY, Date.parse(res.expires_at));
@@ -183,6 +269,77 @@ export async function bootstrapSession(region: string = DEFAULT_REGION): Promise
     return res.token;
 }
 
+export async function createRecoveryKey(): Promise<string> {
+    const res = await apiFetch<{ recovery_key: string; created_at: string }>(
+        "/session/recovery-key",
+        { method: "POST" },
+        { auth: true }
+    );
+    return res.recovery_key;
+}
+
+export async function startDeviceLink(): Promise<{ link_code: string; expires_in_sec: number }> {
+    const { devicePublicId } = await ensureDeviceKeys();
+    return apiFetch<{ link_code: string; expires_in_sec: number }>(
+        "/device/link/start",
+        {
+            method: "POST",
+            body: JSON.stringify({ device_public_id: devicePublicId }),
+        },
+        { auth: false }
+    );
+}
+
+export async function getDeviceLinkStatus(linkCode: string): Promise<"pending" | "approved" | "consumed" | "expired"> {
+    const { devicePublicId } = await ensureDeviceKeys();
+    const res = await apiFetch<{ status: "pending" | "approved" | "consumed" | "expired" }>(
+        `/device/link/${encodeURIComponent(linkCode)}?device_public_id=${encodeURIComponent(devicePublicId)}`,
+        { method: "GET" },
+        { auth: false }
+    );
+    return res.status;
+}
+
+export async function approveDeviceLink(linkCode: string): Promise<void> {
+    await apiFetch(
+        "/device/link/approve",
+        {
+            method: "POST",
+            body: JSON.stringify({ link_code: linkCode }),
+        },
+        { auth: true }
+    );
+}
+
+export type SessionDevice = {
+    id: string;
+    device_public_id?: string;
+    region?: string;
+    user_agent?: string;
+    created_at: string;
+    last_activity_at: string;
+    expires_at: string;
+    current: boolean;
+};
+
+export async function listSessionDevices(): Promise<SessionDevice[]> {
+    const res = await apiFetch<{ sessions: SessionDevice[] }>("/session/devices", { method: "GET" }, { auth: true });
+    return res.sessions;
+}
+
+// Signs out one session, or every session but this one when sessionId is omitted.
+export async function revokeSessions(sessionId?: string): Promise<number> {
+    const res = await apiFetch<{ sessions_revoked: number }>(
+        "/session/revoke",
+        {
+            method: "POST",
+            body: JSON.stringify(sessionId ? { session_id: sessionId } : { others: true }),
+        },
+        { auth: true }
+    );
+    return res.sessions_revoked;
+}
+
 export async function loadSessionMe(): Promise<MeResp> {
     const res = await apiFetch<MeResp>(
         "/session/me",
@@ -255,16 +412,26 @@ export function onSessionIdentityUpdated(listener: () => void): () => void {
 }
 
 export async function refreshSession(): Promise<void> {
+    const refreshToken = storage.getJSON<string | null>(REFRESH_TOKEN_KEY, null);
+    if (!refreshToken) {
+        throw new Error("No refresh token");
+    }
+
     try {
+        // Refresh tokens are single use: the response carries the next one.
         const res = await apiFetch<BootstrapResp>(
             "/session/refresh",
             {
                 method: "POST",
+                body: JSON.stringify({ refresh_token: refreshToken }),
             },
-            { auth: true }
+            { auth: false }
         );
 
         setSessionToken(res.token);
+        if (res.refresh_token) {
+            storage.setJSON(REFRESH_TOKEN_KEY, res.refresh_token);
+        }
         if (res.expires_at) {
             storage.setJSON(SESSION_EXPIRY_KEY, Date.parse(res.expires_at));
         }
@@ -303,12 +470,14 @@ export function clearSession(options: { keepDeviceKeys?: boolean } = {}): void {
     storage.remove(ANON_ID_KEY);
     storage.remove(USERNAME_KEY);
     storage.remove(SESSION_EXPIRY_KEY);
+    storage.remove(REFRESH_TOKEN_KEY);
     setSessionToken(null);
     sessionIsReady = false;
 
     if (!options.keepDeviceKeys) {
         storage.remove(DEVICE_PUBLIC_ID_KEY);
         storage.remove(DEVICE_SECRET_KEY);
+        storage.remove(DEVICE_SIGNING_KEY);
     }
 }
 